@@ -15,6 +15,7 @@
 package beego
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -25,6 +26,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/kfchen81/beego/grace"
@@ -47,15 +49,34 @@ func init() {
 type App struct {
 	Handlers *ControllerRegister
 	Server   *http.Server
+
+	// closed after Shutdown has drained in-flight requests
+	shutdownDone chan struct{}
+	shutdownOnce sync.Once
 }
 
 // NewApp returns a new beego application.
 func NewApp() *App {
 	cr := NewControllerRegister()
-	app := &App{Handlers: cr, Server: &http.Server{}}
+	app := &App{Handlers: cr, Server: &http.Server{}, shutdownDone: make(chan struct{})}
 	return app
 }
 
+// Shutdown gracefully shuts down the server started by Run in normal mode.
+// It stops accepting new connections and waits for in-flight requests until ctx is done,
+// then Run returns.
+func (app *App) Shutdown(ctx context.Context) error {
+	defer app.shutdownOnce.Do(func() { close(app.shutdownDone) })
+	return app.Server.Shutdown(ctx)
+}
+
+// waitShutdown blocks until Shutdown finished if err is caused by Shutdown
+func (app *App) waitShutdown(err error) {
+	if err == http.ErrServerClosed {
+		<-app.shutdownDone
+	}
+}
+
 // MiddleWare function for http.Handler
 type MiddleWare func(http.Handler) http.Handler
 
@@ -204,6 +225,7 @@ func (app *App) Run(mws ...MiddleWare) {
 			}
 			if err := app.Server.ListenAndServeTLS(BConfig.Listen.HTTPSCertFile, BConfig.Listen.HTTPSKeyFile); err != nil {
 				logs.Critical("ListenAndServeTLS: ", err)
+				app.waitShutdown(err)
 				time.Sleep(100 * time.Microsecond)
 				endRunning <- true
 			}
@@ -224,6 +246,7 @@ func (app *App) Run(mws ...MiddleWare) {
 				}
 				if err = app.Server.Serve(ln); err != nil {
 					logs.Critical("ListenAndServe: ", err)
+					app.waitShutdown(err)
 					time.Sleep(100 * time.Microsecond)
 					endRunning <- true
 					return
@@ -231,6 +254,7 @@ func (app *App) Run(mws ...MiddleWare) {
 			} else {
 				if err := app.Server.ListenAndServe(); err != nil {
 					logs.Critical("ListenAndServe: ", err)
+					app.waitShutdown(err)
 					time.Sleep(100 * time.Microsecond)
 					endRunning <- true
 				}
@@ -186,6 +186,10 @@ func errorRenderer(err error) Renderer {
 // if encoding is true, it converts utf-8 to \u0000 type.
 func (output *BeegoOutput) JSON(data interface{}, hasIndent bool, encoding bool) error {
 	output.Header("Content-Type", "application/json; charset=utf-8")
+	if errCode := errCodeOf(data); errCode != "" && output.Context != nil && output.Context.Input != nil {
+		// router records it in endpoint metrics
+		output.Context.Input.SetData("ErrCode", errCode)
+	}
 	var content []byte
 	var err error
 	if hasIndent {
@@ -204,6 +208,24 @@ func (output *BeegoOutput) JSON(data interface{}, hasIndent bool, encoding bool)
 }
 
 
+// ErrCoder is implemented by json responses carrying a business error code.
+type ErrCoder interface {
+	GetErrCode() string
+}
+
+// errCodeOf returns the business error code of a json response, or "" if none.
+func errCodeOf(data interface{}) string {
+	switch d := data.(type) {
+	case ErrCoder:
+		return d.GetErrCode()
+	case map[string]interface{}:
+		if errCode, ok := d["errCode"].(string); ok {
+			return errCode
+		}
+	}
+	return ""
+}
+
 // YAML writes yaml to response body.
 func (output *BeegoOutput) YAML(data interface{}) error {
 	output.Header("Content-Type", "application/x-yaml; charset=utf-8")
@@ -2,16 +2,158 @@ package metrics
 
 import (
 	"fmt"
-	"github.com/kfchen81/beego/orm"
 	"github.com/kfchen81/beego/logs"
+	"github.com/kfchen81/beego/orm"
+	"github.com/prometheus/client_golang/prometheus"
 	_ "github.com/prometheus/client_golang/prometheus/promhttp"
 	"runtime/debug"
+	"sync/atomic"
 	"time"
 )
+
 const _DB_REPORT_INTERVAL = 3
 
-func isEnableDBReportor() bool {
-	return true
+// dbStatsCollector 在prometheus采集时读取每个orm alias的连接池状态
+type dbStatsCollector struct {
+	maxOpen           *prometheus.Desc
+	open              *prometheus.Desc
+	inUse             *prometheus.Desc
+	idle              *prometheus.Desc
+	waitCount         *prometheus.Desc
+	waitDuration      *prometheus.Desc
+	maxIdleClosed     *prometheus.Desc
+	maxLifetimeClosed *prometheus.Desc
+}
+
+func newDBStatsCollector() *dbStatsCollector {
+	labels := []string{"db"}
+	return &dbStatsCollector{
+		maxOpen:           prometheus.NewDesc("db_pool_max_open_connections", "maximum number of open connections to the database", labels, nil),
+		open:              prometheus.NewDesc("db_pool_open_connections", "number of established connections both in use and idle", labels, nil),
+		inUse:             prometheus.NewDesc("db_pool_in_use_connections", "number of connections currently in use", labels, nil),
+		idle:              prometheus.NewDesc("db_pool_idle_connections", "number of idle connections", labels, nil),
+		waitCount:         prometheus.NewDesc("db_pool_wait_count_total", "total number of connections waited for", labels, nil),
+		waitDuration:      prometheus.NewDesc("db_pool_wait_duration_seconds_total", "total time blocked waiting for a new connection", labels, nil),
+		maxIdleClosed:     prometheus.NewDesc("db_pool_max_idle_closed_total", "total number of connections closed due to SetMaxIdleConns", labels, nil),
+		maxLifetimeClosed: prometheus.NewDesc("db_pool_max_lifetime_closed_total", "total number of connections closed due to SetConnMaxLifetime", labels, nil),
+	}
+}
+
+func (this *dbStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- this.maxOpen
+	ch <- this.open
+	ch <- this.inUse
+	ch <- this.idle
+	ch <- this.waitCount
+	ch <- this.waitDuration
+	ch <- this.maxIdleClosed
+	ch <- this.maxLifetimeClosed
+}
+
+func (this *dbStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, dbName := range orm.GetAllDBNames() {
+		db, err := orm.GetDB(dbName)
+		if err != nil {
+			continue
+		}
+		stats := db.Stats()
+		ch <- prometheus.MustNewConstMetric(this.maxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections), dbName)
+		ch <- prometheus.MustNewConstMetric(this.open, prometheus.GaugeValue, float64(stats.OpenConnections), dbName)
+		ch <- prometheus.MustNewConstMetric(this.inUse, prometheus.GaugeValue, float64(stats.InUse), dbName)
+		ch <- prometheus.MustNewConstMetric(this.idle, prometheus.GaugeValue, float64(stats.Idle), dbName)
+		ch <- prometheus.MustNewConstMetric(this.waitCount, prometheus.CounterValue, float64(stats.WaitCount), dbName)
+		ch <- prometheus.MustNewConstMetric(this.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds(), dbName)
+		ch <- prometheus.MustNewConstMetric(this.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed), dbName)
+		ch <- prometheus.MustNewConstMetric(this.maxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed), dbName)
+	}
+}
+
+var dbQueryHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "db_query_duration_seconds",
+	Help:    "duration of sql statements by db alias, operation and table",
+	Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+}, []string{"db", "operation", "table", "result"})
+
+var dbSlowQueryCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "db_slow_query_total",
+	Help: "count of sql statements slower than the slow query threshold",
+}, []string{"db", "operation", "table"})
+
+var dbRowsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "db_rows_returned_total",
+	Help: "total rows read by select statements",
+}, []string{"db", "table"})
+
+var dbLargeResultCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "db_large_result_total",
+	Help: "count of select statements returning more rows than the large result threshold",
+}, []string{"db", "table"})
+
+var dbQueryCacheCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "db_query_cache_total",
+	Help: "count of QuerySeter.Cache lookups by result hit or miss",
+}, []string{"db", "table", "result"})
+
+var dbSlowQueryThreshold int64 = int64(500 * time.Millisecond)
+var dbLargeResultThreshold int64 = 1000
+
+// SetDBQueryThresholds 设置慢查询与大结果集的阈值，阈值<=0时不修改
+func SetDBQueryThresholds(slowQuery time.Duration, largeResultRows int64) {
+	if slowQuery > 0 {
+		atomic.StoreInt64(&dbSlowQueryThreshold, int64(slowQuery))
+	}
+	if largeResultRows > 0 {
+		atomic.StoreInt64(&dbLargeResultThreshold, largeResultRows)
+	}
+}
+
+// dbQueryObserver 将orm的查询记录到prometheus
+type dbQueryObserver struct{}
+
+func (this *dbQueryObserver) ObserveQuery(stats *orm.QueryStats) {
+	result := "ok"
+	if stats.Err != nil {
+		result = "fail"
+	}
+	dbQueryHistogram.WithLabelValues(stats.Alias, stats.Operation, stats.Table, result).Observe(stats.Elapsed.Seconds())
+	if int64(stats.Elapsed) >= atomic.LoadInt64(&dbSlowQueryThreshold) {
+		dbSlowQueryCounter.WithLabelValues(stats.Alias, stats.Operation, stats.Table).Inc()
+	}
+}
+
+func (this *dbQueryObserver) ObserveRows(alias string, table string, rows int64) {
+	dbRowsCounter.WithLabelValues(alias, table).Add(float64(rows))
+	if rows >= atomic.LoadInt64(&dbLargeResultThreshold) {
+		dbLargeResultCounter.WithLabelValues(alias, table).Inc()
+	}
+}
+
+func (this *dbQueryObserver) ObserveQueryCache(alias string, table string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	dbQueryCacheCounter.WithLabelValues(alias, table, result).Inc()
+}
+
+func GetDBQueryHistogram() *prometheus.HistogramVec {
+	return dbQueryHistogram
+}
+
+func GetDBSlowQueryCounter() *prometheus.CounterVec {
+	return dbSlowQueryCounter
+}
+
+func GetDBRowsCounter() *prometheus.CounterVec {
+	return dbRowsCounter
+}
+
+func GetDBLargeResultCounter() *prometheus.CounterVec {
+	return dbLargeResultCounter
+}
+
+func GetDBQueryCacheCounter() *prometheus.CounterVec {
+	return dbQueryCacheCounter
 }
 
 func runReportWorker() {
@@ -29,7 +171,7 @@ func runReportWorker() {
 			GetDBConnectionPoolGauge().WithLabelValues(dbName, "idle").Set(float64(stats.Idle))
 			GetDBConnectionPoolGauge().WithLabelValues(dbName, "in_use").Set(float64(stats.InUse))
 			GetDBConnectionPoolGauge().WithLabelValues(dbName, "wait").Set(float64(stats.WaitCount))
-			GetDBConnectionPoolGauge().WithLabelValues(dbName, "wait_duration").Set(float64(stats.WaitDuration))
+			GetDBConnectionPoolGauge().WithLabelValues(dbName, "wait_duration").Set(stats.WaitDuration.Seconds())
 		}
 	}
 }
@@ -48,15 +190,13 @@ func startReportWorker() {
 	runReportWorker()
 }
 
+// StartDBReportService 启动旧的db_connection_pool_gauge轮询，兼容依赖旧指标的dashboard，
+// 连接池状态同时由采集时读取的collector以db_pool_*提供
 func StartDBReportService() {
-	if isEnableDBReportor() {
-		logs.Info("[db_reportor] enbale report")
-		go startReportWorker()
-	} else {
-		logs.Warn("[db_reportor] DISABLED!!!")
-	}
+	logs.Info("[db_reportor] enbale legacy report")
+	go startReportWorker()
 }
 
 func init() {
-	StartDBReportService()
+	orm.RegisterQueryObserver(new(dbQueryObserver))
 }
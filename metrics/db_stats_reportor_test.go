@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kfchen81/beego/orm"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDBStatsCollector(t *testing.T) {
+	dir, err := os.MkdirTemp("", "metrics_db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := orm.RegisterDataBase("metrics_db", "sqlite3", filepath.Join(dir, "metrics.db"), 2, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	//采集时读取连接池状态
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(newDBStatsCollector())
+	expected := `
+# HELP db_pool_max_open_connections maximum number of open connections to the database
+# TYPE db_pool_max_open_connections gauge
+db_pool_max_open_connections{db="metrics_db"} 5
+# HELP db_pool_in_use_connections number of connections currently in use
+# TYPE db_pool_in_use_connections gauge
+db_pool_in_use_connections{db="metrics_db"} 0
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "db_pool_max_open_connections", "db_pool_in_use_connections"); err != nil {
+		t.Error(err)
+	}
+}
@@ -3,11 +3,17 @@ package metrics
 import (
 	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	_ "github.com/prometheus/client_golang/prometheus/promhttp"
+	"sync"
 )
 
-var endpointCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+//ERR_CODE_OTHER 未注册的错误码在指标中的err_code
+const ERR_CODE_OTHER = "other"
+
+var errCodeLock sync.RWMutex
+var knownErrCodes = make(map[string]bool)
+
+var endpointCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 	Name: "endpoint_call_total",
 	Help: "total counts for panic",
 },
@@ -15,7 +21,7 @@ var endpointCounter = promauto.NewCounterVec(prometheus.CounterOpts{
 )
 
 /*
-var endpointSummary = promauto.NewSummaryVec(
+var endpointSummary = prometheus.NewSummaryVec(
 	prometheus.SummaryOpts{
 		Name:       "endpoint_durations_seconds",
 		Help:       "endpoint latency distributions.",
@@ -26,7 +32,7 @@ var endpointSummary = promauto.NewSummaryVec(
 
 //var normDomain = 0.0002
 //var normMean = 0.00001
-var endpointHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+var endpointHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 	Name: "endpoint_durations_histogram_seconds",
 	Help: "endpoint latency distributions.",
 	//Buckets: prometheus.LinearBuckets(normMean-5*normDomain, .5*normDomain, 20),
@@ -34,95 +40,144 @@ var endpointHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
 	[]string{"endpoint", "method"},
 )
 
-var panicCounter = promauto.NewCounter(prometheus.CounterOpts{
+var endpointRequestHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name: "endpoint_request_duration_seconds",
+	Help: "latency of requests by route pattern, method, status and errCode",
+}, []string{"endpoint", "method", "status", "err_code"})
+
+var endpointRequestCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "endpoint_requests_total",
+	Help: "count of requests by route pattern, method, status and errCode",
+}, []string{"endpoint", "method", "status", "err_code"})
+
+var endpointInFlightGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "endpoint_in_flight_requests",
+	Help: "number of requests being served",
+})
+
+var panicCounter = prometheus.NewCounter(prometheus.CounterOpts{
 	Name: "panic_total",
 	Help: "total counts for panic",
 })
 
-var sentryChannelErrorCounter = promauto.NewCounter(prometheus.CounterOpts{
+var sentryChannelErrorCounter = prometheus.NewCounter(prometheus.CounterOpts{
 	Name: "sentry_channel_error_total",
 	Help: "total error counts for sentry channel",
 })
 
-var sentryChannelUnreadGauge = promauto.NewGauge(prometheus.GaugeOpts{
+var sentryChannelUnreadGauge = prometheus.NewGauge(prometheus.GaugeOpts{
 	Name: "sentry_channel_unread",
 	Help: "unread counts for sentry channel",
 })
 
-var sentryChannelTimeoutCounter = promauto.NewCounter(prometheus.CounterOpts{
+var sentryChannelTimeoutCounter = prometheus.NewCounter(prometheus.CounterOpts{
 	Name: "sentry_channel_timeout_total",
 	Help: "timeout counts for sentry channel",
 })
 
-var resourceRetryCounter = promauto.NewCounter(prometheus.CounterOpts{
+var resourceRetryCounter = prometheus.NewCounter(prometheus.CounterOpts{
 	Name: "resource_retry_total",
 	Help: "total counts for resource's retry",
 })
 
-var businessErrorCounter = promauto.NewCounter(prometheus.CounterOpts{
+var businessErrorCounter = prometheus.NewCounter(prometheus.CounterOpts{
 	Name: "business_error_total",
 	Help: "total counts for business error",
 })
 
-var restwsGauge = promauto.NewGauge(prometheus.GaugeOpts{
+var restwsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
 	Name: "restws_connection",
 	Help: "Number of rest proxy websocket connection is active",
 })
 
-var restwsErrorCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+var restwsErrorCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 	Name: "restws_error_total",
 	Help: "total counts for rest proxy error",
 },
 	[]string{"option"},
 )
 
-var errorJwtInCacheCounter = promauto.NewCounter(prometheus.CounterOpts{
+var restwsMessageCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "restws_message_total",
+	Help: "total counts for rest proxy message",
+},
+	[]string{"type"},
+)
+
+var restwsPushCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "restws_push_total",
+	Help: "total counts for events pushed by rest proxy hub",
+},
+	[]string{"result"},
+)
+
+var errorJwtInCacheCounter = prometheus.NewCounter(prometheus.CounterOpts{
 	Name: "error_jwt_in_cache_count",
 	Help: "count of get error jwt from cache",
 })
 
-var lruCacheCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+var lruCacheCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 	Name: "lru_cache_counter",
 	Help: "Number of operations on the lru cache",
 },
 	[]string{"name", "operation"},
 )
 
-var esRequestTimer = promauto.NewHistogramVec(prometheus.HistogramOpts{
+var esRequestTimer = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 	Name: "es_request_timer",
 	Help: "the time of a es request",
 }, []string{"index", "action"})
 
-var taChannelIsFullCounter = promauto.NewCounter(prometheus.CounterOpts{
+var taChannelIsFullCounter = prometheus.NewCounter(prometheus.CounterOpts{
 	Name: "ta_channel_is_full_counter",
 	Help: "count when ta channel is full",
 })
 
-var taTracedDataCounter = promauto.NewCounter(prometheus.CounterOpts{
+var taTracedDataCounter = prometheus.NewCounter(prometheus.CounterOpts{
 	Name: "ta_traced_data_counter",
 	Help: "data count that ta traced",
 })
 
-var taConsumerCounter = promauto.NewGauge(prometheus.GaugeOpts{
+var taConsumerCounter = prometheus.NewGauge(prometheus.GaugeOpts{
 	Name: "ta_consumer_counter",
 	Help: "count of ta consumer",
 })
 
-var taServerPushCounter = promauto.NewGaugeVec(prometheus.GaugeOpts{
+var taServerPushCounter = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 	Name: "ta_server_push_counter",
 	Help: "count of ta pushed times and failed times",
 }, []string{"name"})
 
-var taServerPushTimer = promauto.NewHistogram(prometheus.HistogramOpts{
+var taServerPushTimer = prometheus.NewHistogram(prometheus.HistogramOpts{
 	Name: "ta_server_push_timer",
 	Help: "using time of ta server push",
 })
 
-var dbConnectionPoolGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+var dbConnectionPoolGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 	Name: "db_connection_pool_gauge",
 	Help: "count of ta pushed times and failed times",
 }, []string{"db", "type"})
 
+var alertCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "alert_total",
+	Help: "count of alerts by channel and result",
+}, []string{"channel", "result"})
+
+var healthCheckGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "health_check_status",
+	Help: "result of dependency health check, 1 for ok and 0 for fail",
+}, []string{"name"})
+
+var requestModeCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "request_mode_total",
+	Help: "count of requests by request mode",
+}, []string{"mode"})
+
+var legacyCorpTokenCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "legacy_corp_token_total",
+	Help: "count of corp tokens in deprecated legacy format by action",
+}, []string{"action"})
+
 func GetEsRequestTimer() *prometheus.HistogramVec{
 	return esRequestTimer
 }
@@ -163,14 +218,74 @@ func GetRestwsErrorCounter() *prometheus.CounterVec {
 	return restwsErrorCounter
 }
 
+func GetRestwsMessageCounter() *prometheus.CounterVec {
+	return restwsMessageCounter
+}
+
+func GetRestwsPushCounter() *prometheus.CounterVec {
+	return restwsPushCounter
+}
+
+func GetAlertCounter() *prometheus.CounterVec {
+	return alertCounter
+}
+
+func GetHealthCheckGauge() *prometheus.GaugeVec {
+	return healthCheckGauge
+}
+
+func GetRequestModeCounter() *prometheus.CounterVec {
+	return requestModeCounter
+}
+
+func GetLegacyCorpTokenCounter() *prometheus.CounterVec {
+	return legacyCorpTokenCounter
+}
+
+// RegisterErrCode 注册指标中可以出现的err_code，未注册的错误码记为other，避免label的基数无限增长
+func RegisterErrCode(codes ...string) {
+	errCodeLock.Lock()
+	defer errCodeLock.Unlock()
+	for _, code := range codes {
+		knownErrCodes[code] = true
+	}
+}
+
+// NormalizeErrCode 返回指标中的err_code，没有错误码时为空，未注册的错误码为other
+func NormalizeErrCode(code string) string {
+	if code == "" {
+		return ""
+	}
+	errCodeLock.RLock()
+	defer errCodeLock.RUnlock()
+	if knownErrCodes[code] {
+		return code
+	}
+	return ERR_CODE_OTHER
+}
+
+// Deprecated: endpoint_call_total只统计RestResource的请求，使用GetEndpointRequestCounter
 func GetEndpointCounter() *prometheus.CounterVec {
 	return endpointCounter
 }
 
+func GetEndpointRequestHistogram() *prometheus.HistogramVec {
+	return endpointRequestHistogram
+}
+
+func GetEndpointRequestCounter() *prometheus.CounterVec {
+	return endpointRequestCounter
+}
+
+func GetEndpointInFlightGauge() prometheus.Gauge {
+	return endpointInFlightGauge
+}
+
 func GetEndpointSummary() *prometheus.SummaryVec {
 	return nil
 }
 
+// Deprecated: endpoint_durations_histogram_seconds的endpoint为请求的url，使用GetEndpointRequestHistogram
 func GetEndpointHistogram() *prometheus.HistogramVec {
 	return endpointHistogram
 }
@@ -191,6 +306,11 @@ func GetSentryChannelErrorCounter() prometheus.Counter {
 	return sentryChannelErrorCounter
 }
 
+func GetSentryChannelUnreadGauge() prometheus.Gauge {
+	return sentryChannelUnreadGauge
+}
+
+// Deprecated: use GetSentryChannelUnreadGauge
 func GetSentryChannelUnreadGuage() prometheus.Gauge {
 	return sentryChannelUnreadGauge
 }
@@ -203,6 +323,46 @@ func GetDBConnectionPoolGauge() *prometheus.GaugeVec {
 	return dbConnectionPoolGauge
 }
 
+// builtinCollectors 框架内置的指标，默认注册到prometheus的默认registry，Configure时迁移
+func builtinCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		endpointCounter,
+		endpointHistogram,
+		endpointRequestHistogram,
+		endpointRequestCounter,
+		endpointInFlightGauge,
+		panicCounter,
+		sentryChannelErrorCounter,
+		sentryChannelUnreadGauge,
+		sentryChannelTimeoutCounter,
+		resourceRetryCounter,
+		businessErrorCounter,
+		restwsGauge,
+		restwsErrorCounter,
+		restwsMessageCounter,
+		restwsPushCounter,
+		errorJwtInCacheCounter,
+		lruCacheCounter,
+		esRequestTimer,
+		taChannelIsFullCounter,
+		taTracedDataCounter,
+		taConsumerCounter,
+		taServerPushCounter,
+		taServerPushTimer,
+		dbConnectionPoolGauge,
+		alertCounter,
+		healthCheckGauge,
+		requestModeCounter,
+		legacyCorpTokenCounter,
+		newDBStatsCollector(),
+		dbQueryHistogram,
+		dbSlowQueryCounter,
+		dbRowsCounter,
+		dbLargeResultCounter,
+		dbQueryCacheCounter,
+	}
+}
+
 func init() {
 	fmt.Println("in metrics init")
 }
@@ -0,0 +1,236 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kfchen81/beego/logs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Options 指标的全局配置
+type Options struct {
+	Namespace   string            //所有指标名的前缀，如namespace_endpoint_call_total
+	ConstLabels map[string]string //所有指标都带上的label，如service、pod、k8s_env
+	Registerer  prometheus.Registerer //为nil时使用prometheus.DefaultRegisterer
+	Gatherer    prometheus.Gatherer   //为nil时，Registerer同时是Gatherer(如*prometheus.Registry)则使用Registerer，否则使用prometheus.DefaultGatherer
+
+	PushURL      string        //Pushgateway地址，为空时不推送
+	PushJob      string        //推送时的job名
+	PushInterval time.Duration //定时推送的间隔，为0时只在调用Push时推送
+}
+
+//配置的来源，高级别的配置可以覆盖低级别的配置
+const (
+	configLevelNone    = iota //未配置，使用prometheus的默认registry
+	configLevelDefault        //按配置文件的默认配置，由vanilla调用ConfigureDefault
+	configLevelApp            //应用调用Configure
+)
+
+type facade struct {
+	lock       sync.Mutex
+	level      int
+	options    Options
+	registerer prometheus.Registerer
+	gatherer   prometheus.Gatherer
+	collectors []prometheus.Collector //已注册的内置指标与自定义指标，重新配置时迁移到新的registry
+	stopPush   chan struct{}
+}
+
+var defaultFacade = newFacade()
+
+//newFacade 内置指标先注册到prometheus的默认registry，未调用Configure时也能采集
+func newFacade() *facade {
+	f := &facade{
+		registerer: prometheus.DefaultRegisterer,
+		gatherer:   prometheus.DefaultGatherer,
+	}
+	for _, collector := range builtinCollectors() {
+		if err := f.registerer.Register(collector); err != nil {
+			logs.Error(fmt.Sprintf("[metrics] register collector failed: %s", err.Error()))
+			continue
+		}
+		f.collectors = append(f.collectors, collector)
+	}
+	return f
+}
+
+// Configure 设置namespace、const labels与registry，内置指标与已注册的自定义指标会迁移到新的registry
+// 应在程序启动时调用，可以覆盖vanilla按metrics::*配置调用的ConfigureDefault，但只能调用一次
+func Configure(options Options) error {
+	return defaultFacade.configure(options, configLevelApp)
+}
+
+// ConfigureDefault 按配置文件设置默认配置，应用调用Configure后不再生效
+func ConfigureDefault(options Options) error {
+	return defaultFacade.configure(options, configLevelDefault)
+}
+
+func (this *facade) configure(options Options, level int) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.level == configLevelApp && level == configLevelApp {
+		return errors.New("metrics: already configured")
+	}
+	if this.level > level {
+		return nil
+	}
+
+	if options.Registerer == nil {
+		options.Registerer = prometheus.DefaultRegisterer
+	}
+	if options.Gatherer == nil {
+		//只设置了Registerer时从同一个registry采集，否则/metrics与推送会看不到指标
+		if gatherer, ok := options.Registerer.(prometheus.Gatherer); ok {
+			options.Gatherer = gatherer
+		} else {
+			options.Gatherer = prometheus.DefaultGatherer
+		}
+	}
+	registerer := options.Registerer
+	if len(options.ConstLabels) > 0 {
+		registerer = prometheus.WrapRegistererWith(prometheus.Labels(options.ConstLabels), registerer)
+	}
+	if options.Namespace != "" {
+		registerer = prometheus.WrapRegistererWithPrefix(options.Namespace+"_", registerer)
+	}
+
+	//从原来的registry迁移到新的registry
+	for _, collector := range this.collectors {
+		this.registerer.Unregister(collector)
+	}
+	collectors := make([]prometheus.Collector, 0, len(this.collectors))
+	for _, collector := range this.collectors {
+		if err := registerer.Register(collector); err != nil {
+			logs.Error(fmt.Sprintf("[metrics] register collector failed: %s", err.Error()))
+			continue
+		}
+		collectors = append(collectors, collector)
+	}
+	this.collectors = collectors
+	this.options = options
+	this.registerer = registerer
+	this.gatherer = options.Gatherer
+	this.level = level
+
+	if this.stopPush != nil {
+		close(this.stopPush)
+		this.stopPush = nil
+	}
+	if options.PushURL != "" && options.PushInterval > 0 {
+		this.stopPush = make(chan struct{})
+		go this.runPush(options.PushInterval, this.stopPush)
+	}
+	return nil
+}
+
+// Register 注册自定义指标，重新配置时会迁移到新的registry
+func Register(collector prometheus.Collector) error {
+	return defaultFacade.register(collector)
+}
+
+// MustRegister 注册自定义指标，失败时panic
+func MustRegister(collectors ...prometheus.Collector) {
+	for _, collector := range collectors {
+		if err := Register(collector); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (this *facade) register(collector prometheus.Collector) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if err := this.registerer.Register(collector); err != nil {
+		return err
+	}
+	this.collectors = append(this.collectors, collector)
+	return nil
+}
+
+// registerOrExisting 注册指标，已注册相同的指标时返回已注册的指标
+func registerOrExisting(collector prometheus.Collector) prometheus.Collector {
+	if err := Register(collector); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+		panic(err)
+	}
+	return collector
+}
+
+// NewCounter 创建并注册counter，名字会加上namespace前缀
+func NewCounter(opts prometheus.CounterOpts) prometheus.Counter {
+	return registerOrExisting(prometheus.NewCounter(opts)).(prometheus.Counter)
+}
+
+// NewCounterVec 创建并注册counter vec
+func NewCounterVec(opts prometheus.CounterOpts, labelNames []string) *prometheus.CounterVec {
+	return registerOrExisting(prometheus.NewCounterVec(opts, labelNames)).(*prometheus.CounterVec)
+}
+
+// NewGauge 创建并注册gauge
+func NewGauge(opts prometheus.GaugeOpts) prometheus.Gauge {
+	return registerOrExisting(prometheus.NewGauge(opts)).(prometheus.Gauge)
+}
+
+// NewGaugeVec 创建并注册gauge vec
+func NewGaugeVec(opts prometheus.GaugeOpts, labelNames []string) *prometheus.GaugeVec {
+	return registerOrExisting(prometheus.NewGaugeVec(opts, labelNames)).(*prometheus.GaugeVec)
+}
+
+// NewHistogram 创建并注册histogram
+func NewHistogram(opts prometheus.HistogramOpts) prometheus.Histogram {
+	return registerOrExisting(prometheus.NewHistogram(opts)).(prometheus.Histogram)
+}
+
+// NewHistogramVec 创建并注册histogram vec
+func NewHistogramVec(opts prometheus.HistogramOpts, labelNames []string) *prometheus.HistogramVec {
+	return registerOrExisting(prometheus.NewHistogramVec(opts, labelNames)).(*prometheus.HistogramVec)
+}
+
+// Handler 返回暴露指标的http handler
+// 每次请求时读取当前的gatherer，在Configure之前创建的handler也能使用Configure设置的registry
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defaultFacade.lock.Lock()
+		gatherer := defaultFacade.gatherer
+		defaultFacade.lock.Unlock()
+		promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
+
+// Push 将指标推送到Pushgateway，适用于cron等短生命周期的任务在退出前调用
+func Push() error {
+	return defaultFacade.push()
+}
+
+func (this *facade) push() error {
+	this.lock.Lock()
+	options := this.options
+	gatherer := this.gatherer
+	this.lock.Unlock()
+	if options.PushURL == "" {
+		return errors.New("metrics: push url is not configured")
+	}
+	return push.New(options.PushURL, options.PushJob).Gatherer(gatherer).Push()
+}
+
+func (this *facade) runPush(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := this.push(); err != nil {
+				logs.Warn(fmt.Sprintf("[metrics] push failed: %s", err.Error()))
+			}
+		}
+	}
+}
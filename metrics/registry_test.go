@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func hasMetric(t *testing.T, gatherer prometheus.Gatherer, name string) bool {
+	families, err := gatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestConfigure(t *testing.T) {
+	//未调用Configure时注册到默认registry
+	if !hasMetric(t, prometheus.DefaultGatherer, "panic_total") {
+		t.Fatal("builtin metrics should be registered to default registry")
+	}
+	NewCounter(prometheus.CounterOpts{Name: "test_custom_total", Help: "test"})
+	if !hasMetric(t, prometheus.DefaultGatherer, "test_custom_total") {
+		t.Fatal("custom metrics should be registered to default registry")
+	}
+
+	//按配置文件的默认配置
+	if err := ConfigureDefault(Options{Namespace: "vd"}); err != nil {
+		t.Fatal(err)
+	}
+	if !hasMetric(t, prometheus.DefaultGatherer, "vd_panic_total") || hasMetric(t, prometheus.DefaultGatherer, "panic_total") {
+		t.Fatal("builtin metrics should be moved to namespace vd")
+	}
+
+	//应用的配置覆盖默认配置
+	registry := prometheus.NewRegistry()
+	//未设置Gatherer时从Registerer采集
+	if err := Configure(Options{Namespace: "app", Registerer: registry}); err != nil {
+		t.Fatal(err)
+	}
+	if defaultFacade.gatherer != prometheus.Gatherer(registry) {
+		t.Error("gatherer should default to the registerer")
+	}
+	for _, name := range []string{"app_panic_total", "app_test_custom_total"} {
+		if !hasMetric(t, registry, name) {
+			t.Errorf("%s should be registered to app registry", name)
+		}
+	}
+	if hasMetric(t, prometheus.DefaultGatherer, "vd_panic_total") || hasMetric(t, prometheus.DefaultGatherer, "vd_test_custom_total") {
+		t.Error("metrics should be unregistered from default registry")
+	}
+
+	if err := ConfigureDefault(Options{Namespace: "vd"}); err != nil || !hasMetric(t, registry, "app_panic_total") {
+		t.Error("default options should not override app options")
+	}
+	if err := Configure(Options{}); err == nil {
+		t.Error("configure twice should fail")
+	}
+}
@@ -29,4 +29,11 @@
 // //Foreign Columns, single columns are only supported, SetOnDelete & SetOnUpdate are available, call appropriately.
 // //Supports standard column methods, automatic reverse.
 // m.ForeignCol("local_col","foreign_col","foreign_table")
+//
+// To generate migrations from the changes of registered models instead of writing them by hand,
+// use the orm commands, which work on MySQL, PostgreSQL, SQLite and TiDB:
+//
+//	./app orm makemigrations -name add_user_email -dry-run
+//	./app orm migrate
+//	./app orm migrate -down 1
 package migration
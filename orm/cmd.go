@@ -34,9 +34,11 @@ var (
 func printHelp(errs ...string) {
 	content := `orm command usage:
 
-    syncdb     - auto create tables
-    sqlall     - print sql of create tables
-    help       - print this help
+    syncdb         - auto create tables
+    sqlall         - print sql of create tables
+    makemigrations - generate migration file of model changes
+    migrate        - apply migration files
+    help           - print this help
 `
 
 	if len(errs) > 0 {
@@ -63,7 +65,9 @@ func RunCommand() {
 
 	if cmd, ok := commands[name]; ok {
 		cmd.Parse(os.Args[3:])
-		cmd.Run()
+		if err := cmd.Run(); err != nil {
+			os.Exit(1)
+		}
 		os.Exit(0)
 	} else {
 		if name == "" {
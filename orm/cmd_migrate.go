@@ -0,0 +1,463 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// the layout of timestamp prefix of migration file name, the same as migration.DateFormat
+const migrationDateFormat = "20060102_150405"
+
+const (
+	migrationUpMarker   = "-- +migrate Up"
+	migrationDownMarker = "-- +migrate Down"
+)
+
+var (
+	// MigrationTable is the table recording applied migrations
+	MigrationTable = "orm_migrations"
+	// MigrationLockTable is the table used as a lock, so only one process runs migrations
+	MigrationLockTable = "orm_migrations_lock"
+
+	// ErrMigrationLocked is returned when another process is running migrations
+	ErrMigrationLocked = errors.New("<orm.migrate> migrations are locked by another process")
+
+	migrationNameRe = regexp.MustCompile(`^[0-9a-zA-Z_]+$`)
+)
+
+// a migration file with sqls of Up and Down
+type migrationFile struct {
+	name string
+	path string
+	up   []string
+	down []string
+}
+
+// render migration file content of schema changes
+func renderMigration(name string, changes []schemaChange) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- migration %s generated by orm makemigrations\n\n", name)
+	b.WriteString(migrationUpMarker + "\n")
+	for _, change := range changes {
+		b.WriteString("\n-- " + change.desc + "\n")
+		for _, sql := range change.up {
+			b.WriteString(strings.TrimRight(sql, ";") + ";\n")
+		}
+	}
+	b.WriteString("\n" + migrationDownMarker + "\n")
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		b.WriteString("\n-- revert " + change.desc + "\n")
+		for _, sql := range change.down {
+			b.WriteString(strings.TrimRight(sql, ";") + ";\n")
+		}
+	}
+	return b.String()
+}
+
+// parse migration file content, a statement ends with ";" at the end of line.
+func parseMigration(name, content string) (*migrationFile, error) {
+	m := &migrationFile{name: name}
+	var current *[]string
+	var stmt []string
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == migrationUpMarker:
+			current = &m.up
+			continue
+		case trimmed == migrationDownMarker:
+			current = &m.down
+			continue
+		case trimmed == "" && len(stmt) == 0, strings.HasPrefix(trimmed, "--"):
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("migration `%s`: statement before %s", name, migrationUpMarker)
+		}
+		stmt = append(stmt, line)
+		if strings.HasSuffix(trimmed, ";") {
+			sql := strings.TrimSpace(strings.Join(stmt, "\n"))
+			*current = append(*current, strings.TrimRight(sql, ";"))
+			stmt = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(stmt) > 0 {
+		return nil, fmt.Errorf("migration `%s`: statement not ended with `;`", name)
+	}
+	return m, nil
+}
+
+// read all migration files in dir, ordered by name
+func readMigrations(dir string) ([]*migrationFile, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	migrations := make([]*migrationFile, 0, len(paths))
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		m, err := parseMigration(strings.TrimSuffix(filepath.Base(path), ".sql"), string(content))
+		if err != nil {
+			return nil, err
+		}
+		m.path = path
+		migrations = append(migrations, m)
+	}
+	return migrations, nil
+}
+
+// migration runner of a database alias
+type migrator struct {
+	al     *alias
+	dir    string
+	dryRun bool
+	out    func(format string, args ...interface{})
+}
+
+func newMigrator(name, dir string, dryRun bool) *migrator {
+	BootStrap()
+	return &migrator{
+		al:     getDbAlias(name),
+		dir:    dir,
+		dryRun: dryRun,
+		out: func(format string, args ...interface{}) {
+			fmt.Printf(format, args...)
+		},
+	}
+}
+
+// create the table recording applied migrations
+func (m *migrator) ensureTable() error {
+	Q := m.al.DbBaser.TableQuote()
+	T := m.al.DbBaser.DbTypes()
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s%s%s (%sname%s %s NOT NULL PRIMARY KEY, %sapplied_at%s %s NOT NULL)",
+		Q, MigrationTable, Q, Q, Q, fmt.Sprintf(T["string"], 255), Q, Q, T["time.Time"])
+	_, err := m.al.DB.Exec(query)
+	return err
+}
+
+// get names of applied migrations
+func (m *migrator) applied() (map[string]bool, error) {
+	tables, err := m.al.DbBaser.GetTables(m.al.DB)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[string]bool)
+	if !tables[MigrationTable] {
+		return applied, nil
+	}
+	Q := m.al.DbBaser.TableQuote()
+	rows, err := m.al.DB.Query(fmt.Sprintf("SELECT %sname%s FROM %s%s%s", Q, Q, Q, MigrationTable, Q))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		applied[name] = true
+	}
+	return applied, rows.Err()
+}
+
+// take the migration lock by inserting the only row of lock table
+func (m *migrator) lock() error {
+	Q := m.al.DbBaser.TableQuote()
+	T := m.al.DbBaser.DbTypes()
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s%s%s (%sid%s %s NOT NULL PRIMARY KEY, %sowner%s %s NOT NULL, %slocked_at%s %s NOT NULL)",
+		Q, MigrationLockTable, Q, Q, Q, T["int32"], Q, Q, fmt.Sprintf(T["string"], 255), Q, Q, T["time.Time"])
+	if _, err := m.al.DB.Exec(query); err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d", hostname, os.Getpid())
+	query = fmt.Sprintf("INSERT INTO %s%s%s (%sid%s, %sowner%s, %slocked_at%s) VALUES (?, ?, ?)", Q, MigrationLockTable, Q, Q, Q, Q, Q, Q, Q)
+	m.al.DbBaser.ReplaceMarks(&query)
+	if _, err := m.al.DB.Exec(query, 1, owner, time.Now()); err != nil {
+		query = fmt.Sprintf("SELECT %sowner%s FROM %s%s%s WHERE %sid%s = 1", Q, Q, Q, MigrationLockTable, Q, Q, Q)
+		if m.al.DB.QueryRow(query).Scan(&owner) == nil {
+			return fmt.Errorf("%s: `%s`, delete the row of table `%s` if the process is gone", ErrMigrationLocked.Error(), owner, MigrationLockTable)
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *migrator) unlock() error {
+	Q := m.al.DbBaser.TableQuote()
+	_, err := m.al.DB.Exec(fmt.Sprintf("DELETE FROM %s%s%s WHERE %sid%s = 1", Q, MigrationLockTable, Q, Q, Q))
+	return err
+}
+
+// run sqls of a migration and record it in a transaction,
+// note that mysql commits DDL statements implicitly.
+func (m *migrator) run(name string, sqls []string, up bool) error {
+	Q := m.al.DbBaser.TableQuote()
+	for _, sql := range sqls {
+		m.out("    %s;\n", strings.Replace(sql, "\n", "\n    ", -1))
+	}
+	if m.dryRun {
+		return nil
+	}
+
+	tx, err := m.al.DB.Begin()
+	if err != nil {
+		return err
+	}
+	for _, sql := range sqls {
+		if _, err := tx.Exec(sql); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration `%s` failed: %s", name, err.Error())
+		}
+	}
+	var query string
+	var args []interface{}
+	if up {
+		query = fmt.Sprintf("INSERT INTO %s%s%s (%sname%s, %sapplied_at%s) VALUES (?, ?)", Q, MigrationTable, Q, Q, Q, Q, Q)
+		args = []interface{}{name, time.Now()}
+	} else {
+		query = fmt.Sprintf("DELETE FROM %s%s%s WHERE %sname%s = ?", Q, MigrationTable, Q, Q, Q)
+		args = []interface{}{name}
+	}
+	m.al.DbBaser.ReplaceMarks(&query)
+	if _, err := tx.Exec(query, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// apply migrations not applied yet, or revert the last steps applied migrations if down > 0
+func (m *migrator) migrate(down int) (err error) {
+	migrations, err := readMigrations(m.dir)
+	if err != nil {
+		return err
+	}
+	if !m.dryRun {
+		if err := m.ensureTable(); err != nil {
+			return err
+		}
+		if err := m.lock(); err != nil {
+			return err
+		}
+		defer func() {
+			if e := m.unlock(); e != nil && err == nil {
+				err = e
+			}
+		}()
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+
+	if down > 0 {
+		for i := len(migrations) - 1; i >= 0 && down > 0; i-- {
+			mf := migrations[i]
+			if !applied[mf.name] {
+				continue
+			}
+			m.out("revert migration `%s`\n", mf.name)
+			if err := m.run(mf.name, mf.down, false); err != nil {
+				return err
+			}
+			down--
+		}
+		return nil
+	}
+
+	count := 0
+	for _, mf := range migrations {
+		if applied[mf.name] {
+			continue
+		}
+		m.out("apply migration `%s`\n", mf.name)
+		if err := m.run(mf.name, mf.up, true); err != nil {
+			return err
+		}
+		count++
+	}
+	if count == 0 {
+		m.out("no migration to apply\n")
+	}
+	return nil
+}
+
+// generate migration file of the changes between models and database, return the file path.
+// an empty path is returned if nothing changed.
+func (m *migrator) makeMigration(name string) (string, error) {
+	if !migrationNameRe.MatchString(name) {
+		return "", fmt.Errorf("migration name `%s` should only contain letters, digits and _", name)
+	}
+	migrations, err := readMigrations(m.dir)
+	if err != nil {
+		return "", err
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return "", err
+	}
+	for _, mf := range migrations {
+		if !applied[mf.name] {
+			return "", fmt.Errorf("migration `%s` is not applied, run migrate before makemigrations", mf.name)
+		}
+	}
+
+	changes, err := getSchemaChanges(m.al, m.al.DB)
+	if err != nil {
+		return "", err
+	}
+	if len(changes) == 0 {
+		m.out("no changes detected\n")
+		return "", nil
+	}
+
+	name = time.Now().Format(migrationDateFormat) + "_" + name
+	content := renderMigration(name, changes)
+	path := filepath.Join(m.dir, name+".sql")
+	if m.dryRun {
+		m.out("%s", content)
+		return path, nil
+	}
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	m.out("create migration %s\n", path)
+	for _, change := range changes {
+		m.out("    %s\n", change.desc)
+	}
+	return path, nil
+}
+
+// make migrations command interface.
+type commandMakeMigrations struct {
+	m    *migrator
+	name string
+}
+
+// parse orm command line arguments.
+func (d *commandMakeMigrations) Parse(args []string) {
+	var db, dir string
+	var dryRun bool
+
+	flagSet := flag.NewFlagSet("orm command: makemigrations", flag.ExitOnError)
+	flagSet.StringVar(&db, "db", "default", "DataBase alias name")
+	flagSet.StringVar(&dir, "dir", "migrations", "directory of migration files")
+	flagSet.StringVar(&d.name, "name", "auto", "name of migration")
+	flagSet.BoolVar(&dryRun, "dry-run", false, "print migration instead of writing file")
+	flagSet.Parse(args)
+
+	d.m = newMigrator(db, dir, dryRun)
+}
+
+// run orm line command.
+func (d *commandMakeMigrations) Run() error {
+	_, err := d.m.makeMigration(d.name)
+	if err != nil {
+		fmt.Printf("    %s\n", err.Error())
+	}
+	return err
+}
+
+// migrate command interface.
+type commandMigrate struct {
+	m    *migrator
+	down int
+}
+
+// parse orm command line arguments.
+func (d *commandMigrate) Parse(args []string) {
+	var db, dir string
+	var dryRun bool
+
+	flagSet := flag.NewFlagSet("orm command: migrate", flag.ExitOnError)
+	flagSet.StringVar(&db, "db", "default", "DataBase alias name")
+	flagSet.StringVar(&dir, "dir", "migrations", "directory of migration files")
+	flagSet.IntVar(&d.down, "down", 0, "revert the last n applied migrations")
+	flagSet.BoolVar(&dryRun, "dry-run", false, "print sqls without executing")
+	flagSet.Parse(args)
+
+	d.m = newMigrator(db, dir, dryRun)
+}
+
+// run orm line command.
+func (d *commandMigrate) Run() error {
+	err := d.m.migrate(d.down)
+	if err != nil {
+		fmt.Printf("    %s\n", err.Error())
+	}
+	return err
+}
+
+func init() {
+	commands["makemigrations"] = new(commandMakeMigrations)
+	commands["migrate"] = new(commandMigrate)
+}
+
+// RunMakeMigrations compare registered models to database and write the changes to a timestamped sql file in dir.
+// name means database alias name, migrationName is appended to the file name.
+// dryRun means print the migration instead of writing the file.
+// it returns the path of file, or empty if nothing changed.
+//
+// new tables, new columns, column type and nullability changes, new indexes and indexes dropped from models are found.
+// rename a column with tag `orm:"rename(old_column)"`, otherwise a new column is added.
+// columns and tables removed from models are kept in database.
+// unique indexes are not compared, add or drop them in the migration by hand.
+func RunMakeMigrations(name, dir, migrationName string, dryRun bool) (string, error) {
+	return newMigrator(name, dir, dryRun).makeMigration(migrationName)
+}
+
+// RunMigrate apply the migration files in dir not applied yet, in the order of file name.
+// applied migrations are recorded in MigrationTable, and MigrationLockTable makes sure only one process runs.
+// dryRun means print the sqls without executing.
+func RunMigrate(name, dir string, dryRun bool) error {
+	return newMigrator(name, dir, dryRun).migrate(0)
+}
+
+// RunMigrateDown revert the last steps applied migrations in dir by their Down sqls.
+func RunMigrateDown(name, dir string, steps int, dryRun bool) error {
+	if steps <= 0 {
+		return ErrArgs
+	}
+	return newMigrator(name, dir, dryRun).migrate(steps)
+}
@@ -0,0 +1,471 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// a change of database schema with the sqls to apply and revert it
+type schemaChange struct {
+	desc string
+	up   []string
+	down []string
+}
+
+var (
+	// the same column types reported by different databases, longest first
+	columnTypeSynonyms = [][2]string{
+		{"timestamp without time zone", "timestamp"},
+		{"timestamp with time zone", "timestamptz"},
+		{"time without time zone", "time"},
+		{"character varying", "varchar"},
+		{"double precision", "double"},
+		{"character", "char"},
+		{"boolean", "bool"},
+		{"integer", "int"},
+		{"numeric", "decimal"},
+	}
+	// display width of integer types in mysql 5.x, such as int(11)
+	columnTypeWidth = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+)
+
+// normalize column type, so the type in model and the type reported by database can be compared.
+func normalizeColumnType(typ string) string {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	if i := strings.Index(typ, " check"); i != -1 {
+		typ = typ[:i]
+	}
+	typ = strings.Join(strings.Fields(typ), " ")
+	typ = strings.Replace(typ, ", ", ",", -1)
+	// mysql reports bool as tinyint(1)
+	if typ == "tinyint(1)" {
+		return "bool"
+	}
+	for _, synonym := range columnTypeSynonyms {
+		if !strings.HasPrefix(typ, synonym[0]) {
+			continue
+		}
+		rest := typ[len(synonym[0]):]
+		if rest == "" || rest[0] == '(' || rest[0] == ' ' {
+			typ = synonym[1] + rest
+			break
+		}
+	}
+	return columnTypeWidth.ReplaceAllString(typ, "$1")
+}
+
+// report whether the column is nullable by the null value of GetColumns
+func isColumnNullable(al *alias, null string) bool {
+	if al.Driver == DRSqlite {
+		// notnull flag of pragma table_info
+		return null == "0"
+	}
+	return strings.EqualFold(null, "YES")
+}
+
+// get column type with NOT NULL, DEFAULT and COMMENT.
+func getColumnDefinition(al *alias, fi *fieldInfo) string {
+	def := getColumnTyp(al, fi)
+	if !fi.null {
+		def += " NOT NULL"
+	}
+	def += strings.TrimRight(getColumnDefault(fi), " ")
+	if fi.description != "" && (al.Driver == DRMySQL || al.Driver == DRTiDB) {
+		def += fmt.Sprintf(" COMMENT '%s'", fi.description)
+	}
+	return strings.Replace(def, "%COL%", fi.column, -1)
+}
+
+// remove comment lines of sql
+func trimSQLComments(sql string) string {
+	lines := strings.Split(sql, "\n")
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n")
+}
+
+// get the sql dropping an index
+func getIndexDropSQL(al *alias, table, name string) string {
+	Q := al.DbBaser.TableQuote()
+	if al.Driver == DRMySQL || al.Driver == DRTiDB {
+		return fmt.Sprintf("DROP INDEX %s%s%s ON %s%s%s", Q, name, Q, Q, table, Q)
+	}
+	return fmt.Sprintf("DROP INDEX %s%s%s", Q, name, Q)
+}
+
+// get the sql creating an index
+func getIndexCreateSQL(al *alias, table, name string, columns []string) string {
+	Q := al.DbBaser.TableQuote()
+	sep := fmt.Sprintf("%s, %s", Q, Q)
+	return fmt.Sprintf("CREATE INDEX %s%s%s ON %s%s%s (%s%s%s)", Q, name, Q, Q, table, Q, Q, strings.Join(columns, sep), Q)
+}
+
+// compare registered models to the schema of database.
+// new tables, new columns, renamed columns, column type and nullability changes,
+// new indexes and dropped indexes created by orm are found.
+// columns and tables not in models are kept.
+// unique indexes (tag `orm:"unique"` and TableUnique) are skipped, they are created with the table only,
+// their changes have to be written to the migration by hand.
+func getSchemaChanges(al *alias, db dbQuerier) ([]schemaChange, error) {
+	switch al.Driver {
+	case DRMySQL, DRTiDB, DRPostgres, DRSqlite:
+	default:
+		return nil, fmt.Errorf("migrations are not supported by driver `%s`", al.DriverName)
+	}
+
+	tables, err := al.DbBaser.GetTables(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []schemaChange
+	for _, mi := range modelCache.allOrdered() {
+		if !tables[mi.table] {
+			changes = append(changes, getTableCreateChange(al, mi))
+			continue
+		}
+		tableChanges, err := getTableChanges(al, db, mi)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, tableChanges...)
+	}
+	return changes, nil
+}
+
+// create table of model
+func getTableCreateChange(al *alias, mi *modelInfo) schemaChange {
+	Q := al.DbBaser.TableQuote()
+	sql, indexes := getTableCreateSQL(al, mi, mi.table)
+	change := schemaChange{desc: fmt.Sprintf("create table `%s`", mi.table)}
+	change.up = append(change.up, trimSQLComments(sql))
+	for _, idx := range indexes {
+		change.up = append(change.up, idx.SQL)
+	}
+	change.down = append(change.down, fmt.Sprintf("DROP TABLE %s%s%s", Q, mi.table, Q))
+	return change
+}
+
+// compare columns and indexes of the table of model
+func getTableChanges(al *alias, db dbQuerier, mi *modelInfo) ([]schemaChange, error) {
+	Q := al.DbBaser.TableQuote()
+	columns, err := al.DbBaser.GetColumns(db, mi.table)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := al.DbBaser.GetIndexes(db, mi.table)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []schemaChange
+	rebuild := false
+	for _, fi := range mi.fields.fieldsDB {
+		column, ok := columns[fi.column]
+		if !ok && fi.renameFrom != "" {
+			if column, ok = columns[fi.renameFrom]; ok {
+				change, err := getColumnRenameChange(al, db, fi, column)
+				if err != nil {
+					return nil, err
+				}
+				changes = append(changes, change)
+			}
+		}
+		if !ok {
+			changes = append(changes, schemaChange{
+				desc: fmt.Sprintf("add column `%s`.`%s`", mi.table, fi.column),
+				up:   []string{fmt.Sprintf("ALTER TABLE %s%s%s ADD COLUMN %s%s%s %s", Q, mi.table, Q, Q, fi.column, Q, getColumnDefinition(al, fi))},
+				down: []string{fmt.Sprintf("ALTER TABLE %s%s%s DROP COLUMN %s%s%s", Q, mi.table, Q, Q, fi.column, Q)},
+			})
+			continue
+		}
+		if fi.pk {
+			continue
+		}
+
+		typ := getColumnTyp(al, fi)
+		typeChanged := typ != "" && normalizeColumnType(typ) != normalizeColumnType(column[1])
+		nullChanged := fi.null != isColumnNullable(al, column[2])
+		if !typeChanged && !nullChanged {
+			continue
+		}
+		if al.Driver == DRSqlite {
+			// sqlite can not alter column
+			rebuild = true
+			continue
+		}
+		change, err := getColumnAlterChange(al, db, fi, column, typeChanged, nullChanged)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	if rebuild {
+		change, err := getTableRebuildChange(al, db, mi, columns)
+		if err != nil {
+			return nil, err
+		}
+		// the rebuilt table includes all the changes of columns and indexes
+		return []schemaChange{change}, nil
+	}
+
+	_, modelIndexes := getTableCreateSQL(al, mi, mi.table)
+	expected := make(map[string]bool, len(modelIndexes))
+	for _, idx := range modelIndexes {
+		expected[idx.Name] = true
+		if al.DbBaser.IndexExists(db, mi.table, idx.Name) {
+			continue
+		}
+		changes = append(changes, schemaChange{
+			desc: fmt.Sprintf("create index `%s` on `%s`", idx.Name, mi.table),
+			up:   []string{strings.TrimRight(idx.SQL, ";")},
+			down: []string{getIndexDropSQL(al, mi.table, idx.Name)},
+		})
+	}
+
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// only drop indexes named by orm, indexes created by hand are kept
+		if expected[name] || !strings.HasPrefix(name, mi.table+"_") {
+			continue
+		}
+		changes = append(changes, schemaChange{
+			desc: fmt.Sprintf("drop index `%s` on `%s`", name, mi.table),
+			up:   []string{getIndexDropSQL(al, mi.table, name)},
+			down: []string{getIndexCreateSQL(al, mi.table, name, indexes[name])},
+		})
+	}
+	return changes, nil
+}
+
+// rename column to the column of field.
+// mysql before 8.0 and tidb have no RENAME COLUMN, CHANGE COLUMN with the current definition is used.
+func getColumnRenameChange(al *alias, db dbQuerier, fi *fieldInfo, column [3]string) (schemaChange, error) {
+	Q := al.DbBaser.TableQuote()
+	table := fi.mi.table
+	change := schemaChange{desc: fmt.Sprintf("rename column `%s`.`%s` to `%s`", table, fi.renameFrom, fi.column)}
+
+	if al.Driver == DRMySQL || al.Driver == DRTiDB {
+		def, err := getMysqlColumnDefinition(db, table, column)
+		if err != nil {
+			return change, err
+		}
+		change.up = append(change.up, fmt.Sprintf("ALTER TABLE %s%s%s CHANGE COLUMN %s%s%s %s%s%s %s", Q, table, Q, Q, fi.renameFrom, Q, Q, fi.column, Q, def))
+		change.down = append(change.down, fmt.Sprintf("ALTER TABLE %s%s%s CHANGE COLUMN %s%s%s %s%s%s %s", Q, table, Q, Q, fi.column, Q, Q, fi.renameFrom, Q, def))
+		return change, nil
+	}
+
+	change.up = append(change.up, fmt.Sprintf("ALTER TABLE %s%s%s RENAME COLUMN %s%s%s TO %s%s%s", Q, table, Q, Q, fi.renameFrom, Q, Q, fi.column, Q))
+	change.down = append(change.down, fmt.Sprintf("ALTER TABLE %s%s%s RENAME COLUMN %s%s%s TO %s%s%s", Q, table, Q, Q, fi.column, Q, Q, fi.renameFrom, Q))
+	return change, nil
+}
+
+// change type or nullability of column for mysql, tidb and postgresql
+func getColumnAlterChange(al *alias, db dbQuerier, fi *fieldInfo, column [3]string, typeChanged, nullChanged bool) (schemaChange, error) {
+	Q := al.DbBaser.TableQuote()
+	table := fi.mi.table
+	change := schemaChange{desc: fmt.Sprintf("alter column `%s`.`%s` from %s to %s", table, fi.column, describeColumn(al, column[1], !isColumnNullable(al, column[2])), describeColumn(al, getColumnTyp(al, fi), !fi.null))}
+
+	if al.Driver != DRPostgres {
+		// MODIFY COLUMN replaces the whole definition, so the down restores the DEFAULT and COMMENT of column too
+		oldDef, err := getMysqlColumnDefinition(db, table, column)
+		if err != nil {
+			return change, err
+		}
+		change.up = append(change.up, fmt.Sprintf("ALTER TABLE %s%s%s MODIFY COLUMN %s%s%s %s", Q, table, Q, Q, fi.column, Q, getColumnDefinition(al, fi)))
+		change.down = append(change.down, fmt.Sprintf("ALTER TABLE %s%s%s MODIFY COLUMN %s%s%s %s", Q, table, Q, Q, fi.column, Q, oldDef))
+		return change, nil
+	}
+
+	alter := fmt.Sprintf("ALTER TABLE %s%s%s ALTER COLUMN %s%s%s ", Q, table, Q, Q, fi.column, Q)
+	if typeChanged {
+		typ := getColumnTyp(al, fi)
+		if i := strings.Index(typ, " CHECK"); i != -1 {
+			typ = typ[:i]
+		}
+		change.up = append(change.up, fmt.Sprintf("%sTYPE %s USING %s%s%s::%s", alter, typ, Q, fi.column, Q, typ))
+		change.down = append(change.down, fmt.Sprintf("%sTYPE %s USING %s%s%s::%s", alter, column[1], Q, fi.column, Q, column[1]))
+	}
+	if nullChanged {
+		if fi.null {
+			change.up = append(change.up, alter+"DROP NOT NULL")
+			change.down = append(change.down, alter+"SET NOT NULL")
+		} else {
+			change.up = append(change.up, alter+"SET NOT NULL")
+			change.down = append(change.down, alter+"DROP NOT NULL")
+		}
+	}
+	return change, nil
+}
+
+// get the current definition of column in mysql and tidb, with NOT NULL, DEFAULT, ON UPDATE and COMMENT.
+func getMysqlColumnDefinition(db dbQuerier, table string, column [3]string) (string, error) {
+	var (
+		def     sql.NullString
+		extra   string
+		comment string
+	)
+	row := db.QueryRow("SELECT COLUMN_DEFAULT, EXTRA, COLUMN_COMMENT FROM information_schema.columns "+
+		"WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?", table, column[0])
+	if err := row.Scan(&def, &extra, &comment); err != nil {
+		return "", fmt.Errorf("get definition of column `%s`.`%s` failed: %s", table, column[0], err)
+	}
+
+	oldDef := column[1]
+	notNull := !strings.EqualFold(column[2], "YES")
+	if notNull {
+		oldDef += " NOT NULL"
+	}
+	if def.Valid {
+		oldDef += " DEFAULT " + mysqlColumnDefault(column[1], def.String, extra)
+	} else if !notNull {
+		oldDef += " DEFAULT NULL"
+	}
+	if i := strings.Index(strings.ToLower(extra), "on update "); i != -1 {
+		oldDef += " " + strings.ToUpper(extra[i:])
+	}
+	if comment != "" {
+		oldDef += " COMMENT " + quoteSQLString(comment)
+	}
+	return oldDef, nil
+}
+
+// the DEFAULT clause value of COLUMN_DEFAULT in information_schema.
+// numbers and CURRENT_TIMESTAMP are kept, expressions of mysql 8 are wrapped in parentheses, others are quoted.
+func mysqlColumnDefault(typ, def, extra string) string {
+	lower := strings.ToLower(def)
+	switch {
+	case strings.HasPrefix(lower, "current_timestamp"), lower == "null":
+		return def
+	case strings.Contains(strings.ToLower(extra), "default_generated"):
+		return "(" + def + ")"
+	case len(def) > 1 && def[0] == '\'' && def[len(def)-1] == '\'':
+		// mariadb quotes the string defaults
+		return def
+	}
+	t := strings.ToLower(typ)
+	for _, prefix := range []string{"tinyint", "smallint", "mediumint", "int", "bigint", "decimal", "float", "double", "bit"} {
+		if strings.HasPrefix(t, prefix) {
+			return def
+		}
+	}
+	return quoteSQLString(def)
+}
+
+// quote s as a sql string literal
+func quoteSQLString(s string) string {
+	s = strings.Replace(s, "\\", "\\\\", -1)
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// describe column type and nullability in the desc of change
+func describeColumn(al *alias, typ string, notNull bool) string {
+	if notNull {
+		return normalizeColumnType(typ) + " NOT NULL"
+	}
+	return normalizeColumnType(typ) + " NULL"
+}
+
+// rebuild the table of sqlite, which can not alter column:
+// create a new table, copy rows, drop the old table and rename the new table.
+func getTableRebuildChange(al *alias, db dbQuerier, mi *modelInfo, columns map[string][3]string) (schemaChange, error) {
+	Q := al.DbBaser.TableQuote()
+	tmp := mi.table + "__migrate"
+	change := schemaChange{desc: fmt.Sprintf("rebuild table `%s`", mi.table)}
+
+	var oldSQL string
+	if err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", mi.table).Scan(&oldSQL); err != nil {
+		return change, err
+	}
+	rows, err := db.Query("SELECT sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", mi.table)
+	if err != nil {
+		return change, err
+	}
+	var oldIndexes []string
+	for rows.Next() {
+		var sql string
+		if err := rows.Scan(&sql); err != nil {
+			rows.Close()
+			return change, err
+		}
+		oldIndexes = append(oldIndexes, sql)
+	}
+	rows.Close()
+
+	// columns copied to the new table
+	var newCols, oldCols []string
+	used := make(map[string]bool)
+	for _, fi := range mi.fields.fieldsDB {
+		old := fi.column
+		if _, ok := columns[old]; !ok {
+			if _, ok := columns[fi.renameFrom]; !ok || fi.renameFrom == "" {
+				continue
+			}
+			old = fi.renameFrom
+		}
+		newCols = append(newCols, fi.column)
+		oldCols = append(oldCols, old)
+		used[old] = true
+	}
+	var dropped []string
+	for name := range columns {
+		if !used[name] {
+			dropped = append(dropped, name)
+		}
+	}
+	if len(dropped) > 0 {
+		sort.Strings(dropped)
+		return change, fmt.Errorf("table `%s` need to be rebuilt, but columns `%s` are not in model, rebuild it by hand", mi.table, strings.Join(dropped, "`, `"))
+	}
+
+	sep := fmt.Sprintf("%s, %s", Q, Q)
+	copySQL := func(to, from string, toCols, fromCols []string) string {
+		return fmt.Sprintf("INSERT INTO %s%s%s (%s%s%s) SELECT %s%s%s FROM %s%s%s",
+			Q, to, Q, Q, strings.Join(toCols, sep), Q, Q, strings.Join(fromCols, sep), Q, Q, from, Q)
+	}
+	swapSQL := []string{
+		fmt.Sprintf("DROP TABLE %s%s%s", Q, mi.table, Q),
+		fmt.Sprintf("ALTER TABLE %s%s%s RENAME TO %s%s%s", Q, tmp, Q, Q, mi.table, Q),
+	}
+
+	sql, indexes := getTableCreateSQL(al, mi, tmp)
+	change.up = append(change.up, trimSQLComments(sql), copySQL(tmp, mi.table, newCols, oldCols))
+	change.up = append(change.up, swapSQL...)
+	for _, idx := range indexes {
+		change.up = append(change.up, idx.SQL)
+	}
+
+	// the old table with the temporary name
+	oldSQL = fmt.Sprintf("CREATE TABLE %s%s%s %s", Q, tmp, Q, oldSQL[strings.Index(oldSQL, "("):])
+	change.down = append(change.down, oldSQL, copySQL(tmp, mi.table, oldCols, newCols))
+	change.down = append(change.down, swapSQL...)
+	change.down = append(change.down, oldIndexes...)
+	return change, nil
+}
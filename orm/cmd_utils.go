@@ -46,6 +46,7 @@ func getColumnTyp(al *alias, fi *fieldInfo) (col string) {
 	T := al.DbBaser.DbTypes()
 	fieldType := fi.fieldType
 	fieldSize := fi.size
+	binary := fi.binary
 
 checkColumn:
 	switch fieldType {
@@ -58,7 +59,16 @@ checkColumn:
 			col = fmt.Sprintf(T["string"], fieldSize)
 		}
 	case TypeCharField:
-		col = fmt.Sprintf(T["string-char"], fieldSize)
+		if binary {
+			s := T["binary"]
+			if !strings.Contains(s, "%d") {
+				col = s
+			} else {
+				col = fmt.Sprintf(s, fieldSize)
+			}
+		} else {
+			col = fmt.Sprintf(T["string-char"], fieldSize)
+		}
 	case TypeTextField:
 		col = T["string-text"]
 	case TypeTimeField:
@@ -111,6 +121,7 @@ checkColumn:
 	case RelForeignKey, RelOneToOne:
 		fieldType = fi.relModelInfo.fields.pk.fieldType
 		fieldSize = fi.relModelInfo.fields.pk.size
+		binary = fi.relModelInfo.fields.pk.binary
 		goto checkColumn
 	}
 
@@ -140,136 +151,140 @@ func getDbCreateSQL(al *alias) (sqls []string, tableIndexes map[string][]dbIndex
 		os.Exit(2)
 	}
 
-	Q := al.DbBaser.TableQuote()
-	T := al.DbBaser.DbTypes()
-	sep := fmt.Sprintf("%s, %s", Q, Q)
-
 	tableIndexes = make(map[string][]dbIndex)
 
 	for _, mi := range modelCache.allOrdered() {
-		sql := fmt.Sprintf("-- %s\n", strings.Repeat("-", 50))
-		sql += fmt.Sprintf("--  Table Structure for `%s`\n", mi.fullName)
-		sql += fmt.Sprintf("-- %s\n", strings.Repeat("-", 50))
-
-		sql += fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s%s%s (\n", Q, mi.table, Q)
+		sql, indexes := getTableCreateSQL(al, mi, mi.table)
+		sqls = append(sqls, sql)
+		tableIndexes[mi.table] = indexes
+	}
 
-		columns := make([]string, 0, len(mi.fields.fieldsDB))
+	return
+}
 
-		sqlIndexes := [][]string{}
+// create table creation string of model with the table name.
+func getTableCreateSQL(al *alias, mi *modelInfo, table string) (sql string, indexes []dbIndex) {
+	Q := al.DbBaser.TableQuote()
+	T := al.DbBaser.DbTypes()
+	sep := fmt.Sprintf("%s, %s", Q, Q)
 
-		for _, fi := range mi.fields.fieldsDB {
+	sql = fmt.Sprintf("-- %s\n", strings.Repeat("-", 50))
+	sql += fmt.Sprintf("--  Table Structure for `%s`\n", mi.fullName)
+	sql += fmt.Sprintf("-- %s\n", strings.Repeat("-", 50))
 
-			column := fmt.Sprintf("    %s%s%s ", Q, fi.column, Q)
-			col := getColumnTyp(al, fi)
+	sql += fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s%s%s (\n", Q, table, Q)
 
-			if fi.auto {
-				switch al.Driver {
-				case DRSqlite, DRPostgres:
-					column += T["auto"]
-				default:
-					column += col + " " + T["auto"]
-				}
-			} else if fi.pk {
-				column += col + " " + T["pk"]
-			} else {
-				column += col
+	columns := make([]string, 0, len(mi.fields.fieldsDB))
 
-				if !fi.null {
-					column += " " + "NOT NULL"
-				}
+	sqlIndexes := [][]string{}
 
-				//if fi.initial.String() != "" {
-				//	column += " DEFAULT " + fi.initial.String()
-				//}
+	for _, fi := range mi.fields.fieldsDB {
 
-				// Append attribute DEFAULT
-				column += getColumnDefault(fi)
+		column := fmt.Sprintf("    %s%s%s ", Q, fi.column, Q)
+		col := getColumnTyp(al, fi)
 
-				if fi.unique {
-					column += " " + "UNIQUE"
-				}
+		if fi.auto {
+			switch al.Driver {
+			case DRSqlite, DRPostgres:
+				column += T["auto"]
+			default:
+				column += col + " " + T["auto"]
+			}
+		} else if fi.pk {
+			column += col + " " + T["pk"]
+		} else {
+			column += col
 
-				if fi.index {
-					sqlIndexes = append(sqlIndexes, []string{fi.column})
-				}
+			if !fi.null {
+				column += " " + "NOT NULL"
 			}
 
-			if strings.Contains(column, "%COL%") {
-				column = strings.Replace(column, "%COL%", fi.column, -1)
+			//if fi.initial.String() != "" {
+			//	column += " DEFAULT " + fi.initial.String()
+			//}
+
+			// Append attribute DEFAULT
+			column += getColumnDefault(fi)
+
+			if fi.unique {
+				column += " " + "UNIQUE"
 			}
-			
-			if fi.description != "" {
-				column += " " + fmt.Sprintf("COMMENT '%s'",fi.description)
+
+			if fi.index {
+				sqlIndexes = append(sqlIndexes, []string{fi.column})
 			}
+		}
 
-			columns = append(columns, column)
+		if strings.Contains(column, "%COL%") {
+			column = strings.Replace(column, "%COL%", fi.column, -1)
+		}
+		
+		if fi.description != "" {
+			column += " " + fmt.Sprintf("COMMENT '%s'",fi.description)
 		}
 
-		if mi.model != nil {
-			allnames := getTableUnique(mi.addrField)
-			if !mi.manual && len(mi.uniques) > 0 {
-				allnames = append(allnames, mi.uniques)
-			}
-			for _, names := range allnames {
-				cols := make([]string, 0, len(names))
-				for _, name := range names {
-					if fi, ok := mi.fields.GetByAny(name); ok && fi.dbcol {
-						cols = append(cols, fi.column)
-					} else {
-						panic(fmt.Errorf("cannot found column `%s` when parse UNIQUE in `%s.TableUnique`", name, mi.fullName))
-					}
+		columns = append(columns, column)
+	}
+
+	if mi.model != nil {
+		allnames := getTableUnique(mi.addrField)
+		if !mi.manual && len(mi.uniques) > 0 {
+			allnames = append(allnames, mi.uniques)
+		}
+		for _, names := range allnames {
+			cols := make([]string, 0, len(names))
+			for _, name := range names {
+				if fi, ok := mi.fields.GetByAny(name); ok && fi.dbcol {
+					cols = append(cols, fi.column)
+				} else {
+					panic(fmt.Errorf("cannot found column `%s` when parse UNIQUE in `%s.TableUnique`", name, mi.fullName))
 				}
-				column := fmt.Sprintf("    UNIQUE (%s%s%s)", Q, strings.Join(cols, sep), Q)
-				columns = append(columns, column)
 			}
+			column := fmt.Sprintf("    UNIQUE (%s%s%s)", Q, strings.Join(cols, sep), Q)
+			columns = append(columns, column)
 		}
+	}
 
-		sql += strings.Join(columns, ",\n")
-		sql += "\n)"
+	sql += strings.Join(columns, ",\n")
+	sql += "\n)"
 
-		if al.Driver == DRMySQL {
-			var engine string
-			if mi.model != nil {
-				engine = getTableEngine(mi.addrField)
-			}
-			if engine == "" {
-				engine = al.Engine
-			}
-			sql += " ENGINE=" + engine
+	if al.Driver == DRMySQL {
+		var engine string
+		if mi.model != nil {
+			engine = getTableEngine(mi.addrField)
 		}
+		if engine == "" {
+			engine = al.Engine
+		}
+		sql += " ENGINE=" + engine
+	}
 
-		sql += ";"
-		sqls = append(sqls, sql)
+	sql += ";"
 
-		if mi.model != nil {
-			for _, names := range getTableIndex(mi.addrField) {
-				cols := make([]string, 0, len(names))
-				for _, name := range names {
-					if fi, ok := mi.fields.GetByAny(name); ok && fi.dbcol {
-						cols = append(cols, fi.column)
-					} else {
-						panic(fmt.Errorf("cannot found column `%s` when parse INDEX in `%s.TableIndex`", name, mi.fullName))
-					}
+	if mi.model != nil {
+		for _, names := range getTableIndex(mi.addrField) {
+			cols := make([]string, 0, len(names))
+			for _, name := range names {
+				if fi, ok := mi.fields.GetByAny(name); ok && fi.dbcol {
+					cols = append(cols, fi.column)
+				} else {
+					panic(fmt.Errorf("cannot found column `%s` when parse INDEX in `%s.TableIndex`", name, mi.fullName))
 				}
-				sqlIndexes = append(sqlIndexes, cols)
 			}
+			sqlIndexes = append(sqlIndexes, cols)
 		}
+	}
 
-		for _, names := range sqlIndexes {
-			name := mi.table + "_" + strings.Join(names, "_")
-			cols := strings.Join(names, sep)
-			sql := fmt.Sprintf("CREATE INDEX %s%s%s ON %s%s%s (%s%s%s);", Q, name, Q, Q, mi.table, Q, Q, cols, Q)
-
-			index := dbIndex{}
-			index.Table = mi.table
-			index.Name = name
-			index.SQL = sql
-
-			tableIndexes[mi.table] = append(tableIndexes[mi.table], index)
-		}
+	for _, names := range sqlIndexes {
+		name := mi.table + "_" + strings.Join(names, "_")
+		cols := strings.Join(names, sep)
+		index := dbIndex{}
+		index.Table = mi.table
+		index.Name = name
+		index.SQL = fmt.Sprintf("CREATE INDEX %s%s%s ON %s%s%s (%s%s%s);", Q, name, Q, Q, mi.table, Q, Q, cols, Q)
 
+		indexes = append(indexes, index)
 	}
-
 	return
 }
 
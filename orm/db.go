@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -36,12 +37,12 @@ var (
 
 var (
 	operators = map[string]bool{
-		"exact":     true,
-		"iexact":    true,
-		"contains":  true,
-		"icontains": true,
-		// "regex":       true,
-		// "iregex":      true,
+		"exact":       true,
+		"iexact":      true,
+		"contains":    true,
+		"icontains":   true,
+		"regex":       true,
+		"iregex":      true,
 		"gt":          true,
 		"gte":         true,
 		"lt":          true,
@@ -56,13 +57,25 @@ var (
 		"in":          true,
 		"notin":       true,
 		"between":     true,
-		// "year":        true,
-		// "month":       true,
-		// "day":         true,
-		// "week_day":    true,
-		"isnull": true,
+		"isnull":      true,
 		// "search":      true,
 	}
+
+	// date parts of time field which can be compared, such as created__year__gte.
+	dateParts = map[string]bool{
+		"year":     true,
+		"month":    true,
+		"day":      true,
+		"week_day": true,
+		"hour":     true,
+		"minute":   true,
+		"second":   true,
+	}
+
+	// field__json__path__to__key compares the value of the path in json field.
+	jsonPathSep = "json"
+
+	jsonPathKeyRegexp = regexp.MustCompile(`^\w+$`)
 )
 
 // an instance of dbBaser interface/
@@ -123,9 +136,14 @@ func (d *dbBase) collectFieldValue(mi *modelInfo, fi *fieldInfo, ind reflect.Val
 		_, value, _ = getExistPk(mi, ind)
 	} else {
 		field := ind.FieldByIndex(fi.fieldIndex)
-		if fi.isFielder {
-			f := field.Addr().Interface().(Fielder)
-			value = f.RawValue()
+		if cf, ok := field.Addr().Interface().(CheckedFielder); ok && fi.isFielder {
+			v, err := cf.CheckedRawValue()
+			if err != nil {
+				return nil, fmt.Errorf("field `%s` value failed, err: %w", fi.fullName, err)
+			}
+			value = v
+		} else if fi.isFielder {
+			value = getFielderValue(fi, field.Addr().Interface().(Fielder))
 		} else {
 			switch fi.fieldType {
 			case TypeBooleanField:
@@ -392,12 +410,14 @@ func (d *dbBase) Insert(q dbQuerier, mi *modelInfo, ind reflect.Value, tz *time.
 }
 
 // multi-insert sql with given slice struct reflect.Value.
-func (d *dbBase) InsertMulti(q dbQuerier, mi *modelInfo, sind reflect.Value, bulk int, tz *time.Location) (int64, error) {
+// the conflicting rows are updated or ignored when onConflict is not nil.
+func (d *dbBase) InsertMulti(q dbQuerier, mi *modelInfo, sind reflect.Value, bulk int, tz *time.Location, onConflict *OnConflict) (int64, error) {
 	var (
 		cnt    int64
 		nums   int
 		values []interface{}
 		names  []string
+		suffix string
 	)
 
 	// typ := reflect.Indirect(mi.addrField).Type()
@@ -424,6 +444,12 @@ func (d *dbBase) InsertMulti(q dbQuerier, mi *modelInfo, sind reflect.Value, bul
 			}
 			values = make([]interface{}, bulk*len(vus))
 			nums += copy(values, vus)
+			if onConflict != nil {
+				suffix, err = d.ins.OnConflictSQL(mi, names, onConflict)
+				if err != nil {
+					return cnt, err
+				}
+			}
 		} else {
 			vus, _, err := d.collectValues(mi, ind, mi.fields.dbcols, false, true, nil, tz)
 			if err != nil {
@@ -437,8 +463,8 @@ func (d *dbBase) InsertMulti(q dbQuerier, mi *modelInfo, sind reflect.Value, bul
 			nums += copy(values[nums:], vus)
 		}
 
-		if i > 1 && i%bulk == 0 || length == i {
-			num, err := d.InsertValue(q, mi, true, names, values[:nums])
+		if i%bulk == 0 || length == i {
+			num, err := d.insertValue(q, mi, true, names, values[:nums], suffix)
 			if err != nil {
 				return cnt, err
 			}
@@ -458,6 +484,11 @@ func (d *dbBase) InsertMulti(q dbQuerier, mi *modelInfo, sind reflect.Value, bul
 // execute insert sql with given struct and given values.
 // insert the given values, not the field values in struct.
 func (d *dbBase) InsertValue(q dbQuerier, mi *modelInfo, isMulti bool, names []string, values []interface{}) (int64, error) {
+	return d.insertValue(q, mi, isMulti, names, values, "")
+}
+
+// execute insert sql with the suffix clause, such as ON CONFLICT.
+func (d *dbBase) insertValue(q dbQuerier, mi *modelInfo, isMulti bool, names []string, values []interface{}, suffix string) (int64, error) {
 	Q := d.ins.TableQuote()
 
 	marks := make([]string, len(names))
@@ -476,6 +507,9 @@ func (d *dbBase) InsertValue(q dbQuerier, mi *modelInfo, isMulti bool, names []s
 	}
 
 	query := fmt.Sprintf("INSERT INTO %s%s%s (%s%s%s) VALUES (%s)", Q, mi.table, Q, Q, columns, Q, qmarks)
+	if suffix != "" {
+		query += " " + suffix
+	}
 
 	d.ins.ReplaceMarks(&query)
 
@@ -492,9 +526,32 @@ func (d *dbBase) InsertValue(q dbQuerier, mi *modelInfo, isMulti bool, names []s
 	row := q.QueryRow(query, values...)
 	var id int64
 	err := row.Scan(&id)
+	if err == nil {
+		invalidateQuerierCache(q, mi.table)
+	}
 	return id, err
 }
 
+// get the ON DUPLICATE KEY UPDATE clause of insert for mysql and tidb,
+// which checks all unique keys instead of the conflict fields.
+func (d *dbBase) OnConflictSQL(mi *modelInfo, names []string, onConflict *OnConflict) (string, error) {
+	Q := d.ins.TableQuote()
+	updates, err := getOnConflictUpdates(mi, names, onConflict)
+	if err != nil {
+		return "", err
+	}
+	if onConflict.DoNothing || len(updates) == 0 {
+		// there is no DO NOTHING in mysql, update the pk to itself
+		col := Q + mi.fields.pk.column + Q
+		return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s = %s", col, col), nil
+	}
+	sets := make([]string, len(updates))
+	for i, col := range updates {
+		sets[i] = fmt.Sprintf("%s%s%s = VALUES(%s%s%s)", Q, col, Q, Q, col, Q)
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", "), nil
+}
+
 // InsertOrUpdate a row
 // If your primary key or unique column conflict will update
 // If no will insert
@@ -594,6 +651,9 @@ func (d *dbBase) InsertOrUpdate(q dbQuerier, mi *modelInfo, ind reflect.Value, a
 	row := q.QueryRow(query, values...)
 	var id int64
 	err = row.Scan(&id)
+	if err == nil {
+		invalidateQuerierCache(q, mi.table)
+	}
 	if err != nil && err.Error() == `pq: syntax error at or near "ON"` {
 		err = fmt.Errorf("postgres version must 9.5 or higher")
 	}
@@ -601,7 +661,8 @@ func (d *dbBase) InsertOrUpdate(q dbQuerier, mi *modelInfo, ind reflect.Value, a
 }
 
 // execute update sql dbQuerier with given struct reflect.Value.
-func (d *dbBase) Update(q dbQuerier, mi *modelInfo, ind reflect.Value, tz *time.Location, cols []string) (int64, error) {
+// if tenantScoped, the tenant column is not updated and the row must be of the tenant.
+func (d *dbBase) Update(q dbQuerier, mi *modelInfo, ind reflect.Value, tz *time.Location, cols []string, tenantScoped bool) (int64, error) {
 	pkName, pkValue, ok := getExistPk(mi, ind)
 	if !ok {
 		return 0, ErrMissPK
@@ -614,35 +675,74 @@ func (d *dbBase) Update(q dbQuerier, mi *modelInfo, ind reflect.Value, tz *time.
 		cols = mi.fields.dbcols
 		setNames = make([]string, 0, len(mi.fields.dbcols)-1)
 	} else {
+		cols = appendAutoNowCols(mi, cols)
 		setNames = make([]string, 0, len(cols))
 	}
 
+	// the version column is always increased by orm
+	vfi := mi.fields.version
+	var version int64
+	if vfi != nil {
+		version = getVersionValue(vfi, ind)
+		cols = removeFieldCol(mi, cols, vfi)
+	}
+
+	// the tenant column is never changed by a scoped orm, and the row must be of the tenant
+	var (
+		tfi       *fieldInfo
+		tenant    interface{}
+		hasTenant bool
+	)
+	if tenantScoped && mi.fields.tenant != nil {
+		tfi, tenant, hasTenant = getExistTenant(mi, ind)
+		cols = removeFieldCol(mi, cols, mi.fields.tenant)
+	}
+
 	setValues, _, err := d.collectValues(mi, ind, cols, true, false, &setNames, tz)
 	if err != nil {
 		return 0, err
 	}
 
-	setValues = append(setValues, pkValue)
-
 	Q := d.ins.TableQuote()
 
+	where := fmt.Sprintf("%s%s%s = ?", Q, pkName, Q)
+	if vfi != nil {
+		setNames = append(setNames, vfi.column)
+		setValues = append(setValues, version+1, pkValue, version)
+		where += fmt.Sprintf(" AND %s%s%s = ?", Q, vfi.column, Q)
+	} else {
+		setValues = append(setValues, pkValue)
+	}
+	if hasTenant {
+		setValues = append(setValues, tenant)
+		where += fmt.Sprintf(" AND %s%s%s = ?", Q, tfi.column, Q)
+	}
+
 	sep := fmt.Sprintf("%s = ?, %s", Q, Q)
 	setColumns := strings.Join(setNames, sep)
 
-	query := fmt.Sprintf("UPDATE %s%s%s SET %s%s%s = ? WHERE %s%s%s = ?", Q, mi.table, Q, Q, setColumns, Q, Q, pkName, Q)
+	query := fmt.Sprintf("UPDATE %s%s%s SET %s%s%s = ? WHERE %s", Q, mi.table, Q, Q, setColumns, Q, where)
 
 	d.ins.ReplaceMarks(&query)
 
 	res, err := q.Exec(query, setValues...)
-	if err == nil {
-		return res.RowsAffected()
+	if err != nil {
+		return 0, err
 	}
-	return 0, err
+	num, err := res.RowsAffected()
+	if err != nil || vfi == nil {
+		return num, err
+	}
+	if num == 0 {
+		return 0, &VersionConflictError{Model: mi.fullName, Pk: pkValue, Version: version}
+	}
+	setVersionValue(vfi, ind, version+1)
+	return num, nil
 }
 
 // execute delete sql dbQuerier with given struct reflect.Value.
-// delete index is pk.
-func (d *dbBase) Delete(q dbQuerier, mi *modelInfo, ind reflect.Value, tz *time.Location, cols []string) (int64, error) {
+// delete index is pk. if tenantScoped, the row must be of the tenant.
+func (d *dbBase) Delete(q dbQuerier, mi *modelInfo, ind reflect.Value, tz *time.Location, cols []string, tenantScoped bool) (int64, error) {
 	var whereCols []string
 	var args []interface{}
 	// if specify cols length > 0, then use it for where condition.
@@ -663,6 +763,14 @@ func (d *dbBase) Delete(q dbQuerier, mi *modelInfo, ind reflect.Value, tz *time.
 		args = append(args, pkValue)
 	}
 
+	// the row must be of the tenant, unless the tenant field is in cols already.
+	// args are the values of where condition without tenant, used to delete related records.
+	whereArgs := args
+	if tfi, tenant, ok := getExistTenant(mi, ind); tenantScoped && ok && len(removeFieldCol(mi, cols, tfi)) == len(cols) {
+		whereCols = append(whereCols, tfi.column)
+		whereArgs = append(whereArgs[:len(whereArgs):len(whereArgs)], tenant)
+	}
+
 	Q := d.ins.TableQuote()
 
 	sep := fmt.Sprintf("%s = ? AND %s", Q, Q)
@@ -671,7 +779,7 @@ func (d *dbBase) Delete(q dbQuerier, mi *modelInfo, ind reflect.Value, tz *time.
 	query := fmt.Sprintf("DELETE FROM %s%s%s WHERE %s%s%s = ?", Q, mi.table, Q, Q, wheres, Q)
 
 	d.ins.ReplaceMarks(&query)
-	res, err := q.Exec(query, args...)
+	res, err := q.Exec(query, whereArgs...)
 	if err == nil {
 		num, err := res.RowsAffected()
 		if err != nil {
@@ -720,8 +828,6 @@ func (d *dbBase) UpdateBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Con
 
 	where, args := tables.getCondSQL(cond, false, tz)
 
-	values = append(values, args...)
-
 	join := tables.getJoinSQL()
 
 	var query, T string
@@ -733,6 +839,14 @@ func (d *dbBase) UpdateBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Con
 	}
 
 	cols := make([]string, 0, len(columns))
+	setValues := make([]interface{}, 0, len(values)+len(args))
+	column := func(name string) string {
+		fi, ok := mi.fields.GetByAny(name)
+		if !ok || !fi.dbcol {
+			panic(fmt.Errorf("wrong field/column name `%s` in expression", name))
+		}
+		return fmt.Sprintf("%s%s%s%s", T, Q, fi.column, Q)
+	}
 
 	for i, v := range columns {
 		col := fmt.Sprintf("%s%s%s%s", T, Q, v, Q)
@@ -747,7 +861,7 @@ func (d *dbBase) UpdateBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Con
 			case ColExcept:
 				cols = append(cols, col+" = "+col+" / ?")
 			}
-			values[i] = c.value
+			setValues = append(setValues, c.value)
 		}else if c, ok := values[i].(colFloatValue); ok {
 			switch c.opt {
 			case ColAdd:
@@ -759,12 +873,19 @@ func (d *dbBase) UpdateBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Con
 			case ColExcept:
 				cols = append(cols, col+" = "+col+" / ?")
 			}
-			values[i] = c.value
+			setValues = append(setValues, c.value)
+		} else if e, ok := values[i].(Expr); ok {
+			expr, exprArgs := e.getSQL(column)
+			cols = append(cols, col+" = "+expr)
+			setValues = append(setValues, exprArgs...)
 		}else {
 			cols = append(cols, col+" = ?")
+			setValues = append(setValues, values[i])
 		}
 	}
 
+	values = append(setValues, args...)
+
 	sets := strings.Join(cols, ", ") + " "
 
 	if d.ins.SupportUpdateJoin() {
@@ -896,45 +1017,17 @@ func (d *dbBase) DeleteBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Con
 	return 0, err
 }
 
-// read related records.
-func (d *dbBase) ReadBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condition, container interface{}, tz *time.Location, cols []string) (int64, error) {
-
-	val := reflect.ValueOf(container)
-	ind := reflect.Indirect(val)
-
-	errTyp := true
-	one := true
-	isPtr := true
-
-	if val.Kind() == reflect.Ptr {
-		fn := ""
-		if ind.Kind() == reflect.Slice {
-			one = false
-			typ := ind.Type().Elem()
-			switch typ.Kind() {
-			case reflect.Ptr:
-				fn = getFullName(typ.Elem())
-			case reflect.Struct:
-				isPtr = false
-				fn = getFullName(typ)
-			}
-		} else {
-			fn = getFullName(ind.Type())
-		}
-		errTyp = fn != mi.fullName
-	}
-
-	if errTyp {
-		if one {
-			panic(fmt.Errorf("wrong object type `%s` for rows scan, need *%s", val.Type(), mi.fullName))
-		} else {
-			panic(fmt.Errorf("wrong object type `%s` for rows scan, need *[]*%s or *[]%s", val.Type(), mi.fullName, mi.fullName))
-		}
-	}
-
-	rlimit := qs.limit
-	offset := qs.offset
+// select sql of ReadBatch and the columns to scan.
+type readQuery struct {
+	query   string
+	args    []interface{}
+	tCols   []string
+	colsNum int
+	tables  *dbTables
+}
 
+// generate select sql of ReadBatch with the limit, the related models selected are scanned after the model columns.
+func (d *dbBase) getReadQuery(qs *querySet, mi *modelInfo, cond *Condition, tz *time.Location, cols []string, rlimit int64) (*readQuery, error) {
 	Q := d.ins.TableQuote()
 
 	var tCols []string
@@ -952,7 +1045,7 @@ func (d *dbBase) ReadBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condi
 					maps[fi.column] = true
 				}
 			} else {
-				return 0, fmt.Errorf("wrong field/column name `%s`", col)
+				return nil, fmt.Errorf("wrong field/column name `%s`", col)
 			}
 		}
 		if hasRel {
@@ -978,7 +1071,7 @@ func (d *dbBase) ReadBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condi
 	where, args := tables.getCondSQL(cond, false, tz)
 	groupBy := tables.getGroupSQL(qs.groups)
 	orderBy := tables.getOrderSQL(qs.orders)
-	limit := tables.getLimitSQL(mi, offset, rlimit)
+	limit := tables.getLimitSQL(mi, qs.offset, rlimit)
 	join := tables.getJoinSQL()
 
 	for _, tbl := range tables.tables {
@@ -1001,21 +1094,116 @@ func (d *dbBase) ReadBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condi
 
 	d.ins.ReplaceMarks(&query)
 
+	return &readQuery{query: query, args: args, tCols: tCols, colsNum: colsNum, tables: tables}, nil
+}
+
+// set the values of scanned row to the model and its selected related models.
+func (d *dbBase) setReadRowValues(mi *modelInfo, mind reflect.Value, rq *readQuery, refs []interface{}, tz *time.Location) {
+	cacheV := make(map[string]*reflect.Value)
+	cacheM := make(map[string]*modelInfo)
+	trefs := refs
+
+	d.setColsValues(mi, &mind, rq.tCols, refs[:len(rq.tCols)], tz)
+	trefs = refs[len(rq.tCols):]
+
+	for _, tbl := range rq.tables.tables {
+		// loop selected tables
+		if tbl.sel {
+			last := mind
+			names := ""
+			mmi := mi
+			// loop cascade models
+			for _, name := range tbl.names {
+				names += name
+				if val, ok := cacheV[names]; ok {
+					last = *val
+					mmi = cacheM[names]
+				} else {
+					fi := mmi.fields.GetByName(name)
+					lastm := mmi
+					mmi = fi.relModelInfo
+					field := last
+					if last.Kind() != reflect.Invalid {
+						field = reflect.Indirect(last.FieldByIndex(fi.fieldIndex))
+						if field.IsValid() {
+							d.setColsValues(mmi, &field, mmi.fields.dbcols, trefs[:len(mmi.fields.dbcols)], tz)
+							for _, fi := range mmi.fields.fieldsReverse {
+								if fi.inModel && fi.reverseFieldInfo.mi == lastm {
+									if fi.reverseFieldInfo != nil {
+										f := field.FieldByIndex(fi.fieldIndex)
+										if f.Kind() == reflect.Ptr {
+											f.Set(last.Addr())
+										}
+									}
+								}
+							}
+							last = field
+						}
+					}
+					cacheV[names] = &field
+					cacheM[names] = mmi
+				}
+			}
+			trefs = trefs[len(mmi.fields.dbcols):]
+		}
+	}
+}
+
+// read related records.
+func (d *dbBase) ReadBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condition, container interface{}, tz *time.Location, cols []string) (int64, error) {
+
+	val := reflect.ValueOf(container)
+	ind := reflect.Indirect(val)
+
+	errTyp := true
+	one := true
+	isPtr := true
+
+	if val.Kind() == reflect.Ptr {
+		fn := ""
+		if ind.Kind() == reflect.Slice {
+			one = false
+			typ := ind.Type().Elem()
+			switch typ.Kind() {
+			case reflect.Ptr:
+				fn = getFullName(typ.Elem())
+			case reflect.Struct:
+				isPtr = false
+				fn = getFullName(typ)
+			}
+		} else {
+			fn = getFullName(ind.Type())
+		}
+		errTyp = fn != mi.fullName
+	}
+
+	if errTyp {
+		if one {
+			panic(fmt.Errorf("wrong object type `%s` for rows scan, need *%s", val.Type(), mi.fullName))
+		} else {
+			panic(fmt.Errorf("wrong object type `%s` for rows scan, need *[]*%s or *[]%s", val.Type(), mi.fullName, mi.fullName))
+		}
+	}
+
+	rq, err := d.getReadQuery(qs, mi, cond, tz, cols, qs.limit)
+	if err != nil {
+		return 0, err
+	}
+
 	var rs *sql.Rows
-	var err error
 	if qs != nil && qs.forContext {
-		rs, err = q.QueryContext(qs.ctx, query, args...)
+		rs, err = q.QueryContext(qs.ctx, rq.query, rq.args...)
 		if err != nil {
 			return 0, err
 		}
 	} else {
-		rs, err = q.Query(query, args...)
+		rs, err = q.Query(rq.query, rq.args...)
 		if err != nil {
 			return 0, err
 		}
 	}
 
-	refs := make([]interface{}, colsNum)
+	refs := make([]interface{}, rq.colsNum)
 	for i := range refs {
 		var ref interface{}
 		refs[i] = &ref
@@ -1035,54 +1223,7 @@ func (d *dbBase) ReadBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condi
 			elm := reflect.New(mi.addrField.Elem().Type())
 			mind := reflect.Indirect(elm)
 
-			cacheV := make(map[string]*reflect.Value)
-			cacheM := make(map[string]*modelInfo)
-			trefs := refs
-
-			d.setColsValues(mi, &mind, tCols, refs[:len(tCols)], tz)
-			trefs = refs[len(tCols):]
-
-			for _, tbl := range tables.tables {
-				// loop selected tables
-				if tbl.sel {
-					last := mind
-					names := ""
-					mmi := mi
-					// loop cascade models
-					for _, name := range tbl.names {
-						names += name
-						if val, ok := cacheV[names]; ok {
-							last = *val
-							mmi = cacheM[names]
-						} else {
-							fi := mmi.fields.GetByName(name)
-							lastm := mmi
-							mmi = fi.relModelInfo
-							field := last
-							if last.Kind() != reflect.Invalid {
-								field = reflect.Indirect(last.FieldByIndex(fi.fieldIndex))
-								if field.IsValid() {
-									d.setColsValues(mmi, &field, mmi.fields.dbcols, trefs[:len(mmi.fields.dbcols)], tz)
-									for _, fi := range mmi.fields.fieldsReverse {
-										if fi.inModel && fi.reverseFieldInfo.mi == lastm {
-											if fi.reverseFieldInfo != nil {
-												f := field.FieldByIndex(fi.fieldIndex)
-												if f.Kind() == reflect.Ptr {
-													f.Set(last.Addr())
-												}
-											}
-										}
-									}
-									last = field
-								}
-							}
-							cacheV[names] = &field
-							cacheM[names] = mmi
-						}
-					}
-					trefs = trefs[len(mmi.fields.dbcols):]
-				}
-			}
+			d.setReadRowValues(mi, mind, rq, refs, tz)
 
 			if one {
 				ind.Set(mind)
@@ -1119,11 +1260,47 @@ func (d *dbBase) ReadBatch(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condi
 		}
 	}
 
+	observeRows(querierAlias(q), mi.table, cnt)
 	return cnt, nil
 }
 
-// excute count sql and return count result int64.
-func (d *dbBase) Count(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condition, tz *time.Location) (cnt int64, err error) {
+// generate select sql of ReadBatch, it is a part of the key of query cache.
+func (d *dbBase) ReadQuery(qs *querySet, mi *modelInfo, cond *Condition, tz *time.Location, cols []string) (*readQuery, error) {
+	return d.getReadQuery(qs, mi, cond, tz, cols, qs.limit)
+}
+
+// read records one by one, the rows are not limited by DefaultRowsLimit.
+func (d *dbBase) ReadIterator(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condition, tz *time.Location, cols []string) (*queryIterator, error) {
+	rlimit := qs.limit
+	if rlimit == 0 {
+		rlimit = -1
+	}
+	rq, err := d.getReadQuery(qs, mi, cond, tz, cols, rlimit)
+	if err != nil {
+		return nil, err
+	}
+
+	var rs *sql.Rows
+	if qs.forContext {
+		rs, err = q.QueryContext(qs.ctx, rq.query, rq.args...)
+	} else {
+		rs, err = q.Query(rq.query, rq.args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]interface{}, rq.colsNum)
+	for i := range refs {
+		var ref interface{}
+		refs[i] = &ref
+	}
+
+	return &queryIterator{d: d, rows: rs, rq: rq, refs: refs, mi: mi, tz: tz, al: querierAlias(q)}, nil
+}
+
+// generate count sql.
+func (d *dbBase) getCountSQL(qs *querySet, mi *modelInfo, cond *Condition, tz *time.Location) (string, []interface{}) {
 	tables := newDbTables(mi, d.ins)
 	tables.parseRelated(qs.related, qs.relDepth)
 
@@ -1141,6 +1318,93 @@ func (d *dbBase) Count(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condition
 	}
 
 	d.ins.ReplaceMarks(&query)
+	return query, args
+}
+
+// generate aggregate sql, the group by exprs are selected before the aggregations.
+func (d *dbBase) AggregateSQL(qs *querySet, mi *modelInfo, cond *Condition, aggs []Aggregation, tz *time.Location) (string, []interface{}) {
+	if len(aggs) == 0 {
+		panic(fmt.Errorf("<QuerySeter.Aggregate> need at least one aggregation"))
+	}
+
+	tables := newDbTables(mi, d.ins)
+	tables.parseRelated(qs.related, qs.relDepth)
+
+	Q := d.ins.TableQuote()
+	column := d.getExprColumn(tables, mi)
+
+	var args []interface{}
+	cols := make([]string, 0, len(qs.groups)+len(aggs))
+	for _, group := range qs.groups {
+		cols = append(cols, fmt.Sprintf("%s %s%s%s", column(group), Q, getExprAlias(group), Q))
+	}
+	for _, agg := range aggs {
+		sql, aggArgs := agg.getSQL(column)
+		cols = append(cols, fmt.Sprintf("%s %s%s%s", sql, Q, agg.getAlias(), Q))
+		args = append(args, aggArgs...)
+	}
+
+	where, whereArgs := tables.getCondSQL(cond, false, tz)
+	groupBy := tables.getGroupSQL(qs.groups)
+	orderBy := tables.getOrderSQL(qs.orders)
+	limit := ""
+	if len(qs.groups) > 0 {
+		limit = tables.getLimitSQL(mi, qs.offset, qs.limit)
+	}
+	join := tables.getJoinSQL()
+
+	query := fmt.Sprintf("SELECT %s FROM %s%s%s T0 %s%s%s%s%s", strings.Join(cols, ", "), Q, mi.table, Q, join, where, groupBy, orderBy, limit)
+	return query, append(args, whereArgs...)
+}
+
+// generate annotate sql, the columns of model are selected with the aggregations of each row.
+func (d *dbBase) AnnotateSQL(qs *querySet, mi *modelInfo, cond *Condition, aggs []Aggregation, tz *time.Location) (string, []interface{}) {
+	if len(aggs) == 0 {
+		panic(fmt.Errorf("<QuerySeter.Annotate> need at least one annotation"))
+	}
+
+	tables := newDbTables(mi, d.ins)
+	tables.parseRelated(qs.related, qs.relDepth)
+
+	Q := d.ins.TableQuote()
+	column := d.getExprColumn(tables, mi)
+
+	var args []interface{}
+	cols := make([]string, 0, len(mi.fields.dbcols)+len(aggs))
+	for _, col := range mi.fields.dbcols {
+		cols = append(cols, fmt.Sprintf("T0.%s%s%s", Q, col, Q))
+	}
+	for _, agg := range aggs {
+		sql, aggArgs := agg.getSQL(column)
+		cols = append(cols, fmt.Sprintf("%s %s%s%s", sql, Q, agg.getAlias(), Q))
+		args = append(args, aggArgs...)
+	}
+
+	where, whereArgs := tables.getCondSQL(cond, false, tz)
+	groupBy := tables.getGroupSQL(qs.groups)
+	orderBy := tables.getOrderSQL(qs.orders)
+	limit := tables.getLimitSQL(mi, qs.offset, qs.limit)
+	join := tables.getJoinSQL()
+
+	query := fmt.Sprintf("SELECT %s FROM %s%s%s T0 %s%s%s%s%s", strings.Join(cols, ", "), Q, mi.table, Q, join, where, groupBy, orderBy, limit)
+	return query, append(args, whereArgs...)
+}
+
+// get the function returning the column sql of field expression, used by aggregations and windows.
+func (d *dbBase) getExprColumn(tables *dbTables, mi *modelInfo) func(string) string {
+	Q := d.ins.TableQuote()
+	return func(expr string) string {
+		index, _, fi, suc := tables.parseExprs(mi, strings.Split(expr, ExprSep))
+		if !suc {
+			panic(fmt.Errorf("unknown field/column name `%s`", expr))
+		}
+		return fmt.Sprintf("%s.%s%s%s", index, Q, fi.column, Q)
+	}
+}
+
+// excute count sql and return count result int64.
+func (d *dbBase) Count(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condition, tz *time.Location) (cnt int64, err error) {
+	query, args := d.getCountSQL(qs, mi, cond, tz)
 
 	var row *sql.Row
 	if qs != nil && qs.forContext {
@@ -1152,6 +1416,11 @@ func (d *dbBase) Count(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condition
 	return
 }
 
+// estimate count result, databases without estimation use exact count.
+func (d *dbBase) EstimateCount(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condition, tz *time.Location) (int64, error) {
+	return d.ins.Count(q, qs, mi, cond, tz)
+}
+
 // generate sql with replacing operator string placeholders and replaced values.
 func (d *dbBase) GenerateOperatorSQL(mi *modelInfo, fi *fieldInfo, operator string, args []interface{}, tz *time.Location) (string, []interface{}) {
 	var sql string
@@ -1221,6 +1490,17 @@ func (d *dbBase) GenerateOperatorLeftCol(*fieldInfo, string, *string) {
 	// default not use
 }
 
+// generate sql of the date part of time field, such as YEAR(created).
+// week_day is from 1 (Sunday) to 7 (Saturday).
+func (d *dbBase) GenerateDatePartLeftCol(fi *fieldInfo, part string, leftCol *string) {
+	*leftCol = fmt.Sprintf(mysqlDateParts[part], *leftCol)
+}
+
+// generate sql of the value at path of json field, such as JSON_UNQUOTE(JSON_EXTRACT(extra, '$."name"')).
+func (d *dbBase) GenerateJSONPathLeftCol(fi *fieldInfo, path []string, args []interface{}, leftCol *string) {
+	*leftCol = fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '%s'))", *leftCol, getJSONPath(path))
+}
+
 // set values to struct column.
 func (d *dbBase) setColsValues(mi *modelInfo, ind *reflect.Value, cols []string, values []interface{}, tz *time.Location) {
 	for i, column := range cols {
@@ -1573,6 +1853,7 @@ setValue:
 	case fieldType&IsRelField > 0:
 		if value != nil {
 			fieldType = fi.relModelInfo.fields.pk.fieldType
+			isNative = !fi.relModelInfo.fields.pk.isFielder
 			mf := reflect.New(fi.relModelInfo.addrField.Elem().Type())
 			field.Set(mf)
 			f := mf.Elem().FieldByIndex(fi.relModelInfo.fields.pk.fieldIndex)
@@ -1760,6 +2041,7 @@ func (d *dbBase) ReadValues(q dbQuerier, qs *querySet, mi *modelInfo, cond *Cond
 		*v = list
 	}
 
+	observeRows(querierAlias(q), mi.table, cnt)
 	return cnt, nil
 }
 
@@ -1882,3 +2164,27 @@ func (d *dbBase) ShowColumnsQuery(table string) string {
 func (d *dbBase) IndexExists(dbQuerier, string, string) bool {
 	panic(ErrNotImplement)
 }
+
+// not implement.
+func (d *dbBase) GetIndexes(dbQuerier, string) (map[string][]string, error) {
+	return nil, ErrNotImplement
+}
+
+// get non-unique indexes of table by the query returning index name and column name ordered by position.
+func getIndexesByQuery(db dbQuerier, query string, args ...interface{}) (map[string][]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := make(map[string][]string)
+	for rows.Next() {
+		var name, column string
+		if err := rows.Scan(&name, &column); err != nil {
+			return nil, err
+		}
+		indexes[name] = append(indexes[name], column)
+	}
+	return indexes, rows.Err()
+}
@@ -15,19 +15,21 @@
 package orm
 
 import (
+	"database/sql"
 	"fmt"
 	"reflect"
 	"strings"
+	"time"
 )
 
 // mysql operators.
 var mysqlOperators = map[string]string{
-	"exact":     "= ?",
-	"iexact":    "LIKE ?",
-	"contains":  "LIKE BINARY ?",
-	"icontains": "LIKE ?",
-	// "regex":       "REGEXP BINARY ?",
-	// "iregex":      "REGEXP ?",
+	"exact":       "= ?",
+	"iexact":      "LIKE ?",
+	"contains":    "LIKE BINARY ?",
+	"icontains":   "LIKE ?",
+	"regex":       "REGEXP BINARY ?",
+	"iregex":      "REGEXP ?",
 	"gt":          "> ?",
 	"gte":         ">= ?",
 	"lt":          "< ?",
@@ -40,6 +42,17 @@ var mysqlOperators = map[string]string{
 	"iendswith":   "LIKE ?",
 }
 
+// mysql date part functions, also used by tidb.
+var mysqlDateParts = map[string]string{
+	"year":     "YEAR(%s)",
+	"month":    "MONTH(%s)",
+	"day":      "DAY(%s)",
+	"week_day": "DAYOFWEEK(%s)",
+	"hour":     "HOUR(%s)",
+	"minute":   "MINUTE(%s)",
+	"second":   "SECOND(%s)",
+}
+
 // mysql column field types.
 var mysqlTypes = map[string]string{
 	"auto":            "AUTO_INCREMENT NOT NULL PRIMARY KEY",
@@ -47,6 +60,7 @@ var mysqlTypes = map[string]string{
 	"bool":            "bool",
 	"string":          "varchar(%d)",
 	"string-char":     "char(%d)",
+	"binary":          "binary(%d)",
 	"string-text":     "longtext",
 	"time.Time-date":  "date",
 	"time.Time":       "datetime",
@@ -99,6 +113,60 @@ func (d *dbBaseMysql) IndexExists(db dbQuerier, table string, name string) bool
 	return cnt > 0
 }
 
+// get non-unique indexes of table with their columns in mysql.
+// unique indexes are skipped, migrations do not compare them.
+func (d *dbBaseMysql) GetIndexes(db dbQuerier, table string) (map[string][]string, error) {
+	return getIndexesByQuery(db, "SELECT index_name, column_name FROM information_schema.statistics "+
+		"WHERE table_schema = DATABASE() AND table_name = ? AND non_unique = 1 ORDER BY index_name, seq_in_index", table)
+}
+
+// estimate count by the rows and filtered columns of EXPLAIN result.
+func (d *dbBaseMysql) EstimateCount(q dbQuerier, qs *querySet, mi *modelInfo, cond *Condition, tz *time.Location) (int64, error) {
+	query, args := d.getCountSQL(qs, mi, cond, tz)
+	query = "EXPLAIN " + query
+
+	var rs *sql.Rows
+	var err error
+	if qs != nil && qs.forContext {
+		rs, err = q.QueryContext(qs.ctx, query, args...)
+	} else {
+		rs, err = q.Query(query, args...)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer rs.Close()
+
+	columns, err := rs.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rs.Next() {
+		return 0, rs.Err()
+	}
+	refs := make([]interface{}, len(columns))
+	values := make([]sql.NullString, len(columns))
+	for i := range refs {
+		refs[i] = &values[i]
+	}
+	if err := rs.Scan(refs...); err != nil {
+		return 0, err
+	}
+
+	rows, filtered := float64(0), float64(100)
+	for i, column := range columns {
+		switch strings.ToLower(column) {
+		case "rows":
+			rows, _ = StrTo(values[i].String).Float64()
+		case "filtered":
+			if values[i].Valid {
+				filtered, _ = StrTo(values[i].String).Float64()
+			}
+		}
+	}
+	return int64(rows * filtered / 100), nil
+}
+
 // InsertOrUpdate a row
 // If your primary key or unique column conflict will update
 // If no will insert
@@ -172,6 +240,9 @@ func (d *dbBaseMysql) InsertOrUpdate(q dbQuerier, mi *modelInfo, ind reflect.Val
 	row := q.QueryRow(query, values...)
 	var id int64
 	err = row.Scan(&id)
+	if err == nil {
+		invalidateQuerierCache(q, mi.table)
+	}
 	return id, err
 }
 
@@ -35,6 +35,7 @@ var oracleTypes = map[string]string{
 	"bool":            "bool",
 	"string":          "VARCHAR2(%d)",
 	"string-char":     "CHAR(%d)",
+	"binary":          "RAW(%d)",
 	"string-text":     "VARCHAR2(%d)",
 	"time.Time-date":  "DATE",
 	"time.Time":       "TIMESTAMP",
@@ -133,5 +134,8 @@ func (d *dbBaseOracle) InsertValue(q dbQuerier, mi *modelInfo, isMulti bool, nam
 	row := q.QueryRow(query, values...)
 	var id int64
 	err := row.Scan(&id)
+	if err == nil {
+		invalidateQuerierCache(q, mi.table)
+	}
 	return id, err
 }
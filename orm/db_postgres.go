@@ -17,6 +17,7 @@ package orm
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 // postgresql operators.
@@ -25,6 +26,8 @@ var postgresOperators = map[string]string{
 	"iexact":      "= UPPER(?)",
 	"contains":    "LIKE ?",
 	"icontains":   "LIKE UPPER(?)",
+	"regex":       "~ ?",
+	"iregex":      "~* ?",
 	"gt":          "> ?",
 	"gte":         ">= ?",
 	"lt":          "< ?",
@@ -37,6 +40,17 @@ var postgresOperators = map[string]string{
 	"iendswith":   "LIKE UPPER(?)",
 }
 
+// postgresql date part functions, DOW is from 0 (Sunday) to 6 (Saturday).
+var postgresDateParts = map[string]string{
+	"year":     "EXTRACT(YEAR FROM %s)",
+	"month":    "EXTRACT(MONTH FROM %s)",
+	"day":      "EXTRACT(DAY FROM %s)",
+	"week_day": "(EXTRACT(DOW FROM %s) + 1)",
+	"hour":     "EXTRACT(HOUR FROM %s)",
+	"minute":   "EXTRACT(MINUTE FROM %s)",
+	"second":   "FLOOR(EXTRACT(SECOND FROM %s))",
+}
+
 // postgresql column field types.
 var postgresTypes = map[string]string{
 	"auto":            "serial NOT NULL PRIMARY KEY",
@@ -44,6 +58,7 @@ var postgresTypes = map[string]string{
 	"bool":            "bool",
 	"string":          "varchar(%d)",
 	"string-char":     "char(%d)",
+	"binary":          "bytea",
 	"string-text":     "text",
 	"time.Time-date":  "date",
 	"time.Time":       "timestamp with time zone",
@@ -76,13 +91,37 @@ func (d *dbBasePostgres) OperatorSQL(operator string) string {
 // generate functioned sql string, such as contains(text).
 func (d *dbBasePostgres) GenerateOperatorLeftCol(fi *fieldInfo, operator string, leftCol *string) {
 	switch operator {
-	case "contains", "startswith", "endswith":
+	case "contains", "startswith", "endswith", "regex", "iregex":
 		*leftCol = fmt.Sprintf("%s::text", *leftCol)
 	case "iexact", "icontains", "istartswith", "iendswith":
 		*leftCol = fmt.Sprintf("UPPER(%s::text)", *leftCol)
 	}
 }
 
+// get the ON CONFLICT clause of insert.
+func (d *dbBasePostgres) OnConflictSQL(mi *modelInfo, names []string, onConflict *OnConflict) (string, error) {
+	return getOnConflictSQL(d, mi, names, onConflict)
+}
+
+// generate sql of the date part of time field, such as EXTRACT(YEAR FROM created).
+func (d *dbBasePostgres) GenerateDatePartLeftCol(fi *fieldInfo, part string, leftCol *string) {
+	*leftCol = fmt.Sprintf(postgresDateParts[part], *leftCol)
+}
+
+// generate sql of the text value at path of json field, such as (extra #>> '{name}').
+// the value is compared as number when args are numeric.
+func (d *dbBasePostgres) GenerateJSONPathLeftCol(fi *fieldInfo, path []string, args []interface{}, leftCol *string) {
+	col := *leftCol
+	if fi.fieldType != TypeJSONField && fi.fieldType != TypeJsonbField {
+		col += "::jsonb"
+	}
+	col = fmt.Sprintf("(%s #>> '{%s}')", col, strings.Join(path, ","))
+	if len(args) > 0 && isNumericArgs(args) {
+		col += "::numeric"
+	}
+	*leftCol = col
+}
+
 // postgresql unsupports updating joined record.
 func (d *dbBasePostgres) SupportUpdateJoin() bool {
 	return false
@@ -164,7 +203,11 @@ func (d *dbBasePostgres) ShowTablesQuery() string {
 
 // show table columns sql for postgresql.
 func (d *dbBasePostgres) ShowColumnsQuery(table string) string {
-	return fmt.Sprintf("SELECT column_name, data_type, is_nullable FROM information_schema.columns where table_schema NOT IN ('pg_catalog', 'information_schema') and table_name = '%s'", table)
+	// format_type returns the type with size, such as character varying(255)
+	return fmt.Sprintf("SELECT a.attname, format_type(a.atttypid, a.atttypmod), CASE WHEN a.attnotnull THEN 'NO' ELSE 'YES' END "+
+		"FROM pg_attribute a JOIN pg_class c ON a.attrelid = c.oid JOIN pg_namespace n ON c.relnamespace = n.oid "+
+		"WHERE n.nspname NOT IN ('pg_catalog', 'information_schema') AND c.relkind = 'r' AND c.relname = '%s' "+
+		"AND a.attnum > 0 AND NOT a.attisdropped", table)
 }
 
 // get column types of postgresql.
@@ -181,6 +224,17 @@ func (d *dbBasePostgres) IndexExists(db dbQuerier, table string, name string) bo
 	return cnt > 0
 }
 
+// get non-unique indexes of table with their columns in postgresql.
+// unique indexes are skipped, migrations do not compare them.
+func (d *dbBasePostgres) GetIndexes(db dbQuerier, table string) (map[string][]string, error) {
+	query := fmt.Sprintf("SELECT i.relname, a.attname FROM pg_index x "+
+		"JOIN pg_class t ON t.oid = x.indrelid JOIN pg_class i ON i.oid = x.indexrelid "+
+		"JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = ANY(x.indkey) "+
+		"WHERE t.relname = '%s' AND NOT x.indisunique AND NOT x.indisprimary "+
+		"ORDER BY i.relname, array_position(x.indkey::int2[], a.attnum)", table)
+	return getIndexesByQuery(db, query)
+}
+
 // create new postgresql dbBaser.
 func newdbBasePostgres() dbBaser {
 	b := new(dbBasePostgres)
@@ -20,11 +20,15 @@ import (
 )
 
 // sqlite operators.
+// regex and iregex need the regexp function of go regexp syntax, which is not built in sqlite,
+// such as registered by RegisterFunc in ConnectHook of go-sqlite3.
 var sqliteOperators = map[string]string{
 	"exact":       "= ?",
 	"iexact":      "LIKE ? ESCAPE '\\'",
 	"contains":    "LIKE ? ESCAPE '\\'",
 	"icontains":   "LIKE ? ESCAPE '\\'",
+	"regex":       "REGEXP ?",
+	"iregex":      "REGEXP '(?i)' || ?",
 	"gt":          "> ?",
 	"gte":         ">= ?",
 	"lt":          "< ?",
@@ -37,6 +41,17 @@ var sqliteOperators = map[string]string{
 	"iendswith":   "LIKE ? ESCAPE '\\'",
 }
 
+// sqlite date part functions, %w is from 0 (Sunday) to 6 (Saturday).
+var sqliteDateParts = map[string]string{
+	"year":     "CAST(strftime('%%Y', %s) AS INTEGER)",
+	"month":    "CAST(strftime('%%m', %s) AS INTEGER)",
+	"day":      "CAST(strftime('%%d', %s) AS INTEGER)",
+	"week_day": "(CAST(strftime('%%w', %s) AS INTEGER) + 1)",
+	"hour":     "CAST(strftime('%%H', %s) AS INTEGER)",
+	"minute":   "CAST(strftime('%%M', %s) AS INTEGER)",
+	"second":   "CAST(strftime('%%S', %s) AS INTEGER)",
+}
+
 // sqlite column types.
 var sqliteTypes = map[string]string{
 	"auto":            "integer NOT NULL PRIMARY KEY AUTOINCREMENT",
@@ -44,6 +59,7 @@ var sqliteTypes = map[string]string{
 	"bool":            "bool",
 	"string":          "varchar(%d)",
 	"string-char":     "character(%d)",
+	"binary":          "blob",
 	"string-text":     "text",
 	"time.Time-date":  "date",
 	"time.Time":       "datetime",
@@ -79,6 +95,21 @@ func (d *dbBaseSqlite) GenerateOperatorLeftCol(fi *fieldInfo, operator string, l
 	}
 }
 
+// get the ON CONFLICT clause of insert.
+func (d *dbBaseSqlite) OnConflictSQL(mi *modelInfo, names []string, onConflict *OnConflict) (string, error) {
+	return getOnConflictSQL(d, mi, names, onConflict)
+}
+
+// generate sql of the date part of time field, such as CAST(strftime('%Y', created) AS INTEGER).
+func (d *dbBaseSqlite) GenerateDatePartLeftCol(fi *fieldInfo, part string, leftCol *string) {
+	*leftCol = fmt.Sprintf(sqliteDateParts[part], *leftCol)
+}
+
+// generate sql of the value at path of json field, such as json_extract(extra, '$."name"').
+func (d *dbBaseSqlite) GenerateJSONPathLeftCol(fi *fieldInfo, path []string, args []interface{}, leftCol *string) {
+	*leftCol = fmt.Sprintf("json_extract(%s, '%s')", *leftCol, getJSONPath(path))
+}
+
 // unable updating joined record in sqlite.
 func (d *dbBaseSqlite) SupportUpdateJoin() bool {
 	return false
@@ -143,6 +174,38 @@ func (d *dbBaseSqlite) IndexExists(db dbQuerier, table string, name string) bool
 	return false
 }
 
+// get non-unique indexes of table with their columns in sqlite.
+// unique indexes are skipped, migrations do not compare them.
+func (d *dbBaseSqlite) GetIndexes(db dbQuerier, table string) (map[string][]string, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA index_list('%s')", table))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for rows.Next() {
+		var tmp, index sql.NullString
+		var unique int
+		if err := rows.Scan(&tmp, &index, &unique, &tmp, &tmp); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if unique == 0 {
+			names = append(names, index.String)
+		}
+	}
+	rows.Close()
+
+	indexes := make(map[string][]string, len(names))
+	for _, name := range names {
+		columns, err := getIndexesByQuery(db, fmt.Sprintf("SELECT '%s', name FROM pragma_index_info('%s') ORDER BY seqno", name, name))
+		if err != nil {
+			return nil, err
+		}
+		indexes[name] = columns[name]
+	}
+	return indexes, nil
+}
+
 // create new sqlite dbBaser.
 func newdbBaseSqlite() dbBaser {
 	b := new(dbBaseSqlite)
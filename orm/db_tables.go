@@ -367,7 +367,30 @@ func (t *dbTables) getCondSQL(cond *Condition, sub bool, tz *time.Location) (whe
 				exprs = exprs[:num]
 			}
 
-			index, _, fi, suc := t.parseExprs(mi, exprs)
+			var (
+				index    string
+				fi       *fieldInfo
+				suc      bool
+				datePart string
+				jsonPath []string
+			)
+			if i := getJSONPathIndex(exprs); i > 0 {
+				jsonPath = exprs[i+1:]
+				exprs = exprs[:i]
+				for _, key := range jsonPath {
+					if !jsonPathKeyRegexp.MatchString(key) {
+						panic(fmt.Errorf("wrong json path key `%s` in `%s`", key, strings.Join(p.exprs, ExprSep)))
+					}
+				}
+			} else if num = len(exprs) - 1; num > 0 && dateParts[exprs[num]] {
+				index, _, fi, suc = t.parseExprs(mi, exprs[:num])
+				if suc && (fi.fieldType == TypeDateField || fi.fieldType == TypeDateTimeField || fi.fieldType == TypeTimeField) {
+					datePart = exprs[num]
+				}
+			}
+			if datePart == "" {
+				index, _, fi, suc = t.parseExprs(mi, exprs)
+			}
 			if !suc && !rawSql{
 				panic(fmt.Errorf("unknown field/column name `%s`", strings.Join(p.exprs, ExprSep)))
 			}
@@ -376,18 +399,32 @@ func (t *dbTables) getCondSQL(cond *Condition, sub bool, tz *time.Location) (whe
 				operator = "exact"
 			}
 
+			leftCol := ""
+			if !rawSql{
+				leftCol = fmt.Sprintf("%s.%s%s%s", index, Q, fi.column, Q)
+			}
+
+			// the value of date part or json path is compared instead of the field
+			valueFi := fi
+			switch {
+			case datePart != "":
+				t.base.GenerateDatePartLeftCol(fi, datePart, &leftCol)
+				valueFi = &fieldInfo{fieldType: TypeBigIntegerField}
+			case jsonPath != nil:
+				t.base.GenerateJSONPathLeftCol(fi, jsonPath, p.args, &leftCol)
+				valueFi = &fieldInfo{fieldType: TypeTextField}
+			}
+
 			var operSQL string
 			var args []interface{}
 			if p.isRaw {
 				operSQL = p.sql
 			} else {
-				operSQL, args = t.base.GenerateOperatorSQL(mi, fi, operator, p.args, tz)
+				operSQL, args = t.base.GenerateOperatorSQL(mi, valueFi, operator, p.args, tz)
 			}
 
-			leftCol := ""
-			if !rawSql{
-				leftCol = fmt.Sprintf("%s.%s%s%s", index, Q, fi.column, Q)
-				t.base.GenerateOperatorLeftCol(fi, operator, &leftCol)
+			if !rawSql {
+				t.base.GenerateOperatorLeftCol(valueFi, operator, &leftCol)
 			}
 
 			where += fmt.Sprintf("%s %s ", leftCol, operSQL)
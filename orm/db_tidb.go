@@ -55,6 +55,13 @@ func (d *dbBaseTidb) IndexExists(db dbQuerier, table string, name string) bool {
 	return cnt > 0
 }
 
+// get non-unique indexes of table with their columns in tidb.
+// unique indexes are skipped, migrations do not compare them.
+func (d *dbBaseTidb) GetIndexes(db dbQuerier, table string) (map[string][]string, error) {
+	return getIndexesByQuery(db, "SELECT index_name, column_name FROM information_schema.statistics "+
+		"WHERE table_schema = DATABASE() AND table_name = ? AND non_unique = 1 ORDER BY index_name, seq_in_index", table)
+}
+
 // create new mysql dbBaser.
 func newdbBaseTidb() dbBaser {
 	b := new(dbBaseTidb)
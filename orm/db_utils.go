@@ -17,6 +17,8 @@ package orm
 import (
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -28,12 +30,64 @@ func getDbAlias(name string) *alias {
 	panic(fmt.Errorf("unknown DataBase alias name %s", name))
 }
 
+// append auto_now_always fields to the update columns,
+// so they are set to now even if they are not specified.
+// auto_now fields are only set when they are in the columns.
+func appendAutoNowCols(mi *modelInfo, cols []string) []string {
+	// full slice expression, append never writes to the array of caller
+	cols = cols[:len(cols):len(cols)]
+	for _, fi := range mi.fields.fieldsDB {
+		if !fi.autoNowAlways {
+			continue
+		}
+		found := false
+		for _, col := range cols {
+			if f, ok := mi.fields.GetByAny(col); ok && f == fi {
+				found = true
+				break
+			}
+		}
+		if !found {
+			cols = append(cols, fi.column)
+		}
+	}
+	return cols
+}
+
+// remove the field from cols, such as the version field which is set by orm only.
+func removeFieldCol(mi *modelInfo, cols []string, field *fieldInfo) []string {
+	result := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if fi, ok := mi.fields.GetByAny(col); ok && fi == field {
+			continue
+		}
+		result = append(result, col)
+	}
+	return result
+}
+
+// get the tenant value of model struct, exist is false if model has no tenant field or it is zero.
+func getExistTenant(mi *modelInfo, ind reflect.Value) (fi *fieldInfo, value interface{}, exist bool) {
+	fi = mi.fields.tenant
+	if fi == nil {
+		return nil, nil, false
+	}
+	v := ind.FieldByIndex(fi.fieldIndex)
+	if v.IsZero() {
+		return nil, nil, false
+	}
+	return fi, v.Interface(), true
+}
+
 // get pk column info.
 func getExistPk(mi *modelInfo, ind reflect.Value) (column string, value interface{}, exist bool) {
 	fi := mi.fields.pk
 
 	v := ind.FieldByIndex(fi.fieldIndex)
-	if fi.fieldType&IsPositiveIntegerField > 0 {
+	if fi.isFielder {
+		exist = !v.IsZero()
+		value = getFielderValue(fi, v.Addr().Interface().(Fielder))
+	} else if fi.fieldType&IsPositiveIntegerField > 0 {
 		vu := v.Uint()
 		exist = vu > 0
 		value = vu
@@ -53,6 +107,14 @@ func getExistPk(mi *modelInfo, ind reflect.Value) (column string, value interfac
 	return
 }
 
+// get the value stored in db of a Fielder.
+func getFielderValue(fi *fieldInfo, f Fielder) interface{} {
+	if bf, ok := f.(BinaryFielder); ok && fi != nil && fi.binary {
+		return bf.RawBytes()
+	}
+	return f.RawValue()
+}
+
 // get fields description as flatted string.
 func getFlatParams(fi *fieldInfo, args []interface{}, tz *time.Location) (params []interface{}) {
 
@@ -67,11 +129,25 @@ outFor:
 
 		kind := val.Kind()
 		if kind == reflect.Ptr {
+			if f, ok := arg.(Fielder); ok {
+				params = append(params, getFielderValue(fi, f))
+				continue
+			}
 			val = val.Elem()
 			kind = val.Kind()
 			arg = val.Interface()
 		}
 
+		// Fielder passed by value, such as ormfield.UUID of vanilla/uuid
+		if kind == reflect.Array || kind == reflect.Struct {
+			ptr := reflect.New(val.Type())
+			ptr.Elem().Set(val)
+			if f, ok := ptr.Interface().(Fielder); ok {
+				params = append(params, getFielderValue(fi, f))
+				continue
+			}
+		}
+
 		switch kind {
 		case reflect.String:
 			v := val.String()
@@ -135,6 +211,11 @@ outFor:
 				if vu == nil {
 					continue
 				}
+				// nested slices are not hashable, flatted without dedup
+				if !reflect.TypeOf(vu).Comparable() {
+					args = append(args, vu)
+					continue
+				}
 				if _, ok := existK[vu]; ok{
 					continue
 				}
@@ -182,3 +263,115 @@ outFor:
 	}
 	return
 }
+
+// get the json path of keys for mysql and sqlite, such as $."tags"[0]."name".
+// number keys are array indexes.
+func getJSONPath(path []string) string {
+	jsonPath := "$"
+	for _, key := range path {
+		if _, err := strconv.Atoi(key); err == nil {
+			jsonPath += "[" + key + "]"
+		} else {
+			jsonPath += `."` + key + `"`
+		}
+	}
+	return jsonPath
+}
+
+// check all args are numbers
+func isNumericArgs(args []interface{}) bool {
+	for _, arg := range args {
+		switch reflect.Indirect(reflect.ValueOf(arg)).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// get the index of json path separator in filter expressions, 0 if there is no json path.
+func getJSONPathIndex(exprs []string) int {
+	for i := 1; i < len(exprs)-1; i++ {
+		if exprs[i] == jsonPathSep {
+			return i
+		}
+	}
+	return 0
+}
+
+// get the columns of conflict fields, default is the primary key.
+func getOnConflictColumns(mi *modelInfo, onConflict *OnConflict) ([]string, error) {
+	if len(onConflict.Fields) == 0 {
+		return []string{mi.fields.pk.column}, nil
+	}
+	columns := make([]string, 0, len(onConflict.Fields))
+	for _, name := range onConflict.Fields {
+		fi, ok := mi.fields.GetByAny(name)
+		if !ok || !fi.dbcol {
+			return nil, fmt.Errorf("wrong conflict field/column name `%s`", name)
+		}
+		columns = append(columns, fi.column)
+	}
+	return columns, nil
+}
+
+// get the columns updated on conflict, default all inserted columns
+// except the conflict columns, primary key and auto_now_add columns.
+func getOnConflictUpdates(mi *modelInfo, names []string, onConflict *OnConflict) ([]string, error) {
+	if len(onConflict.Update) > 0 {
+		columns := make([]string, 0, len(onConflict.Update))
+		for _, name := range onConflict.Update {
+			fi, ok := mi.fields.GetByAny(name)
+			if !ok || !fi.dbcol {
+				return nil, fmt.Errorf("wrong update field/column name `%s`", name)
+			}
+			columns = append(columns, fi.column)
+		}
+		return columns, nil
+	}
+	conflicts, err := getOnConflictColumns(mi, onConflict)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, 0, len(names))
+loopNames:
+	for _, name := range names {
+		fi := mi.fields.GetByColumn(name)
+		if fi == nil || fi.pk || fi.autoNowAdd {
+			continue
+		}
+		for _, col := range conflicts {
+			if col == name {
+				continue loopNames
+			}
+		}
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
+// get the ON CONFLICT clause of insert for postgres and sqlite.
+func getOnConflictSQL(d dbBaser, mi *modelInfo, names []string, onConflict *OnConflict) (string, error) {
+	Q := d.TableQuote()
+	conflicts, err := getOnConflictColumns(mi, onConflict)
+	if err != nil {
+		return "", err
+	}
+	updates, err := getOnConflictUpdates(mi, names, onConflict)
+	if err != nil {
+		return "", err
+	}
+	sep := fmt.Sprintf("%s, %s", Q, Q)
+	clause := fmt.Sprintf("ON CONFLICT (%s%s%s)", Q, strings.Join(conflicts, sep), Q)
+	if onConflict.DoNothing || len(updates) == 0 {
+		return clause + " DO NOTHING", nil
+	}
+	sets := make([]string, len(updates))
+	for i, col := range updates {
+		sets[i] = fmt.Sprintf("%s%s%s = EXCLUDED.%s%s%s", Q, col, Q, Q, col, Q)
+	}
+	return clause + " DO UPDATE SET " + strings.Join(sets, ", "), nil
+}
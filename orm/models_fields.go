@@ -155,6 +155,7 @@ var _ Fielder = new(CharField)
 //
 // auto_now:
 // Automatically set the field to now every time the object is saved. Useful for “last-modified” timestamps.
+// It is also set by Ormer.Update with cols not including it, and by QuerySeter.Update.
 // Note that the current date is always used; it’s not just a default value that you can override.
 //
 // auto_now_add:
@@ -214,6 +215,7 @@ var _ Fielder = new(TimeField)
 //
 // auto_now:
 // Automatically set the field to now every time the object is saved. Useful for “last-modified” timestamps.
+// It is also set by Ormer.Update with cols not including it, and by QuerySeter.Update.
 // Note that the current date is always used; it’s not just a default value that you can override.
 //
 // auto_now_add:
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -26,6 +27,9 @@ var errSkipField = errors.New("skip field")
 // field info collection
 type fields struct {
 	pk            *fieldInfo
+	softDelete    *fieldInfo
+	version       *fieldInfo
+	tenant        *fieldInfo
 	columns       map[string]*fieldInfo
 	fields        map[string]*fieldInfo
 	fieldsLow     map[string]*fieldInfo
@@ -121,7 +125,11 @@ type fieldInfo struct {
 	size                int
 	toText              bool
 	autoNow             bool
+	autoNowAlways       bool // auto_now also set by partial and bulk updates
 	autoNowAdd          bool
+	softDelete          bool // deleted-at column of soft delete
+	version             bool // row version column of optimistic locking
+	tenant              bool // tenant column scoped by the tenant of Ormer
 	rel                 bool // if type equal to RelForeignKey, RelOneToOne, RelManyToMany then true
 	reverse             bool
 	reverseField        string
@@ -135,8 +143,10 @@ type fieldInfo struct {
 	digits              int
 	decimals            int
 	isFielder           bool // implement Fielder interface
+	binary              bool // BinaryFielder stored as binary
 	onDelete            string
 	description         string
+	renameFrom          string // old column name, see tag rename
 }
 
 // new field info
@@ -302,6 +312,7 @@ checkType:
 	fi.fullName = mi.fullName + mName + "." + sf.Name
 
 	fi.description = sf.Tag.Get("description")
+	fi.renameFrom = tags["rename"]
 	fi.null = attrs["null"]
 	fi.index = attrs["index"]
 	fi.auto = attrs["auto"]
@@ -359,6 +370,18 @@ checkType:
 		fi.onDelete = onDelete
 	}
 
+	if bf, ok := addrField.Interface().(BinaryFielder); ok && size == "" {
+		if tags["type"] == "binary" {
+			fi.binary = true
+			size = strconv.Itoa(len(bf.RawBytes()))
+		} else {
+			size = strconv.Itoa(len(bf.String()))
+		}
+	} else if tags["type"] == "binary" && fieldType&IsRelField == 0 {
+		err = fmt.Errorf("type(binary) only support BinaryFielder, such as ormfield.UUID of vanilla/uuid")
+		goto end
+	}
+
 	switch fieldType {
 	case TypeBooleanField:
 	case TypeVarCharField, TypeCharField, TypeJSONField, TypeJsonbField:
@@ -377,11 +400,21 @@ checkType:
 		fi.index = false
 		fi.unique = false
 	case TypeTimeField, TypeDateField, TypeDateTimeField:
-		if attrs["auto_now"] {
+		if attrs["auto_now_always"] {
+			fi.autoNow = true
+			fi.autoNowAlways = true
+		} else if attrs["auto_now"] {
 			fi.autoNow = true
 		} else if attrs["auto_now_add"] {
 			fi.autoNowAdd = true
 		}
+		if attrs["soft_delete"] {
+			if !fi.null || fi.autoNow || fi.autoNowAdd {
+				err = fmt.Errorf("soft_delete field must be null and cannot set auto_now/auto_now_add")
+				goto end
+			}
+			fi.softDelete = true
+		}
 	case TypeFloatField:
 	case TypeDecimalField:
 		d1 := digits
@@ -401,6 +434,39 @@ checkType:
 		}
 	}
 
+	if attrs["soft_delete"] && !fi.softDelete {
+		err = fmt.Errorf("soft_delete only support time/date/datetime field")
+		goto end
+	}
+
+	if attrs["version"] {
+		switch addrField.Elem().Kind() {
+		case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		default:
+			err = fmt.Errorf("version only support int, int32, int64, uint, uint32, uint64 but found `%s`", addrField.Elem().Kind())
+			goto end
+		}
+		if fi.pk || fi.auto {
+			err = fmt.Errorf("version field cannot be pk or auto")
+			goto end
+		}
+		fi.version = true
+	}
+
+	if attrs["tenant"] {
+		switch addrField.Elem().Kind() {
+		case reflect.Int, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.String:
+		default:
+			err = fmt.Errorf("tenant only support int, int32, int64, uint, uint32, uint64, string but found `%s`", addrField.Elem().Kind())
+			goto end
+		}
+		if fi.pk || fi.auto || fi.null {
+			err = fmt.Errorf("tenant field cannot be pk, auto or null")
+			goto end
+		}
+		fi.tenant = true
+	}
+
 	if fieldType&IsIntegerField == 0 {
 		if fi.auto {
 			err = fmt.Errorf("non-integer type cannot set auto")
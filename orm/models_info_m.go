@@ -91,6 +91,30 @@ func addModelFields(mi *modelInfo, ind reflect.Value, mName string, index []int)
 				mi.fields.pk = fi
 			}
 		}
+		if fi.softDelete {
+			if mi.fields.softDelete != nil {
+				err = fmt.Errorf("one model must have one soft_delete field only")
+				break
+			} else {
+				mi.fields.softDelete = fi
+			}
+		}
+		if fi.version {
+			if mi.fields.version != nil {
+				err = fmt.Errorf("one model must have one version field only")
+				break
+			} else {
+				mi.fields.version = fi
+			}
+		}
+		if fi.tenant {
+			if mi.fields.tenant != nil {
+				err = fmt.Errorf("one model must have one tenant field only")
+				break
+			} else {
+				mi.fields.tenant = fi
+			}
+		}
 	}
 
 	if err != nil {
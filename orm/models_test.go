@@ -15,6 +15,7 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -411,6 +412,56 @@ type PtrPk struct {
 	Positive bool
 }
 
+type Note struct {
+	ID      int        `orm:"column(id)"`
+	Title   string     `orm:"size(60)"`
+	Created time.Time  `orm:"auto_now_add"`
+	Updated time.Time  `orm:"auto_now_always"`
+	Deleted *time.Time `orm:"null;soft_delete"`
+	Version int        `orm:"version"`
+	Hooks   string     `orm:"-"`
+}
+
+func (n *Note) BeforeInsert(ctx context.Context, o Ormer) error {
+	if n.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+	n.Hooks += "bi,"
+	return nil
+}
+
+func (n *Note) AfterInsert(ctx context.Context, o Ormer) error {
+	n.Hooks += "ai,"
+	return nil
+}
+
+func (n *Note) BeforeUpdate(ctx context.Context, o Ormer) error {
+	n.Hooks += "bu,"
+	return nil
+}
+
+func (n *Note) AfterDelete(ctx context.Context, o Ormer) error {
+	n.Hooks += "ad,"
+	return nil
+}
+
+func (n *Note) AfterRead(ctx context.Context, o Ormer) error {
+	n.Hooks += "ar,"
+	return nil
+}
+
+func (n *Note) BeforeBulkUpdate(ctx context.Context, o Ormer, values Params) error {
+	values["Title"] = strings.TrimSpace(values["Title"].(string))
+	return nil
+}
+
+// Memo is scoped by the tenant of Ormer
+type Memo struct {
+	ID      int    `orm:"column(id)"`
+	CorpID  int    `orm:"column(corp_id);tenant"`
+	Content string `orm:"size(60)"`
+}
+
 var DBARGS = struct {
 	Driver string
 	Source string
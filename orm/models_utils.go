@@ -25,25 +25,30 @@ import (
 // 1 is attr
 // 2 is tag
 var supportTag = map[string]int{
-	"-":            1,
-	"null":         1,
-	"index":        1,
-	"unique":       1,
-	"pk":           1,
-	"auto":         1,
-	"auto_now":     1,
-	"auto_now_add": 1,
-	"size":         2,
-	"column":       2,
-	"default":      2,
-	"rel":          2,
-	"reverse":      2,
-	"rel_table":    2,
-	"rel_through":  2,
-	"digits":       2,
-	"decimals":     2,
-	"on_delete":    2,
-	"type":         2,
+	"-":               1,
+	"null":            1,
+	"index":           1,
+	"unique":          1,
+	"pk":              1,
+	"auto":            1,
+	"auto_now":        1,
+	"auto_now_add":    1,
+	"auto_now_always": 1,
+	"soft_delete":     1,
+	"version":         1,
+	"tenant":          1,
+	"size":            2,
+	"column":          2,
+	"default":         2,
+	"rel":             2,
+	"reverse":         2,
+	"rel_table":       2,
+	"rel_through":     2,
+	"digits":          2,
+	"decimals":        2,
+	"on_delete":       2,
+	"type":            2,
+	"rename":          2,
 }
 
 // get reflect.Type name with package path.
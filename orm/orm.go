@@ -92,10 +92,29 @@ type Params map[string]interface{}
 type ParamsList []interface{}
 
 type orm struct {
+	*ormState
+	span opentracing.Span
+	ctx   context.Context // default context for operations without ctx
+
+	tenant interface{} // the tenant scoping models with a tenant field, see WithTenant
+}
+
+// the database and transaction state of orm, shared with the Ormer returned by CrossTenant.
+type ormState struct {
+	stickyUntil int64 // unix nano until which reads go to primary, see RegisterReplica; first for 64-bit alignment of atomic
+
 	alias *alias
 	db    dbQuerier
 	isTx  bool
-	span opentracing.Span
+
+	txLevels []*txLevel // the transaction and its savepoints, see orm_tx.go
+
+	tableSuffix string // the suffix of model tables, see UsingTableSuffix
+}
+
+// create an orm with its own state
+func newOrm() *orm {
+	return &orm{ormState: new(ormState)}
 }
 
 var _ Ormer = new(orm)
@@ -110,7 +129,7 @@ func (o *orm) getMiInd(md interface{}, needPtr bool) (mi *modelInfo, ind reflect
 	}
 	name := getFullName(typ)
 	if mi, ok := modelCache.getByFullName(name); ok {
-		return mi, ind
+		return o.suffixedModelInfo(mi), ind
 	}
 	panic(fmt.Errorf("<Ormer> table: `%s` not found, make sure it was registered with `RegisterModel()`", name))
 }
@@ -126,24 +145,55 @@ func (o *orm) getFieldInfo(mi *modelInfo, name string) *fieldInfo {
 
 // read data to model
 func (o *orm) Read(md interface{}, cols ...string) error {
+	return o.ReadWithCtx(o.ctx, md, cols...)
+}
+
+// read data to model with context
+func (o *orm) ReadWithCtx(ctx context.Context, md interface{}, cols ...string) error {
 	mi, ind := o.getMiInd(md, true)
-	return o.alias.DbBaser.Read(o.db, mi, ind, o.alias.TZ, cols, false)
+	return o.read(ctx, md, mi, ind, cols, false)
+}
+
+// read data to model and call read hooks
+func (o *orm) read(ctx context.Context, md interface{}, mi *modelInfo, ind reflect.Value, cols []string, isForUpdate bool) error {
+	hookCtx := o.hookContext(ctx)
+	if err := beforeRead(md, hookCtx, o); err != nil {
+		return err
+	}
+	cols, err := o.getTenantCols(mi, ind, cols)
+	if err != nil {
+		return err
+	}
+	if err := o.alias.DbBaser.Read(o.querier(ctx), mi, ind, o.alias.TZ, cols, isForUpdate); err != nil {
+		return err
+	}
+	return afterRead(md, hookCtx, o)
 }
 
 // read data to model, like Read(), but use "SELECT FOR UPDATE" form
 func (o *orm) ReadForUpdate(md interface{}, cols ...string) error {
+	return o.ReadForUpdateWithCtx(o.ctx, md, cols...)
+}
+
+// read data to model with context, like ReadWithCtx(), but use "SELECT FOR UPDATE" form
+func (o *orm) ReadForUpdateWithCtx(ctx context.Context, md interface{}, cols ...string) error {
 	mi, ind := o.getMiInd(md, true)
-	return o.alias.DbBaser.Read(o.db, mi, ind, o.alias.TZ, cols, true)
+	return o.read(ctx, md, mi, ind, cols, true)
 }
 
 // Try to read a row from the database, or insert one if it doesn't exist
 func (o *orm) ReadOrCreate(md interface{}, col1 string, cols ...string) (bool, int64, error) {
+	return o.ReadOrCreateWithCtx(o.ctx, md, col1, cols...)
+}
+
+// Try to read a row from the database with context, or insert one if it doesn't exist
+func (o *orm) ReadOrCreateWithCtx(ctx context.Context, md interface{}, col1 string, cols ...string) (bool, int64, error) {
 	cols = append([]string{col1}, cols...)
 	mi, ind := o.getMiInd(md, true)
-	err := o.alias.DbBaser.Read(o.db, mi, ind, o.alias.TZ, cols, false)
+	err := o.read(ctx, md, mi, ind, cols, false)
 	if err == ErrNoRows {
 		// Create
-		id, err := o.Insert(md)
+		id, err := o.InsertWithCtx(ctx, md)
 		return (err == nil), id, err
 	}
 
@@ -151,7 +201,7 @@ func (o *orm) ReadOrCreate(md interface{}, col1 string, cols ...string) (bool, i
 	if mi.fields.pk.fieldType&IsPositiveIntegerField > 0 {
 		id = int64(vid.Uint())
 	} else if mi.fields.pk.rel {
-		return o.ReadOrCreate(vid.Interface(), mi.fields.pk.relModelInfo.fields.pk.name)
+		return o.ReadOrCreateWithCtx(ctx, vid.Interface(), mi.fields.pk.relModelInfo.fields.pk.name)
 	} else {
 		id = vid.Int()
 	}
@@ -161,15 +211,27 @@ func (o *orm) ReadOrCreate(md interface{}, col1 string, cols ...string) (bool, i
 
 // insert model data to database
 func (o *orm) Insert(md interface{}) (int64, error) {
+	return o.InsertWithCtx(o.ctx, md)
+}
+
+// insert model data to database with context
+func (o *orm) InsertWithCtx(ctx context.Context, md interface{}) (int64, error) {
 	mi, ind := o.getMiInd(md, true)
-	id, err := o.alias.DbBaser.Insert(o.db, mi, ind, o.alias.TZ)
+	hookCtx := o.hookContext(ctx)
+	if err := beforeInsert(md, hookCtx, o); err != nil {
+		return 0, err
+	}
+	if err := o.setTenantValue(mi, ind); err != nil {
+		return 0, err
+	}
+	id, err := o.alias.DbBaser.Insert(o.querier(ctx), mi, ind, o.alias.TZ)
 	if err != nil {
 		return id, err
 	}
 
 	o.setPk(mi, ind, id)
 
-	return id, nil
+	return id, afterInsert(md, hookCtx, o)
 }
 
 // set auto pk field
@@ -184,8 +246,28 @@ func (o *orm) setPk(mi *modelInfo, ind reflect.Value, id int64) {
 }
 
 // insert some models to database
-func (o *orm) InsertMulti(bulk int, mds interface{}) (int64, error) {
+func (o *orm) InsertMulti(bulk int, mds interface{}, onConflict ...OnConflict) (int64, error) {
+	return o.InsertMultiWithCtx(o.ctx, bulk, mds, onConflict...)
+}
+
+// insert some models to database with context
+func (o *orm) InsertMultiWithCtx(ctx context.Context, bulk int, mds interface{}, onConflict ...OnConflict) (int64, error) {
 	var cnt int64
+	q := o.querier(ctx)
+
+	var oc *OnConflict
+	if len(onConflict) > 0 {
+		switch o.alias.Driver {
+		case DRMySQL, DRPostgres, DRSqlite, DRTiDB:
+		default:
+			return cnt, fmt.Errorf("`%s` nonsupport InsertMulti with OnConflict in beego", o.alias.DriverName)
+		}
+		oc = &onConflict[0]
+		// conflicting rows are handled by the multi insert sql
+		if bulk <= 1 {
+			bulk = 1
+		}
+	}
 
 	sind := reflect.Indirect(reflect.ValueOf(mds))
 
@@ -198,11 +280,23 @@ func (o *orm) InsertMulti(bulk int, mds interface{}) (int64, error) {
 		return cnt, ErrArgs
 	}
 
-	if bulk <= 1 {
+	hookCtx := o.hookContext(ctx)
+	if err := callSliceHook(beforeInsert, sind, hookCtx, o); err != nil {
+		return cnt, err
+	}
+	for i := 0; i < sind.Len(); i++ {
+		ind := reflect.Indirect(sind.Index(i))
+		mi, _ := o.getMiInd(ind.Interface(), false)
+		if err := o.setTenantValue(mi, ind); err != nil {
+			return cnt, err
+		}
+	}
+
+	if bulk <= 1 && oc == nil {
 		for i := 0; i < sind.Len(); i++ {
 			ind := reflect.Indirect(sind.Index(i))
 			mi, _ := o.getMiInd(ind.Interface(), false)
-			id, err := o.alias.DbBaser.Insert(o.db, mi, ind, o.alias.TZ)
+			id, err := o.alias.DbBaser.Insert(q, mi, ind, o.alias.TZ)
 			if err != nil {
 				return cnt, err
 			}
@@ -213,47 +307,105 @@ func (o *orm) InsertMulti(bulk int, mds interface{}) (int64, error) {
 		}
 	} else {
 		mi, _ := o.getMiInd(sind.Index(0).Interface(), false)
-		return o.alias.DbBaser.InsertMulti(o.db, mi, sind, bulk, o.alias.TZ)
+		num, err := o.alias.DbBaser.InsertMulti(q, mi, sind, bulk, o.alias.TZ, oc)
+		if err != nil {
+			return num, err
+		}
+		cnt = num
 	}
-	return cnt, nil
+	return cnt, callSliceHook(afterInsert, sind, hookCtx, o)
 }
 
 // InsertOrUpdate data to database
 func (o *orm) InsertOrUpdate(md interface{}, colConflitAndArgs ...string) (int64, error) {
+	return o.InsertOrUpdateWithCtx(o.ctx, md, colConflitAndArgs...)
+}
+
+// InsertOrUpdateWithCtx data to database with context
+func (o *orm) InsertOrUpdateWithCtx(ctx context.Context, md interface{}, colConflitAndArgs ...string) (int64, error) {
 	mi, ind := o.getMiInd(md, true)
-	id, err := o.alias.DbBaser.InsertOrUpdate(o.db, mi, ind, o.alias, colConflitAndArgs...)
+	hookCtx := o.hookContext(ctx)
+	if err := beforeInsert(md, hookCtx, o); err != nil {
+		return 0, err
+	}
+	if err := o.setTenantValue(mi, ind); err != nil {
+		return 0, err
+	}
+	id, err := o.alias.DbBaser.InsertOrUpdate(o.querier(ctx), mi, ind, o.alias, colConflitAndArgs...)
 	if err != nil {
 		return id, err
 	}
 
 	o.setPk(mi, ind, id)
 
-	return id, nil
+	return id, afterInsert(md, hookCtx, o)
 }
 
 // update model to database.
 // cols set the columns those want to update.
 func (o *orm) Update(md interface{}, cols ...string) (int64, error) {
+	return o.UpdateWithCtx(o.ctx, md, cols...)
+}
+
+// update model to database with context.
+func (o *orm) UpdateWithCtx(ctx context.Context, md interface{}, cols ...string) (int64, error) {
 	mi, ind := o.getMiInd(md, true)
-	return o.alias.DbBaser.Update(o.db, mi, ind, o.alias.TZ, cols)
+	hookCtx := o.hookContext(ctx)
+	if err := beforeUpdate(md, hookCtx, o); err != nil {
+		return 0, err
+	}
+	if err := o.setTenantValue(mi, ind); err != nil {
+		return 0, err
+	}
+	num, err := o.alias.DbBaser.Update(o.querier(ctx), mi, ind, o.alias.TZ, cols, o.tenant != nil)
+	if err != nil {
+		return num, err
+	}
+	return num, afterUpdate(md, hookCtx, o)
 }
 
 // delete model in database
 // cols shows the delete conditions values read from. default is pk
+// if the model has a soft_delete field, the field is set to now instead of deleting the row,
+// use QuerySeter.Unscoped().Delete() to delete it from database.
 func (o *orm) Delete(md interface{}, cols ...string) (int64, error) {
+	return o.DeleteWithCtx(o.ctx, md, cols...)
+}
+
+// delete model in database with context
+func (o *orm) DeleteWithCtx(ctx context.Context, md interface{}, cols ...string) (int64, error) {
 	mi, ind := o.getMiInd(md, true)
-	num, err := o.alias.DbBaser.Delete(o.db, mi, ind, o.alias.TZ, cols)
+	hookCtx := o.hookContext(ctx)
+	if err := beforeDelete(md, hookCtx, o); err != nil {
+		return 0, err
+	}
+	if err := o.setTenantValue(mi, ind); err != nil {
+		return 0, err
+	}
+	if mi.fields.softDelete != nil {
+		num, err := o.softDelete(ctx, mi, ind, cols)
+		if err != nil {
+			return num, err
+		}
+		return num, afterDelete(md, hookCtx, o)
+	}
+	num, err := o.alias.DbBaser.Delete(o.querier(ctx), mi, ind, o.alias.TZ, cols, o.tenant != nil)
 	if err != nil {
 		return num, err
 	}
 	if num > 0 {
 		o.setPk(mi, ind, 0)
 	}
-	return num, nil
+	return num, afterDelete(md, hookCtx, o)
 }
 
 // create a models to models queryer
 func (o *orm) QueryM2M(md interface{}, name string) QueryM2Mer {
+	return o.QueryM2MWithCtx(o.ctx, md, name)
+}
+
+// create a models to models queryer with context
+func (o *orm) QueryM2MWithCtx(ctx context.Context, md interface{}, name string) QueryM2Mer {
 	mi, ind := o.getMiInd(md, true)
 	fi := o.getFieldInfo(mi, name)
 
@@ -264,7 +416,11 @@ func (o *orm) QueryM2M(md interface{}, name string) QueryM2Mer {
 		panic(fmt.Errorf("<Ormer.QueryM2M> model `%s` . name `%s` is not a m2m field", fi.name, mi.fullName))
 	}
 
-	return newQueryM2M(md, o, mi, fi, ind)
+	m2m := newQueryM2M(md, o, mi, fi, ind)
+	if ctx == nil {
+		return m2m
+	}
+	return m2m.WithContext(ctx)
 }
 
 // load related models to md model.
@@ -276,9 +432,15 @@ func (o *orm) QueryM2M(md interface{}, name string) QueryM2Mer {
 //
 // make sure the relation is defined in model struct tags.
 func (o *orm) LoadRelated(md interface{}, name string, args ...interface{}) (int64, error) {
+	return o.LoadRelatedWithCtx(o.ctx, md, name, args...)
+}
+
+// load related models to md model with context.
+func (o *orm) LoadRelatedWithCtx(ctx context.Context, md interface{}, name string, args ...interface{}) (int64, error) {
 	_, fi, ind, qseter := o.queryRelated(md, name)
 
 	qs := qseter.(*querySet)
+	qs.ctx = ctx
 
 	var relDepth int
 	var limit, offset int64
@@ -429,12 +591,12 @@ func (o *orm) QueryTable(ptrStructOrTableName interface{}) (qs QuerySeter) {
 	if table, ok := ptrStructOrTableName.(string); ok {
 		name = nameStrategyMap[defaultNameStrategy](table)
 		if mi, ok := modelCache.get(name); ok {
-			qs = newQuerySet(o, mi)
+			qs = newQuerySet(o, o.suffixedModelInfo(mi))
 		}
 	} else {
 		name = getFullName(indirectType(reflect.TypeOf(ptrStructOrTableName)))
 		if mi, ok := modelCache.getByFullName(name); ok {
-			qs = newQuerySet(o, mi)
+			qs = newQuerySet(o, o.suffixedModelInfo(mi))
 		}
 	}
 	if qs == nil {
@@ -443,6 +605,11 @@ func (o *orm) QueryTable(ptrStructOrTableName interface{}) (qs QuerySeter) {
 	return
 }
 
+// return a QuerySeter for table operations with context.
+func (o *orm) QueryTableWithCtx(ctx context.Context, ptrStructOrTableName interface{}) QuerySeter {
+	return o.QueryTable(ptrStructOrTableName).WithContext(ctx)
+}
+
 // switch to another registered database driver by given name.
 func (o *orm) Using(name string) error {
 	if o.isTx {
@@ -463,14 +630,15 @@ func (o *orm) Using(name string) error {
 	return nil
 }
 
-// begin transaction
+// begin transaction, or a nested transaction by savepoint if transaction has begun.
 func (o *orm) Begin() error {
-	return o.BeginTx(context.Background(), nil)
+	return o.BeginTx(o.context(), nil)
 }
 
+// begin transaction with context and options, or a nested transaction by savepoint if transaction has begun.
 func (o *orm) BeginTx(ctx context.Context, opts *sql.TxOptions) error {
 	if o.isTx {
-		return ErrTxHasBegan
+		return o.savepoint(ctx, opts)
 	}
 	var tx *sql.Tx
 	tx, err := o.db.(txer).BeginTx(ctx, opts)
@@ -478,6 +646,8 @@ func (o *orm) BeginTx(ctx context.Context, opts *sql.TxOptions) error {
 		return err
 	}
 	o.isTx = true
+	o.txLevels = []*txLevel{{ctx: ctx}}
+	o.stickToPrimary()
 	if Debug {
 		//o.db.(*dbQueryLog).SetDB(tx)
 		o.db.(*dbQueryTracable).SetDB(tx)
@@ -488,30 +658,38 @@ func (o *orm) BeginTx(ctx context.Context, opts *sql.TxOptions) error {
 	return nil
 }
 
-// commit transaction
+// commit transaction, or release the savepoint of nested transaction.
 func (o *orm) Commit() error {
 	if !o.isTx {
 		return ErrTxDone
 	}
+	if len(o.txLevels) > 1 {
+		return o.releaseSavepoint()
+	}
 	err := o.db.(txEnder).Commit()
 	if err == nil {
 		o.isTx = false
 		o.Using(o.alias.Name)
+		o.endTx(true)
 	} else if err == sql.ErrTxDone {
 		return ErrTxDone
 	}
 	return err
 }
 
-// rollback transaction
+// rollback transaction, or rollback to the savepoint of nested transaction.
 func (o *orm) Rollback() error {
 	if !o.isTx {
 		return ErrTxDone
 	}
+	if len(o.txLevels) > 1 {
+		return o.rollbackSavepoint()
+	}
 	err := o.db.(txEnder).Rollback()
 	if err == nil {
 		o.isTx = false
 		o.Using(o.alias.Name)
+		o.endTx(false)
 	} else if err == sql.ErrTxDone {
 		return ErrTxDone
 	}
@@ -523,6 +701,11 @@ func (o *orm) Raw(query string, args ...interface{}) RawSeter {
 	return newRawSet(o, query, args)
 }
 
+// return a raw query seter for raw sql string with context.
+func (o *orm) RawWithCtx(ctx context.Context, query string, args ...interface{}) RawSeter {
+	return newRawSet(o, query, args).WithContext(ctx)
+}
+
 // return current using database Driver
 func (o *orm) Driver() Driver {
 	return driver(o.alias.Name)
@@ -532,7 +715,7 @@ func (o *orm) Driver() Driver {
 func NewOrm() Ormer {
 	BootStrap() // execute only once
 
-	o := new(orm)
+	o := newOrm()
 	err := o.Using("default")
 	if err != nil {
 		panic(err)
@@ -543,7 +726,7 @@ func NewOrm() Ormer {
 func NewOrmWithSpan(span opentracing.Span) Ormer {
 	BootStrap() // execute only once
 	
-	o := new(orm)
+	o := newOrm()
 	o.span = span
 	err := o.Using("default")
 	if err != nil {
@@ -552,6 +735,25 @@ func NewOrmWithSpan(span opentracing.Span) Ormer {
 	return o
 }
 
+// NewOrmWithContext create new orm with a default context,
+// which is used by operations without ctx, and its tracing span is the parent of query spans.
+// for example:
+//	o := orm.NewOrmWithContext(bCtx)
+//	err := o.Read(&user) // cancelled with bCtx
+func NewOrmWithContext(ctx context.Context) Ormer {
+	BootStrap() // execute only once
+
+	o := newOrm()
+	o.ctx = ctx
+	o.span = opentracing.SpanFromContext(ctx)
+	o.tenant, _ = GetTenant(ctx)
+	err := o.Using("default")
+	if err != nil {
+		panic(err)
+	}
+	return o
+}
+
 // NewOrmWithDB create a new ormer object with specify *sql.DB for query
 func NewOrmWithDB(driverName, aliasName string, db *sql.DB) (Ormer, error) {
 	var al *alias
@@ -570,14 +772,10 @@ func NewOrmWithDB(driverName, aliasName string, db *sql.DB) (Ormer, error) {
 
 	detectTZ(al)
 
-	o := new(orm)
+	o := newOrm()
 	o.alias = al
-
-	if Debug {
-		o.db = newDbQueryLog(o.alias, db)
-	} else {
-		o.db = db
-	}
+	// same as Using, BeginTx relies on the querier being a *dbQueryTracable
+	o.db = newDbQueryTracable(o.alias, db, nil)
 
 	return o, nil
 }
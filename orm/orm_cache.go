@@ -0,0 +1,270 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kfchen81/beego/cache"
+)
+
+// QueryCacheObserver can be implemented by a QueryObserver to receive the hits and misses of QuerySeter.Cache.
+type QueryCacheObserver interface {
+	ObserveQueryCache(alias string, table string, hit bool)
+}
+
+var (
+	queryCacheLock   sync.RWMutex
+	queryCache       cache.Cache
+	queryCachePrefix string
+)
+
+// SetQueryCache set the cache adapter used by QuerySeter.Cache, nil to disable query cache.
+// prefix is added to all keys, so services sharing a cache adapter do not conflict.
+//
+// every table has a version in the cache, which is part of the key of cached queries,
+// inserts, updates and deletes through orm increase the version of table after commit,
+// so the cached queries of table are not read any more and expire by ttl.
+// the versions are stored in the adapter too, so they are shared by all pods when it is redis.
+// for example:
+//
+//	bm, err := cache.NewCache("redis", `{"conn":"127.0.0.1:6379"}`)
+//	orm.SetQueryCache(bm, "order_service")
+func SetQueryCache(c cache.Cache, prefix string) {
+	queryCacheLock.Lock()
+	defer queryCacheLock.Unlock()
+	queryCache = c
+	queryCachePrefix = prefix
+}
+
+func getQueryCache() (cache.Cache, string) {
+	queryCacheLock.RLock()
+	defer queryCacheLock.RUnlock()
+	return queryCache, queryCachePrefix
+}
+
+// get the key of table version
+func getTableVersionKey(prefix string, al *alias, table string) string {
+	return fmt.Sprintf("%s:orm:version:%s:%s", prefix, al.Name, table)
+}
+
+// increase the versions of tables, the cached queries of them are not read any more.
+func invalidateQueryCache(al *alias, tables ...string) {
+	c, prefix := getQueryCache()
+	if c == nil || al == nil {
+		return
+	}
+	for _, table := range tables {
+		key := getTableVersionKey(prefix, al, table)
+		if err := c.Incr(key); err != nil {
+			// some adapters, such as memory, cannot increase a missing key
+			c.Put(key, int64(1), 0)
+		}
+	}
+}
+
+// invalidate the query cache of the table written by query,
+// it is delayed until commit in transaction, as others still read the old rows.
+func (d *dbQueryLog) invalidateQueryCache(query string, err error) {
+	if err != nil {
+		return
+	}
+	if c, _ := getQueryCache(); c == nil {
+		return
+	}
+	operation, table := parseQuery(query)
+	switch operation {
+	case "insert", "replace", "update", "delete":
+	default:
+		return
+	}
+	d.invalidateTable(table)
+}
+
+// invalidate the query cache of table, or delay it until commit in transaction.
+func (d *dbQueryLog) invalidateTable(table string) {
+	if table == "" {
+		return
+	}
+	if _, ok := d.db.(*sql.Tx); ok {
+		d.dirtyTables = append(d.dirtyTables, table)
+		return
+	}
+	invalidateQueryCache(d.alias, table)
+}
+
+// invalidate the query cache of tables written in transaction if it is committed.
+func (d *dbQueryLog) endTxQueryCache(committed bool) {
+	tables := d.dirtyTables
+	d.dirtyTables = nil
+	if committed {
+		invalidateQueryCache(d.alias, tables...)
+	}
+}
+
+// invalidate the query cache of table written by orm without querier, such as prepared statement.
+func (o *orm) invalidateQueryCache(table string) {
+	invalidateQuerierCache(o.db, table)
+}
+
+// invalidate the query cache of table written by a query which returns rows, such as INSERT ... RETURNING,
+// as the querier only invalidates the query cache on Exec.
+func invalidateQuerierCache(q dbQuerier, table string) {
+	if c, _ := getQueryCache(); c == nil {
+		return
+	}
+	switch d := q.(type) {
+	case *dbQueryTracable:
+		d.invalidateTable(table)
+	case *dbQueryLog:
+		d.invalidateTable(table)
+	case *dbQueryCtx:
+		invalidateQuerierCache(d.dbQuerier, table)
+	case *dbQueryRouter:
+		invalidateQuerierCache(d.dbQuerier, table)
+	}
+}
+
+// notify observers about a hit or miss of query cache.
+func observeQueryCache(al *alias, table string, hit bool) {
+	for _, observer := range getQueryObservers() {
+		if o, ok := observer.(QueryCacheObserver); ok {
+			o.ObserveQueryCache(al.Name, table, hit)
+		}
+	}
+}
+
+// get the cache adapter and the key of QuerySeter, ok is false if the query cannot be cached.
+// the key is the hash of kind, exprs, sql, args and the versions of tables in sql.
+func (o *querySet) getQueryCacheKey(kind string, exprs []string) (c cache.Cache, key string, ok bool) {
+	if o.cacheTTL <= 0 || o.orm.isTx || o.forupdate || len(o.related) > 0 || o.relDepth > 0 {
+		return nil, "", false
+	}
+	c, prefix := getQueryCache()
+	if c == nil {
+		return nil, "", false
+	}
+	cond, err := o.condition()
+	if err != nil {
+		return nil, "", false
+	}
+	rq, err := o.orm.alias.DbBaser.ReadQuery(o, o.mi, cond, o.orm.alias.TZ, nil)
+	if err != nil {
+		return nil, "", false
+	}
+
+	tables := []string{o.mi.table}
+	for _, tbl := range rq.tables.tables {
+		tables = append(tables, tbl.mi.table)
+	}
+	keys := make([]string, 0, len(tables))
+	for _, table := range tables {
+		keys = append(keys, getTableVersionKey(prefix, o.orm.alias, table))
+	}
+	versions := c.GetMulti(keys)
+	if len(versions) != len(keys) {
+		return nil, "", false
+	}
+
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%#v\n", kind, strings.Join(exprs, ","), rq.query, rq.args)
+	for i, table := range tables {
+		fmt.Fprintf(h, "%s=%s\n", table, cache.GetString(versions[i]))
+	}
+	key = fmt.Sprintf("%s:orm:query:%s:%s:%s", prefix, o.orm.alias.Name, o.mi.table, hex.EncodeToString(h.Sum(nil)))
+	return c, key, true
+}
+
+// read the result of QuerySeter from query cache into container,
+// or call read and put the result into query cache when missed.
+// container can be nil when the result is the number returned by read only.
+func (o *querySet) readQueryCache(kind string, exprs []string, container interface{}, read func() (int64, error)) (int64, error) {
+	c, key, ok := o.getQueryCacheKey(kind, exprs)
+	if !ok {
+		return read()
+	}
+
+	if num, ok := getQueryCacheValue(c.Get(key), container); ok {
+		observeQueryCache(o.orm.alias, o.mi.table, true)
+		return num, nil
+	}
+	observeQueryCache(o.orm.alias, o.mi.table, false)
+
+	num, err := read()
+	if err != nil {
+		return num, err
+	}
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(num); err != nil {
+		return num, nil
+	}
+	if container != nil {
+		if err := enc.Encode(container); err != nil {
+			// the container cannot be encoded, such as a model with a cycle or unexported fields
+			return num, nil
+		}
+	}
+	c.Put(key, buf.Bytes(), o.cacheTTL)
+	return num, nil
+}
+
+// decode the cached value into container.
+func getQueryCacheValue(value interface{}, container interface{}) (int64, bool) {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return 0, false
+	}
+
+	var num int64
+	dec := gob.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&num); err != nil {
+		return 0, false
+	}
+	if container != nil {
+		// gob does not send zero values, so the container is reset before decoding
+		ind := reflect.Indirect(reflect.ValueOf(container))
+		ind.Set(reflect.Zero(ind.Type()))
+		if err := dec.Decode(container); err != nil {
+			return 0, false
+		}
+	}
+	return num, true
+}
+
+// cache the results of QuerySeter for ttl.
+func (o querySet) Cache(ttl time.Duration) QuerySeter {
+	o.cacheTTL = ttl
+	return &o
+}
+
+func init() {
+	// the values of Params and ParamsList
+	gob.Register(time.Time{})
+}
@@ -0,0 +1,73 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+)
+
+// dbQueryCtx binds a context to a dbQuerier.
+// dbBaser only calls Prepare/Exec/Query/QueryRow, so they are redirected to the *Context methods,
+// which passes cancellation, deadline and tracing span of ctx to the database driver.
+type dbQueryCtx struct {
+	dbQuerier
+	ctx context.Context
+}
+
+var _ dbQuerier = new(dbQueryCtx)
+
+func (d *dbQueryCtx) Prepare(query string) (*sql.Stmt, error) {
+	return d.dbQuerier.PrepareContext(d.ctx, query)
+}
+
+func (d *dbQueryCtx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.dbQuerier.ExecContext(d.ctx, query, args...)
+}
+
+func (d *dbQueryCtx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.dbQuerier.QueryContext(d.ctx, query, args...)
+}
+
+func (d *dbQueryCtx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return d.dbQuerier.QueryRowContext(d.ctx, query, args...)
+}
+
+// get the querier running queries with ctx.
+// if ctx is nil, use the default context of orm, or run queries without context.
+// reads are sent to replicas if the alias has replicas and not in transaction.
+func (o *orm) querier(ctx context.Context) dbQuerier {
+	if ctx == nil {
+		ctx = o.ctx
+	}
+	q := o.db
+	if !o.isTx {
+		if group := getReplicaGroup(o.alias.Name); group != nil {
+			q = &dbQueryRouter{dbQuerier: o.db, o: o, group: group}
+		}
+	}
+	if ctx == nil {
+		return q
+	}
+	return &dbQueryCtx{dbQuerier: q, ctx: ctx}
+}
+
+// get the default context of orm.
+func (o *orm) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
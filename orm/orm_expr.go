@@ -0,0 +1,269 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expr is an arithmetic expression of fields and values, created by F.
+type Expr struct {
+	field string
+	value interface{}
+	opt   operator
+	left  *Expr
+	right *Expr
+}
+
+// F refer to the field of model in expression.
+// it can be used as the value of QuerySeter.Update, or in aggregations. for example:
+//
+//	// UPDATE product SET stock = stock - 1, sold = sold + 1 WHERE id = 1
+//	qs.Filter("id", 1).Update(orm.Params{
+//		"stock": orm.F("stock").Minus(1),
+//		"sold":  orm.F("sold").Add(1),
+//	})
+//	// SELECT SUM(price * quantity) AS total FROM item
+//	qs.Aggregate(&total, orm.Sum(orm.F("price").Multiply(orm.F("quantity"))).As("total"))
+func F(field string) Expr {
+	return Expr{field: field}
+}
+
+func toExpr(v interface{}) *Expr {
+	if e, ok := v.(Expr); ok {
+		return &e
+	}
+	if e, ok := v.(*Expr); ok {
+		return e
+	}
+	return &Expr{value: v}
+}
+
+func (e Expr) combine(opt operator, v interface{}) Expr {
+	return Expr{opt: opt, left: &e, right: toExpr(v)}
+}
+
+// Add return expression e + v, v is a value or another Expr
+func (e Expr) Add(v interface{}) Expr {
+	return e.combine(ColAdd, v)
+}
+
+// Minus return expression e - v, v is a value or another Expr
+func (e Expr) Minus(v interface{}) Expr {
+	return e.combine(ColMinus, v)
+}
+
+// Multiply return expression e * v, v is a value or another Expr
+func (e Expr) Multiply(v interface{}) Expr {
+	return e.combine(ColMultiply, v)
+}
+
+// Except return expression e / v, v is a value or another Expr
+func (e Expr) Except(v interface{}) Expr {
+	return e.combine(ColExcept, v)
+}
+
+// generate the sql of expression, column returns the column sql of field name.
+func (e *Expr) getSQL(column func(string) string) (string, []interface{}) {
+	if e.left == nil {
+		if e.field != "" {
+			return column(e.field), nil
+		}
+		return "?", []interface{}{e.value}
+	}
+	var sign string
+	switch e.opt {
+	case ColAdd:
+		sign = "+"
+	case ColMinus:
+		sign = "-"
+	case ColMultiply:
+		sign = "*"
+	case ColExcept:
+		sign = "/"
+	}
+	left, args := e.left.getSQL(column)
+	right, rargs := e.right.getSQL(column)
+	return fmt.Sprintf("(%s %s %s)", left, sign, right), append(args, rargs...)
+}
+
+// Aggregation is an aggregate function of field or expression, used by QuerySeter.Aggregate.
+// with Over, it is a window function computed for each row, used by QuerySeter.Annotate.
+type Aggregation struct {
+	fn       string
+	expr     interface{}
+	distinct bool
+	alias    string
+	window   *Window
+}
+
+// Count aggregate the count of rows, expr is the field name, "*" or an Expr
+func Count(expr interface{}) Aggregation {
+	return Aggregation{fn: "COUNT", expr: expr}
+}
+
+// Sum aggregate the sum of field name or Expr
+func Sum(expr interface{}) Aggregation {
+	return Aggregation{fn: "SUM", expr: expr}
+}
+
+// Avg aggregate the average of field name or Expr
+func Avg(expr interface{}) Aggregation {
+	return Aggregation{fn: "AVG", expr: expr}
+}
+
+// Max aggregate the max value of field name or Expr
+func Max(expr interface{}) Aggregation {
+	return Aggregation{fn: "MAX", expr: expr}
+}
+
+// Min aggregate the min value of field name or Expr
+func Min(expr interface{}) Aggregation {
+	return Aggregation{fn: "MIN", expr: expr}
+}
+
+// RowNumber is the window function numbering rows of the window from 1, it must be used with Over
+func RowNumber() Aggregation {
+	return Aggregation{fn: "ROW_NUMBER"}
+}
+
+// Rank is the window function ranking rows of the window with gaps, it must be used with Over
+func Rank() Aggregation {
+	return Aggregation{fn: "RANK"}
+}
+
+// DenseRank is the window function ranking rows of the window without gaps, it must be used with Over
+func DenseRank() Aggregation {
+	return Aggregation{fn: "DENSE_RANK"}
+}
+
+// Distinct aggregate the distinct values only, such as COUNT(DISTINCT user_id)
+func (a Aggregation) Distinct() Aggregation {
+	a.distinct = true
+	return a
+}
+
+// As set the result column name of aggregation.
+// the default name is field name and function joined by "_", such as price_sum.
+func (a Aggregation) As(alias string) Aggregation {
+	a.alias = alias
+	return a
+}
+
+// Over compute the aggregation over the window of each row instead of grouping rows.
+// for example:
+//
+//	// SUM(price) OVER (PARTITION BY user_id ORDER BY created DESC) AS running_total
+//	orm.Sum("Price").Over(orm.PartitionBy("UserId").OrderBy("-Created")).As("running_total")
+//	// ROW_NUMBER() OVER (ORDER BY price DESC) AS row_number
+//	orm.RowNumber().Over(orm.Window{}.OrderBy("-Price"))
+func (a Aggregation) Over(window Window) Aggregation {
+	a.window = &window
+	return a
+}
+
+// generate the sql of aggregation, column returns the column sql of field name.
+func (a Aggregation) getSQL(column func(string) string) (string, []interface{}) {
+	var (
+		expr string
+		args []interface{}
+	)
+	switch v := a.expr.(type) {
+	case nil:
+		if a.window == nil {
+			panic(fmt.Errorf("<Aggregation> window function `%s` must be used with Over", a.fn))
+		}
+	case string:
+		if v == "*" {
+			expr = v
+		} else {
+			expr = column(v)
+		}
+	case Expr, *Expr:
+		expr, args = toExpr(v).getSQL(column)
+	default:
+		panic(fmt.Errorf("<Aggregation> unsupported expression type `%T`", a.expr))
+	}
+	if a.distinct {
+		expr = "DISTINCT " + expr
+	}
+	if a.window != nil {
+		return fmt.Sprintf("%s(%s) OVER (%s)", a.fn, expr, a.window.getSQL(column)), args
+	}
+	return fmt.Sprintf("%s(%s)", a.fn, expr), args
+}
+
+// get the result column name of aggregation
+func (a Aggregation) getAlias() string {
+	if a.alias != "" {
+		return a.alias
+	}
+	fn := strings.ToLower(a.fn)
+	if name, ok := a.expr.(string); ok && name != "*" {
+		return getExprAlias(name) + "_" + fn
+	}
+	return fn
+}
+
+// Window is the rows related to the current row for window functions, created by PartitionBy or Window{}.
+type Window struct {
+	partitions []string
+	orders     []string
+}
+
+// PartitionBy return the window of rows with the same values of exprs
+func PartitionBy(exprs ...string) Window {
+	return Window{partitions: exprs}
+}
+
+// OrderBy order the rows of window, "-" prefix means DESC, such as "-Created"
+func (w Window) OrderBy(exprs ...string) Window {
+	w.orders = exprs
+	return w
+}
+
+// generate the sql of window definition, column returns the column sql of field name.
+func (w *Window) getSQL(column func(string) string) string {
+	var clauses []string
+	if len(w.partitions) > 0 {
+		cols := make([]string, 0, len(w.partitions))
+		for _, expr := range w.partitions {
+			cols = append(cols, column(expr))
+		}
+		clauses = append(clauses, "PARTITION BY "+strings.Join(cols, ", "))
+	}
+	if len(w.orders) > 0 {
+		cols := make([]string, 0, len(w.orders))
+		for _, expr := range w.orders {
+			if expr != "" && expr[0] == '-' {
+				cols = append(cols, column(expr[1:])+" DESC")
+			} else {
+				cols = append(cols, column(expr)+" ASC")
+			}
+		}
+		clauses = append(clauses, "ORDER BY "+strings.Join(cols, ", "))
+	}
+	return strings.Join(clauses, " ")
+}
+
+// get the result column name of field expression, such as profile__Age to profile_age
+func getExprAlias(expr string) string {
+	names := strings.Split(expr, ExprSep)
+	for i, name := range names {
+		names[i] = nameStrategyMap[nameStrategy](name)
+	}
+	return strings.Join(names, "_")
+}
@@ -0,0 +1,273 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// Model hooks, implement them on the pointer of model struct.
+// Ormer o is the one running the operation, so queries in hooks join its transaction.
+// An error returned by a Before hook aborts the operation,
+// an error returned by an After hook is returned after the operation is done.
+// for example:
+//
+//	func (u *User) BeforeInsert(ctx context.Context, o orm.Ormer) error {
+//		u.CreatedBy = getOperator(ctx)
+//		return nil
+//	}
+
+// BeforeInserter is called before Ormer.Insert, InsertOrUpdate, InsertMulti and Inserter.Insert
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context, o Ormer) error
+}
+
+// AfterInserter is called after Ormer.Insert, InsertOrUpdate, InsertMulti and Inserter.Insert
+type AfterInserter interface {
+	AfterInsert(ctx context.Context, o Ormer) error
+}
+
+// BeforeUpdater is called before Ormer.Update
+type BeforeUpdater interface {
+	BeforeUpdate(ctx context.Context, o Ormer) error
+}
+
+// AfterUpdater is called after Ormer.Update
+type AfterUpdater interface {
+	AfterUpdate(ctx context.Context, o Ormer) error
+}
+
+// BeforeDeleter is called before Ormer.Delete
+type BeforeDeleter interface {
+	BeforeDelete(ctx context.Context, o Ormer) error
+}
+
+// AfterDeleter is called after Ormer.Delete
+type AfterDeleter interface {
+	AfterDelete(ctx context.Context, o Ormer) error
+}
+
+// BeforeReader is called before Ormer.Read, ReadForUpdate and ReadOrCreate
+type BeforeReader interface {
+	BeforeRead(ctx context.Context, o Ormer) error
+}
+
+// AfterReader is called after a model is read by Ormer.Read, ReadForUpdate, ReadOrCreate,
+// QuerySeter.One and QuerySeter.All
+type AfterReader interface {
+	AfterRead(ctx context.Context, o Ormer) error
+}
+
+// BeforeBulkUpdater is called before QuerySeter.Update with the values to update,
+// which can be changed by the hook. there is no model instance, the receiver is a zero model.
+type BeforeBulkUpdater interface {
+	BeforeBulkUpdate(ctx context.Context, o Ormer, values Params) error
+}
+
+// AfterBulkUpdater is called after QuerySeter.Update with the number of updated rows.
+type AfterBulkUpdater interface {
+	AfterBulkUpdate(ctx context.Context, o Ormer, values Params, num int64) error
+}
+
+type hookFunc func(md interface{}, ctx context.Context, o Ormer) error
+
+func beforeInsert(md interface{}, ctx context.Context, o Ormer) error {
+	if h, ok := md.(BeforeInserter); ok {
+		return h.BeforeInsert(ctx, o)
+	}
+	return nil
+}
+
+func afterInsert(md interface{}, ctx context.Context, o Ormer) error {
+	if h, ok := md.(AfterInserter); ok {
+		return h.AfterInsert(ctx, o)
+	}
+	return nil
+}
+
+func beforeUpdate(md interface{}, ctx context.Context, o Ormer) error {
+	if h, ok := md.(BeforeUpdater); ok {
+		return h.BeforeUpdate(ctx, o)
+	}
+	return nil
+}
+
+func afterUpdate(md interface{}, ctx context.Context, o Ormer) error {
+	if h, ok := md.(AfterUpdater); ok {
+		return h.AfterUpdate(ctx, o)
+	}
+	return nil
+}
+
+func beforeDelete(md interface{}, ctx context.Context, o Ormer) error {
+	if h, ok := md.(BeforeDeleter); ok {
+		return h.BeforeDelete(ctx, o)
+	}
+	return nil
+}
+
+func afterDelete(md interface{}, ctx context.Context, o Ormer) error {
+	if h, ok := md.(AfterDeleter); ok {
+		return h.AfterDelete(ctx, o)
+	}
+	return nil
+}
+
+func beforeRead(md interface{}, ctx context.Context, o Ormer) error {
+	if h, ok := md.(BeforeReader); ok {
+		return h.BeforeRead(ctx, o)
+	}
+	return nil
+}
+
+func afterRead(md interface{}, ctx context.Context, o Ormer) error {
+	if h, ok := md.(AfterReader); ok {
+		return h.AfterRead(ctx, o)
+	}
+	return nil
+}
+
+// call hook on the models of a slice, models which are not addressable are skipped
+func callSliceHook(hook hookFunc, sind reflect.Value, ctx context.Context, o Ormer) error {
+	for i := 0; i < sind.Len(); i++ {
+		ind := reflect.Indirect(sind.Index(i))
+		if ind.Kind() != reflect.Struct || !ind.CanAddr() {
+			continue
+		}
+		if err := hook(ind.Addr().Interface(), ctx, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// call AfterRead on the models read into container of QuerySeter
+func callAfterRead(mi *modelInfo, container interface{}, ctx context.Context, o Ormer) error {
+	if _, ok := mi.addrField.Interface().(AfterReader); !ok {
+		return nil
+	}
+	ind := reflect.Indirect(reflect.ValueOf(container))
+	switch ind.Kind() {
+	case reflect.Struct:
+		if ind.CanAddr() {
+			return afterRead(ind.Addr().Interface(), ctx, o)
+		}
+	case reflect.Slice:
+		return callSliceHook(afterRead, ind, ctx, o)
+	}
+	return nil
+}
+
+// add auto_now_always fields to values of QuerySeter.Update if they are not set
+func (o *querySet) addAutoNowValues(values Params) Params {
+	var result Params
+	for _, fi := range o.mi.fields.fieldsDB {
+		if !fi.autoNowAlways {
+			continue
+		}
+		found := false
+		for col := range values {
+			if f, ok := o.mi.fields.GetByAny(col); ok && f == fi {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		if result == nil {
+			// do not change values of the caller
+			result = make(Params, len(values)+1)
+			for k, v := range values {
+				result[k] = v
+			}
+		}
+		result[fi.name] = o.orm.nowToDB()
+	}
+	if result == nil {
+		return values
+	}
+	return result
+}
+
+// get current time converted for the database
+func (o *orm) nowToDB() time.Time {
+	tnow := time.Now()
+	o.alias.DbBaser.TimeToDB(&tnow, o.alias.TZ)
+	return tnow
+}
+
+// soft delete the model by setting its soft_delete field,
+// cols shows the delete conditions values read from. default is pk
+func (o *orm) softDelete(ctx context.Context, mi *modelInfo, ind reflect.Value, cols []string) (int64, error) {
+	cond := NewCondition()
+	if len(cols) == 0 {
+		_, pkValue, ok := getExistPk(mi, ind)
+		if !ok {
+			return 0, ErrMissPK
+		}
+		cond = cond.And(mi.fields.pk.name, pkValue)
+	} else {
+		for _, col := range cols {
+			fi := o.getFieldInfo(mi, col)
+			cond = cond.And(fi.name, ind.FieldByIndex(fi.fieldIndex).Interface())
+		}
+	}
+
+	fi := mi.fields.softDelete
+	tnow := o.nowToDB()
+	qs := newQuerySet(o, mi).(*querySet)
+	qs.ctx = ctx
+	qs.cond = cond
+	num, err := qs.update(Params{fi.name: tnow})
+	if err != nil || num == 0 {
+		return num, err
+	}
+
+	v := tnow.In(DefaultTimeLoc)
+	field := ind.FieldByIndex(fi.fieldIndex)
+	if fi.isFielder {
+		err = field.Addr().Interface().(Fielder).SetRaw(v)
+	} else if field.Kind() == reflect.Ptr {
+		field.Set(reflect.ValueOf(&v))
+	} else {
+		field.Set(reflect.ValueOf(v))
+	}
+	return num, err
+}
+
+// get the condition of QuerySeter, rows soft deleted are filtered out unless Unscoped,
+// and rows of other tenants are filtered out if orm is scoped by tenant.
+func (o *querySet) condition() (*Condition, error) {
+	fi := o.mi.fields.softDelete
+	if fi == nil || o.unscoped {
+		return o.addTenantCond(o.cond)
+	}
+	cond := NewCondition().And(fi.name+ExprSep+"isnull", true)
+	if o.cond == nil || o.cond.IsEmpty() {
+		return o.addTenantCond(cond)
+	}
+	return o.addTenantCond(cond.AndCond(o.cond))
+}
+
+// get the context passed to hooks, never nil
+func (o *orm) hookContext(ctx context.Context) context.Context {
+	if ctx != nil {
+		return ctx
+	}
+	return o.context()
+}
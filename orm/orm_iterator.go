@@ -0,0 +1,151 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ErrIteratorNoRow is returned by Iterator.Scan when it is not called after a successful Next.
+var ErrIteratorNoRow = errors.New("<Iterator.Scan> no row to scan, call Next first")
+
+// iterator of QuerySeter, scan the rows into model struct one by one.
+type queryIterator struct {
+	d      *dbBase
+	rows   *sql.Rows
+	rq     *readQuery
+	refs   []interface{}
+	mi     *modelInfo
+	tz     *time.Location
+	al     *alias
+	orm    *orm
+	ctx    context.Context
+	cnt    int64
+	err    error
+	ready  bool
+	closed bool
+}
+
+var _ Iterator = new(queryIterator)
+
+// move to the next row, the rows are closed when there is no more row or an error occurs.
+func (it *queryIterator) Next() bool {
+	it.ready = false
+	if it.closed {
+		return false
+	}
+	if !it.rows.Next() {
+		it.err = it.rows.Err()
+		it.Close()
+		return false
+	}
+	if err := it.rows.Scan(it.refs...); err != nil {
+		it.err = err
+		it.Close()
+		return false
+	}
+	it.cnt++
+	it.ready = true
+	return true
+}
+
+// scan the current row into a model struct pointer, the selected related models are set too.
+func (it *queryIterator) Scan(containers ...interface{}) error {
+	if len(containers) != 1 {
+		return fmt.Errorf("<Iterator.Scan> need one *%s, got %d args", it.mi.fullName, len(containers))
+	}
+	val := reflect.ValueOf(containers[0])
+	ind := reflect.Indirect(val)
+	if val.Kind() != reflect.Ptr || ind.Kind() != reflect.Struct || getFullName(ind.Type()) != it.mi.fullName {
+		return fmt.Errorf("wrong object type `%s` for rows scan, need *%s", val.Type(), it.mi.fullName)
+	}
+	if !it.ready {
+		return ErrIteratorNoRow
+	}
+
+	ind.Set(reflect.New(ind.Type()).Elem())
+	it.d.setReadRowValues(it.mi, ind, it.rq, it.refs, it.tz)
+	return afterRead(containers[0], it.orm.hookContext(it.ctx), it.orm)
+}
+
+// return the error occurred during iteration.
+func (it *queryIterator) Err() error {
+	return it.err
+}
+
+// close the rows and release the connection.
+func (it *queryIterator) Close() error {
+	if it.closed {
+		return nil
+	}
+	it.closed = true
+	it.ready = false
+	observeRows(it.al, it.mi.table, it.cnt)
+	return it.rows.Close()
+}
+
+// iterator of RawSeter, scan the rows like QueryRow one by one.
+type rawIterator struct {
+	rs     *rawSet
+	rows   *sql.Rows
+	err    error
+	ready  bool
+	closed bool
+}
+
+var _ Iterator = new(rawIterator)
+
+// move to the next row, the rows are closed when there is no more row or an error occurs.
+func (it *rawIterator) Next() bool {
+	it.ready = false
+	if it.closed {
+		return false
+	}
+	if !it.rows.Next() {
+		it.err = it.rows.Err()
+		it.Close()
+		return false
+	}
+	it.ready = true
+	return true
+}
+
+// scan the current row into containers, see RawSeter.QueryRow
+func (it *rawIterator) Scan(containers ...interface{}) error {
+	if !it.ready {
+		return ErrIteratorNoRow
+	}
+	return it.rs.scanRow(it.rows, containers)
+}
+
+// return the error occurred during iteration.
+func (it *rawIterator) Err() error {
+	return it.err
+}
+
+// close the rows and release the connection.
+func (it *rawIterator) Close() error {
+	if it.closed {
+		return nil
+	}
+	it.closed = true
+	it.ready = false
+	return it.rows.Close()
+}
@@ -0,0 +1,211 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// parse a keyset order expression to field info and direction.
+func (o *querySet) parseKeysetOrder(order string) (*fieldInfo, bool, error) {
+	desc := false
+	name := order
+	if len(name) > 0 && name[0] == '-' {
+		desc = true
+		name = name[1:]
+	}
+	if strings.Contains(name, ExprSep) {
+		return nil, false, fmt.Errorf("<QuerySeter> keyset order on related field `%s` is not supported", order)
+	}
+	fi, ok := o.mi.fields.GetByAny(name)
+	if !ok || !fi.dbcol {
+		return nil, false, fmt.Errorf("<QuerySeter> wrong field/column name `%s`", name)
+	}
+	return fi, desc, nil
+}
+
+// return the ORDER BY expressions used by keyset pagination.
+// they are the orders of QuerySeter followed by the primary key,
+// so rows with equal sort values still have a stable order.
+func (o querySet) KeysetOrders() ([]string, error) {
+	orders := make([]string, 0, len(o.orders)+1)
+	hasPk := false
+	desc := false
+	for _, order := range o.orders {
+		fi, isDesc, err := o.parseKeysetOrder(order)
+		if err != nil {
+			return nil, err
+		}
+		desc = isDesc
+		if fi.pk {
+			hasPk = true
+		}
+		if desc {
+			orders = append(orders, "-"+fi.column)
+		} else {
+			orders = append(orders, fi.column)
+		}
+	}
+
+	if !hasPk {
+		// the tie-breaker follows the direction of the last order
+		pk := o.mi.fields.pk.column
+		if desc {
+			pk = "-" + pk
+		}
+		orders = append(orders, pk)
+	}
+	return orders, nil
+}
+
+// read the values of KeysetOrders from a model struct.
+// time values are formatted as the field type, so they can be used as filter args.
+func (o querySet) KeysetValues(md interface{}) ([]interface{}, error) {
+	ind := reflect.Indirect(reflect.ValueOf(md))
+	if ind.Kind() != reflect.Struct || getFullName(ind.Type()) != o.mi.fullName {
+		return nil, fmt.Errorf("<QuerySeter.KeysetValues> wrong object type `%s`, need *%s", reflect.TypeOf(md), o.mi.fullName)
+	}
+
+	orders, err := o.KeysetOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, 0, len(orders))
+	for _, order := range orders {
+		fi, _, err := o.parseKeysetOrder(order)
+		if err != nil {
+			return nil, err
+		}
+		field := ind.FieldByIndex(fi.fieldIndex)
+		var value interface{}
+		if fi.rel {
+			if field.IsNil() {
+				return nil, fmt.Errorf("<QuerySeter.KeysetValues> related field `%s` is nil", fi.name)
+			}
+			_, value, _ = getExistPk(fi.relModelInfo, reflect.Indirect(field))
+		} else if t, ok := field.Interface().(time.Time); ok {
+			tz := o.orm.alias.TZ
+			switch fi.fieldType {
+			case TypeDateField:
+				value = t.In(tz).Format(formatDate)
+			case TypeTimeField:
+				value = t.In(tz).Format(formatTime)
+			default:
+				value = t.In(tz).Format(formatDateTime)
+			}
+		} else {
+			value = field.Interface()
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// return a QuerySeter which seeks the rows after the row with given keyset values.
+// values must be in the order of KeysetOrders, the query is also ordered by KeysetOrders.
+// for example, orders ["-created_at", "-id"] generate:
+//	WHERE (created_at < ?) OR (created_at = ? AND id < ?) ORDER BY created_at DESC, id DESC
+func (o querySet) SeekAfter(values ...interface{}) (QuerySeter, error) {
+	orders, err := o.KeysetOrders()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != len(orders) {
+		return nil, fmt.Errorf("<QuerySeter.SeekAfter> need %d values for orders %v, got %d", len(orders), orders, len(values))
+	}
+	for i, value := range values {
+		if value == nil {
+			return nil, fmt.Errorf("<QuerySeter.SeekAfter> value of order `%s` is null", orders[i])
+		}
+	}
+
+	seek := NewCondition()
+	for i := range orders {
+		branch := NewCondition()
+		for j := 0; j < i; j++ {
+			branch = branch.And(strings.TrimPrefix(orders[j], "-"), values[j])
+		}
+		if strings.HasPrefix(orders[i], "-") {
+			branch = branch.And(orders[i][1:]+ExprSep+"lt", values[i])
+		} else {
+			branch = branch.And(orders[i]+ExprSep+"gt", values[i])
+		}
+		seek = seek.OrCond(branch)
+	}
+
+	if o.cond == nil {
+		o.cond = NewCondition()
+	}
+	o.cond = o.cond.AndCond(seek)
+	o.orders = orders
+	return &o, nil
+}
+
+// read the rows in batches of size ordered by KeysetOrders, and call fn after each batch is read into container.
+// the next batch seeks after the last row of container, which is read before fn is called,
+// so fn is free to modify container.
+func (o querySet) ForEachBatch(ctx context.Context, size int, container interface{}, fn func(num int64) error) error {
+	if size <= 0 {
+		return fmt.Errorf("<QuerySeter.ForEachBatch> size must be greater than 0, got %d", size)
+	}
+	ind := reflect.Indirect(reflect.ValueOf(container))
+	if ind.Kind() != reflect.Slice {
+		return fmt.Errorf("<QuerySeter.ForEachBatch> wrong object type `%T`, need *[]*%s or *[]%s", container, o.mi.fullName, o.mi.fullName)
+	}
+
+	orders, err := o.KeysetOrders()
+	if err != nil {
+		return err
+	}
+	o.orders = orders
+	o.offset = 0
+	o.ctx = ctx
+	o.forContext = true
+	var qs QuerySeter = o.Limit(size)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		num, err := qs.All(container)
+		if err != nil {
+			return err
+		}
+		if num == 0 {
+			return nil
+		}
+
+		values, err := o.KeysetValues(ind.Index(ind.Len() - 1).Interface())
+		if err != nil {
+			return err
+		}
+		if err := fn(num); err != nil {
+			return err
+		}
+		if num < int64(size) {
+			return nil
+		}
+
+		if qs, err = o.SeekAfter(values...); err != nil {
+			return err
+		}
+		qs = qs.Limit(size)
+	}
+}
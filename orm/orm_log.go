@@ -77,6 +77,7 @@ func (d *stmtQueryLog) Close() error {
 func (d *stmtQueryLog) Exec(args ...interface{}) (sql.Result, error) {
 	a := time.Now()
 	res, err := d.stmt.Exec(args...)
+	observeQuery(d.alias, d.query, a, res, err)
 	debugLogQueies(d.alias, "st.Exec", d.query, a, err, args...)
 	return res, err
 }
@@ -84,6 +85,7 @@ func (d *stmtQueryLog) Exec(args ...interface{}) (sql.Result, error) {
 func (d *stmtQueryLog) Query(args ...interface{}) (*sql.Rows, error) {
 	a := time.Now()
 	res, err := d.stmt.Query(args...)
+	observeQuery(d.alias, d.query, a, nil, err)
 	debugLogQueies(d.alias, "st.Query", d.query, a, err, args...)
 	return res, err
 }
@@ -91,6 +93,7 @@ func (d *stmtQueryLog) Query(args ...interface{}) (*sql.Rows, error) {
 func (d *stmtQueryLog) QueryRow(args ...interface{}) *sql.Row {
 	a := time.Now()
 	res := d.stmt.QueryRow(args...)
+	observeQuery(d.alias, d.query, a, nil, nil)
 	debugLogQueies(d.alias, "st.QueryRow", d.query, a, nil, args...)
 	return res
 }
@@ -110,6 +113,8 @@ type dbQueryLog struct {
 	db    dbQuerier
 	tx    txer
 	txe   txEnder
+
+	dirtyTables []string // tables written in transaction, their query cache is invalidated after commit
 }
 
 var _ dbQuerier = new(dbQueryLog)
@@ -133,6 +138,8 @@ func (d *dbQueryLog) PrepareContext(ctx context.Context, query string) (*sql.Stm
 func (d *dbQueryLog) Exec(query string, args ...interface{}) (sql.Result, error) {
 	a := time.Now()
 	res, err := d.db.Exec(query, args...)
+	observeQuery(d.alias, query, a, res, err)
+	d.invalidateQueryCache(query, err)
 	debugLogQueies(d.alias, "db.Exec", query, a, err, args...)
 	return res, err
 }
@@ -140,6 +147,8 @@ func (d *dbQueryLog) Exec(query string, args ...interface{}) (sql.Result, error)
 func (d *dbQueryLog) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	a := time.Now()
 	res, err := d.db.ExecContext(ctx, query, args...)
+	observeQuery(d.alias, query, a, res, err)
+	d.invalidateQueryCache(query, err)
 	debugLogQueies(d.alias, "db.Exec", query, a, err, args...)
 	return res, err
 }
@@ -147,6 +156,7 @@ func (d *dbQueryLog) ExecContext(ctx context.Context, query string, args ...inte
 func (d *dbQueryLog) Query(query string, args ...interface{}) (*sql.Rows, error) {
 	a := time.Now()
 	res, err := d.db.Query(query, args...)
+	observeQuery(d.alias, query, a, nil, err)
 	debugLogQueies(d.alias, "db.Query", query, a, err, args...)
 	return res, err
 }
@@ -154,6 +164,7 @@ func (d *dbQueryLog) Query(query string, args ...interface{}) (*sql.Rows, error)
 func (d *dbQueryLog) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
 	a := time.Now()
 	res, err := d.db.QueryContext(ctx, query, args...)
+	observeQuery(d.alias, query, a, nil, err)
 	debugLogQueies(d.alias, "db.Query", query, a, err, args...)
 	return res, err
 }
@@ -161,6 +172,7 @@ func (d *dbQueryLog) QueryContext(ctx context.Context, query string, args ...int
 func (d *dbQueryLog) QueryRow(query string, args ...interface{}) *sql.Row {
 	a := time.Now()
 	res := d.db.QueryRow(query, args...)
+	observeQuery(d.alias, query, a, nil, nil)
 	debugLogQueies(d.alias, "db.QueryRow", query, a, nil, args...)
 	return res
 }
@@ -168,6 +180,7 @@ func (d *dbQueryLog) QueryRow(query string, args ...interface{}) *sql.Row {
 func (d *dbQueryLog) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
 	a := time.Now()
 	res := d.db.QueryRowContext(ctx, query, args...)
+	observeQuery(d.alias, query, a, nil, nil)
 	debugLogQueies(d.alias, "db.QueryRow", query, a, nil, args...)
 	return res
 }
@@ -175,6 +188,7 @@ func (d *dbQueryLog) QueryRowContext(ctx context.Context, query string, args ...
 func (d *dbQueryLog) Begin() (*sql.Tx, error) {
 	a := time.Now()
 	tx, err := d.db.(txer).Begin()
+	observeQuery(d.alias, "BEGIN", a, nil, err)
 	debugLogQueies(d.alias, "db.Begin", "START TRANSACTION", a, err)
 	return tx, err
 }
@@ -182,6 +196,7 @@ func (d *dbQueryLog) Begin() (*sql.Tx, error) {
 func (d *dbQueryLog) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
 	a := time.Now()
 	tx, err := d.db.(txer).BeginTx(ctx, opts)
+	observeQuery(d.alias, "BEGIN", a, nil, err)
 	debugLogQueies(d.alias, "db.BeginTx", "START TRANSACTION", a, err)
 	return tx, err
 }
@@ -189,6 +204,8 @@ func (d *dbQueryLog) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx,
 func (d *dbQueryLog) Commit() error {
 	a := time.Now()
 	err := d.db.(txEnder).Commit()
+	observeQuery(d.alias, "COMMIT", a, nil, err)
+	d.endTxQueryCache(err == nil)
 	debugLogQueies(d.alias, "tx.Commit", "COMMIT", a, err)
 	return err
 }
@@ -196,6 +213,8 @@ func (d *dbQueryLog) Commit() error {
 func (d *dbQueryLog) Rollback() error {
 	a := time.Now()
 	err := d.db.(txEnder).Rollback()
+	observeQuery(d.alias, "ROLLBACK", a, nil, err)
+	d.endTxQueryCache(false)
 	debugLogQueies(d.alias, "tx.Rollback", "ROLLBACK", a, err)
 	return err
 }
@@ -15,6 +15,7 @@
 package orm
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 )
@@ -23,6 +24,7 @@ import (
 type insertSet struct {
 	mi     *modelInfo
 	orm    *orm
+	ctx    context.Context
 	stmt   stmtQuerier
 	closed bool
 }
@@ -44,10 +46,18 @@ func (o *insertSet) Insert(md interface{}) (int64, error) {
 	if name != o.mi.fullName {
 		panic(fmt.Errorf("<Inserter.Insert> need model `%s` but found `%s`", o.mi.fullName, name))
 	}
+	ctx := o.orm.hookContext(o.ctx)
+	if err := beforeInsert(md, ctx, o.orm); err != nil {
+		return 0, err
+	}
+	if err := o.orm.setTenantValue(o.mi, ind); err != nil {
+		return 0, err
+	}
 	id, err := o.orm.alias.DbBaser.InsertStmt(o.stmt, o.mi, ind, o.orm.alias.TZ)
 	if err != nil {
 		return id, err
 	}
+	o.orm.invalidateQueryCache(o.mi.table)
 	if id > 0 {
 		if o.mi.fields.pk.auto {
 			if o.mi.fields.pk.fieldType&IsPositiveIntegerField > 0 {
@@ -57,7 +67,7 @@ func (o *insertSet) Insert(md interface{}) (int64, error) {
 			}
 		}
 	}
-	return id, nil
+	return id, afterInsert(md, ctx, o.orm)
 }
 
 // close insert queryer statement
@@ -70,11 +80,12 @@ func (o *insertSet) Close() error {
 }
 
 // create new insert queryer.
-func newInsertSet(orm *orm, mi *modelInfo) (Inserter, error) {
+func newInsertSet(orm *orm, mi *modelInfo, ctx context.Context) (Inserter, error) {
 	bi := new(insertSet)
 	bi.orm = orm
 	bi.mi = mi
-	st, query, err := orm.alias.DbBaser.PrepareInsert(orm.db, mi)
+	bi.ctx = ctx
+	st, query, err := orm.alias.DbBaser.PrepareInsert(orm.querier(ctx), mi)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,152 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueryStats describes one sql statement executed through an Ormer.
+type QueryStats struct {
+	Alias     string
+	Operation string // select, insert, update, delete, begin, commit, rollback...
+	Table     string // first table of the statement, empty if unknown
+	Elapsed   time.Duration
+	Rows      int64 // rows affected for exec, -1 if unknown
+	Err       error
+}
+
+// QueryObserver receives stats of executed queries, used for metrics.
+// Observers are called synchronously in the query path and should be fast.
+type QueryObserver interface {
+	// ObserveQuery is called after a statement is executed.
+	ObserveQuery(stats *QueryStats)
+	// ObserveRows is called after rows of a select are read into a container.
+	ObserveRows(alias string, table string, rows int64)
+}
+
+var (
+	queryObserversLock sync.RWMutex
+	queryObservers     []QueryObserver
+)
+
+// RegisterQueryObserver adds an observer for all Ormers.
+func RegisterQueryObserver(observer QueryObserver) {
+	queryObserversLock.Lock()
+	defer queryObserversLock.Unlock()
+	queryObservers = append(queryObservers, observer)
+}
+
+func getQueryObservers() []QueryObserver {
+	queryObserversLock.RLock()
+	defer queryObserversLock.RUnlock()
+	return queryObservers
+}
+
+// parseQuery returns the lower cased operation and the first table of query.
+func parseQuery(query string) (operation string, table string) {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	operation = strings.ToLower(fields[0])
+
+	var keyword string
+	switch operation {
+	case "select", "delete":
+		keyword = "from"
+	case "insert", "replace":
+		keyword = "into"
+	case "update":
+		if len(fields) > 1 {
+			return operation, trimTableName(fields[1])
+		}
+		return operation, ""
+	default:
+		return operation, ""
+	}
+	for i, field := range fields[:len(fields)-1] {
+		if strings.ToLower(field) == keyword {
+			return operation, trimTableName(fields[i+1])
+		}
+	}
+	return operation, ""
+}
+
+func trimTableName(name string) string {
+	if pos := strings.Index(name, "("); pos != -1 {
+		name = name[:pos]
+	}
+	name = strings.Trim(name, "`\"")
+	// schema.table
+	if pos := strings.LastIndex(name, "."); pos != -1 {
+		name = strings.Trim(name[pos+1:], "`\"")
+	}
+	return name
+}
+
+// observeQuery notifies observers about an executed statement.
+func observeQuery(al *alias, query string, start time.Time, res sql.Result, err error) {
+	observers := getQueryObservers()
+	if len(observers) == 0 || al == nil {
+		return
+	}
+	operation, table := parseQuery(query)
+	stats := &QueryStats{
+		Alias:     al.Name,
+		Operation: operation,
+		Table:     table,
+		Elapsed:   time.Since(start),
+		Rows:      -1,
+		Err:       err,
+	}
+	if res != nil && err == nil {
+		if rows, e := res.RowsAffected(); e == nil {
+			stats.Rows = rows
+		}
+	}
+	for _, observer := range observers {
+		observer.ObserveQuery(stats)
+	}
+}
+
+// querierAlias returns the alias of an Ormer's querier, nil for raw *sql.DB or *sql.Tx.
+func querierAlias(q dbQuerier) *alias {
+	switch d := q.(type) {
+	case *dbQueryTracable:
+		return d.alias
+	case *dbQueryLog:
+		return d.alias
+	case *dbQueryCtx:
+		return querierAlias(d.dbQuerier)
+	case *dbQueryRouter:
+		return querierAlias(d.dbQuerier)
+	}
+	return nil
+}
+
+// observeRows notifies observers about rows read by a select.
+func observeRows(al *alias, table string, rows int64) {
+	observers := getQueryObservers()
+	if len(observers) == 0 || al == nil {
+		return
+	}
+	for _, observer := range observers {
+		observer.ObserveRows(al.Name, table, rows)
+	}
+}
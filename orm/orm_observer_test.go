@@ -0,0 +1,39 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import "testing"
+
+func TestParseQuery(t *testing.T) {
+	cases := []struct {
+		query     string
+		operation string
+		table     string
+	}{
+		{"SELECT T0.`id` FROM `user` T0 WHERE T0.`id` = ?", "select", "user"},
+		{"INSERT INTO `user` (`name`) VALUES (?)", "insert", "user"},
+		{"INSERT INTO \"user\"(\"name\") VALUES ($1)", "insert", "user"},
+		{"UPDATE `user` T0 SET T0.`name` = ?", "update", "user"},
+		{"DELETE T0 FROM `user` T0 WHERE T0.`id` = ?", "delete", "user"},
+		{"select count(*) from db.`order`", "select", "order"},
+		{"BEGIN", "begin", ""},
+	}
+	for _, c := range cases {
+		operation, table := parseQuery(c.query)
+		if operation != c.operation || table != c.table {
+			t.Errorf("parseQuery(%s) = %s, %s", c.query, operation, table)
+		}
+	}
+}
@@ -14,7 +14,10 @@
 
 package orm
 
-import "reflect"
+import (
+	"context"
+	"reflect"
+)
 
 // model to model struct
 type queryM2M struct {
@@ -96,7 +99,7 @@ func (o *queryM2M) Add(mds ...interface{}) (int64, error) {
 	}
 	names = append(names, otherNames...)
 	values = append(values, otherValues...)
-	return dbase.InsertValue(orm.db, mi, true, names, values)
+	return dbase.InsertValue(orm.querier(o.qs.ctx), mi, true, names, values)
 }
 
 // remove models following the origin model relationship
@@ -126,6 +129,12 @@ func (o *queryM2M) Count() (int64, error) {
 	return o.qs.Filter(fi.reverseFieldInfo.name, o.md).Count()
 }
 
+// set context to QueryM2Mer.
+func (o queryM2M) WithContext(ctx context.Context) QueryM2Mer {
+	o.qs = o.qs.WithContext(ctx).(*querySet)
+	return &o
+}
+
 var _ QueryM2Mer = new(queryM2M)
 
 // create new M2M queryer.
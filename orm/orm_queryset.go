@@ -17,6 +17,8 @@ package orm
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"time"
 )
 
 type colValue struct {
@@ -90,6 +92,8 @@ type querySet struct {
 	orm        *orm
 	ctx        context.Context
 	forContext bool
+	unscoped   bool
+	cacheTTL   time.Duration
 }
 
 var _ QuerySeter = new(querySet)
@@ -170,6 +174,12 @@ func (o querySet) Distinct() QuerySeter {
 	return &o
 }
 
+// include rows soft deleted, and Delete removes rows from database.
+func (o querySet) Unscoped() QuerySeter {
+	o.unscoped = true
+	return &o
+}
+
 // add FOR UPDATE to SELECT
 func (o querySet) ForUpdate() QuerySeter {
 	o.forupdate = true
@@ -209,23 +219,84 @@ func (o querySet) GetCond() *Condition {
 
 // return QuerySeter execution result number
 func (o *querySet) Count() (int64, error) {
-	return o.orm.alias.DbBaser.Count(o.orm.db, o, o.mi, o.cond, o.orm.alias.TZ)
+	return o.readQueryCache("count", nil, nil, func() (int64, error) {
+		cond, err := o.condition()
+		if err != nil {
+			return 0, err
+		}
+		return o.orm.alias.DbBaser.Count(o.orm.querier(o.ctx), o, o.mi, cond, o.orm.alias.TZ)
+	})
+}
+
+// return estimated QuerySeter execution result number
+func (o *querySet) CountEstimate() (int64, error) {
+	cond, err := o.condition()
+	if err != nil {
+		return 0, err
+	}
+	return o.orm.alias.DbBaser.EstimateCount(o.orm.querier(o.ctx), o, o.mi, cond, o.orm.alias.TZ)
 }
 
 // check result empty or not after QuerySeter executed
 func (o *querySet) Exist() bool {
-	cnt, _ := o.orm.alias.DbBaser.Count(o.orm.db, o, o.mi, o.cond, o.orm.alias.TZ)
+	cnt, _ := o.Count()
 	return cnt > 0
 }
 
-// execute update with parameters
+// execute update with parameters.
+// auto_now fields are set to now if they are not in values.
 func (o *querySet) Update(values Params) (int64, error) {
-	return o.orm.alias.DbBaser.UpdateBatch(o.orm.db, o, o.mi, o.cond, values, o.orm.alias.TZ)
+	ctx := o.orm.hookContext(o.ctx)
+	md := reflect.New(o.mi.addrField.Elem().Type()).Interface()
+	if h, ok := md.(BeforeBulkUpdater); ok {
+		if err := h.BeforeBulkUpdate(ctx, o.orm, values); err != nil {
+			return 0, err
+		}
+	}
+	num, err := o.update(values)
+	if err != nil {
+		return num, err
+	}
+	if h, ok := md.(AfterBulkUpdater); ok {
+		return num, h.AfterBulkUpdate(ctx, o.orm, values, num)
+	}
+	return num, nil
 }
 
-// execute delete
+// execute update without hooks
+func (o *querySet) update(values Params) (int64, error) {
+	if err := o.checkTenantValues(values); err != nil {
+		return 0, err
+	}
+	values = o.addAutoNowValues(values)
+	cond, err := o.condition()
+	if err != nil {
+		return 0, err
+	}
+	var expected interface{}
+	if o.mi.fields.version != nil {
+		values, cond, expected = o.addVersionValues(values, cond)
+	}
+	num, err := o.orm.alias.DbBaser.UpdateBatch(o.orm.querier(o.ctx), o, o.mi, cond, values, o.orm.alias.TZ)
+	if err == nil && expected != nil && num == 0 {
+		version, _ := StrTo(ToStr(expected)).Int64()
+		return 0, &VersionConflictError{Model: o.mi.fullName, Version: version}
+	}
+	return num, err
+}
+
+// execute delete.
+// if the model has a soft_delete field, the field is set to now instead of deleting rows,
+// unless the QuerySeter is Unscoped.
 func (o *querySet) Delete() (int64, error) {
-	return o.orm.alias.DbBaser.DeleteBatch(o.orm.db, o, o.mi, o.cond, o.orm.alias.TZ)
+	if fi := o.mi.fields.softDelete; fi != nil && !o.unscoped {
+		return o.update(Params{fi.name: o.orm.nowToDB()})
+	}
+	cond, err := o.condition()
+	if err != nil {
+		return 0, err
+	}
+	return o.orm.alias.DbBaser.DeleteBatch(o.orm.querier(o.ctx), o, o.mi, cond, o.orm.alias.TZ)
 }
 
 // return a insert queryer.
@@ -234,20 +305,36 @@ func (o *querySet) Delete() (int64, error) {
 // 	i,err := sq.PrepareInsert()
 // 	i.Add(&user1{},&user2{})
 func (o *querySet) PrepareInsert() (Inserter, error) {
-	return newInsertSet(o.orm, o.mi)
+	return newInsertSet(o.orm, o.mi, o.ctx)
 }
 
 // query all data and map to containers.
 // cols means the columns when querying.
 func (o *querySet) All(container interface{}, cols ...string) (int64, error) {
-	return o.orm.alias.DbBaser.ReadBatch(o.orm.db, o, o.mi, o.cond, container, o.orm.alias.TZ, cols)
+	num, err := o.readQueryCache("all", cols, container, func() (int64, error) {
+		cond, err := o.condition()
+		if err != nil {
+			return 0, err
+		}
+		return o.orm.alias.DbBaser.ReadBatch(o.orm.querier(o.ctx), o, o.mi, cond, container, o.orm.alias.TZ, cols)
+	})
+	if err != nil {
+		return num, err
+	}
+	return num, callAfterRead(o.mi, container, o.orm.hookContext(o.ctx), o.orm)
 }
 
 // query one row data and map to containers.
 // cols means the columns when querying.
 func (o *querySet) One(container interface{}, cols ...string) error {
 	o.limit = 1
-	num, err := o.orm.alias.DbBaser.ReadBatch(o.orm.db, o, o.mi, o.cond, container, o.orm.alias.TZ, cols)
+	num, err := o.readQueryCache("one", cols, container, func() (int64, error) {
+		cond, err := o.condition()
+		if err != nil {
+			return 0, err
+		}
+		return o.orm.alias.DbBaser.ReadBatch(o.orm.querier(o.ctx), o, o.mi, cond, container, o.orm.alias.TZ, cols)
+	})
 	if err != nil {
 		return err
 	}
@@ -258,26 +345,96 @@ func (o *querySet) One(container interface{}, cols ...string) error {
 	if num > 1 {
 		return ErrMultiRows
 	}
-	return nil
+	return callAfterRead(o.mi, container, o.orm.hookContext(o.ctx), o.orm)
+}
+
+// return an iterator which scans the rows into model struct one by one.
+// cols means the columns when querying, the rows are not limited by DefaultRowsLimit.
+func (o *querySet) Iterator(cols ...string) (Iterator, error) {
+	cond, err := o.condition()
+	if err != nil {
+		return nil, err
+	}
+	it, err := o.orm.alias.DbBaser.ReadIterator(o.orm.querier(o.ctx), o, o.mi, cond, o.orm.alias.TZ, cols)
+	if err != nil {
+		return nil, err
+	}
+	it.orm = o.orm
+	it.ctx = o.ctx
+	return it, nil
 }
 
 // query all data and map to []map[string]interface.
 // expres means condition expression.
 // it converts data to []map[column]value.
 func (o *querySet) Values(results *[]Params, exprs ...string) (int64, error) {
-	return o.orm.alias.DbBaser.ReadValues(o.orm.db, o, o.mi, o.cond, exprs, results, o.orm.alias.TZ)
+	return o.readQueryCache("values", exprs, results, func() (int64, error) {
+		cond, err := o.condition()
+		if err != nil {
+			return 0, err
+		}
+		return o.orm.alias.DbBaser.ReadValues(o.orm.querier(o.ctx), o, o.mi, cond, exprs, results, o.orm.alias.TZ)
+	})
 }
 
 // query all data and map to [][]interface
 // it converts data to [][column_index]value
 func (o *querySet) ValuesList(results *[]ParamsList, exprs ...string) (int64, error) {
-	return o.orm.alias.DbBaser.ReadValues(o.orm.db, o, o.mi, o.cond, exprs, results, o.orm.alias.TZ)
+	return o.readQueryCache("values_list", exprs, results, func() (int64, error) {
+		cond, err := o.condition()
+		if err != nil {
+			return 0, err
+		}
+		return o.orm.alias.DbBaser.ReadValues(o.orm.querier(o.ctx), o, o.mi, cond, exprs, results, o.orm.alias.TZ)
+	})
 }
 
 // query all data and map to []interface.
 // it's designed for one row record set, auto change to []value, not [][column]value.
 func (o *querySet) ValuesFlat(result *ParamsList, expr string) (int64, error) {
-	return o.orm.alias.DbBaser.ReadValues(o.orm.db, o, o.mi, o.cond, []string{expr}, result, o.orm.alias.TZ)
+	return o.readQueryCache("values_flat", []string{expr}, result, func() (int64, error) {
+		cond, err := o.condition()
+		if err != nil {
+			return 0, err
+		}
+		return o.orm.alias.DbBaser.ReadValues(o.orm.querier(o.ctx), o, o.mi, cond, []string{expr}, result, o.orm.alias.TZ)
+	})
+}
+
+// aggregate the rows and read the results into container.
+// the GroupBy exprs are read with aggregations.
+func (o *querySet) Aggregate(container interface{}, aggs ...Aggregation) (int64, error) {
+	cond, err := o.condition()
+	if err != nil {
+		return 0, err
+	}
+	query, args := o.orm.alias.DbBaser.AggregateSQL(o, o.mi, cond, aggs, o.orm.alias.TZ)
+	rs := newRawSet(o.orm, query, args).WithContext(o.ctx)
+	if results, ok := container.(*[]Params); ok {
+		return rs.Values(results)
+	}
+	val := reflect.ValueOf(container)
+	if val.Kind() == reflect.Ptr && val.Elem().Kind() == reflect.Slice {
+		return rs.QueryRows(container)
+	}
+	if err := rs.QueryRow(container); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// read the columns of model with the annotations of each row into container.
+func (o *querySet) Annotate(container interface{}, aggs ...Aggregation) (int64, error) {
+	cond, err := o.condition()
+	if err != nil {
+		return 0, err
+	}
+	query, args := o.orm.alias.DbBaser.AnnotateSQL(o, o.mi, cond, aggs, o.orm.alias.TZ)
+	rs := newRawSet(o.orm, query, args).WithContext(o.ctx)
+	if results, ok := container.(*[]Params); ok {
+		return rs.Values(results)
+	}
+	return rs.QueryRows(container)
 }
 
 // query all rows into map[string]interface with specify key and value column name.
@@ -309,7 +466,12 @@ func (o *querySet) RowsToStruct(ptrStruct interface{}, keyCol, valueCol string)
 }
 
 // set context to QuerySeter.
+// the context is used by all operations of the QuerySeter, instead of the default context of Ormer.
+// nil ctx is ignored.
 func (o querySet) WithContext(ctx context.Context) QuerySeter {
+	if ctx == nil {
+		return &o
+	}
 	o.ctx = ctx
 	o.forContext = true
 	return &o
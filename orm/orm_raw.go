@@ -15,6 +15,7 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
@@ -32,7 +33,13 @@ func (o *rawPrepare) Exec(args ...interface{}) (sql.Result, error) {
 	if o.closed {
 		return nil, ErrStmtClosed
 	}
-	return o.stmt.Exec(args...)
+	res, err := o.stmt.Exec(args...)
+	if err == nil {
+		if operation, table := parseQuery(o.rs.query); operation != "select" {
+			o.rs.orm.invalidateQueryCache(table)
+		}
+	}
+	return res, err
 }
 
 func (o *rawPrepare) Close() error {
@@ -47,7 +54,7 @@ func newRawPreparer(rs *rawSet) (RawPreparer, error) {
 	query := rs.query
 	rs.orm.alias.DbBaser.ReplaceMarks(&query)
 
-	st, err := rs.orm.db.Prepare(query)
+	st, err := rs.orm.querier(rs.ctx).Prepare(query)
 	if err != nil {
 		return nil, err
 	}
@@ -64,6 +71,7 @@ type rawSet struct {
 	query string
 	args  []interface{}
 	orm   *orm
+	ctx   context.Context
 }
 
 var _ RawSeter = new(rawSet)
@@ -74,13 +82,19 @@ func (o rawSet) SetArgs(args ...interface{}) RawSeter {
 	return &o
 }
 
+// set context to RawSeter.
+func (o rawSet) WithContext(ctx context.Context) RawSeter {
+	o.ctx = ctx
+	return &o
+}
+
 // execute raw sql and return sql.Result
 func (o *rawSet) Exec() (sql.Result, error) {
 	query := o.query
 	o.orm.alias.DbBaser.ReplaceMarks(&query)
 
 	args := getFlatParams(nil, o.args, o.orm.alias.TZ)
-	return o.orm.db.Exec(query, args...)
+	return o.orm.querier(o.ctx).Exec(query, args...)
 }
 
 // set field value to row container
@@ -253,8 +267,8 @@ func (o *rawSet) loopSetRefs(refs []interface{}, sInds []reflect.Value, nIndsPtr
 	}
 }
 
-// query data and map to container
-func (o *rawSet) QueryRow(containers ...interface{}) error {
+// scan the current row of rows and map to containers, it is shared by QueryRow and Iterator.
+func (o *rawSet) scanRow(rows *sql.Rows, containers []interface{}) error {
 	var (
 		refs  = make([]interface{}, 0, len(containers))
 		sInds []reflect.Value
@@ -295,96 +309,112 @@ func (o *rawSet) QueryRow(containers ...interface{}) error {
 		}
 	}
 
-	query := o.query
-	o.orm.alias.DbBaser.ReplaceMarks(&query)
-
-	args := getFlatParams(nil, o.args, o.orm.alias.TZ)
-	rows, err := o.orm.db.Query(query, args...)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return ErrNoRows
+	if structMode {
+		columns, err := rows.Columns()
+		if err != nil {
+			return err
 		}
-		return err
-	}
 
-	defer rows.Close()
+		columnsMp := make(map[string]interface{}, len(columns))
 
-	if rows.Next() {
-		if structMode {
-			columns, err := rows.Columns()
-			if err != nil {
-				return err
-			}
+		refs = make([]interface{}, 0, len(columns))
+		for _, col := range columns {
+			var ref interface{}
+			columnsMp[col] = &ref
+			refs = append(refs, &ref)
+		}
 
-			columnsMp := make(map[string]interface{}, len(columns))
+		if err := rows.Scan(refs...); err != nil {
+			return err
+		}
 
-			refs = make([]interface{}, 0, len(columns))
-			for _, col := range columns {
-				var ref interface{}
-				columnsMp[col] = &ref
-				refs = append(refs, &ref)
-			}
+		ind := sInds[0]
 
-			if err := rows.Scan(refs...); err != nil {
-				return err
+		if ind.Kind() == reflect.Ptr {
+			if ind.IsNil() || !ind.IsValid() {
+				ind.Set(reflect.New(eTyps[0].Elem()))
 			}
+			ind = ind.Elem()
+		}
 
-			ind := sInds[0]
-
-			if ind.Kind() == reflect.Ptr {
-				if ind.IsNil() || !ind.IsValid() {
-					ind.Set(reflect.New(eTyps[0].Elem()))
+		if sMi != nil {
+			for _, col := range columns {
+				if fi := sMi.fields.GetByColumn(col); fi != nil {
+					value := reflect.ValueOf(columnsMp[col]).Elem().Interface()
+					field := ind.FieldByIndex(fi.fieldIndex)
+					if fi.fieldType&IsRelField > 0 {
+						mf := reflect.New(fi.relModelInfo.addrField.Elem().Type())
+						field.Set(mf)
+						field = mf.Elem().FieldByIndex(fi.relModelInfo.fields.pk.fieldIndex)
+					}
+					o.setFieldValue(field, value)
 				}
-				ind = ind.Elem()
 			}
-
-			if sMi != nil {
-				for _, col := range columns {
-					if fi := sMi.fields.GetByColumn(col); fi != nil {
-						value := reflect.ValueOf(columnsMp[col]).Elem().Interface()
-						field := ind.FieldByIndex(fi.fieldIndex)
-						if fi.fieldType&IsRelField > 0 {
-							mf := reflect.New(fi.relModelInfo.addrField.Elem().Type())
-							field.Set(mf)
-							field = mf.Elem().FieldByIndex(fi.relModelInfo.fields.pk.fieldIndex)
-						}
-						o.setFieldValue(field, value)
-					}
+		} else {
+			for i := 0; i < ind.NumField(); i++ {
+				f := ind.Field(i)
+				fe := ind.Type().Field(i)
+				_, tags := parseStructTag(fe.Tag.Get(defaultStructTagName))
+				var col string
+				if col = tags["column"]; col == "" {
+					col = nameStrategyMap[nameStrategy](fe.Name)
 				}
-			} else {
-				for i := 0; i < ind.NumField(); i++ {
-					f := ind.Field(i)
-					fe := ind.Type().Field(i)
-					_, tags := parseStructTag(fe.Tag.Get(defaultStructTagName))
-					var col string
-					if col = tags["column"]; col == "" {
-						col = nameStrategyMap[nameStrategy](fe.Name)
-					}
-					if v, ok := columnsMp[col]; ok {
-						value := reflect.ValueOf(v).Elem().Interface()
-						o.setFieldValue(f, value)
-					}
+				if v, ok := columnsMp[col]; ok {
+					value := reflect.ValueOf(v).Elem().Interface()
+					o.setFieldValue(f, value)
 				}
 			}
+		}
 
-		} else {
-			if err := rows.Scan(refs...); err != nil {
-				return err
-			}
+	} else {
+		if err := rows.Scan(refs...); err != nil {
+			return err
+		}
 
-			nInds := make([]reflect.Value, len(sInds))
-			o.loopSetRefs(refs, sInds, &nInds, eTyps, true)
-			for i, sInd := range sInds {
-				nInd := nInds[i]
-				sInd.Set(nInd)
-			}
+		nInds := make([]reflect.Value, len(sInds))
+		o.loopSetRefs(refs, sInds, &nInds, eTyps, true)
+		for i, sInd := range sInds {
+			nInd := nInds[i]
+			sInd.Set(nInd)
 		}
+	}
 
-	} else {
+	return nil
+}
+
+// query data and map to container
+func (o *rawSet) QueryRow(containers ...interface{}) error {
+	query := o.query
+	o.orm.alias.DbBaser.ReplaceMarks(&query)
+
+	args := getFlatParams(nil, o.args, o.orm.alias.TZ)
+	rows, err := o.orm.querier(o.ctx).Query(query, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNoRows
+		}
+		return err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
 		return ErrNoRows
 	}
+	return o.scanRow(rows, containers)
+}
 
-	return nil
+// return an iterator which scans the rows one by one
+func (o *rawSet) Iterator() (Iterator, error) {
+	query := o.query
+	o.orm.alias.DbBaser.ReplaceMarks(&query)
+
+	args := getFlatParams(nil, o.args, o.orm.alias.TZ)
+	rows, err := o.orm.querier(o.ctx).Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &rawIterator{rs: o, rows: rows}, nil
 }
 
 // query data rows and map to container
@@ -432,7 +462,7 @@ func (o *rawSet) QueryRows(containers ...interface{}) (int64, error) {
 	o.orm.alias.DbBaser.ReplaceMarks(&query)
 
 	args := getFlatParams(nil, o.args, o.orm.alias.TZ)
-	rows, err := o.orm.db.Query(query, args...)
+	rows, err := o.orm.querier(o.ctx).Query(query, args...)
 	if err != nil {
 		return 0, err
 	}
@@ -579,7 +609,7 @@ func (o *rawSet) readValues(container interface{}, needCols []string) (int64, er
 	args := getFlatParams(nil, o.args, o.orm.alias.TZ)
 
 	var rs *sql.Rows
-	rs, err := o.orm.db.Query(query, args...)
+	rs, err := o.orm.querier(o.ctx).Query(query, args...)
 	if err != nil {
 		return 0, err
 	}
@@ -705,7 +735,7 @@ func (o *rawSet) queryRowsTo(container interface{}, keyCol, valueCol string) (in
 
 	args := getFlatParams(nil, o.args, o.orm.alias.TZ)
 
-	rs, err := o.orm.db.Query(query, args...)
+	rs, err := o.orm.querier(o.ctx).Query(query, args...)
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,378 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReplicaCheckInterval is the interval of replica health and lag checking.
+var ReplicaCheckInterval = 5 * time.Second
+
+// ReplicaStickyDuration is how long the reads of an Ormer go to primary after its write or transaction.
+var ReplicaStickyDuration = 5 * time.Second
+
+// ReplicaOptions define how a replica is picked
+type ReplicaOptions struct {
+	// relative weight when picking a replica, default 1
+	Weight int
+	// the replica is skipped when its replication lag exceeds MaxLag, 0 means no limit.
+	// lag is read from SHOW SLAVE STATUS for mysql/tidb.
+	MaxLag time.Duration
+}
+
+type replica struct {
+	alias   *alias
+	weight  int
+	maxLag  time.Duration
+	healthy int32
+	lag     int64 // time.Duration
+}
+
+// available reports whether the replica can serve reads
+func (r *replica) available() bool {
+	if atomic.LoadInt32(&r.healthy) == 0 {
+		return false
+	}
+	return r.maxLag <= 0 || time.Duration(atomic.LoadInt64(&r.lag)) <= r.maxLag
+}
+
+// a primary alias with its replicas
+type replicaGroup struct {
+	primary  *alias
+	replicas []*replica
+}
+
+var replicaGroups = struct {
+	sync.RWMutex
+	groups map[string]*replicaGroup
+}{groups: make(map[string]*replicaGroup)}
+
+// RegisterReplica add a registered database alias as a replica of the primary alias.
+// reads of Ormer using the primary alias go to a healthy replica automatically when:
+// not in a transaction, no write has been done by the Ormer in ReplicaStickyDuration (read-your-writes),
+// and the context is not marked by ForcePrimary.
+// for example:
+//
+//	orm.RegisterDataBase("default", "mysql", "root:root@tcp(master)/db")
+//	orm.RegisterDataBase("slave", "mysql", "root:root@tcp(slave)/db")
+//	orm.RegisterReplica("default", "slave", orm.ReplicaOptions{Weight: 1, MaxLag: 3 * time.Second})
+func RegisterReplica(primaryName, replicaName string, options ...ReplicaOptions) error {
+	primary, ok := dataBaseCache.get(primaryName)
+	if !ok {
+		return fmt.Errorf("<RegisterReplica> unknown primary db alias name `%s`", primaryName)
+	}
+	al, ok := dataBaseCache.get(replicaName)
+	if !ok {
+		return fmt.Errorf("<RegisterReplica> unknown replica db alias name `%s`", replicaName)
+	}
+	if primaryName == replicaName {
+		return fmt.Errorf("<RegisterReplica> db alias `%s` can not be the replica of itself", replicaName)
+	}
+	if primary.Driver != al.Driver {
+		return fmt.Errorf("<RegisterReplica> replica `%s` should use the same driver as primary `%s`", replicaName, primaryName)
+	}
+
+	r := &replica{alias: al, weight: 1, healthy: 1}
+	if len(options) > 0 {
+		if options[0].Weight > 0 {
+			r.weight = options[0].Weight
+		}
+		r.maxLag = options[0].MaxLag
+	}
+
+	replicaGroups.Lock()
+	defer replicaGroups.Unlock()
+	if _, ok := replicaGroups.groups[replicaName]; ok {
+		return fmt.Errorf("<RegisterReplica> db alias `%s` is a primary, can not be a replica", replicaName)
+	}
+	group, ok := replicaGroups.groups[primaryName]
+	if !ok {
+		group = &replicaGroup{primary: primary}
+		if ReplicaCheckInterval > 0 {
+			go group.run(ReplicaCheckInterval)
+		}
+	}
+	for _, exist := range group.replicas {
+		if exist.alias == al {
+			return fmt.Errorf("<RegisterReplica> replica `%s` already registered for `%s`", replicaName, primaryName)
+		}
+	}
+	// copy on write, readers use a snapshot of replicas
+	replicas := make([]*replica, 0, len(group.replicas)+1)
+	replicas = append(replicas, group.replicas...)
+	group.replicas = append(replicas, r)
+	replicaGroups.groups[primaryName] = group
+	return nil
+}
+
+// HasReplica reports whether the db alias has replicas registered.
+func HasReplica(primaryName string) bool {
+	return getReplicaGroup(primaryName) != nil
+}
+
+func getReplicaGroup(primaryName string) *replicaGroup {
+	replicaGroups.RLock()
+	defer replicaGroups.RUnlock()
+	return replicaGroups.groups[primaryName]
+}
+
+// pick a replica by weight among the available ones, return nil if none is available.
+func (g *replicaGroup) pick() *alias {
+	replicaGroups.RLock()
+	replicas := g.replicas
+	replicaGroups.RUnlock()
+
+	total := 0
+	for _, r := range replicas {
+		if r.available() {
+			total += r.weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	n := rand.Intn(total)
+	for _, r := range replicas {
+		if !r.available() {
+			continue
+		}
+		if n < r.weight {
+			return r.alias
+		}
+		n -= r.weight
+	}
+	return nil
+}
+
+// mark the replica of alias unhealthy until the next check
+func (g *replicaGroup) markUnhealthy(al *alias) {
+	replicaGroups.RLock()
+	replicas := g.replicas
+	replicaGroups.RUnlock()
+	for _, r := range replicas {
+		if r.alias == al {
+			atomic.StoreInt32(&r.healthy, 0)
+		}
+	}
+}
+
+func (g *replicaGroup) run(interval time.Duration) {
+	for range time.Tick(interval) {
+		g.check(interval)
+	}
+}
+
+// check health and lag of all replicas
+func (g *replicaGroup) check(timeout time.Duration) {
+	replicaGroups.RLock()
+	replicas := g.replicas
+	replicaGroups.RUnlock()
+
+	for _, r := range replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := r.alias.DB.PingContext(ctx)
+		var lag time.Duration
+		if err == nil {
+			lag, err = replicationLag(ctx, r.alias)
+		}
+		cancel()
+
+		if err != nil {
+			if atomic.SwapInt32(&r.healthy, 0) == 1 {
+				DebugLog.Printf("replica `%s` of `%s` is unavailable: %s\n", r.alias.Name, g.primary.Name, err.Error())
+			}
+			continue
+		}
+		atomic.StoreInt64(&r.lag, int64(lag))
+		if atomic.SwapInt32(&r.healthy, 1) == 0 {
+			DebugLog.Printf("replica `%s` of `%s` is available again\n", r.alias.Name, g.primary.Name)
+		}
+	}
+}
+
+// get the replication lag of a mysql/tidb replica, 0 for other databases
+// or when the account has no privilege to read the slave status.
+func replicationLag(ctx context.Context, al *alias) (time.Duration, error) {
+	if al.Driver != DRMySQL && al.Driver != DRTiDB {
+		return 0, nil
+	}
+	rows, err := al.DB.QueryContext(ctx, "SHOW SLAVE STATUS")
+	if err != nil {
+		return 0, nil
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	values := make([]sql.RawBytes, len(columns))
+	refs := make([]interface{}, len(columns))
+	for i := range values {
+		refs[i] = &values[i]
+	}
+	if err := rows.Scan(refs...); err != nil {
+		return 0, err
+	}
+	for i, column := range columns {
+		if column != "Seconds_Behind_Master" && column != "Seconds_Behind_Source" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, fmt.Errorf("replication is not running")
+		}
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, nil
+}
+
+type forcePrimaryKey struct{}
+
+// ForcePrimary return a context which makes queries run with it go to the primary.
+// for example:
+//
+//	err := o.ReadWithCtx(orm.ForcePrimary(ctx), &user)
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+func isForcePrimary(ctx context.Context) bool {
+	force, _ := ctx.Value(forcePrimaryKey{}).(bool)
+	return force
+}
+
+// isReadQuery reports whether the query can be served by a replica
+func isReadQuery(query string) bool {
+	query = strings.TrimSpace(query)
+	if len(query) < 6 || !strings.EqualFold(query[:6], "SELECT") {
+		return false
+	}
+	upper := strings.ToUpper(query)
+	return !strings.Contains(upper, "FOR UPDATE") && !strings.Contains(upper, "LOCK IN SHARE MODE") && !strings.Contains(upper, "FOR SHARE")
+}
+
+// isConnError reports whether err is caused by the connection rather than the query,
+// such as a bad connection or a network error.
+func isConnError(err error) bool {
+	if errors.Is(err, sqldriver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// reads of the Ormer go to primary in ReplicaStickyDuration after a write or a transaction.
+// the Ormer may be shared by goroutines, so the deadline is accessed atomically.
+func (o *orm) stickToPrimary() {
+	atomic.StoreInt64(&o.stickyUntil, time.Now().Add(ReplicaStickyDuration).UnixNano())
+}
+
+func (o *orm) isStickyPrimary() bool {
+	return time.Now().UnixNano() < atomic.LoadInt64(&o.stickyUntil)
+}
+
+// dbQueryRouter sends reads to a replica and writes to the primary.
+// after a write, the Ormer sticks to the primary to read its own writes.
+type dbQueryRouter struct {
+	dbQuerier // primary
+	o         *orm
+	group     *replicaGroup
+}
+
+var _ dbQuerier = new(dbQueryRouter)
+
+// get the querier of a replica for read query, nil if the query should go to primary.
+func (d *dbQueryRouter) replica(ctx context.Context, query string) (*alias, dbQuerier) {
+	if d.o.isStickyPrimary() || isForcePrimary(ctx) || !isReadQuery(query) {
+		return nil, nil
+	}
+	al := d.group.pick()
+	if al == nil {
+		return nil, nil
+	}
+	return al, newDbQueryTracable(al, al.DB, d.o.span)
+}
+
+// prepared statements are used for inserts, so they always go to primary
+func (d *dbQueryRouter) Prepare(query string) (*sql.Stmt, error) {
+	d.o.stickToPrimary()
+	return d.dbQuerier.Prepare(query)
+}
+
+func (d *dbQueryRouter) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	d.o.stickToPrimary()
+	return d.dbQuerier.PrepareContext(ctx, query)
+}
+
+func (d *dbQueryRouter) Exec(query string, args ...interface{}) (sql.Result, error) {
+	d.o.stickToPrimary()
+	return d.dbQuerier.Exec(query, args...)
+}
+
+func (d *dbQueryRouter) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	d.o.stickToPrimary()
+	return d.dbQuerier.ExecContext(ctx, query, args...)
+}
+
+func (d *dbQueryRouter) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.QueryContext(context.Background(), query, args...)
+}
+
+func (d *dbQueryRouter) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if al, q := d.replica(ctx, query); q != nil {
+		rows, err := q.QueryContext(ctx, query, args...)
+		if err == nil || ctx.Err() != nil || !isConnError(err) {
+			return rows, err
+		}
+		// fall back to primary when the replica can not be connected
+		d.group.markUnhealthy(al)
+	}
+	return d.dbQuerier.QueryContext(ctx, query, args...)
+}
+
+func (d *dbQueryRouter) QueryRow(query string, args ...interface{}) *sql.Row {
+	return d.QueryRowContext(context.Background(), query, args...)
+}
+
+func (d *dbQueryRouter) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if al, q := d.replica(ctx, query); q != nil {
+		row := q.QueryRowContext(ctx, query, args...)
+		err := row.Err()
+		if err == nil || ctx.Err() != nil || !isConnError(err) {
+			return row
+		}
+		// fall back to primary when the replica can not be connected
+		d.group.markUnhealthy(al)
+	}
+	return d.dbQuerier.QueryRowContext(ctx, query, args...)
+}
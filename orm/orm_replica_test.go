@@ -0,0 +1,67 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestIsReadQuery(t *testing.T) {
+	cases := map[string]bool{
+		"SELECT T0.`id` FROM `user` T0":                       true,
+		"  select count(*) from user":                         true,
+		"SELECT T0.`id` FROM `user` T0 FOR UPDATE":            false,
+		"SELECT * FROM user LOCK IN SHARE MODE":               false,
+		"INSERT INTO `user` (`name`) VALUES (?)":              false,
+		"UPDATE `user` SET `name` = ?":                        false,
+		"DELETE FROM `user` WHERE id IN (SELECT id FROM tag)": false,
+	}
+	for query, expected := range cases {
+		throwFail(t, AssertIs(isReadQuery(query), expected), query)
+	}
+}
+
+func TestReplicaPick(t *testing.T) {
+	a1, a2 := &alias{Name: "r1"}, &alias{Name: "r2"}
+	r1 := &replica{alias: a1, weight: 3, healthy: 1}
+	r2 := &replica{alias: a2, weight: 1, healthy: 1, maxLag: time.Second}
+	g := &replicaGroup{primary: &alias{Name: "default"}, replicas: []*replica{r1, r2}}
+
+	counts := map[*alias]int{}
+	for i := 0; i < 4000; i++ {
+		counts[g.pick()]++
+	}
+	throwFail(t, AssertIs(counts[a1] > counts[a2]*2, true))
+
+	// lag exceeds MaxLag
+	r2.lag = int64(2 * time.Second)
+	throwFail(t, AssertIs(g.pick(), a1))
+
+	// no available replica, use primary
+	g.markUnhealthy(a1)
+	throwFail(t, AssertIs(g.pick() == nil, true))
+}
+
+func TestIsConnError(t *testing.T) {
+	throwFail(t, AssertIs(isConnError(sqldriver.ErrBadConn), true))
+	throwFail(t, AssertIs(isConnError(fmt.Errorf("query: %w", sqldriver.ErrBadConn)), true))
+	throwFail(t, AssertIs(isConnError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}), true))
+	throwFail(t, AssertIs(isConnError(errors.New("no such table: replica_only_primary")), false))
+}
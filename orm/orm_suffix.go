@@ -0,0 +1,56 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"fmt"
+	"sync"
+)
+
+// the model infos of suffixed tables, key is full name of model and suffix.
+var suffixedModelInfos sync.Map
+
+// return the model info of table with the table suffix of orm.
+// the copy shares the fields of mi, only the table name is changed.
+func (o *orm) suffixedModelInfo(mi *modelInfo) *modelInfo {
+	if o.tableSuffix == "" {
+		return mi
+	}
+	key := mi.fullName + "\x00" + o.tableSuffix
+	if smi, ok := suffixedModelInfos.Load(key); ok {
+		return smi.(*modelInfo)
+	}
+	smi := *mi
+	smi.table = mi.table + o.tableSuffix
+	actual, _ := suffixedModelInfos.LoadOrStore(key, &smi)
+	return actual.(*modelInfo)
+}
+
+// use the tables with suffix for models, such as `user_test` for model `User` with suffix `_test`.
+// the suffixed tables must be created with the same schema as the model tables.
+// the related tables of RelatedSel, QueryM2M, LoadRelated and the cascade of Delete, and raw sql are not suffixed.
+// it cannot be changed in transaction, empty suffix uses the model tables.
+func (o *orm) UsingTableSuffix(suffix string) error {
+	if o.isTx {
+		return fmt.Errorf("<Ormer.UsingTableSuffix> transaction has been start, cannot change table suffix")
+	}
+	o.tableSuffix = suffix
+	return nil
+}
+
+// return true if transaction has begun.
+func (o *orm) InTransaction() bool {
+	return o.isTx
+}
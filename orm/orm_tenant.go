@@ -0,0 +1,180 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+
+	"github.com/kfchen81/beego/logs"
+)
+
+// ErrTenantMismatch is returned when a model or update values belong to another tenant than the Ormer.
+var ErrTenantMismatch = errors.New("<Ormer> tenant mismatch")
+
+type tenantContextKey struct{}
+
+// TenantAuditFunc logs the cross tenant access of CrossTenant for audit, ctx is the default context of the Ormer.
+// it logs the tenant, caller and reason by beego logs, set it to log the user of the request too.
+var TenantAuditFunc = func(ctx context.Context, tenant interface{}, caller string, reason string) {
+	logs.Warn("[orm] cross tenant access of tenant `%v` at %s: %s", tenant, caller, reason)
+}
+
+// WithTenant return a copy of ctx with the tenant, such as the corp id.
+// the Ormer created by NewOrmWithContext(ctx) is scoped by the tenant,
+// for the models with a tenant field (tag `orm:"tenant"`):
+//
+//	QuerySeter reads, updates and deletes the rows of the tenant only
+//	Read, Update and Delete of model match the row of the tenant only
+//	Insert, InsertMulti and InsertOrUpdate set the tenant field, or fail with ErrTenantMismatch if it is another tenant
+//
+// Raw sql, QueryM2M, LoadRelated and the related models of RelatedSel are not scoped,
+// use Ormer.CrossTenant to access the rows of all tenants.
+func WithTenant(ctx context.Context, tenant interface{}) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// GetTenant return the tenant set by WithTenant.
+func GetTenant(ctx context.Context) (interface{}, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	tenant := ctx.Value(tenantContextKey{})
+	return tenant, tenant != nil
+}
+
+// HasTenantModel return true if any registered model has a tenant field.
+func HasTenantModel() bool {
+	for _, mi := range modelCache.allOrdered() {
+		if mi.fields.tenant != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// convert the tenant to the type of tenant field, ErrTenantMismatch is returned if it is invalid.
+func toTenantValue(fi *fieldInfo, tenant interface{}) (interface{}, error) {
+	var (
+		value interface{}
+		err   error
+	)
+	switch fi.addrValue.Elem().Kind() {
+	case reflect.String:
+		return ToStr(tenant), nil
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		value, err = StrTo(ToStr(tenant)).Uint64()
+	default:
+		value, err = StrTo(ToStr(tenant)).Int64()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: tenant `%v` is invalid for field `%s`", ErrTenantMismatch, tenant, fi.fullName)
+	}
+	return value, nil
+}
+
+// get the tenant value of model, fi is nil if orm is not scoped or model has no tenant field.
+func (o *orm) getTenantValue(mi *modelInfo) (fi *fieldInfo, value interface{}, err error) {
+	fi = mi.fields.tenant
+	if fi == nil || o.tenant == nil {
+		return nil, nil, nil
+	}
+	value, err = toTenantValue(fi, o.tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fi, value, nil
+}
+
+// set the tenant field of model struct to the tenant of orm if it is zero,
+// ErrTenantMismatch is returned if it is another tenant.
+func (o *orm) setTenantValue(mi *modelInfo, ind reflect.Value) error {
+	fi, value, err := o.getTenantValue(mi)
+	if fi == nil {
+		return err
+	}
+	field := ind.FieldByIndex(fi.fieldIndex)
+	if !field.IsZero() {
+		if ToStr(field.Interface()) != ToStr(value) {
+			return fmt.Errorf("%w: `%s` of tenant `%v`, expected `%v`", ErrTenantMismatch, mi.fullName, field.Interface(), value)
+		}
+		return nil
+	}
+	field.Set(reflect.ValueOf(value).Convert(field.Type()))
+	return nil
+}
+
+// get the where cols of Read with the tenant field, whose value is set to the tenant of orm.
+// default cols is pk.
+func (o *orm) getTenantCols(mi *modelInfo, ind reflect.Value, cols []string) ([]string, error) {
+	fi, value, err := o.getTenantValue(mi)
+	if fi == nil {
+		return cols, err
+	}
+	if len(cols) == 0 {
+		if _, _, exist := getExistPk(mi, ind); !exist {
+			return cols, nil
+		}
+		cols = []string{mi.fields.pk.name}
+	}
+	field := ind.FieldByIndex(fi.fieldIndex)
+	field.Set(reflect.ValueOf(value).Convert(field.Type()))
+	return append(removeFieldCol(mi, cols, fi), fi.name), nil
+}
+
+// check the tenant field in values of QuerySeter.Update, the tenant of rows cannot be changed.
+func (o *querySet) checkTenantValues(values Params) error {
+	fi, value, err := o.orm.getTenantValue(o.mi)
+	if fi == nil {
+		return err
+	}
+	for k, v := range values {
+		if f, ok := o.mi.fields.GetByAny(k); ok && f == fi && ToStr(v) != ToStr(value) {
+			return fmt.Errorf("%w: cannot update `%s` of tenant `%v` to `%v`", ErrTenantMismatch, o.mi.fullName, value, v)
+		}
+	}
+	return nil
+}
+
+// add the tenant of orm to cond.
+func (o *querySet) addTenantCond(cond *Condition) (*Condition, error) {
+	fi, value, err := o.orm.getTenantValue(o.mi)
+	if fi == nil {
+		return cond, err
+	}
+	tcond := NewCondition().And(fi.name, value)
+	if cond == nil || cond.IsEmpty() {
+		return tcond, nil
+	}
+	return tcond.AndCond(cond), nil
+}
+
+// return a Ormer which is not scoped by tenant, the access is logged with reason and caller.
+// it shares the connection, transaction and table suffix of o, so they can be changed by either of them.
+func (o *orm) CrossTenant(reason string) Ormer {
+	if reason == "" {
+		panic(fmt.Errorf("<Ormer.CrossTenant> reason cannot be empty"))
+	}
+	if o.tenant != nil {
+		_, file, line, _ := runtime.Caller(1)
+		TenantAuditFunc(o.ctx, o.tenant, fmt.Sprintf("%s:%d", file, line), reason)
+	}
+	c := *o // the copy shares ormState of o
+	c.tenant = nil
+	return &c
+}
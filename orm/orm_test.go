@@ -20,16 +20,23 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"runtime"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/kfchen81/beego/cache"
+	"github.com/mattn/go-sqlite3"
 )
 
 var _ = os.PathSeparator
@@ -197,6 +204,8 @@ func TestSyncDb(t *testing.T) {
 	RegisterModel(new(IntegerPk))
 	RegisterModel(new(UintPk))
 	RegisterModel(new(PtrPk))
+	RegisterModel(new(Note))
+	RegisterModel(new(Memo))
 
 	err := RunSyncdb("default", true, Debug)
 	throwFail(t, err)
@@ -221,6 +230,8 @@ func TestRegisterModels(t *testing.T) {
 	RegisterModel(new(IntegerPk))
 	RegisterModel(new(UintPk))
 	RegisterModel(new(PtrPk))
+	RegisterModel(new(Note))
+	RegisterModel(new(Memo))
 
 	BootStrap()
 
@@ -2010,6 +2021,87 @@ func TestTransaction(t *testing.T) {
 
 }
 
+func TestNestedTransaction(t *testing.T) {
+	o := NewOrm()
+	var events []string
+	throwFail(t, o.Begin())
+	o.AfterCommit(func() { events = append(events, "commit") })
+
+	_, err := o.Insert(&Tag{Name: "nested-outer"})
+	throwFail(t, err)
+
+	// rolled back nested transaction
+	errFail := errors.New("fail")
+	err = o.Transaction(context.Background(), func(txOrm Ormer) error {
+		txOrm.AfterCommit(func() { events = append(events, "inner commit") })
+		txOrm.AfterRollback(func() { events = append(events, "inner rollback") })
+		_, err := txOrm.Insert(&Tag{Name: "nested-inner"})
+		throwFail(t, err)
+		return errFail
+	})
+	throwFail(t, AssertIs(err, errFail))
+	throwFail(t, AssertIs(strings.Join(events, ","), "inner rollback"))
+
+	// panic in nested transaction
+	func() {
+		defer func() {
+			throwFail(t, AssertIs(recover(), "boom"))
+		}()
+		o.Transaction(context.Background(), func(txOrm Ormer) error {
+			txOrm.Insert(&Tag{Name: "nested-panic"})
+			panic("boom")
+		})
+	}()
+
+	err = o.Transaction(context.Background(), func(txOrm Ormer) error {
+		_, err := txOrm.Insert(&Tag{Name: "nested-released"})
+		txOrm.AfterCommit(func() { events = append(events, "released commit") })
+		return err
+	})
+	throwFail(t, err)
+	throwFail(t, AssertIs(len(events), 1))
+
+	throwFail(t, o.Commit())
+	throwFail(t, AssertIs(strings.Join(events, ","), "inner rollback,commit,released commit"))
+
+	var names ParamsList
+	num, err := o.QueryTable("tag").Filter("name__startswith", "nested-").OrderBy("id").ValuesFlat(&names, "name")
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 2))
+	throwFail(t, AssertIs(names[0], "nested-outer"))
+	throwFail(t, AssertIs(names[1], "nested-released"))
+
+	// Transaction in Transaction
+	events = nil
+	err = o.Transaction(context.Background(), func(txOrm Ormer) error {
+		txOrm.AfterCommit(func() { events = append(events, "commit") })
+		txOrm.AfterRollback(func() { events = append(events, "rollback") })
+		return txOrm.Transaction(context.Background(), func(txOrm Ormer) error {
+			_, err := txOrm.QueryTable("tag").Filter("name__startswith", "nested-").Delete()
+			return err
+		})
+	})
+	throwFail(t, err)
+	throwFail(t, AssertIs(strings.Join(events, ","), "commit"))
+	throwFail(t, AssertIs(o.Commit(), ErrTxDone))
+
+	// RollbackAll ends the outer transaction with all nested ones
+	events = nil
+	throwFail(t, o.Begin())
+	o.AfterRollback(func() { events = append(events, "outer rollback") })
+	_, err = o.Insert(&Tag{Name: "nested-rollback-all"})
+	throwFail(t, err)
+	throwFail(t, o.Begin())
+	o.AfterRollback(func() { events = append(events, "inner rollback") })
+	throwFail(t, o.RollbackAll())
+	throwFail(t, AssertIs(o.InTransaction(), false))
+	throwFail(t, AssertIs(strings.Join(events, ","), "inner rollback,outer rollback"))
+	num, err = o.QueryTable("tag").Filter("name", "nested-rollback-all").Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 0))
+	throwFail(t, AssertIs(o.RollbackAll(), ErrTxDone))
+}
+
 func TestTransactionIsolationLevel(t *testing.T) {
 	// this test worked when database support transaction isolation level
 	if IsSqlite {
@@ -2070,6 +2162,1029 @@ func TestBeginTxWithContextCanceled(t *testing.T) {
 	throwFail(t, AssertIs(err, context.Canceled))
 }
 
+func TestOperationsWithContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := dORM.ReadWithCtx(ctx, &User{ID: 1})
+	throwFail(t, AssertIs(err, context.Canceled))
+
+	_, err = dORM.QueryTableWithCtx(ctx, "user").Count()
+	throwFail(t, AssertIs(err, context.Canceled))
+
+	var users []*User
+	_, err = dORM.QueryTable("user").WithContext(ctx).All(&users)
+	throwFail(t, AssertIs(err, context.Canceled))
+
+	_, err = dORM.Raw("SELECT * FROM user").WithContext(ctx).QueryRows(&users)
+	throwFail(t, AssertIs(err, context.Canceled))
+
+	// the default context of Ormer covers operations without ctx
+	o := NewOrmWithContext(ctx)
+	_, err = o.QueryTable("user").Count()
+	throwFail(t, AssertIs(err, context.Canceled))
+
+	num, err := dORM.QueryTable("user").Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num > 0, true))
+}
+
+func TestHooksAndSoftDelete(t *testing.T) {
+	_, err := dORM.Insert(&Note{})
+	throwFail(t, AssertIs(err.Error(), "title is required"))
+
+	notes := []*Note{{Title: "a"}, {Title: "b"}, {Title: "c"}}
+	num, err := dORM.InsertMulti(100, notes)
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 3))
+	throwFail(t, AssertIs(notes[2].Hooks, "bi,ai,"))
+
+	note := &Note{Title: "d"}
+	id, err := dORM.Insert(note)
+	throwFail(t, err)
+	throwFail(t, AssertIs(note.Hooks, "bi,ai,"))
+
+	// auto_now_always is updated even if it is not in cols, auto_now is not
+	userMi, _ := modelCache.get("user")
+	throwFail(t, AssertIs(len(appendAutoNowCols(userMi, []string{"UserName"})), 1))
+	noteMi, _ := modelCache.get("note")
+	throwFail(t, AssertIs(len(appendAutoNowCols(noteMi, []string{"Title"})), 2))
+	updated := note.Updated
+	time.Sleep(1100 * time.Millisecond)
+	note.Title = "dd"
+	num, err = dORM.Update(note, "Title")
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	throwFail(t, AssertIs(note.Hooks, "bi,ai,bu,"))
+	throwFail(t, AssertIs(note.Updated.After(updated), true))
+
+	read := &Note{ID: int(id)}
+	throwFail(t, dORM.Read(read))
+	throwFail(t, AssertIs(read.Title, "dd"))
+	throwFail(t, AssertIs(read.Hooks, "ar,"))
+
+	num, err = dORM.QueryTable("note").Filter("title", "a").Update(Params{"Title": " aa "})
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+
+	var all []*Note
+	num, err = dORM.QueryTable("note").OrderBy("id").All(&all)
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 4))
+	throwFail(t, AssertIs(all[0].Title, "aa"))
+	throwFail(t, AssertIs(all[0].Hooks, "ar,"))
+
+	// soft delete
+	num, err = dORM.Delete(note)
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	throwFail(t, AssertIs(note.Deleted != nil, true))
+	throwFail(t, AssertIs(note.Hooks, "bi,ai,bu,ad,"))
+
+	num, err = dORM.Delete(note)
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 0))
+
+	num, err = dORM.QueryTable("note").Filter("title__in", "b", "c").Delete()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 2))
+
+	num, err = dORM.QueryTable("note").Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+
+	num, err = dORM.QueryTable("note").Unscoped().Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 4))
+
+	num, err = dORM.QueryTable("note").Unscoped().Filter("deleted__isnull", false).Delete()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 3))
+
+	num, err = dORM.QueryTable("note").Unscoped().Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+}
+
+func TestQueryExprs(t *testing.T) {
+	// bulk upsert
+	num, err := dORM.InsertMulti(100, []*Note{{ID: 100, Title: "x"}, {ID: 101, Title: "y"}}, OnConflict{})
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 2))
+
+	notes := []*Note{{ID: 101, Title: "yy"}, {ID: 102, Title: "z"}}
+	_, err = dORM.InsertMulti(1, notes, OnConflict{Fields: []string{"ID"}, Update: []string{"Title"}})
+	throwFail(t, err)
+	_, err = dORM.InsertMulti(100, []*Note{{ID: 102, Title: "zz"}}, OnConflict{DoNothing: true})
+	throwFail(t, err)
+
+	var titles ParamsList
+	num, err = dORM.QueryTable("note").Filter("id__gte", 100).OrderBy("id").ValuesFlat(&titles, "title")
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 3))
+	throwFail(t, AssertIs(titles[1], "yy"))
+	throwFail(t, AssertIs(titles[2], "z"))
+
+	// F expression
+	qs := dORM.QueryTable("user")
+	user := User{UserName: "slene"}
+	throwFail(t, dORM.Read(&user, "UserName"))
+	num, err = qs.Filter("user_name", "slene").Update(Params{
+		"Nums": F("Nums").Add(F("Status")).Multiply(2),
+	})
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	nums := (user.Nums + int(user.Status)) * 2
+	throwFail(t, dORM.Read(&user, "UserName"))
+	throwFail(t, AssertIs(user.Nums, nums))
+
+	// date part and json path
+	total, err := qs.Count()
+	throwFail(t, err)
+	num, err = qs.Filter("Created__year", user.Created.Year()).Filter("Created__month__gte", 1).Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, total))
+
+	// json1 is an optional extension of sqlite
+	if !IsSqlite {
+		num, err = qs.Filter("Extra__json__Name", "beego").Count()
+		throwFail(t, err)
+		throwFail(t, AssertIs(num, 1))
+	}
+
+	// aggregate
+	var count int64
+	num, err = qs.Aggregate(&count, Count("*"))
+	throwFail(t, err)
+	throwFail(t, AssertIs(count, total))
+
+	var stats []struct {
+		Status  int
+		Total   int64
+		NumsMax int
+	}
+	num, err = qs.GroupBy("Status").OrderBy("Status").Aggregate(&stats, Count("*").As("total"), Max("Nums"))
+	throwFail(t, err)
+	throwFail(t, AssertIs(num > 0, true))
+	count = 0
+	for _, stat := range stats {
+		count += stat.Total
+	}
+	throwFail(t, AssertIs(count, total))
+
+	var sum float64
+	_, err = qs.Filter("user_name", "slene").Aggregate(&sum, Sum(F("Nums").Except(2)))
+	throwFail(t, err)
+	throwFail(t, AssertIs(int(sum), nums/2))
+
+	// annotate with window functions
+	var ranks []struct {
+		ID        int `orm:"column(id)"`
+		Status    int `orm:"column(Status)"`
+		Nums      int
+		RowNumber int
+		NumsTotal int
+	}
+	num, err = qs.OrderBy("id").Annotate(&ranks, RowNumber().Over(Window{}.OrderBy("-id")), Sum("Nums").Over(PartitionBy("Status")).As("nums_total"))
+	throwFailNow(t, err)
+	throwFailNow(t, AssertIs(num, total))
+	totals := make(map[int]int)
+	for _, rank := range ranks {
+		totals[rank.Status] += rank.Nums
+	}
+	for i, rank := range ranks {
+		throwFail(t, AssertIs(rank.ID > 0, true))
+		throwFail(t, AssertIs(rank.RowNumber, len(ranks)-i))
+		throwFail(t, AssertIs(rank.NumsTotal, totals[rank.Status]))
+	}
+
+	var values []Params
+	num, err = qs.Filter("user_name", "slene").Annotate(&values, Rank().Over(PartitionBy("Status").OrderBy("Nums")))
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	throwFail(t, AssertIs(values[0]["user_name"], "slene"))
+	throwFail(t, AssertIs(ToStr(values[0]["rank"]), "1"))
+}
+
+// sqlite3 driver with the regexp function of go regexp syntax
+type regexpSqliteDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *regexpSqliteDriver) Open(dsn string) (sqldriver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	err = conn.(*sqlite3.SQLiteConn).RegisterFunc("regexp", func(re, s string) (bool, error) {
+		return regexp.MatchString(re, s)
+	}, true)
+	return conn, err
+}
+
+func TestRegexOperators(t *testing.T) {
+	dir, err := os.MkdirTemp("", "orm_regexp")
+	throwFailNow(t, err)
+	defer os.RemoveAll(dir)
+
+	sql.Register("sqlite3_regexp", new(regexpSqliteDriver))
+	throwFailNow(t, RegisterDriver("sqlite3_regexp", DRSqlite))
+	throwFailNow(t, RegisterDataBase("regexp", "sqlite3_regexp", filepath.Join(dir, "regexp.db")))
+
+	o := NewOrm()
+	throwFailNow(t, o.Using("regexp"))
+	_, err = o.Raw("CREATE TABLE tag (id integer PRIMARY KEY AUTOINCREMENT, name varchar(30) NOT NULL DEFAULT '', best_post_id integer)").Exec()
+	throwFailNow(t, err)
+	for _, name := range []string{"golang", "Gopher", "python"} {
+		_, err = o.Insert(&Tag{Name: name})
+		throwFailNow(t, err)
+	}
+
+	num, err := o.QueryTable("tag").Filter("name__regex", "^go").Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	num, err = o.QueryTable("tag").Filter("name__iregex", "^go").Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 2))
+
+	query, _ := dbBasers[DRPostgres].GenerateOperatorSQL(nil, nil, "iregex", []interface{}{"^go"}, nil)
+	throwFail(t, AssertIs(query, "~* ?"))
+	query, _ = dbBasers[DRMySQL].GenerateOperatorSQL(nil, nil, "regex", []interface{}{"^go"}, nil)
+	throwFail(t, AssertIs(query, "REGEXP BINARY ?"))
+}
+
+func TestOptimisticLock(t *testing.T) {
+	id, err := dORM.Insert(&Note{Title: "v"})
+	throwFail(t, err)
+
+	first := &Note{ID: int(id)}
+	second := &Note{ID: int(id)}
+	throwFail(t, dORM.Read(first))
+	throwFail(t, dORM.Read(second))
+
+	first.Title = "v1"
+	num, err := dORM.Update(first, "Title")
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	throwFail(t, AssertIs(first.Version, 1))
+
+	second.Title = "v2"
+	_, err = dORM.Update(second)
+	throwFail(t, AssertIs(errors.Is(err, ErrVersionConflict), true))
+	conflict, ok := err.(*VersionConflictError)
+	throwFail(t, AssertIs(ok, true))
+	throwFail(t, AssertIs(conflict.Version, 0))
+
+	tries := 0
+	num, err = dORM.RetryUpdate(second, 3, func() error {
+		tries++
+		second.Title += "+"
+		return nil
+	}, "Title")
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	throwFail(t, AssertIs(tries, 2))
+	throwFail(t, AssertIs(second.Title, "v1+"))
+	throwFail(t, AssertIs(second.Version, 2))
+
+	// no retry in transaction
+	tries = 0
+	err = dORM.Transaction(context.Background(), func(txOrm Ormer) error {
+		_, err := txOrm.RetryUpdate(first, 3, func() error {
+			tries++
+			return nil
+		}, "Title")
+		return err
+	})
+	throwFail(t, AssertIs(errors.Is(err, ErrVersionConflict), true))
+	throwFail(t, AssertIs(tries, 1))
+
+	qs := dORM.QueryTable("note").Filter("id", id)
+	_, err = qs.Update(Params{"Title": "v3", "version": 1})
+	throwFail(t, AssertIs(errors.Is(err, ErrVersionConflict), true))
+	num, err = qs.Update(Params{"Title": "v3", "version": 2})
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	num, err = qs.Update(Params{"Title": "v4"})
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+
+	throwFail(t, dORM.Read(first))
+	throwFail(t, AssertIs(first.Title, "v4"))
+	throwFail(t, AssertIs(first.Version, 4))
+}
+
+func TestIterators(t *testing.T) {
+	qs := dORM.QueryTable("user")
+	total, err := qs.Count()
+	throwFail(t, err)
+
+	it, err := qs.OrderBy("id").Iterator()
+	throwFail(t, err)
+	throwFail(t, AssertIs(it.Scan(new(User)), ErrIteratorNoRow))
+	var num int64
+	var lastID int
+	for it.Next() {
+		var user User
+		throwFail(t, it.Scan(&user))
+		throwFail(t, AssertIs(user.ID > lastID, true))
+		lastID = user.ID
+		num++
+	}
+	throwFail(t, it.Err())
+	throwFail(t, it.Close())
+	throwFail(t, AssertIs(num, total))
+
+	// close on early exit
+	it, err = qs.RelatedSel().Iterator()
+	throwFail(t, err)
+	throwFail(t, AssertIs(it.Next(), true))
+	var user User
+	throwFail(t, it.Scan(&user))
+	throwFail(t, AssertIs(user.Profile == nil || user.Profile.ID > 0, true))
+	throwFail(t, it.Close())
+	throwFail(t, it.Close())
+	throwFail(t, AssertIs(it.Next(), false))
+
+	Q := dDbBaser.TableQuote()
+	rit, err := dORM.Raw(fmt.Sprintf("SELECT %suser_name%s FROM %suser%s ORDER BY %sid%s", Q, Q, Q, Q, Q, Q)).Iterator()
+	throwFail(t, err)
+	names := 0
+	for rit.Next() {
+		var name string
+		throwFail(t, rit.Scan(&name))
+		names++
+	}
+	throwFail(t, rit.Err())
+	throwFail(t, AssertIs(int64(names), total))
+
+	// keyset batches
+	var users []*User
+	var ids []int
+	err = qs.ForEachBatch(context.Background(), 2, &users, func(num int64) error {
+		throwFail(t, AssertIs(num <= 2, true))
+		for _, user := range users {
+			ids = append(ids, user.ID)
+		}
+		return nil
+	})
+	throwFail(t, err)
+	throwFail(t, AssertIs(int64(len(ids)), total))
+	for i := 1; i < len(ids); i++ {
+		throwFail(t, AssertIs(ids[i] > ids[i-1], true))
+	}
+
+	errStop := errors.New("stop")
+	batches := 0
+	err = qs.ForEachBatch(context.Background(), 1, &users, func(num int64) error {
+		batches++
+		return errStop
+	})
+	throwFail(t, AssertIs(err, errStop))
+	throwFail(t, AssertIs(batches, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = qs.ForEachBatch(ctx, 1, &users, func(num int64) error {
+		return nil
+	})
+	throwFail(t, AssertIs(err, context.Canceled))
+}
+
+type testCacheObserver struct {
+	hits, misses int
+}
+
+func (o *testCacheObserver) ObserveQuery(stats *QueryStats)                     {}
+func (o *testCacheObserver) ObserveRows(alias string, table string, rows int64) {}
+func (o *testCacheObserver) ObserveQueryCache(alias string, table string, hit bool) {
+	if hit {
+		o.hits++
+	} else {
+		o.misses++
+	}
+}
+
+func TestQueryCache(t *testing.T) {
+	observer := new(testCacheObserver)
+	RegisterQueryObserver(observer)
+	SetQueryCache(cache.NewMemoryCache(), "test")
+	defer SetQueryCache(nil, "")
+
+	qs := dORM.QueryTable("tag").Cache(time.Minute)
+	total, err := qs.Count()
+	throwFail(t, err)
+	num, err := qs.Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, total))
+	throwFail(t, AssertIs(observer.hits, 1))
+	throwFail(t, AssertIs(observer.misses, 1))
+
+	var tags []*Tag
+	num, err = qs.OrderBy("id").All(&tags)
+	throwFail(t, err)
+	var cached []*Tag
+	num, err = qs.OrderBy("id").All(&cached)
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, total))
+	throwFail(t, AssertIs(observer.hits, 2))
+	throwFail(t, AssertIs(len(cached), len(tags)))
+	throwFail(t, AssertIs(cached[0].Name, tags[0].Name))
+
+	var names ParamsList
+	_, err = qs.OrderBy("id").ValuesFlat(&names, "name")
+	throwFail(t, err)
+	_, err = qs.OrderBy("id").ValuesFlat(&names, "name")
+	throwFail(t, err)
+	throwFail(t, AssertIs(observer.hits, 3))
+	throwFail(t, AssertIs(names[0], tags[0].Name))
+
+	// insert invalidates the cache of table
+	id, err := dORM.Insert(&Tag{Name: "cached"})
+	throwFail(t, err)
+	num, err = qs.Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, total+1))
+	throwFail(t, AssertIs(observer.misses, 4))
+
+	// in transaction, the cache is bypassed and invalidated after commit
+	o := NewOrm()
+	throwFail(t, o.Begin())
+	_, err = o.QueryTable("tag").Filter("id", id).Delete()
+	throwFail(t, err)
+	num, err = o.QueryTable("tag").Cache(time.Minute).Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, total))
+	num, err = qs.Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(observer.hits, 4))
+	throwFail(t, o.Commit())
+
+	num, err = qs.Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, total))
+	throwFail(t, AssertIs(observer.misses, 5))
+}
+
+// sqlite3 driver emulating INSERT ... RETURNING, as the bundled sqlite does not support it
+type returningSqliteDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *returningSqliteDriver) Open(dsn string) (sqldriver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &returningSqliteConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type returningSqliteConn struct {
+	*sqlite3.SQLiteConn
+}
+
+func (c *returningSqliteConn) QueryContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	i := strings.Index(query, " RETURNING ")
+	if i < 0 {
+		return c.SQLiteConn.QueryContext(ctx, query, args)
+	}
+	res, err := c.SQLiteConn.ExecContext(ctx, query[:i], args)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return c.SQLiteConn.QueryContext(ctx, "SELECT ?", []sqldriver.NamedValue{{Ordinal: 1, Value: id}})
+}
+
+// sqlite dbBaser which has returning id like postgres
+type dbBaseReturningSqlite struct {
+	dbBaseSqlite
+}
+
+func (d *dbBaseReturningSqlite) HasReturningID(mi *modelInfo, query *string) bool {
+	if query != nil {
+		*query = fmt.Sprintf(`%s RETURNING "%s"`, *query, mi.fields.pk.column)
+	}
+	return true
+}
+
+func TestQueryCacheReturningID(t *testing.T) {
+	dir, err := os.MkdirTemp("", "orm_returning")
+	throwFailNow(t, err)
+	defer os.RemoveAll(dir)
+
+	sql.Register("sqlite3_returning", new(returningSqliteDriver))
+	throwFailNow(t, RegisterDriver("sqlite3_returning", DRSqlite))
+	throwFailNow(t, RegisterDataBase("returning", "sqlite3_returning", filepath.Join(dir, "returning.db")))
+	al := getDbAlias("returning")
+	baser := new(dbBaseReturningSqlite)
+	baser.ins = baser
+	al.DbBaser = baser
+
+	SetQueryCache(cache.NewMemoryCache(), "test")
+	defer SetQueryCache(nil, "")
+
+	o := NewOrm()
+	throwFailNow(t, o.Using("returning"))
+	_, err = o.Raw("CREATE TABLE tag (id integer PRIMARY KEY AUTOINCREMENT, name varchar(30) NOT NULL DEFAULT '', best_post_id integer)").Exec()
+	throwFailNow(t, err)
+
+	qs := o.QueryTable("tag").Cache(time.Minute)
+	num, err := qs.Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 0))
+
+	// insert by RETURNING invalidates the cache of table
+	tag := &Tag{Name: "returning"}
+	id, err := o.Insert(tag)
+	throwFail(t, err)
+	throwFail(t, AssertIs(id, 1))
+	throwFail(t, AssertIs(tag.ID, 1))
+	num, err = qs.Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+
+	// in transaction, it is invalidated after commit
+	throwFail(t, o.Begin())
+	_, err = o.Insert(&Tag{Name: "returning-tx"})
+	throwFail(t, err)
+	throwFail(t, o.Commit())
+	num, err = qs.Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 2))
+
+	// insert by the Ormer with context
+	co := NewOrmWithContext(context.Background())
+	throwFailNow(t, co.Using("returning"))
+	_, err = co.Insert(&Tag{Name: "returning-ctx"})
+	throwFail(t, err)
+	num, err = qs.Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 3))
+}
+
+func TestTenant(t *testing.T) {
+	throwFail(t, AssertIs(HasTenantModel(), true))
+
+	o1 := NewOrmWithContext(WithTenant(context.Background(), 1))
+	o2 := NewOrmWithContext(WithTenant(context.Background(), "2"))
+
+	memo := &Memo{Content: "corp 1"}
+	_, err := o1.Insert(memo)
+	throwFail(t, err)
+	throwFail(t, AssertIs(memo.CorpID, 1))
+
+	_, err = o2.Insert(&Memo{CorpID: 1, Content: "corp 2"})
+	throwFail(t, AssertIs(errors.Is(err, ErrTenantMismatch), true))
+	memos := []*Memo{{Content: "corp 2"}, {Content: "corp 2"}}
+	_, err = o2.InsertMulti(2, memos)
+	throwFail(t, err)
+
+	num, err := o1.QueryTable("memo").Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	num, err = o2.QueryTable("memo").Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 2))
+	num, err = dORM.QueryTable("memo").Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 3))
+
+	// the row of other tenant cannot be read, updated or deleted
+	err = o2.Read(&Memo{ID: memo.ID})
+	throwFail(t, AssertIs(err, ErrNoRows))
+	num, err = o2.Update(&Memo{ID: memo.ID, Content: "leaked"})
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 0))
+	_, err = o2.Update(memo)
+	throwFail(t, AssertIs(errors.Is(err, ErrTenantMismatch), true))
+	num, err = o2.QueryTable("memo").Filter("id", memo.ID).Update(Params{"content": "leaked"})
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 0))
+	_, err = o2.QueryTable("memo").Update(Params{"corp_id": 1})
+	throwFail(t, AssertIs(errors.Is(err, ErrTenantMismatch), true))
+	num, err = o2.Delete(&Memo{ID: memo.ID})
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 0))
+	num, err = o2.QueryTable("memo").Filter("id", memo.ID).Delete()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 0))
+
+	read := &Memo{ID: memo.ID}
+	throwFail(t, o1.Read(read))
+	throwFail(t, AssertIs(read.Content, "corp 1"))
+
+	num, err = o2.CrossTenant("count memos of all corps").QueryTable("memo").Count()
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 3))
+
+	// the tenant column can be updated by the Ormer not scoped
+	cross := o2.CrossTenant("move memo to corp 2")
+	memo.CorpID = 2
+	num, err = cross.Update(memo)
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+	throwFail(t, o2.Read(read))
+	throwFail(t, AssertIs(read.CorpID, 2))
+	memo.CorpID = 1
+	num, err = cross.Update(memo, "CorpID")
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+
+	// the Ormer of CrossTenant shares the transaction
+	throwFail(t, o1.Begin())
+	cross = o1.CrossTenant("read memos in transaction")
+	throwFail(t, AssertIs(cross.InTransaction(), true))
+	throwFail(t, cross.Commit())
+	throwFail(t, AssertIs(o1.InTransaction(), false))
+
+	// invalid tenant
+	o3 := NewOrmWithContext(WithTenant(context.Background(), "corp"))
+	_, err = o3.QueryTable("memo").Count()
+	throwFail(t, AssertIs(errors.Is(err, ErrTenantMismatch), true))
+	err = o3.Read(&Memo{ID: memo.ID})
+	throwFail(t, AssertIs(errors.Is(err, ErrTenantMismatch), true))
+	_, err = o3.Insert(&Memo{Content: "invalid"})
+	throwFail(t, AssertIs(errors.Is(err, ErrTenantMismatch), true))
+
+	num, err = o1.Delete(memo)
+	throwFail(t, err)
+	throwFail(t, AssertIs(num, 1))
+}
+
+func TestKeyset(t *testing.T) {
+	qs := dORM.QueryTable("tag")
+	orders, err := qs.OrderBy("-name").KeysetOrders()
+	throwFail(t, err)
+	throwFail(t, AssertIs(strings.Join(orders, ","), "-name,-id"))
+	orders, err = qs.OrderBy("id").KeysetOrders()
+	throwFail(t, err)
+	throwFail(t, AssertIs(strings.Join(orders, ","), "id"))
+	_, err = dORM.QueryTable("user").OrderBy("profile__age").KeysetOrders()
+	throwFail(t, AssertIs(err != nil, true))
+
+	var expected []*Tag
+	_, err = qs.OrderBy("-name", "-id").All(&expected)
+	throwFail(t, err)
+	throwFail(t, AssertIs(len(expected) > 2, true))
+
+	// page through the tags by keyset, 2 per page
+	var ids []int
+	page := qs.OrderBy("-name")
+	for {
+		var tags []*Tag
+		num, err := page.Limit(2).All(&tags)
+		throwFail(t, err)
+		for _, tag := range tags {
+			ids = append(ids, tag.ID)
+		}
+		if num < 2 {
+			break
+		}
+		values, err := page.KeysetValues(tags[len(tags)-1])
+		throwFail(t, err)
+		throwFail(t, AssertIs(len(values), 2))
+		page, err = qs.OrderBy("-name").SeekAfter(values...)
+		throwFail(t, err)
+	}
+	throwFail(t, AssertIs(len(ids), len(expected)))
+	for i, tag := range expected {
+		throwFail(t, AssertIs(ids[i], tag.ID))
+	}
+
+	_, err = qs.OrderBy("-name").SeekAfter(1)
+	throwFail(t, AssertIs(err != nil, true))
+	_, err = qs.OrderBy("-name").KeysetValues(&User{})
+	throwFail(t, AssertIs(err != nil, true))
+
+	total, err := qs.Count()
+	throwFail(t, err)
+	estimated, err := qs.CountEstimate()
+	throwFail(t, err)
+	if IsMysql {
+		throwFail(t, AssertIs(estimated > 0, true))
+	} else {
+		throwFail(t, AssertIs(estimated, total))
+	}
+}
+
+func TestQueryM2MWithoutContext(t *testing.T) {
+	o := NewOrm()
+	post := Post{ID: 1}
+	m2m := o.QueryM2M(&post, "Tags")
+	num, err := m2m.Count()
+	throwFailNow(t, err)
+	throwFailNow(t, AssertIs(num > 0, true))
+
+	var tag Tag
+	throwFailNow(t, o.QueryTable("tag").Limit(1).One(&tag))
+	m2m.Exist(&tag)
+
+	num, err = o.QueryTable("post").WithContext(nil).Count()
+	throwFailNow(t, err)
+	throwFailNow(t, AssertIs(num > 0, true))
+}
+
+func TestReplicaRouting(t *testing.T) {
+	dir, err := os.MkdirTemp("", "orm_replica")
+	throwFailNow(t, err)
+	defer os.RemoveAll(dir)
+
+	interval := ReplicaCheckInterval
+	ReplicaCheckInterval = 0
+	defer func() {
+		ReplicaCheckInterval = interval
+	}()
+	for _, name := range []string{"replica_primary", "replica_replica"} {
+		throwFailNow(t, RegisterDataBase(name, "sqlite3", filepath.Join(dir, name+".db")))
+		o := NewOrm()
+		throwFailNow(t, o.Using(name))
+		_, err := o.Raw("CREATE TABLE replica_item (id integer PRIMARY KEY, name varchar(20))").Exec()
+		throwFailNow(t, err)
+		_, err = o.Raw("INSERT INTO replica_item (id, name) VALUES (1, ?)", name).Exec()
+		throwFailNow(t, err)
+	}
+	o := NewOrm()
+	throwFailNow(t, o.Using("replica_primary"))
+	_, err = o.Raw("CREATE TABLE replica_only_primary (id integer PRIMARY KEY)").Exec()
+	throwFailNow(t, err)
+	throwFailNow(t, RegisterReplica("replica_primary", "replica_replica"))
+
+	readName := func(o Ormer, ctx context.Context) string {
+		var name string
+		throwFailNow(t, o.RawWithCtx(ctx, "SELECT name FROM replica_item WHERE id = 1").QueryRow(&name))
+		return name
+	}
+
+	// reads go to replica
+	o = NewOrm()
+	throwFailNow(t, o.Using("replica_primary"))
+	throwFail(t, AssertIs(readName(o, context.Background()), "replica_replica"))
+
+	// ForcePrimary
+	throwFail(t, AssertIs(readName(o, ForcePrimary(context.Background())), "replica_primary"))
+
+	// errors of query are returned without falling back to primary
+	var id int
+	err = o.Raw("SELECT id FROM replica_only_primary").QueryRow(&id)
+	throwFail(t, AssertIs(err != nil, true))
+	throwFail(t, AssertIs(getReplicaGroup("replica_primary").pick() != nil, true))
+
+	// read your writes after a write
+	_, err = o.Raw("UPDATE replica_item SET name = ? WHERE id = 1", "updated").Exec()
+	throwFailNow(t, err)
+	throwFail(t, AssertIs(readName(o, context.Background()), "updated"))
+
+	// a new Ormer reads from replica again
+	o = NewOrm()
+	throwFailNow(t, o.Using("replica_primary"))
+	throwFail(t, AssertIs(readName(o, context.Background()), "replica_replica"))
+
+	// reads in transaction go to primary
+	throwFailNow(t, o.Begin())
+	throwFail(t, AssertIs(readName(o, context.Background()), "updated"))
+	throwFailNow(t, o.Rollback())
+	throwFail(t, AssertIs(readName(o, context.Background()), "updated"))
+
+	// stickiness to primary expires
+	sticky := ReplicaStickyDuration
+	ReplicaStickyDuration = 50 * time.Millisecond
+	defer func() {
+		ReplicaStickyDuration = sticky
+	}()
+	o = NewOrm()
+	throwFailNow(t, o.Using("replica_primary"))
+	_, err = o.Raw("UPDATE replica_item SET name = ? WHERE id = 1", "updated").Exec()
+	throwFailNow(t, err)
+	throwFail(t, AssertIs(readName(o, context.Background()), "updated"))
+	time.Sleep(100 * time.Millisecond)
+	throwFail(t, AssertIs(readName(o, context.Background()), "replica_replica"))
+
+	// QueryRow falls back to primary when the replica can not be connected
+	al := getReplicaGroup("replica_primary").replicas[0].alias
+	replicaDB := al.DB
+	al.DB = sql.OpenDB(badConnConnector{})
+	defer func() {
+		al.DB = replicaDB
+	}()
+	throwFail(t, AssertIs(readName(o, context.Background()), "updated"))
+	throwFail(t, AssertIs(getReplicaGroup("replica_primary").pick() == nil, true))
+}
+
+// a connector of the replica which can not be connected
+type badConnConnector struct{}
+
+func (badConnConnector) Connect(context.Context) (sqldriver.Conn, error) {
+	return nil, sqldriver.ErrBadConn
+}
+
+func (badConnConnector) Driver() sqldriver.Driver {
+	return nil
+}
+
+func TestNormalizeColumnType(t *testing.T) {
+	cases := [][2]string{
+		{"int(11)", "integer"},
+		{"int(10) unsigned", "integer unsigned"},
+		{"tinyint(1)", "bool"},
+		{"boolean", "bool"},
+		{"character varying(255)", "varchar(255)"},
+		{"character(36)", "char(36)"},
+		{"decimal(10,2)", "numeric(10, 2)"},
+		{"double", "double precision"},
+		{"smallint", `smallint CHECK("status" >= 0 AND "status" <= 255)`},
+		{"timestamptz", "timestamp with time zone"},
+	}
+	for _, c := range cases {
+		throwFail(t, AssertIs(normalizeColumnType(c[0]), normalizeColumnType(c[1])), c[0], c[1])
+	}
+	throwFail(t, AssertNot(normalizeColumnType("varchar(100)"), normalizeColumnType("varchar(255)")))
+	throwFail(t, AssertNot(normalizeColumnType("bigint"), normalizeColumnType("int")))
+}
+
+func TestMysqlColumnDefault(t *testing.T) {
+	cases := [][4]string{
+		{"int(11)", "0", "", "0"},
+		{"decimal(10,2)", "1.50", "", "1.50"},
+		{"varchar(20)", "it's", "", "'it''s'"},
+		{"varchar(20)", "'mariadb'", "", "'mariadb'"},
+		{"datetime", "CURRENT_TIMESTAMP", "DEFAULT_GENERATED on update CURRENT_TIMESTAMP", "CURRENT_TIMESTAMP"},
+		{"json", "json_array()", "DEFAULT_GENERATED", "(json_array())"},
+	}
+	for _, c := range cases {
+		throwFail(t, AssertIs(mysqlColumnDefault(c[0], c[1], c[2]), c[3]), c[0], c[1])
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orm_migrations")
+	throwFailNow(t, err)
+	defer os.RemoveAll(dir)
+
+	// synced database has no changes
+	changes, err := getSchemaChanges(dORM.(*orm).alias, dORM.(*orm).alias.DB)
+	throwFailNow(t, err)
+	throwFailNow(t, AssertIs(len(changes), 0), changes)
+
+	al := dORM.(*orm).alias
+	_, err = dORM.Raw(getIndexDropSQL(al, "post", "post_id_created")).Exec()
+	throwFailNow(t, err)
+
+	path, err := RunMakeMigrations("default", dir, "post_index", true)
+	throwFail(t, err)
+	_, err = os.Stat(path)
+	throwFail(t, AssertIs(os.IsNotExist(err), true))
+
+	path, err = RunMakeMigrations("default", dir, "post_index", false)
+	throwFailNow(t, err)
+	content, err := ioutil.ReadFile(path)
+	throwFailNow(t, err)
+	mf, err := parseMigration("post_index", string(content))
+	throwFailNow(t, err)
+	throwFail(t, AssertIs(len(mf.up), 1))
+	throwFail(t, AssertIs(len(mf.down), 1))
+
+	// not applied yet
+	_, err = RunMakeMigrations("default", dir, "again", true)
+	throwFail(t, AssertNot(err, nil))
+
+	throwFailNow(t, RunMigrate("default", dir, false))
+	throwFail(t, AssertIs(al.DbBaser.IndexExists(al.DB, "post", "post_id_created"), true))
+	changes, err = getSchemaChanges(al, al.DB)
+	throwFail(t, err)
+	throwFail(t, AssertIs(len(changes), 0))
+
+	throwFailNow(t, RunMigrateDown("default", dir, 1, false))
+	throwFail(t, AssertIs(al.DbBaser.IndexExists(al.DB, "post", "post_id_created"), false))
+
+	// the lock is held by another process
+	m := newMigrator("default", dir, false)
+	throwFailNow(t, m.lock())
+	err = RunMigrate("default", dir, false)
+	throwFail(t, AssertIs(err != nil && strings.Contains(err.Error(), ErrMigrationLocked.Error()), true))
+	throwFailNow(t, m.unlock())
+
+	throwFailNow(t, RunMigrate("default", dir, false))
+	throwFail(t, AssertIs(al.DbBaser.IndexExists(al.DB, "post", "post_id_created"), true))
+}
+
+// a model renaming column `name` to `title`
+type RenameItem struct {
+	Id    int
+	Title string `orm:"size(60);rename(name)"`
+}
+
+// a connection of fake schema, query containing the key of results returns the rows
+type fakeSchemaConn struct {
+	results map[string][][]sqldriver.Value
+}
+
+func (c *fakeSchemaConn) Prepare(query string) (sqldriver.Stmt, error) {
+	return nil, errors.New("fake schema: prepare is not supported")
+}
+
+func (c *fakeSchemaConn) Close() error {
+	return nil
+}
+
+func (c *fakeSchemaConn) Begin() (sqldriver.Tx, error) {
+	return nil, errors.New("fake schema: transaction is not supported")
+}
+
+func (c *fakeSchemaConn) QueryContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	for key, rows := range c.results {
+		if strings.Contains(query, key) {
+			return &fakeSchemaRows{rows: rows}, nil
+		}
+	}
+	return &fakeSchemaRows{}, nil
+}
+
+type fakeSchemaRows struct {
+	rows [][]sqldriver.Value
+}
+
+func (r *fakeSchemaRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return []string{"value"}
+	}
+	columns := make([]string, len(r.rows[0]))
+	for i := range columns {
+		columns[i] = fmt.Sprintf("column%d", i)
+	}
+	return columns
+}
+
+func (r *fakeSchemaRows) Close() error {
+	return nil
+}
+
+func (r *fakeSchemaRows) Next(dest []sqldriver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+type fakeSchemaConnector struct {
+	conn *fakeSchemaConn
+}
+
+func (c fakeSchemaConnector) Connect(context.Context) (sqldriver.Conn, error) {
+	return c.conn, nil
+}
+
+func (c fakeSchemaConnector) Driver() sqldriver.Driver {
+	return nil
+}
+
+func TestGetTableChangesRename(t *testing.T) {
+	mi := newModelInfo(reflect.ValueOf(&RenameItem{}))
+	mi.table = "rename_item"
+
+	mysqlSchema := map[string][][]sqldriver.Value{
+		"SELECT COLUMN_NAME":    {{"id", "int(11)", "NO"}, {"name", "varchar(60)", "NO"}},
+		"SELECT COLUMN_DEFAULT": {{"", "", "item title"}},
+	}
+	cases := []struct {
+		driver DriverType
+		schema map[string][][]sqldriver.Value
+		up     string
+		down   string
+	}{
+		{DRMySQL, mysqlSchema,
+			"ALTER TABLE `rename_item` CHANGE COLUMN `name` `title` varchar(60) NOT NULL DEFAULT '' COMMENT 'item title'",
+			"ALTER TABLE `rename_item` CHANGE COLUMN `title` `name` varchar(60) NOT NULL DEFAULT '' COMMENT 'item title'"},
+		{DRTiDB, mysqlSchema,
+			"ALTER TABLE `rename_item` CHANGE COLUMN `name` `title` varchar(60) NOT NULL DEFAULT '' COMMENT 'item title'",
+			"ALTER TABLE `rename_item` CHANGE COLUMN `title` `name` varchar(60) NOT NULL DEFAULT '' COMMENT 'item title'"},
+		{DRPostgres, map[string][][]sqldriver.Value{
+			"FROM pg_attribute a JOIN": {{"id", "integer", "NO"}, {"name", "character varying(60)", "NO"}},
+		},
+			`ALTER TABLE "rename_item" RENAME COLUMN "name" TO "title"`,
+			`ALTER TABLE "rename_item" RENAME COLUMN "title" TO "name"`},
+		{DRSqlite, map[string][][]sqldriver.Value{
+			"pragma table_info": {{0, "id", "integer", 1, nil, 1}, {1, "name", "varchar(60)", 1, nil, 0}},
+		},
+			"ALTER TABLE `rename_item` RENAME COLUMN `name` TO `title`",
+			"ALTER TABLE `rename_item` RENAME COLUMN `title` TO `name`"},
+	}
+	for _, c := range cases {
+		al := &alias{Driver: c.driver, DbBaser: dbBasers[c.driver]}
+		al.DB = sql.OpenDB(fakeSchemaConnector{&fakeSchemaConn{results: c.schema}})
+		changes, err := getTableChanges(al, al.DB, mi)
+		throwFailNow(t, err, c.driver)
+		throwFailNow(t, AssertIs(len(changes), 1), c.driver, changes)
+		throwFail(t, AssertIs(strings.Join(changes[0].up, ";"), c.up), c.driver)
+		throwFail(t, AssertIs(strings.Join(changes[0].down, ";"), c.down), c.driver)
+	}
+}
+
 func TestReadOrCreate(t *testing.T) {
 	u := &User{
 		UserName: "Kyle",
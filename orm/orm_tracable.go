@@ -36,15 +36,27 @@ var _ txer = new(dbQueryTracable)
 var _ txEnder = new(dbQueryTracable)
 
 func (d *dbQueryTracable) CreateSpan(query string) opentracing.Span {
-	if d.span == nil {
+	return d.createSpan(d.span, query)
+}
+
+// create a span for query, the span in ctx is preferred to the span of orm as parent
+func (d *dbQueryTracable) createSpanWithCtx(ctx context.Context, query string) opentracing.Span {
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		return d.createSpan(parent, query)
+	}
+	return d.createSpan(d.span, query)
+}
+
+func (d *dbQueryTracable) createSpan(parent opentracing.Span, query string) opentracing.Span {
+	if parent == nil {
 		return nil
 	}
 	
 	items := strings.Split(query, " ")
 	operationName := fmt.Sprintf("db-%s", items[0])
-	span := d.span.Tracer().StartSpan(
+	span := parent.Tracer().StartSpan(
 		operationName,
-		opentracing.ChildOf(d.span.Context()),
+		opentracing.ChildOf(parent.Context()),
 	)
 	span.LogKV("sql", query)
 	span.SetTag("db.statement", query)
@@ -58,10 +70,7 @@ func (d *dbQueryTracable) Prepare(query string) (*sql.Stmt, error) {
 		defer span.Finish()
 	}
 	
-	var a time.Time
-	if Debug {
-		a = time.Now()
-	}
+	a := time.Now()
 	stmt, err := d.db.Prepare(query)
 	if Debug {
 		debugLogQueies(d.alias, "[orm_tracable] db.Prepare", query, a, err)
@@ -75,11 +84,10 @@ func (d *dbQueryTracable) Exec(query string, args ...interface{}) (sql.Result, e
 		defer span.Finish()
 	}
 	
-	var a time.Time
-	if Debug {
-		a = time.Now()
-	}
+	a := time.Now()
 	res, err := d.db.Exec(query, args...)
+	observeQuery(d.alias, query, a, res, err)
+	d.invalidateQueryCache(query, err)
 	if Debug {
 		debugLogQueies(d.alias, "[orm_tracable] db.Exec", query, a, err, args...)
 	}
@@ -92,11 +100,9 @@ func (d *dbQueryTracable) Query(query string, args ...interface{}) (*sql.Rows, e
 		defer span.Finish()
 	}
 	
-	var a time.Time
-	if Debug {
-		a = time.Now()
-	}
+	a := time.Now()
 	res, err := d.db.Query(query, args...)
+	observeQuery(d.alias, query, a, nil, err)
 	if Debug {
 		debugLogQueies(d.alias, "[orm_tracable] db.Query", query, a, err, args...)
 	}
@@ -109,11 +115,69 @@ func (d *dbQueryTracable) QueryRow(query string, args ...interface{}) *sql.Row {
 		defer span.Finish()
 	}
 	
-	var a time.Time
+	a := time.Now()
+	res := d.db.QueryRow(query, args...)
+	observeQuery(d.alias, query, a, nil, nil)
 	if Debug {
-		a = time.Now()
+		debugLogQueies(d.alias, "[orm_tracable] db.QueryRow", query, a, nil, args...)
 	}
-	res := d.db.QueryRow(query, args...)
+	return res
+}
+
+func (d *dbQueryTracable) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	span := d.createSpanWithCtx(ctx, query)
+	if span != nil {
+		defer span.Finish()
+	}
+	
+	a := time.Now()
+	stmt, err := d.db.PrepareContext(ctx, query)
+	if Debug {
+		debugLogQueies(d.alias, "[orm_tracable] db.Prepare", query, a, err)
+	}
+	return stmt, err
+}
+
+func (d *dbQueryTracable) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	span := d.createSpanWithCtx(ctx, query)
+	if span != nil {
+		defer span.Finish()
+	}
+	
+	a := time.Now()
+	res, err := d.db.ExecContext(ctx, query, args...)
+	observeQuery(d.alias, query, a, res, err)
+	d.invalidateQueryCache(query, err)
+	if Debug {
+		debugLogQueies(d.alias, "[orm_tracable] db.Exec", query, a, err, args...)
+	}
+	return res, err
+}
+
+func (d *dbQueryTracable) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	span := d.createSpanWithCtx(ctx, query)
+	if span != nil {
+		defer span.Finish()
+	}
+	
+	a := time.Now()
+	res, err := d.db.QueryContext(ctx, query, args...)
+	observeQuery(d.alias, query, a, nil, err)
+	if Debug {
+		debugLogQueies(d.alias, "[orm_tracable] db.Query", query, a, err, args...)
+	}
+	return res, err
+}
+
+func (d *dbQueryTracable) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	span := d.createSpanWithCtx(ctx, query)
+	if span != nil {
+		defer span.Finish()
+	}
+	
+	a := time.Now()
+	res := d.db.QueryRowContext(ctx, query, args...)
+	observeQuery(d.alias, query, a, nil, nil)
 	if Debug {
 		debugLogQueies(d.alias, "[orm_tracable] db.QueryRow", query, a, nil, args...)
 	}
@@ -126,11 +190,9 @@ func (d *dbQueryTracable) Begin() (*sql.Tx, error) {
 		defer span.Finish()
 	}
 	
-	var a time.Time
-	if Debug {
-		a = time.Now()
-	}
+	a := time.Now()
 	tx, err := d.db.(txer).Begin()
+	observeQuery(d.alias, "BEGIN", a, nil, err)
 	if Debug {
 		debugLogQueies(d.alias, "[orm_tracable] db.Begin", "START TRANSACTION", a, err)
 	}
@@ -138,16 +200,14 @@ func (d *dbQueryTracable) Begin() (*sql.Tx, error) {
 }
 
 func (d *dbQueryTracable) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
-	span := d.CreateSpan("BEGIN")
+	span := d.createSpanWithCtx(ctx, "BEGIN")
 	if span != nil {
 		defer span.Finish()
 	}
 	
-	var a time.Time
-	if Debug {
-		a = time.Now()
-	}
+	a := time.Now()
 	tx, err := d.db.(txer).BeginTx(ctx, opts)
+	observeQuery(d.alias, "BEGIN", a, nil, err)
 	if Debug {
 		debugLogQueies(d.alias, "[orm_tracable] db.BeginTx", "START TRANSACTION", a, err)
 	}
@@ -160,11 +220,10 @@ func (d *dbQueryTracable) Commit() error {
 		defer span.Finish()
 	}
 	
-	var a time.Time
-	if Debug {
-		a = time.Now()
-	}
+	a := time.Now()
 	err := d.db.(txEnder).Commit()
+	observeQuery(d.alias, "COMMIT", a, nil, err)
+	d.endTxQueryCache(err == nil)
 	if Debug {
 		debugLogQueies(d.alias, "[orm_tracable] tx.Commit", "COMMIT", a, err)
 	}
@@ -177,11 +236,10 @@ func (d *dbQueryTracable) Rollback() error {
 		defer span.Finish()
 	}
 	
-	var a time.Time
-	if Debug {
-		a = time.Now()
-	}
+	a := time.Now()
 	err := d.db.(txEnder).Rollback()
+	observeQuery(d.alias, "ROLLBACK", a, nil, err)
+	d.endTxQueryCache(false)
 	if Debug {
 		debugLogQueies(d.alias, "[orm_tracable] tx.Rollback", "ROLLBACK", a, err)
 	}
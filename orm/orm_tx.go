@@ -0,0 +1,188 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// a level of transaction, the first is the transaction itself and others are savepoints.
+type txLevel struct {
+	ctx           context.Context
+	savepoint     string
+	afterCommit   []func()
+	afterRollback []func()
+}
+
+// drivers support nested transaction by savepoint
+var savepointDrivers = map[DriverType]bool{
+	DRMySQL:    true,
+	DRPostgres: true,
+	DRSqlite:   true,
+	DRTiDB:     true,
+}
+
+// get the current level of transaction
+func (o *orm) currentTxLevel() *txLevel {
+	return o.txLevels[len(o.txLevels)-1]
+}
+
+// begin a nested transaction by savepoint.
+func (o *orm) savepoint(ctx context.Context, opts *sql.TxOptions) error {
+	if !savepointDrivers[o.alias.Driver] {
+		return fmt.Errorf("`%s` nonsupport nested transaction in beego", o.alias.DriverName)
+	}
+	if opts != nil {
+		return fmt.Errorf("<Ormer.BeginTx> nested transaction cannot set TxOptions")
+	}
+	name := fmt.Sprintf("beego_sp_%d", len(o.txLevels))
+	if _, err := o.db.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	o.txLevels = append(o.txLevels, &txLevel{ctx: ctx, savepoint: name})
+	return nil
+}
+
+// release the savepoint of current level, its callbacks are moved to the parent level.
+func (o *orm) releaseSavepoint() error {
+	level := o.currentTxLevel()
+	if _, err := o.db.ExecContext(level.ctx, "RELEASE SAVEPOINT "+level.savepoint); err != nil {
+		return err
+	}
+	o.txLevels = o.txLevels[:len(o.txLevels)-1]
+	parent := o.currentTxLevel()
+	parent.afterCommit = append(parent.afterCommit, level.afterCommit...)
+	parent.afterRollback = append(parent.afterRollback, level.afterRollback...)
+	return nil
+}
+
+// rollback to the savepoint of current level, the after rollback callbacks of it are called.
+func (o *orm) rollbackSavepoint() error {
+	level := o.currentTxLevel()
+	if _, err := o.db.ExecContext(level.ctx, "ROLLBACK TO SAVEPOINT "+level.savepoint); err != nil {
+		return err
+	}
+	o.txLevels = o.txLevels[:len(o.txLevels)-1]
+	for _, fn := range level.afterRollback {
+		fn()
+	}
+	return nil
+}
+
+// end the transaction and call the callbacks of committed or rolled back.
+func (o *orm) endTx(committed bool) {
+	level := o.txLevels[0]
+	o.txLevels = nil
+	fns := level.afterRollback
+	if committed {
+		fns = level.afterCommit
+	}
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// rollback the transaction with all its nested transactions.
+// the outer transaction is rolled back even if a savepoint can not be released or rolled back,
+// so the transaction is always ended. the after rollback callbacks of all levels are called.
+func (o *orm) RollbackAll() error {
+	if !o.isTx {
+		return ErrTxDone
+	}
+	for len(o.txLevels) > 1 {
+		level := o.currentTxLevel()
+		o.txLevels = o.txLevels[:len(o.txLevels)-1]
+		for _, fn := range level.afterRollback {
+			fn()
+		}
+	}
+	err := o.db.(txEnder).Rollback()
+	o.isTx = false
+	o.Using(o.alias.Name)
+	o.endTx(false)
+	if err == sql.ErrTxDone {
+		// the transaction has been ended by a failed commit
+		return nil
+	}
+	return err
+}
+
+// register a callback called after the transaction is committed.
+// if it is registered in a nested transaction which is rolled back, it is never called.
+// it is called at once when there is no transaction.
+func (o *orm) AfterCommit(fn func()) {
+	if !o.isTx {
+		fn()
+		return
+	}
+	level := o.currentTxLevel()
+	level.afterCommit = append(level.afterCommit, fn)
+}
+
+// register a callback called after the transaction, or the nested transaction it registered in, is rolled back.
+// it is ignored when there is no transaction.
+func (o *orm) AfterRollback(fn func()) {
+	if !o.isTx {
+		return
+	}
+	level := o.currentTxLevel()
+	level.afterRollback = append(level.afterRollback, fn)
+}
+
+// run fn in a transaction, or a nested transaction if transaction has begun.
+// it is committed if fn returns nil, otherwise rolled back, and it is rolled back when fn panics too.
+func (o *orm) Transaction(ctx context.Context, fn func(txOrm Ormer) error) error {
+	if ctx == nil {
+		ctx = o.context()
+	}
+	if err := o.BeginTx(ctx, nil); err != nil {
+		return err
+	}
+	depth := len(o.txLevels)
+
+	defer func() {
+		if p := recover(); p != nil {
+			o.endTxLevels(depth, false)
+			panic(p)
+		}
+	}()
+
+	if err := fn(o); err != nil {
+		if rerr := o.endTxLevels(depth, false); rerr != nil {
+			return fmt.Errorf("%v, rollback error: %v", err, rerr)
+		}
+		return err
+	}
+	return o.endTxLevels(depth, true)
+}
+
+// commit or rollback the levels of transaction from depth,
+// including the nested transactions which are not ended by fn of Transaction.
+func (o *orm) endTxLevels(depth int, commit bool) error {
+	for o.isTx && len(o.txLevels) >= depth {
+		var err error
+		if commit {
+			err = o.Commit()
+		} else {
+			err = o.Rollback()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
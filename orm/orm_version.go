@@ -0,0 +1,119 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrVersionConflict is matched by errors.Is for every *VersionConflictError.
+var ErrVersionConflict = errors.New("<Ormer.Update> version conflict")
+
+// VersionConflictError is returned by update of a model with version field,
+// when no row matches the expected version, it is changed by others or deleted.
+type VersionConflictError struct {
+	Model   string
+	Pk      interface{}
+	Version int64
+}
+
+// Error implements error.
+func (e *VersionConflictError) Error() string {
+	if e.Pk == nil {
+		return fmt.Sprintf("%s: `%s` version %d", ErrVersionConflict, e.Model, e.Version)
+	}
+	return fmt.Sprintf("%s: `%s` pk %v version %d", ErrVersionConflict, e.Model, e.Pk, e.Version)
+}
+
+// Is make errors.Is(err, ErrVersionConflict) true.
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// get the version value of model struct.
+func getVersionValue(fi *fieldInfo, ind reflect.Value) int64 {
+	field := ind.FieldByIndex(fi.fieldIndex)
+	if fi.fieldType&IsPositiveIntegerField > 0 {
+		return int64(field.Uint())
+	}
+	return field.Int()
+}
+
+// set the version value of model struct.
+func setVersionValue(fi *fieldInfo, ind reflect.Value, version int64) {
+	field := ind.FieldByIndex(fi.fieldIndex)
+	if fi.fieldType&IsPositiveIntegerField > 0 {
+		field.SetUint(uint64(version))
+	} else {
+		field.SetInt(version)
+	}
+}
+
+// increase the version field in values of QuerySeter.Update.
+// a version value in values is the expected version, it is removed from values and added to condition.
+func (o *querySet) addVersionValues(values Params, cond *Condition) (Params, *Condition, interface{}) {
+	fi := o.mi.fields.version
+	result := make(Params, len(values)+1)
+	var expected interface{}
+	for k, v := range values {
+		if f, ok := o.mi.fields.GetByAny(k); ok && f == fi {
+			expected = v
+			continue
+		}
+		result[k] = v
+	}
+	result[fi.name] = F(fi.name).Add(1)
+	if expected == nil {
+		return result, cond, nil
+	}
+
+	vcond := NewCondition().And(fi.name, expected)
+	if cond != nil && !cond.IsEmpty() {
+		vcond = vcond.AndCond(cond)
+	}
+	return result, vcond, expected
+}
+
+// RetryUpdate apply fn to md and update it, the model must have a version field.
+// when the version conflicts, md is read again by pk and fn is applied again,
+// attempts is the max times of update.
+// in a transaction it does not retry, the read again sees the same snapshot under
+// REPEATABLE READ and conflicts again, the conflict is returned to rollback the transaction.
+func (o *orm) RetryUpdate(md interface{}, attempts int, fn func() error, cols ...string) (int64, error) {
+	return o.RetryUpdateWithCtx(o.ctx, md, attempts, fn, cols...)
+}
+
+// RetryUpdateWithCtx is RetryUpdate with context.
+func (o *orm) RetryUpdateWithCtx(ctx context.Context, md interface{}, attempts int, fn func() error, cols ...string) (int64, error) {
+	mi, _ := o.getMiInd(md, true)
+	if mi.fields.version == nil {
+		panic(fmt.Errorf("<Ormer.RetryUpdate> model `%s` has no version field", mi.fullName))
+	}
+	for i := 1; ; i++ {
+		if err := fn(); err != nil {
+			return 0, err
+		}
+		num, err := o.UpdateWithCtx(ctx, md, cols...)
+		if err == nil || !errors.Is(err, ErrVersionConflict) || i >= attempts || o.isTx {
+			return num, err
+		}
+		if err := o.ReadWithCtx(ctx, md); err != nil {
+			return 0, err
+		}
+	}
+}
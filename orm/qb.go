@@ -14,34 +14,51 @@
 
 package orm
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
-// QueryBuilder is the Query builder interface
+// QueryBuilder is the Query builder interface.
+// conditions with args use `?` as the mark of args, InArgs and ValuesArgs bind values as args,
+// Build returns the sql with its args, String returns the sql only:
+//
+//	qb, _ := orm.NewQueryBuilder("postgres")
+//	qb.Select("id", "name").From("user").Where("age > ?", 18).And("status").InArgs(1, 2)
+//	sql, args := qb.Build()
+//	// SELECT id, name FROM user WHERE age > $1 AND status IN ( $2, $3 )
+//	o.Raw(sql, args...).QueryRows(&users)
 type QueryBuilder interface {
+	With(name string, sub QueryBuilder) QueryBuilder
 	Select(fields ...string) QueryBuilder
 	ForUpdate() QueryBuilder
 	From(tables ...string) QueryBuilder
 	InnerJoin(table string) QueryBuilder
 	LeftJoin(table string) QueryBuilder
 	RightJoin(table string) QueryBuilder
-	On(cond string) QueryBuilder
-	Where(cond string) QueryBuilder
-	And(cond string) QueryBuilder
-	Or(cond string) QueryBuilder
+	On(cond string, args ...interface{}) QueryBuilder
+	Where(cond string, args ...interface{}) QueryBuilder
+	And(cond string, args ...interface{}) QueryBuilder
+	Or(cond string, args ...interface{}) QueryBuilder
 	In(vals ...string) QueryBuilder
+	InArgs(vals ...interface{}) QueryBuilder
 	OrderBy(fields ...string) QueryBuilder
 	Asc() QueryBuilder
 	Desc() QueryBuilder
 	Limit(limit int) QueryBuilder
 	Offset(offset int) QueryBuilder
 	GroupBy(fields ...string) QueryBuilder
-	Having(cond string) QueryBuilder
+	Having(cond string, args ...interface{}) QueryBuilder
 	Update(tables ...string) QueryBuilder
 	Set(kv ...string) QueryBuilder
 	Delete(tables ...string) QueryBuilder
 	InsertInto(table string, fields ...string) QueryBuilder
 	Values(vals ...string) QueryBuilder
+	ValuesArgs(vals ...interface{}) QueryBuilder
+	Union(sub QueryBuilder) QueryBuilder
+	UnionAll(sub QueryBuilder) QueryBuilder
 	Subquery(sub string, alias string) string
+	Build() (string, []interface{})
 	String() string
 }
 
@@ -52,11 +69,60 @@ func NewQueryBuilder(driver string) (qb QueryBuilder, err error) {
 	} else if driver == "tidb" {
 		qb = new(TiDBQueryBuilder)
 	} else if driver == "postgres" {
-		err = errors.New("postgres query builder is not supported yet")
-	} else if driver == "sqlite" {
-		err = errors.New("sqlite query builder is not supported yet")
+		qb = new(PostgresQueryBuilder)
+	} else if driver == "sqlite" || driver == "sqlite3" {
+		qb = new(SQLiteQueryBuilder)
 	} else {
 		err = errors.New("unknown driver for query builder")
 	}
 	return
 }
+
+// the mark of args emitted by query builders, replaced by the mark of driver in Build,
+// so `?` in quoted literals and operators such as the jsonb `?` of postgres are kept.
+const qbArgMark = "\x00"
+
+// query builder which keeps qbArgMark as the mark of args in the sql,
+// used when it is a part of another query builder.
+type markQueryBuilder interface {
+	build() (string, []interface{})
+}
+
+// get the sql and args of the sub query builder
+func getSubQuery(sub QueryBuilder) (string, []interface{}) {
+	if qb, ok := sub.(markQueryBuilder); ok {
+		return qb.build()
+	}
+	return sub.Build()
+}
+
+// get the marks of n args
+func getQueryMarks(n int) string {
+	return strings.TrimSuffix(strings.Repeat(qbArgMark+CommaSpace, n), CommaSpace)
+}
+
+// replace `?` in cond with the mark of args if cond has args,
+// `?` in quoted literals and identifiers are kept.
+func markQueryArgs(cond string, n int) string {
+	if n == 0 {
+		return cond
+	}
+	buf := make([]byte, 0, len(cond))
+	var quote byte
+	for i := 0; i < len(cond); i++ {
+		c := cond[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			buf = append(buf, qbArgMark...)
+			continue
+		}
+		buf = append(buf, c)
+	}
+	return string(buf)
+}
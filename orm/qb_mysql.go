@@ -26,6 +26,20 @@ const CommaSpace = ", "
 // MySQLQueryBuilder is the SQL build
 type MySQLQueryBuilder struct {
 	Tokens []string
+	Args   []interface{}
+}
+
+// With add the common table expression of sub query builder
+func (qb *MySQLQueryBuilder) With(name string, sub QueryBuilder) QueryBuilder {
+	sql, args := getSubQuery(sub)
+	if len(qb.Tokens) == 0 {
+		qb.Tokens = append(qb.Tokens, "WITH")
+	} else {
+		qb.Tokens[len(qb.Tokens)-1] += ","
+	}
+	qb.Tokens = append(qb.Tokens, name, "AS", "("+sql+")")
+	qb.Args = append(qb.Args, args...)
+	return qb
 }
 
 // Select will join the fields
@@ -65,26 +79,30 @@ func (qb *MySQLQueryBuilder) RightJoin(table string) QueryBuilder {
 }
 
 // On join with on cond
-func (qb *MySQLQueryBuilder) On(cond string) QueryBuilder {
-	qb.Tokens = append(qb.Tokens, "ON", cond)
+func (qb *MySQLQueryBuilder) On(cond string, args ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "ON", markQueryArgs(cond, len(args)))
+	qb.Args = append(qb.Args, args...)
 	return qb
 }
 
 // Where join the Where cond
-func (qb *MySQLQueryBuilder) Where(cond string) QueryBuilder {
-	qb.Tokens = append(qb.Tokens, "WHERE", cond)
+func (qb *MySQLQueryBuilder) Where(cond string, args ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "WHERE", markQueryArgs(cond, len(args)))
+	qb.Args = append(qb.Args, args...)
 	return qb
 }
 
 // And join the and cond
-func (qb *MySQLQueryBuilder) And(cond string) QueryBuilder {
-	qb.Tokens = append(qb.Tokens, "AND", cond)
+func (qb *MySQLQueryBuilder) And(cond string, args ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "AND", markQueryArgs(cond, len(args)))
+	qb.Args = append(qb.Args, args...)
 	return qb
 }
 
 // Or join the or cond
-func (qb *MySQLQueryBuilder) Or(cond string) QueryBuilder {
-	qb.Tokens = append(qb.Tokens, "OR", cond)
+func (qb *MySQLQueryBuilder) Or(cond string, args ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "OR", markQueryArgs(cond, len(args)))
+	qb.Args = append(qb.Args, args...)
 	return qb
 }
 
@@ -94,6 +112,13 @@ func (qb *MySQLQueryBuilder) In(vals ...string) QueryBuilder {
 	return qb
 }
 
+// InArgs join the IN (vals) with vals as args
+func (qb *MySQLQueryBuilder) InArgs(vals ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "IN", "(", getQueryMarks(len(vals)), ")")
+	qb.Args = append(qb.Args, vals...)
+	return qb
+}
+
 // OrderBy join the Order by fields
 func (qb *MySQLQueryBuilder) OrderBy(fields ...string) QueryBuilder {
 	qb.Tokens = append(qb.Tokens, "ORDER BY", strings.Join(fields, CommaSpace))
@@ -131,8 +156,9 @@ func (qb *MySQLQueryBuilder) GroupBy(fields ...string) QueryBuilder {
 }
 
 // Having join the Having cond
-func (qb *MySQLQueryBuilder) Having(cond string) QueryBuilder {
-	qb.Tokens = append(qb.Tokens, "HAVING", cond)
+func (qb *MySQLQueryBuilder) Having(cond string, args ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "HAVING", markQueryArgs(cond, len(args)))
+	qb.Args = append(qb.Args, args...)
 	return qb
 }
 
@@ -174,12 +200,47 @@ func (qb *MySQLQueryBuilder) Values(vals ...string) QueryBuilder {
 	return qb
 }
 
+// ValuesArgs join the Values(vals) with vals as args
+func (qb *MySQLQueryBuilder) ValuesArgs(vals ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "VALUES", "(", getQueryMarks(len(vals)), ")")
+	qb.Args = append(qb.Args, vals...)
+	return qb
+}
+
+// Union join the UNION sub query builder
+func (qb *MySQLQueryBuilder) Union(sub QueryBuilder) QueryBuilder {
+	sql, args := getSubQuery(sub)
+	qb.Tokens = append(qb.Tokens, "UNION", sql)
+	qb.Args = append(qb.Args, args...)
+	return qb
+}
+
+// UnionAll join the UNION ALL sub query builder
+func (qb *MySQLQueryBuilder) UnionAll(sub QueryBuilder) QueryBuilder {
+	sql, args := getSubQuery(sub)
+	qb.Tokens = append(qb.Tokens, "UNION ALL", sql)
+	qb.Args = append(qb.Args, args...)
+	return qb
+}
+
 // Subquery join the sub as alias
 func (qb *MySQLQueryBuilder) Subquery(sub string, alias string) string {
 	return fmt.Sprintf("(%s) AS %s", sub, alias)
 }
 
+// build join all Tokens with the marks of args emitted by query builder
+func (qb *MySQLQueryBuilder) build() (string, []interface{}) {
+	return strings.Join(qb.Tokens, " "), qb.Args
+}
+
+// Build join all Tokens, return the sql with its args
+func (qb *MySQLQueryBuilder) Build() (string, []interface{}) {
+	query, args := qb.build()
+	return strings.Replace(query, qbArgMark, "?", -1), args
+}
+
 // String join all Tokens
 func (qb *MySQLQueryBuilder) String() string {
-	return strings.Join(qb.Tokens, " ")
+	query, _ := qb.Build()
+	return query
 }
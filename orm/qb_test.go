@@ -0,0 +1,83 @@
+// Copyright 2014 beego Author. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	for _, driver := range []string{"mysql", "tidb", "postgres", "sqlite3"} {
+		if _, err := NewQueryBuilder(driver); err != nil {
+			t.Fatalf("%s: %s", driver, err)
+		}
+	}
+
+	active, _ := NewQueryBuilder("postgres")
+	active.Select("id").From("user").Where("status = ?", 1)
+
+	qb, _ := NewQueryBuilder("postgres")
+	qb.With("active", active).
+		Select("id", "name").From("active").Where("age > ?", 18).And("id").InArgs(1, 2, 3).
+		Union(new(PostgresQueryBuilder).Select("id", "name").From("admin").Where("name = ?", "slene"))
+	query, args := qb.Build()
+	expected := "WITH active AS (SELECT id FROM user WHERE status = $1) SELECT id, name FROM active WHERE age > $2 AND id IN ( $3, $4, $5 ) UNION SELECT id, name FROM admin WHERE name = $6"
+	if query != expected {
+		t.Errorf("got %s, expected %s", query, expected)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, 18, 1, 2, 3, "slene"}) {
+		t.Errorf("unexpected args %v", args)
+	}
+
+	pg := new(PostgresQueryBuilder)
+	pg.InsertInto("user", "name", "age").ValuesArgs("slene", 28)
+	pg.OnConflict("name").DoUpdate("age = EXCLUDED.age").Returning("id")
+	query, args = pg.Build()
+	expected = "INSERT INTO user ( name, age ) VALUES ( $1, $2 ) ON CONFLICT ( name ) DO UPDATE SET age = EXCLUDED.age RETURNING id"
+	if query != expected || len(args) != 2 {
+		t.Errorf("got %s %v, expected %s", query, args, expected)
+	}
+
+	mysql, _ := NewQueryBuilder("mysql")
+	query, args = mysql.Select("*").From("user").Where("name = ?", "slene").Limit(10).Build()
+	expected = "SELECT * FROM user WHERE name = ? LIMIT 10"
+	if query != expected || len(args) != 1 {
+		t.Errorf("got %s %v, expected %s", query, args, expected)
+	}
+
+	// `?` in literals and the jsonb operator are not marks of args
+	pg = new(PostgresQueryBuilder)
+	pg.Select("id").From("doc").Where("data ? 'tag'").And("note <> '?' AND id > ?", 1)
+	query, args = pg.Build()
+	expected = "SELECT id FROM doc WHERE data ? 'tag' AND note <> '?' AND id > $1"
+	if query != expected || len(args) != 1 {
+		t.Errorf("got %s %v, expected %s", query, args, expected)
+	}
+
+	// String, In and Values with strings are kept for the sql without args
+	mysql, _ = NewQueryBuilder("mysql")
+	query = mysql.InsertInto("user", "name", "age").Values("?", "?").String()
+	expected = "INSERT INTO user ( name, age ) VALUES ( ?, ? )"
+	if query != expected {
+		t.Errorf("got %s, expected %s", query, expected)
+	}
+	mysql, _ = NewQueryBuilder("mysql")
+	query = mysql.Select("*").From("user").Where("name").In("'a'", "'b'").String()
+	expected = "SELECT * FROM user WHERE name IN ( 'a', 'b' )"
+	if query != expected {
+		t.Errorf("got %s, expected %s", query, expected)
+	}
+}
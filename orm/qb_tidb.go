@@ -23,6 +23,20 @@ import (
 // TiDBQueryBuilder is the SQL build
 type TiDBQueryBuilder struct {
 	Tokens []string
+	Args   []interface{}
+}
+
+// With add the common table expression of sub query builder
+func (qb *TiDBQueryBuilder) With(name string, sub QueryBuilder) QueryBuilder {
+	sql, args := getSubQuery(sub)
+	if len(qb.Tokens) == 0 {
+		qb.Tokens = append(qb.Tokens, "WITH")
+	} else {
+		qb.Tokens[len(qb.Tokens)-1] += ","
+	}
+	qb.Tokens = append(qb.Tokens, name, "AS", "("+sql+")")
+	qb.Args = append(qb.Args, args...)
+	return qb
 }
 
 // Select will join the fields
@@ -62,26 +76,30 @@ func (qb *TiDBQueryBuilder) RightJoin(table string) QueryBuilder {
 }
 
 // On join with on cond
-func (qb *TiDBQueryBuilder) On(cond string) QueryBuilder {
-	qb.Tokens = append(qb.Tokens, "ON", cond)
+func (qb *TiDBQueryBuilder) On(cond string, args ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "ON", markQueryArgs(cond, len(args)))
+	qb.Args = append(qb.Args, args...)
 	return qb
 }
 
 // Where join the Where cond
-func (qb *TiDBQueryBuilder) Where(cond string) QueryBuilder {
-	qb.Tokens = append(qb.Tokens, "WHERE", cond)
+func (qb *TiDBQueryBuilder) Where(cond string, args ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "WHERE", markQueryArgs(cond, len(args)))
+	qb.Args = append(qb.Args, args...)
 	return qb
 }
 
 // And join the and cond
-func (qb *TiDBQueryBuilder) And(cond string) QueryBuilder {
-	qb.Tokens = append(qb.Tokens, "AND", cond)
+func (qb *TiDBQueryBuilder) And(cond string, args ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "AND", markQueryArgs(cond, len(args)))
+	qb.Args = append(qb.Args, args...)
 	return qb
 }
 
 // Or join the or cond
-func (qb *TiDBQueryBuilder) Or(cond string) QueryBuilder {
-	qb.Tokens = append(qb.Tokens, "OR", cond)
+func (qb *TiDBQueryBuilder) Or(cond string, args ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "OR", markQueryArgs(cond, len(args)))
+	qb.Args = append(qb.Args, args...)
 	return qb
 }
 
@@ -91,6 +109,13 @@ func (qb *TiDBQueryBuilder) In(vals ...string) QueryBuilder {
 	return qb
 }
 
+// InArgs join the IN (vals) with vals as args
+func (qb *TiDBQueryBuilder) InArgs(vals ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "IN", "(", getQueryMarks(len(vals)), ")")
+	qb.Args = append(qb.Args, vals...)
+	return qb
+}
+
 // OrderBy join the Order by fields
 func (qb *TiDBQueryBuilder) OrderBy(fields ...string) QueryBuilder {
 	qb.Tokens = append(qb.Tokens, "ORDER BY", strings.Join(fields, CommaSpace))
@@ -128,8 +153,9 @@ func (qb *TiDBQueryBuilder) GroupBy(fields ...string) QueryBuilder {
 }
 
 // Having join the Having cond
-func (qb *TiDBQueryBuilder) Having(cond string) QueryBuilder {
-	qb.Tokens = append(qb.Tokens, "HAVING", cond)
+func (qb *TiDBQueryBuilder) Having(cond string, args ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "HAVING", markQueryArgs(cond, len(args)))
+	qb.Args = append(qb.Args, args...)
 	return qb
 }
 
@@ -171,12 +197,47 @@ func (qb *TiDBQueryBuilder) Values(vals ...string) QueryBuilder {
 	return qb
 }
 
+// ValuesArgs join the Values(vals) with vals as args
+func (qb *TiDBQueryBuilder) ValuesArgs(vals ...interface{}) QueryBuilder {
+	qb.Tokens = append(qb.Tokens, "VALUES", "(", getQueryMarks(len(vals)), ")")
+	qb.Args = append(qb.Args, vals...)
+	return qb
+}
+
+// Union join the UNION sub query builder
+func (qb *TiDBQueryBuilder) Union(sub QueryBuilder) QueryBuilder {
+	sql, args := getSubQuery(sub)
+	qb.Tokens = append(qb.Tokens, "UNION", sql)
+	qb.Args = append(qb.Args, args...)
+	return qb
+}
+
+// UnionAll join the UNION ALL sub query builder
+func (qb *TiDBQueryBuilder) UnionAll(sub QueryBuilder) QueryBuilder {
+	sql, args := getSubQuery(sub)
+	qb.Tokens = append(qb.Tokens, "UNION ALL", sql)
+	qb.Args = append(qb.Args, args...)
+	return qb
+}
+
 // Subquery join the sub as alias
 func (qb *TiDBQueryBuilder) Subquery(sub string, alias string) string {
 	return fmt.Sprintf("(%s) AS %s", sub, alias)
 }
 
+// build join all Tokens with the marks of args emitted by query builder
+func (qb *TiDBQueryBuilder) build() (string, []interface{}) {
+	return strings.Join(qb.Tokens, " "), qb.Args
+}
+
+// Build join all Tokens, return the sql with its args
+func (qb *TiDBQueryBuilder) Build() (string, []interface{}) {
+	query, args := qb.build()
+	return strings.Replace(query, qbArgMark, "?", -1), args
+}
+
 // String join all Tokens
 func (qb *TiDBQueryBuilder) String() string {
-	return strings.Join(qb.Tokens, " ")
+	query, _ := qb.Build()
+	return query
 }
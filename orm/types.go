@@ -35,6 +35,34 @@ type Fielder interface {
 	RawValue() interface{}
 }
 
+// BinaryFielder define a fixed length Fielder, such as ormfield.UUID of vanilla/uuid.
+// it is stored as CHAR(len(String())) by default,
+// or as BINARY(len(RawBytes())) with tag `orm:"type(binary)"`
+type BinaryFielder interface {
+	Fielder
+	RawBytes() []byte
+}
+
+// CheckedFielder define a Fielder whose value stored in db may fail to build,
+// such as an encrypted field without key configured.
+// Insert, InsertMulti, InsertOrUpdate and Update return the error of CheckedRawValue, instead of calling RawValue.
+type CheckedFielder interface {
+	Fielder
+	CheckedRawValue() (interface{}, error)
+}
+
+// OnConflict define how InsertMulti handles the rows conflicting with existing rows
+type OnConflict struct {
+	// the unique fields to check conflict, default is the primary key.
+	// mysql and tidb ignore it and check all the unique keys.
+	Fields []string
+	// the fields updated to the inserted values, default all the inserted fields
+	// except the conflict fields, primary key and auto_now_add fields.
+	Update []string
+	// ignore the conflicting rows instead of updating them
+	DoNothing bool
+}
+
 // Ormer define the orm interface
 type Ormer interface {
 	// read data to model
@@ -46,24 +74,42 @@ type Ormer interface {
 	// 	u = &User{UserName: "astaxie", Password: "pass"}
 	//	err = Ormer.Read(u, "UserName")
 	Read(md interface{}, cols ...string) error
+	// Like Read(), but the query is cancelled when ctx is done.
+	// all the *WithCtx methods run with ctx instead of the default context of Ormer,
+	// and the tracing span in ctx is the parent of the query span.
+	// for example:
+	//	ctx, cancel := context.WithTimeout(bCtx, time.Second)
+	//	defer cancel()
+	//	err = Ormer.ReadWithCtx(ctx, u)
+	ReadWithCtx(ctx context.Context, md interface{}, cols ...string) error
 	// Like Read(), but with "FOR UPDATE" clause, useful in transaction.
 	// Some databases are not support this feature.
 	ReadForUpdate(md interface{}, cols ...string) error
+	ReadForUpdateWithCtx(ctx context.Context, md interface{}, cols ...string) error
 	// Try to read a row from the database, or insert one if it doesn't exist
 	ReadOrCreate(md interface{}, col1 string, cols ...string) (bool, int64, error)
+	ReadOrCreateWithCtx(ctx context.Context, md interface{}, col1 string, cols ...string) (bool, int64, error)
 	// insert model data to database
 	// for example:
 	//  user := new(User)
 	//  id, err = Ormer.Insert(user)
 	//  user must a pointer and Insert will set user's pk field
 	Insert(interface{}) (int64, error)
+	InsertWithCtx(context.Context, interface{}) (int64, error)
 	// mysql:InsertOrUpdate(model) or InsertOrUpdate(model,"colu=colu+value")
 	// if colu type is integer : can use(+-*/), string : convert(colu,"value")
 	// postgres: InsertOrUpdate(model,"conflictColumnName") or InsertOrUpdate(model,"conflictColumnName","colu=colu+value")
 	// if colu type is integer : can use(+-*/), string : colu || "value"
 	InsertOrUpdate(md interface{}, colConflitAndArgs ...string) (int64, error)
-	// insert some models to database
-	InsertMulti(bulk int, mds interface{}) (int64, error)
+	InsertOrUpdateWithCtx(ctx context.Context, md interface{}, colConflitAndArgs ...string) (int64, error)
+	// insert some models to database.
+	// the rows conflicting with existing rows are updated or ignored when onConflict is given,
+	// which is supported by mysql, postgres, sqlite and tidb.
+	// for example:
+	//	// INSERT ... ON CONFLICT ("code") DO UPDATE SET "stock" = EXCLUDED."stock"
+	//	num, err = Ormer.InsertMulti(100, products, orm.OnConflict{Fields: []string{"Code"}, Update: []string{"Stock"}})
+	InsertMulti(bulk int, mds interface{}, onConflict ...OnConflict) (int64, error)
+	InsertMultiWithCtx(ctx context.Context, bulk int, mds interface{}, onConflict ...OnConflict) (int64, error)
 	// update model to database.
 	// cols set the columns those want to update.
 	// find model by Id(pk) field and update columns specified by fields, if cols is null then update all columns
@@ -73,9 +119,28 @@ type Ormer interface {
 	//	user.Extra.Name = "beego"
 	//	user.Extra.Data = "orm"
 	//	num, err = Ormer.Update(&user, "Langs", "Extra")
+	// if the model has a version field (tag `orm:"version"`), the row is updated only when
+	// its version equals md's, and the version is increased in database and md.
+	// a *VersionConflictError is returned when no row matches, check it by errors.Is(err, orm.ErrVersionConflict)
 	Update(md interface{}, cols ...string) (int64, error)
+	UpdateWithCtx(ctx context.Context, md interface{}, cols ...string) (int64, error)
+	// apply fn to md and update it with version check, md is read again and fn is applied again on version conflict.
+	// attempts is the max times of update, the last conflict error is returned when all attempts fail.
+	// it does not retry in a transaction, call it outside of transaction to retry.
+	// for example:
+	//	num, err = Ormer.RetryUpdate(&product, 3, func() error {
+	//		if product.Stock < 1 {
+	//			return ErrSoldOut
+	//		}
+	//		product.Stock--
+	//		return nil
+	//	}, "Stock")
+	RetryUpdate(md interface{}, attempts int, fn func() error, cols ...string) (int64, error)
+	RetryUpdateWithCtx(ctx context.Context, md interface{}, attempts int, fn func() error, cols ...string) (int64, error)
 	// delete model in database
+	// if the model has a soft_delete field, set it to now instead of deleting the row.
 	Delete(md interface{}, cols ...string) (int64, error)
+	DeleteWithCtx(ctx context.Context, md interface{}, cols ...string) (int64, error)
 	// load related models to md model.
 	// args are limit, offset int and order string.
 	//
@@ -89,17 +154,26 @@ type Ormer interface {
 	//args[3] string order  for example : "-Id"
 	// make sure the relation is defined in model struct tags.
 	LoadRelated(md interface{}, name string, args ...interface{}) (int64, error)
+	LoadRelatedWithCtx(ctx context.Context, md interface{}, name string, args ...interface{}) (int64, error)
 	// create a models to models queryer
 	// for example:
 	// 	post := Post{Id: 4}
 	// 	m2m := Ormer.QueryM2M(&post, "Tags")
 	QueryM2M(md interface{}, name string) QueryM2Mer
+	QueryM2MWithCtx(ctx context.Context, md interface{}, name string) QueryM2Mer
 	// return a QuerySeter for table operations.
 	// table name can be string or struct.
 	// e.g. QueryTable("user"), QueryTable(&user{}) or QueryTable((*User)(nil)),
 	QueryTable(ptrStructOrTableName interface{}) QuerySeter
+	QueryTableWithCtx(ctx context.Context, ptrStructOrTableName interface{}) QuerySeter
 	// switch to another registered database driver by given name.
 	Using(name string) error
+	// use the tables with suffix for models, e.g. UsingTableSuffix("_test") reads and writes `user_test` for model User.
+	// the suffixed tables must be created with the same schema, the related tables and raw sql are not suffixed.
+	// it returns an error in transaction.
+	UsingTableSuffix(suffix string) error
+	// return true if transaction has begun.
+	InTransaction() bool
 	// begin transaction
 	// for example:
 	// 	o := NewOrm()
@@ -122,11 +196,44 @@ type Ormer interface {
 	Commit() error
 	// rollback transaction
 	Rollback() error
+	// rollback the transaction with all its nested transactions,
+	// the outer transaction is rolled back even if a savepoint fails, so the transaction is always ended.
+	RollbackAll() error
+	// Begin/BeginTx in a transaction begin a nested transaction by savepoint (mysql, postgres, sqlite and tidb),
+	// Commit releases the savepoint and Rollback rolls back to it, the outer transaction goes on.
+	//
+	// Transaction runs fn in a transaction, or a nested transaction if transaction has begun,
+	// it is committed if fn returns nil, otherwise rolled back, and it is rolled back when fn panics too.
+	// for example:
+	//	err := o.Transaction(ctx, func(txOrm orm.Ormer) error {
+	//		if _, err := txOrm.Insert(&order); err != nil {
+	//			return err
+	//		}
+	//		txOrm.AfterCommit(func() {
+	//			cache.Delete(order.CacheKey())
+	//		})
+	//		return nil
+	//	})
+	Transaction(ctx context.Context, fn func(txOrm Ormer) error) error
+	// register a callback called after the transaction is committed, so the data is durable.
+	// the callbacks of a nested transaction are dropped if it is rolled back.
+	// it is called at once when there is no transaction.
+	AfterCommit(fn func())
+	// register a callback called after the transaction, or the nested transaction it is registered in, is rolled back.
+	// it is ignored when there is no transaction.
+	AfterRollback(fn func())
+	// return an Ormer which is not scoped by the tenant of context, see WithTenant.
+	// reason is required and logged with the caller, for audit of cross tenant access.
+	// it shares the connection, transaction and table suffix of the Ormer, they can be changed by either of them.
+	// for example:
+	//	o.CrossTenant("sync product stocks of all corps").QueryTable("product").All(&products)
+	CrossTenant(reason string) Ormer
 	// return a raw query seter for raw sql string.
 	// for example:
 	//	 ormer.Raw("UPDATE `user` SET `user_name` = ? WHERE `user_name` = ?", "slene", "testing").Exec()
 	//	// update user testing's name to slene
 	Raw(query string, args ...interface{}) RawSeter
+	RawWithCtx(ctx context.Context, query string, args ...interface{}) RawSeter
 	Driver() Driver
 }
 
@@ -136,6 +243,20 @@ type Inserter interface {
 	Close() error
 }
 
+// Iterator iterate the rows of query one by one.
+// it holds a database connection until Next returns false or Close is called,
+// so call Close when leaving the loop early. Close is safe to be called more than once.
+type Iterator interface {
+	// move to the next row, return false when no more row or an error occurs.
+	Next() bool
+	// scan the current row into containers.
+	Scan(containers ...interface{}) error
+	// return the error occurred during iteration.
+	Err() error
+	// close the rows and release the connection.
+	Close() error
+}
+
 // QuerySeter query seter
 type QuerySeter interface {
 	// add condition expression to QuerySeter.
@@ -214,10 +335,20 @@ type QuerySeter interface {
 	// for example:
 	//  o.QueryTable("user").Filter("uid", uid).ForUpdate().All(&users)
 	ForUpdate() QuerySeter
+	// include rows soft deleted by the soft_delete field of model,
+	// and Delete removes rows from database instead of setting the soft_delete field.
+	// for example:
+	//  o.QueryTable("user").Unscoped().Filter("deleted_at__isnull", false).All(&users)
+	Unscoped() QuerySeter
 	// return QuerySeter execution result number
 	// for example:
 	//	num, err = qs.Filter("profile__age__gt", 28).Count()
 	Count() (int64, error)
+	// return the estimated row count of the query, which is much cheaper than Count on big tables.
+	// mysql reads it from the EXPLAIN result, other databases (including tidb, whose EXPLAIN format differs) fall back to Count.
+	// for example:
+	//	num, err = qs.Filter("status", 1).CountEstimate()
+	CountEstimate() (int64, error)
 	// check result empty or not after QuerySeter executed
 	// the same as QuerySeter.Count > 0
 	Exist() bool
@@ -229,11 +360,18 @@ type QuerySeter interface {
 	//	num, err = qs.Filter("UserName", "slene").Update(Params{
 	//		"user_name": "slene2"
 	//	}) // user slene's  name will change to slene2
+	// the version field of model is increased, and if values has the version field,
+	// rows are updated only when their version equals it, otherwise a *VersionConflictError is returned.
+	//	num, err = qs.Filter("id", 1).Update(Params{
+	//		"status":  2,
+	//		"version": 3,
+	//	}) // UPDATE ... SET status = 2, version = version + 1 WHERE version = 3 AND id = 1
 	Update(values Params) (int64, error)
 	// delete from table
 	//for example:
 	//	num ,err = qs.Filter("user_name__in", "testing1", "testing2").Delete()
 	// 	//delete two user  who's name is testing1 or testing2
+	// if the model has a soft_delete field, rows are soft deleted unless Unscoped.
 	Delete() (int64, error)
 	// return a insert queryer.
 	// it can be used in times.
@@ -255,6 +393,40 @@ type QuerySeter interface {
 	//	var user User
 	//	qs.One(&user) //user.UserName == "slene"
 	One(container interface{}, cols ...string) error
+	// return an iterator which scans the rows into model struct one by one,
+	// instead of loading all rows into memory. the rows are not limited by DefaultRowsLimit.
+	// the iterator holds a connection until the rows are exhausted or it is closed.
+	// for example:
+	//	it, err := qs.Iterator()
+	//	if err != nil {
+	//		return err
+	//	}
+	//	defer it.Close()
+	//	for it.Next() {
+	//		var user User
+	//		if err := it.Scan(&user); err != nil {
+	//			return err
+	//		}
+	//	}
+	//	return it.Err()
+	Iterator(cols ...string) (Iterator, error)
+	// cache the results of All, One, Count, Exist, Values, ValuesList and ValuesFlat for ttl,
+	// it works after the cache adapter is set by SetQueryCache.
+	// the cache is invalidated when the tables in query are written through orm,
+	// and it is bypassed in transaction, with ForUpdate or RelatedSel.
+	// for example:
+	//	num, err = qs.Filter("status", 1).Cache(time.Minute).All(&products)
+	Cache(ttl time.Duration) QuerySeter
+	// read the rows in batches of size ordered by KeysetOrders (the primary key by default),
+	// and call fn after each batch is read into container, num is the rows of batch.
+	// each batch seeks after the last row of previous batch, so it is stable on large tables.
+	// it stops when ctx is done, fn returns an error, or the rows are exhausted.
+	// for example:
+	//	var users []*User
+	//	err := qs.ForEachBatch(ctx, 500, &users, func(num int64) error {
+	//		return export(users)
+	//	})
+	ForEachBatch(ctx context.Context, size int, container interface{}, fn func(num int64) error) error
 	// query all data and map to []map[string]interface.
 	// expres means condition expression.
 	// it converts data to []map[column]value.
@@ -296,6 +468,50 @@ type QuerySeter interface {
 	// 	Found int
 	// }
 	RowsToStruct(ptrStruct interface{}, keyCol, valueCol string) (int64, error)
+	// aggregate the rows and read the results into container, the GroupBy exprs are read with aggregations.
+	// container can be a pointer of number, struct, []struct or []Params,
+	// the struct fields are matched by the alias of aggregations, default field name and function joined by "_".
+	// for example:
+	//	var total float64
+	//	qs.Filter("status", 1).Aggregate(&total, orm.Sum("Price"))
+	//	var stats []struct {
+	//		UserId   int
+	//		Orders   int
+	//		PriceSum float64
+	//	}
+	//	// SELECT user_id, COUNT(*) orders, SUM(price) price_sum FROM order GROUP BY user_id
+	//	num, err = qs.GroupBy("UserId").Aggregate(&stats, orm.Count("*").As("orders"), orm.Sum("Price"))
+	Aggregate(container interface{}, aggs ...Aggregation) (int64, error)
+	// read the columns of model with the annotations of each row into container,
+	// annotations are usually window functions by Over, plain aggregations need GroupBy.
+	// container can be a pointer of []struct or []Params, the struct can embed the model struct,
+	// its fields are matched by the column names and the alias of annotations.
+	// for example:
+	//	var posts []struct {
+	//		Post
+	//		RowNumber int
+	//	}
+	//	// SELECT T0.*, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created DESC) row_number FROM post T0
+	//	num, err = qs.Annotate(&posts, orm.RowNumber().Over(orm.PartitionBy("User").OrderBy("-Created")))
+	Annotate(container interface{}, aggs ...Aggregation) (int64, error)
+	// return the ORDER BY expressions used by keyset pagination,
+	// the primary key is appended as tie-breaker if it is not in the orders.
+	// for example:
+	//	orders, err := qs.OrderBy("-created_at").KeysetOrders() // orders == ["-created_at", "-id"]
+	KeysetOrders() ([]string, error)
+	// read the values of KeysetOrders from a model struct.
+	// for example:
+	//	values, err := qs.OrderBy("-created_at").KeysetValues(users[len(users)-1])
+	KeysetValues(md interface{}) ([]interface{}, error)
+	// seek the rows after the row with given KeysetValues, ordered by KeysetOrders.
+	// for example:
+	//	qs, err = qs.OrderBy("-created_at").SeekAfter("2019-12-01 10:00:00", 100)
+	//	num, err = qs.Limit(20).All(&users)
+	SeekAfter(values ...interface{}) (QuerySeter, error)
+	// set the context used by all operations of the QuerySeter.
+	// for example:
+	//	num, err = qs.WithContext(ctx).Filter("status", 1).All(&users)
+	WithContext(ctx context.Context) QuerySeter
 }
 
 // QueryM2Mer model to model query struct
@@ -325,6 +541,8 @@ type QueryM2Mer interface {
 	Clear() (int64, error)
 	// count all related models of origin model
 	Count() (int64, error)
+	// set the context used by all operations of the QueryM2Mer.
+	WithContext(ctx context.Context) QueryM2Mer
 }
 
 // RawPreparer raw query statement
@@ -354,6 +572,15 @@ type RawSeter interface {
 	//	query = fmt.Sprintf("SELECT 'id','name' FROM %suser%s", Q, Q)
 	//	num, err = dORM.Raw(query).QueryRows(&ids,&names) // ids=>{1,2},names=>{"nobody","slene"}
 	QueryRows(containers ...interface{}) (int64, error)
+	// return an iterator which scans the rows one by one like QueryRow.
+	// the iterator must be closed if the rows are not exhausted.
+	// for example:
+	//	it, err := dORM.Raw("SELECT id, name FROM user").Iterator()
+	//	defer it.Close()
+	//	for it.Next() {
+	//		err = it.Scan(&id, &name)
+	//	}
+	Iterator() (Iterator, error)
 	SetArgs(...interface{}) RawSeter
 	// query data to []map[string]interface
 	// see QuerySeter's Values
@@ -392,6 +619,10 @@ type RawSeter interface {
 	// 	pre, err := dORM.Raw("INSERT INTO tag (name) VALUES (?)").Prepare()
 	// 	r, err := pre.Exec("name1") // INSERT INTO tag (name) VALUES (`name1`)
 	Prepare() (RawPreparer, error)
+	// set the context used by all operations of the RawSeter.
+	// for example:
+	//	num, err = dORM.Raw("SELECT * FROM user").WithContext(ctx).QueryRows(&users)
+	WithContext(ctx context.Context) RawSeter
 }
 
 // stmtQuerier statement querier
@@ -442,19 +673,27 @@ type dbBaser interface {
 	Read(dbQuerier, *modelInfo, reflect.Value, *time.Location, []string, bool) error
 	Insert(dbQuerier, *modelInfo, reflect.Value, *time.Location) (int64, error)
 	InsertOrUpdate(dbQuerier, *modelInfo, reflect.Value, *alias, ...string) (int64, error)
-	InsertMulti(dbQuerier, *modelInfo, reflect.Value, int, *time.Location) (int64, error)
+	InsertMulti(dbQuerier, *modelInfo, reflect.Value, int, *time.Location, *OnConflict) (int64, error)
+	OnConflictSQL(*modelInfo, []string, *OnConflict) (string, error)
+	AggregateSQL(*querySet, *modelInfo, *Condition, []Aggregation, *time.Location) (string, []interface{})
+	AnnotateSQL(*querySet, *modelInfo, *Condition, []Aggregation, *time.Location) (string, []interface{})
 	InsertValue(dbQuerier, *modelInfo, bool, []string, []interface{}) (int64, error)
 	InsertStmt(stmtQuerier, *modelInfo, reflect.Value, *time.Location) (int64, error)
-	Update(dbQuerier, *modelInfo, reflect.Value, *time.Location, []string) (int64, error)
-	Delete(dbQuerier, *modelInfo, reflect.Value, *time.Location, []string) (int64, error)
+	Update(dbQuerier, *modelInfo, reflect.Value, *time.Location, []string, bool) (int64, error)
+	Delete(dbQuerier, *modelInfo, reflect.Value, *time.Location, []string, bool) (int64, error)
 	ReadBatch(dbQuerier, *querySet, *modelInfo, *Condition, interface{}, *time.Location, []string) (int64, error)
+	ReadIterator(dbQuerier, *querySet, *modelInfo, *Condition, *time.Location, []string) (*queryIterator, error)
+	ReadQuery(*querySet, *modelInfo, *Condition, *time.Location, []string) (*readQuery, error)
 	SupportUpdateJoin() bool
 	UpdateBatch(dbQuerier, *querySet, *modelInfo, *Condition, Params, *time.Location) (int64, error)
 	DeleteBatch(dbQuerier, *querySet, *modelInfo, *Condition, *time.Location) (int64, error)
 	Count(dbQuerier, *querySet, *modelInfo, *Condition, *time.Location) (int64, error)
+	EstimateCount(dbQuerier, *querySet, *modelInfo, *Condition, *time.Location) (int64, error)
 	OperatorSQL(string) string
 	GenerateOperatorSQL(*modelInfo, *fieldInfo, string, []interface{}, *time.Location) (string, []interface{})
 	GenerateOperatorLeftCol(*fieldInfo, string, *string)
+	GenerateDatePartLeftCol(*fieldInfo, string, *string)
+	GenerateJSONPathLeftCol(*fieldInfo, []string, []interface{}, *string)
 	PrepareInsert(dbQuerier, *modelInfo) (stmtQuerier, string, error)
 	ReadValues(dbQuerier, *querySet, *modelInfo, *Condition, []string, interface{}, *time.Location) (int64, error)
 	RowsTo(dbQuerier, *querySet, *modelInfo, *Condition, interface{}, string, string, *time.Location) (int64, error)
@@ -470,6 +709,7 @@ type dbBaser interface {
 	ShowTablesQuery() string
 	ShowColumnsQuery(string) string
 	IndexExists(dbQuerier, string, string) bool
+	GetIndexes(dbQuerier, string) (map[string][]string, error)
 	collectFieldValue(*modelInfo, *fieldInfo, reflect.Value, bool, *time.Location) (interface{}, error)
 	setval(dbQuerier, *modelInfo, []string) error
 }
@@ -666,6 +666,33 @@ func (p *ControllerRegister) execFilter(context *beecontext.Context, urlPath str
 	return false
 }
 
+// endpointPattern returns the endpoint label of a request for metrics.
+// It uses the route pattern instead of the raw url to keep the cardinality bounded.
+func endpointPattern(routerInfo *ControllerInfo, runRouter reflect.Type, findRouter bool) string {
+	if routerInfo != nil {
+		return routerInfo.pattern
+	}
+	if runRouter != nil {
+		return runRouter.String()
+	}
+	if findRouter {
+		return "static"
+	}
+	return "unmatched"
+}
+
+// recordEndpointMetrics records latency and count of a request by endpoint, method, status and errCode.
+func recordEndpointMetrics(context *beecontext.Context, endpoint string, startTime time.Time) {
+	statusCode := context.ResponseWriter.Status
+	if statusCode == 0 {
+		statusCode = 200
+	}
+	errCode, _ := context.Input.GetData("ErrCode").(string)
+	labels := []string{endpoint, context.Input.Method(), strconv.Itoa(statusCode), metrics.NormalizeErrCode(errCode)}
+	metrics.GetEndpointRequestHistogram().WithLabelValues(labels...).Observe(time.Since(startTime).Seconds())
+	metrics.GetEndpointRequestCounter().WithLabelValues(labels...).Inc()
+}
+
 // Implement http.Handler interface.
 func (p *ControllerRegister) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	//gauge := metrics.GetConcurrentVisitGauge()
@@ -685,6 +712,14 @@ func (p *ControllerRegister) ServeHTTP(rw http.ResponseWriter, r *http.Request)
 	context.Reset(rw, r)
 
 	defer p.pool.Put(context)
+
+	// registered before RecoverFunc, so it runs after a panic is recovered and the error response is written
+	metrics.GetEndpointInFlightGauge().Inc()
+	defer func() {
+		metrics.GetEndpointInFlightGauge().Dec()
+		recordEndpointMetrics(context, endpointPattern(routerInfo, runRouter, findRouter), startTime)
+	}()
+
 	if BConfig.RecoverFunc != nil {
 		defer BConfig.RecoverFunc(context)
 	}
@@ -905,9 +940,8 @@ Admin:
 	logAccess(context, &startTime, statusCode)
 
 	timeDur := time.Since(startTime)
-	method := context.Input.Method()
-	//metrics.GetEndpointSummary().WithLabelValues(urlPath, method).Observe(timeDur.Seconds())
-	metrics.GetEndpointHistogram().WithLabelValues(urlPath, method).Observe(timeDur.Seconds())
+	// legacy endpoint_durations_histogram_seconds, labeled by url path
+	metrics.GetEndpointHistogram().WithLabelValues(urlPath, context.Input.Method()).Observe(timeDur.Seconds())
 	
 	context.ResponseWriter.Elapsed = timeDur
 	if BConfig.Listen.EnableAdmin {
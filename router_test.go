@@ -22,6 +22,10 @@ import (
 
 	"github.com/kfchen81/beego/context"
 	"github.com/kfchen81/beego/logs"
+	"github.com/kfchen81/beego/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 )
 
 type TestController struct {
@@ -722,3 +726,39 @@ func TestYAMLPrepare(t *testing.T) {
 		t.Errorf(w.Body.String())
 	}
 }
+
+type ErrCodeController struct {
+	Controller
+}
+
+func (jc *ErrCodeController) Get() {
+	jc.Data["json"] = map[string]interface{}{"errCode": jc.GetString("code")}
+	jc.ServeJSON()
+}
+
+func TestEndpointMetrics(t *testing.T) {
+	metrics.RegisterErrCode("test_order:not_found")
+	handler := NewControllerRegister()
+	handler.Add("/metrics/err_code", &ErrCodeController{})
+	for _, code := range []string{"test_order:not_found", "unknown:1", "unknown:2"} {
+		r, _ := http.NewRequest("GET", "/metrics/err_code?code="+code, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	counter := metrics.GetEndpointRequestCounter()
+	if v := testutil.ToFloat64(counter.WithLabelValues("/metrics/err_code", "GET", "200", "test_order:not_found")); v != 1 {
+		t.Errorf("registered err_code should be kept, got %v", v)
+	}
+	if v := testutil.ToFloat64(counter.WithLabelValues("/metrics/err_code", "GET", "200", metrics.ERR_CODE_OTHER)); v != 2 {
+		t.Errorf("unregistered err_code should be other, got %v", v)
+	}
+
+	// legacy endpoint_durations_histogram_seconds
+	m := &dto.Metric{}
+	if err := metrics.GetEndpointHistogram().WithLabelValues("/metrics/err_code", "GET").(prometheus.Metric).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if m.GetHistogram().GetSampleCount() != 3 {
+		t.Errorf("endpoint histogram should be observed, got %d", m.GetHistogram().GetSampleCount())
+	}
+}
@@ -210,7 +210,7 @@ func runSentryWorker(ch chan map[string]interface{}) {
 	
 	for {
 		data := <-sentryChannel
-		metrics.GetSentryChannelUnreadGuage().Set(float64(len(sentryChannel)))
+		metrics.GetSentryChannelUnreadGauge().Set(float64(len(sentryChannel)))
 		metrics.GetSentryChannelErrorCounter().Inc()
 		sendSentryPacketV2(data)
 	}
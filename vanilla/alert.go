@@ -0,0 +1,423 @@
+package vanilla
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/kfchen81/beego"
+	"github.com/kfchen81/beego/metrics"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ALERT_INFO = 1
+const ALERT_WARN = 2
+const ALERT_ERROR = 3
+const ALERT_CRITICAL = 4
+
+var alertSeverityNames = map[int]string{
+	ALERT_INFO:     "info",
+	ALERT_WARN:     "warn",
+	ALERT_ERROR:    "error",
+	ALERT_CRITICAL: "critical",
+}
+
+var alertSeverityTitles = map[int]string{
+	ALERT_INFO:     "通知...",
+	ALERT_WARN:     "警告-_-",
+	ALERT_ERROR:    "错误>_<",
+	ALERT_CRITICAL: "严重错误！！！",
+}
+
+//ParseAlertSeverity 将info/warn/error/critical解析为告警级别
+func ParseAlertSeverity(name string) int {
+	for severity, severityName := range alertSeverityNames {
+		if severityName == strings.ToLower(name) {
+			return severity
+		}
+	}
+	return ALERT_INFO
+}
+
+//Alert 告警
+type Alert struct {
+	Service     string            `json:"service"`
+	Severity    int               `json:"severity"`
+	Title       string            `json:"title"`
+	Content     string            `json:"content"`
+	Fingerprint string            `json:"fingerprint"` //相同fingerprint的告警会被分组限流，为空时由service、severity、title生成
+	Labels      map[string]string `json:"labels"`
+	Count       int               `json:"count"` //分组内的告警数，包含本条
+	FirstAt     time.Time         `json:"first_at"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+func (this *Alert) SeverityName() string {
+	return alertSeverityNames[this.Severity]
+}
+
+//GetFingerprint 获取告警分组的指纹
+func (this *Alert) GetFingerprint() string {
+	if this.Fingerprint != "" {
+		return this.Fingerprint
+	}
+	sum := md5.Sum([]byte(fmt.Sprintf("%s|%d|%s", this.Service, this.Severity, this.Title)))
+	return hex.EncodeToString(sum[:])
+}
+
+//Markdown 将告警格式化为markdown文本
+func (this *Alert) Markdown() string {
+	lines := []string{
+		fmt.Sprintf("### %s %s", alertSeverityTitles[this.Severity], this.Title),
+		this.Content,
+	}
+	if len(this.Labels) > 0 {
+		keys := make([]string, 0, len(this.Labels))
+		for key := range this.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			lines = append(lines, fmt.Sprintf("- %s: %s", key, this.Labels[key]))
+		}
+	}
+	if this.Count > 1 {
+		lines = append(lines, fmt.Sprintf("> 自 %s 起同类告警共 %d 次", this.FirstAt.Format("2006-01-02 15:04:05"), this.Count))
+	}
+	lines = append(lines, fmt.Sprintf("> %s%s %s", platformName, envName, this.Service))
+	return strings.Join(lines, " \n\n ")
+}
+
+//IAlertChannel 告警渠道
+type IAlertChannel interface {
+	Name() string
+	Send(alert *Alert) error
+}
+
+//AlertRoute 告警路由，Services为空时匹配所有服务
+type AlertRoute struct {
+	Services    []string `json:"services"`
+	MinSeverity string   `json:"min_severity"`
+	Channels    []string `json:"channels"`
+}
+
+func (this *AlertRoute) match(alert *Alert) bool {
+	if alert.Severity < ParseAlertSeverity(this.MinSeverity) {
+		return false
+	}
+	if len(this.Services) == 0 {
+		return true
+	}
+	for _, service := range this.Services {
+		if service == "*" || service == alert.Service {
+			return true
+		}
+	}
+	return false
+}
+
+type alertGroup struct {
+	firstAt    time.Time //第一条被抑制告警的时间
+	lastSentAt time.Time
+	count      int //上次发送后被抑制的告警数
+	last       *Alert
+}
+
+//AlertManager 对告警进行分组、限流与路由
+//同一fingerprint的告警在window内只发送一次，被抑制的告警在window结束后汇总发送
+//window<=0时不分组，每条告警都直接发送
+type AlertManager struct {
+	window   time.Duration
+	channels map[string]IAlertChannel
+	routes   []*AlertRoute
+	enabled  bool
+
+	lock   sync.Mutex
+	groups map[string]*alertGroup
+	now    func() time.Time
+}
+
+func NewAlertManager(window time.Duration) *AlertManager {
+	return &AlertManager{
+		window:   window,
+		channels: make(map[string]IAlertChannel),
+		routes:   make([]*AlertRoute, 0),
+		enabled:  true,
+		groups:   make(map[string]*alertGroup),
+		now:      time.Now,
+	}
+}
+
+// AddChannel 添加告警渠道
+func (this *AlertManager) AddChannel(channel IAlertChannel) *AlertManager {
+	this.channels[channel.Name()] = channel
+	return this
+}
+
+// AddRoute 添加告警路由，没有任何路由时告警发送到所有渠道
+func (this *AlertManager) AddRoute(route *AlertRoute) *AlertManager {
+	this.routes = append(this.routes, route)
+	return this
+}
+
+// Fire 发送告警
+func (this *AlertManager) Fire(alert *Alert) {
+	now := this.now()
+	if alert.CreatedAt.IsZero() {
+		alert.CreatedAt = now
+	}
+	if alert.Severity == 0 {
+		alert.Severity = ALERT_ERROR
+	}
+
+	//不限流时不记录分组，避免分组只增不减
+	if this.window <= 0 {
+		alert.Count = 1
+		alert.FirstAt = now
+		this.dispatch(alert)
+		return
+	}
+
+	fingerprint := alert.GetFingerprint()
+	this.lock.Lock()
+	group, ok := this.groups[fingerprint]
+	if ok && now.Sub(group.lastSentAt) < this.window {
+		if group.count == 0 {
+			group.firstAt = now
+		}
+		group.count += 1
+		group.last = alert
+		this.lock.Unlock()
+		metrics.GetAlertCounter().WithLabelValues("all", "throttled").Inc()
+		return
+	}
+	if !ok {
+		group = new(alertGroup)
+		this.groups[fingerprint] = group
+	}
+	// 未汇总发送的被抑制告警随本条一起计数
+	alert.Count = group.count + 1
+	alert.FirstAt = now
+	if group.count > 0 {
+		alert.FirstAt = group.firstAt
+	}
+	group.lastSentAt = now
+	group.count = 0
+	group.last = nil
+	this.lock.Unlock()
+
+	this.dispatch(alert)
+}
+
+// Flush 发送window已结束的分组中被抑制的告警，并清理过期的分组
+func (this *AlertManager) Flush() {
+	now := this.now()
+	alerts := make([]*Alert, 0)
+	this.lock.Lock()
+	for fingerprint, group := range this.groups {
+		if now.Sub(group.lastSentAt) < this.window {
+			continue
+		}
+		if group.count == 0 {
+			delete(this.groups, fingerprint)
+			continue
+		}
+		alert := group.last
+		alert.Count = group.count
+		alert.FirstAt = group.firstAt
+		alerts = append(alerts, alert)
+		group.lastSentAt = now
+		group.count = 0
+		group.last = nil
+	}
+	this.lock.Unlock()
+
+	for _, alert := range alerts {
+		this.dispatch(alert)
+	}
+}
+
+func (this *AlertManager) dispatch(alert *Alert) {
+	if !this.enabled {
+		beego.Info(alert.Title, alert.Markdown())
+		return
+	}
+
+	for _, channel := range this.route(alert) {
+		if err := channel.Send(alert); err != nil {
+			beego.Error(fmt.Sprintf("[alert] send to channel(%s) failed: %s", channel.Name(), err.Error()))
+			metrics.GetAlertCounter().WithLabelValues(channel.Name(), "fail").Inc()
+		} else {
+			metrics.GetAlertCounter().WithLabelValues(channel.Name(), "success").Inc()
+		}
+	}
+}
+
+func (this *AlertManager) route(alert *Alert) []IAlertChannel {
+	channels := make([]IAlertChannel, 0)
+	if len(this.routes) == 0 {
+		for _, channel := range this.channels {
+			channels = append(channels, channel)
+		}
+		return channels
+	}
+
+	visited := make(map[string]bool)
+	for _, route := range this.routes {
+		if !route.match(alert) {
+			continue
+		}
+		for _, name := range route.Channels {
+			if visited[name] {
+				continue
+			}
+			visited[name] = true
+			if channel, ok := this.channels[name]; ok {
+				channels = append(channels, channel)
+			} else {
+				beego.Warn(fmt.Sprintf("[alert] unknown channel: %s", name))
+			}
+		}
+	}
+	return channels
+}
+
+// Info 发送通知级别的告警
+func (this *AlertManager) Info(service string, title string, content string) {
+	this.Fire(&Alert{Service: service, Severity: ALERT_INFO, Title: title, Content: content})
+}
+
+// Warn 发送警告级别的告警
+func (this *AlertManager) Warn(service string, title string, content string) {
+	this.Fire(&Alert{Service: service, Severity: ALERT_WARN, Title: title, Content: content})
+}
+
+// Error 发送错误级别的告警
+func (this *AlertManager) Error(service string, title string, content string) {
+	this.Fire(&Alert{Service: service, Severity: ALERT_ERROR, Title: title, Content: content})
+}
+
+// Critical 发送严重错误级别的告警
+func (this *AlertManager) Critical(service string, title string, content string) {
+	this.Fire(&Alert{Service: service, Severity: ALERT_CRITICAL, Title: title, Content: content})
+}
+
+//TestAlertChannel 记录收到的告警，用于单元测试
+type TestAlertChannel struct {
+	name   string
+	lock   sync.Mutex
+	alerts []*Alert
+}
+
+func NewTestAlertChannel(name string) *TestAlertChannel {
+	return &TestAlertChannel{
+		name:   name,
+		alerts: make([]*Alert, 0),
+	}
+}
+
+func (this *TestAlertChannel) Name() string {
+	return this.name
+}
+
+func (this *TestAlertChannel) Send(alert *Alert) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.alerts = append(this.alerts, alert)
+	return nil
+}
+
+// Alerts 获取收到的告警
+func (this *TestAlertChannel) Alerts() []*Alert {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	alerts := make([]*Alert, len(this.alerts))
+	copy(alerts, this.alerts)
+	return alerts
+}
+
+// Reset 清空收到的告警
+func (this *TestAlertChannel) Reset() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.alerts = make([]*Alert, 0)
+}
+
+//alertChannelConfig alert::CHANNELS中的渠道配置
+type alertChannelConfig struct {
+	Type   string `json:"type"`
+	Token  string `json:"token"`
+	Key    string `json:"key"`
+	Url    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+func newAlertChannel(name string, conf *alertChannelConfig) (IAlertChannel, error) {
+	switch conf.Type {
+	case "ding":
+		return NewDingAlertChannel(name, conf.Token, conf.Secret), nil
+	case "wecom":
+		return NewWeComAlertChannel(name, conf.Key), nil
+	case "slack":
+		return NewSlackAlertChannel(name, conf.Url), nil
+	case "webhook":
+		return NewWebhookAlertChannel(name, conf.Url), nil
+	default:
+		return nil, fmt.Errorf("unknown alert channel type: %s", conf.Type)
+	}
+}
+
+var Alerter *AlertManager //暴露的告警管理器
+
+func init() {
+	window := beego.AppConfig.DefaultInt("alert::THROTTLE_WINDOW", 300)
+	Alerter = NewAlertManager(time.Duration(window) * time.Second)
+
+	alertMode := beego.AppConfig.DefaultString("alert::MODE", beego.AppConfig.String("ding::DINGBOT_MODE"))
+	Alerter.enabled = alertMode != "develop"
+
+	channelStr := beego.AppConfig.String("alert::CHANNELS")
+	if channelStr != "" {
+		confs := make(map[string]*alertChannelConfig)
+		if err := json.Unmarshal([]byte(channelStr), &confs); err != nil {
+			beego.Error(fmt.Sprintf("[alert] parse alert::CHANNELS failed: %s", err.Error()))
+		}
+		for name, conf := range confs {
+			channel, err := newAlertChannel(name, conf)
+			if err != nil {
+				beego.Error(fmt.Sprintf("[alert] %s", err.Error()))
+				continue
+			}
+			Alerter.AddChannel(channel)
+		}
+	} else if alertMode == "deploy" {
+		// 兼容ding::BOT配置，秀儿只在生产环境使用
+		if bot := NewDingBot().Use("xiuer"); bot.token != "" {
+			Alerter.AddChannel(NewDingAlertChannel("xiuer", bot.token, ""))
+		}
+	}
+
+	routeStr := beego.AppConfig.String("alert::ROUTES")
+	if routeStr != "" {
+		routes := make([]*AlertRoute, 0)
+		if err := json.Unmarshal([]byte(routeStr), &routes); err != nil {
+			beego.Error(fmt.Sprintf("[alert] parse alert::ROUTES failed: %s", err.Error()))
+		}
+		for _, route := range routes {
+			Alerter.AddRoute(route)
+		}
+	}
+
+	if window > 0 {
+		go func() {
+			ticker := time.NewTicker(time.Duration(window) * time.Second / 5)
+			defer ticker.Stop()
+			for range ticker.C {
+				Alerter.Flush()
+			}
+		}()
+	}
+}
@@ -0,0 +1,186 @@
+package vanilla
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var alertHttpClient = &http.Client{
+	Timeout: 5 * time.Second,
+}
+
+//postAlertJson 以json格式POST数据，返回响应内容
+func postAlertJson(apiUrl string, data interface{}) ([]byte, error) {
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := alertHttpClient.Post(apiUrl, "application/json", bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return body, fmt.Errorf("post webhook failed %s: %s", resp.Status, string(body))
+	}
+	return body, nil
+}
+
+//checkAlertErrCode 检查钉钉、企业微信返回的errcode
+func checkAlertErrCode(body []byte) error {
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return err
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("errcode(%d): %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
+
+//DingAlertChannel 钉钉群机器人，secret不为空时对请求加签
+type DingAlertChannel struct {
+	name   string
+	token  string
+	secret string
+}
+
+func NewDingAlertChannel(name string, token string, secret string) *DingAlertChannel {
+	return &DingAlertChannel{
+		name:   name,
+		token:  token,
+		secret: secret,
+	}
+}
+
+func (this *DingAlertChannel) Name() string {
+	return this.name
+}
+
+func (this *DingAlertChannel) Send(alert *Alert) error {
+	apiUrl := fmt.Sprintf("https://oapi.dingtalk.com/robot/send?access_token=%s", this.token)
+	if this.secret != "" {
+		timestamp := time.Now().UnixNano() / int64(time.Millisecond)
+		h := hmac.New(sha256.New, []byte(this.secret))
+		h.Write([]byte(fmt.Sprintf("%d\n%s", timestamp, this.secret)))
+		sign := url.QueryEscape(base64.StdEncoding.EncodeToString(h.Sum(nil)))
+		apiUrl = fmt.Sprintf("%s&timestamp=%d&sign=%s", apiUrl, timestamp, sign)
+	}
+
+	body, err := postAlertJson(apiUrl, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": alert.Title,
+			"text":  alert.Markdown(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return checkAlertErrCode(body)
+}
+
+//WeComAlertChannel 企业微信群机器人
+type WeComAlertChannel struct {
+	name string
+	key  string
+}
+
+func NewWeComAlertChannel(name string, key string) *WeComAlertChannel {
+	return &WeComAlertChannel{
+		name: name,
+		key:  key,
+	}
+}
+
+func (this *WeComAlertChannel) Name() string {
+	return this.name
+}
+
+func (this *WeComAlertChannel) Send(alert *Alert) error {
+	apiUrl := fmt.Sprintf("https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=%s", this.key)
+	body, err := postAlertJson(apiUrl, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"content": alert.Markdown(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return checkAlertErrCode(body)
+}
+
+//SlackAlertChannel slack incoming webhook
+type SlackAlertChannel struct {
+	name       string
+	webhookUrl string
+}
+
+func NewSlackAlertChannel(name string, webhookUrl string) *SlackAlertChannel {
+	return &SlackAlertChannel{
+		name:       name,
+		webhookUrl: webhookUrl,
+	}
+}
+
+func (this *SlackAlertChannel) Name() string {
+	return this.name
+}
+
+func (this *SlackAlertChannel) Send(alert *Alert) error {
+	_, err := postAlertJson(this.webhookUrl, map[string]interface{}{
+		"text": alert.Markdown(),
+	})
+	return err
+}
+
+//WebhookAlertChannel 通用webhook，以json格式POST告警
+type WebhookAlertChannel struct {
+	name string
+	url  string
+}
+
+func NewWebhookAlertChannel(name string, url string) *WebhookAlertChannel {
+	return &WebhookAlertChannel{
+		name: name,
+		url:  url,
+	}
+}
+
+func (this *WebhookAlertChannel) Name() string {
+	return this.name
+}
+
+func (this *WebhookAlertChannel) Send(alert *Alert) error {
+	data := map[string]interface{}{
+		"service":     alert.Service,
+		"severity":    alert.SeverityName(),
+		"title":       alert.Title,
+		"content":     alert.Content,
+		"fingerprint": alert.GetFingerprint(),
+		"labels":      alert.Labels,
+		"count":       alert.Count,
+		"first_at":    alert.FirstAt.Format("2006-01-02 15:04:05"),
+		"created_at":  alert.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	_, err := postAlertJson(this.url, data)
+	return err
+}
@@ -0,0 +1,69 @@
+package vanilla
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlertManager_Throttle(t *testing.T) {
+	now := time.Date(2020, 1, 1, 10, 0, 0, 0, time.Local)
+	sink := NewTestAlertChannel("test")
+	manager := NewAlertManager(time.Minute).AddChannel(sink)
+	manager.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		manager.Error("order", "db timeout", "query timeout")
+	}
+	if len(sink.Alerts()) != 1 {
+		t.Fatalf("expect 1 alert, got %d", len(sink.Alerts()))
+	}
+
+	now = now.Add(30 * time.Second)
+	manager.Flush()
+	if len(sink.Alerts()) != 1 {
+		t.Fatalf("expect 1 alert before window end, got %d", len(sink.Alerts()))
+	}
+
+	now = now.Add(31 * time.Second)
+	manager.Flush()
+	alerts := sink.Alerts()
+	if len(alerts) != 2 {
+		t.Fatalf("expect 2 alerts after window end, got %d", len(alerts))
+	}
+	if alerts[1].Count != 4 {
+		t.Errorf("expect 4 suppressed alerts, got %d", alerts[1].Count)
+	}
+}
+
+func TestAlertManager_NoWindow(t *testing.T) {
+	sink := NewTestAlertChannel("test")
+	manager := NewAlertManager(0).AddChannel(sink)
+	for i := 0; i < 3; i++ {
+		manager.Error("order", "db timeout", "query timeout")
+	}
+	if len(sink.Alerts()) != 3 {
+		t.Errorf("expect 3 alerts without window, got %d", len(sink.Alerts()))
+	}
+	if len(manager.groups) != 0 {
+		t.Errorf("groups should not be kept without window, got %d", len(manager.groups))
+	}
+}
+
+func TestAlertManager_Route(t *testing.T) {
+	ops := NewTestAlertChannel("ops")
+	order := NewTestAlertChannel("order")
+	manager := NewAlertManager(time.Minute).AddChannel(ops).AddChannel(order)
+	manager.AddRoute(&AlertRoute{MinSeverity: "critical", Channels: []string{"ops"}})
+	manager.AddRoute(&AlertRoute{Services: []string{"order"}, MinSeverity: "warn", Channels: []string{"order", "ops"}})
+
+	manager.Info("order", "info", "")
+	manager.Warn("order", "warn", "")
+	manager.Critical("user", "critical", "")
+
+	if len(ops.Alerts()) != 2 {
+		t.Errorf("expect 2 alerts in ops, got %d", len(ops.Alerts()))
+	}
+	if len(order.Alerts()) != 1 {
+		t.Errorf("expect 1 alert in order, got %d", len(order.Alerts()))
+	}
+}
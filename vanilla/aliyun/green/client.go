@@ -15,8 +15,13 @@ type DefaultClient struct {
 }
 
 func (defaultClient DefaultClient) GetResponse(path string, clinetInfo ClinetInfo, bizData BizData) string{
+	return defaultClient.Post(path, clinetInfo, bizData)
+}
+
+// Post 以json格式提交任意请求体，用于异步检测任务的提交与结果查询
+func (defaultClient DefaultClient) Post(path string, clinetInfo ClinetInfo, data interface{}) string{
 	clientInfoJson, _ := json.Marshal(clinetInfo)
-	bizDataJson, _ := json.Marshal(bizData)
+	bizDataJson, _ := json.Marshal(data)
 	
 	var netTransport = &http.Transport{
 		Dial: (&net.Dialer{
@@ -37,7 +42,10 @@ func (defaultClient DefaultClient) GetResponse(path string, clinetInfo ClinetInf
 	} else {
 		addRequestHeader(string(bizDataJson), req, string(clientInfoJson), path, defaultClient.Profile.AccessKeyId, defaultClient.Profile.AccessKeySecret)
 
-		response, _ := client.Do(req)
+		response, err := client.Do(req)
+		if err != nil {
+			return ErrorResult(err)
+		}
 
 		defer response.Body.Close()
 
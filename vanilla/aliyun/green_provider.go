@@ -0,0 +1,203 @@
+package aliyun
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kfchen81/beego"
+	"github.com/kfchen81/beego/vanilla/aliyun/green"
+	"github.com/kfchen81/beego/vanilla/moderation"
+	"github.com/kfchen81/beego/vanilla/uuid"
+	"strings"
+)
+
+type aliyunSceneResult struct {
+	Scene      string  `json:"scene"`
+	Label      string  `json:"label"`
+	Suggestion string  `json:"suggestion"`
+	Rate       float64 `json:"rate"`
+}
+
+type aliyunTaskResult struct {
+	Code    int                  `json:"code"`
+	Msg     string               `json:"msg"`
+	DataId  string               `json:"dataId"`
+	TaskId  string               `json:"taskId"`
+	Results []*aliyunSceneResult `json:"results"`
+}
+
+type aliyunResponse struct {
+	Code int                 `json:"code"`
+	Msg  string              `json:"msg"`
+	Data []*aliyunTaskResult `json:"data"`
+}
+
+func (this *aliyunTaskResult) toResult() *moderation.Result {
+	result := moderation.NewPassResult(this.DataId)
+	for _, sceneResult := range this.Results {
+		result.AddLabel(&moderation.Label{
+			Scene:      sceneResult.Scene,
+			Label:      sceneResult.Label,
+			Score:      sceneResult.Rate,
+			Suggestion: sceneResult.Suggestion,
+		})
+	}
+	return result
+}
+
+func parseAliyunResponse(content string) (*aliyunResponse, error) {
+	resp := new(aliyunResponse)
+	if err := json.Unmarshal([]byte(content), resp); err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid aliyun green response: %s", content))
+	}
+	if resp.Code != 200 {
+		return nil, errors.New(fmt.Sprintf("aliyun green request fail: %d %s", resp.Code, resp.Msg))
+	}
+	return resp, nil
+}
+
+//AliyunModerationProvider 阿里云内容安全
+type AliyunModerationProvider struct {
+	client      green.DefaultClient
+	imageScenes []string
+	videoScenes []string
+	textScenes  []string
+	voiceScenes []string
+}
+
+func NewAliyunModerationProvider(accessKeyId string, accessKeySecret string) *AliyunModerationProvider {
+	return &AliyunModerationProvider{
+		client:      green.DefaultClient{Profile: green.Profile{AccessKeyId: accessKeyId, AccessKeySecret: accessKeySecret}},
+		imageScenes: []string{"porn", "terrorism"},
+		videoScenes: []string{"porn", "terrorism"},
+		textScenes:  []string{"antispam"},
+		voiceScenes: []string{"antispam"},
+	}
+}
+
+func (this *AliyunModerationProvider) Name() string {
+	return "aliyun"
+}
+
+func (this *AliyunModerationProvider) post(path string, data interface{}) (*aliyunResponse, error) {
+	clientInfo := green.ClinetInfo{Ip: "127.0.0.1"}
+	return parseAliyunResponse(this.client.Post(path, clientInfo, data))
+}
+
+func (this *AliyunModerationProvider) scan(path string, scenes []string, tasks []green.Task) ([]*moderation.Result, error) {
+	resp, err := this.post(path, green.BizData{BizType: "Green", Scenes: scenes, Tasks: tasks})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*moderation.Result, 0, len(resp.Data))
+	for _, taskResult := range resp.Data {
+		if taskResult.Code != 200 {
+			beego.Warn(fmt.Sprintf("[aliyun] green check data(%s) fail: %s", taskResult.DataId, taskResult.Msg))
+			results = append(results, moderation.NewPassResult(taskResult.DataId))
+			continue
+		}
+		results = append(results, taskResult.toResult())
+	}
+	return results, nil
+}
+
+func (this *AliyunModerationProvider) CheckText(content string) (*moderation.Result, error) {
+	tasks := []green.Task{{DataId: uuid.Rand().Hex(), Content: content}}
+	results, err := this.scan("/green/text/scan", this.textScenes, tasks)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, errors.New("aliyun green text check returns no result")
+	}
+	return results[0], nil
+}
+
+func (this *AliyunModerationProvider) CheckImages(urls []string) ([]*moderation.Result, error) {
+	return this.checkImages(urls, this.imageScenes)
+}
+
+//checkImages 按指定的场景检测图片，只请求需要检查的场景
+func (this *AliyunModerationProvider) checkImages(urls []string, scenes []string) ([]*moderation.Result, error) {
+	tasks := make([]green.Task, 0, len(urls))
+	for _, url := range urls {
+		tasks = append(tasks, green.Task{DataId: uuid.Rand().Hex(), Url: url})
+	}
+	return this.scan("/green/image/scan", scenes, tasks)
+}
+
+func (this *AliyunModerationProvider) submit(kind string, scenes []string, task green.Task) (string, error) {
+	resp, err := this.post(fmt.Sprintf("/green/%s/asyncscan", kind), green.BizData{BizType: "Green", Scenes: scenes, Tasks: []green.Task{task}})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Data) == 0 || resp.Data[0].Code != 200 {
+		msg := ""
+		if len(resp.Data) > 0 {
+			msg = resp.Data[0].Msg
+		}
+		return "", errors.New(fmt.Sprintf("submit aliyun green %s task fail: %s", kind, msg))
+	}
+	//任务id带上任务类型，查询时据此选择接口
+	return fmt.Sprintf("%s:%s", kind, resp.Data[0].TaskId), nil
+}
+
+func (this *AliyunModerationProvider) SubmitVideo(url string) (string, error) {
+	return this.submit("video", this.videoScenes, green.Task{DataId: uuid.Rand().Hex(), Url: url})
+}
+
+func (this *AliyunModerationProvider) SubmitVoice(url string) (string, error) {
+	return this.submit("voice", this.voiceScenes, green.Task{DataId: uuid.Rand().Hex(), Url: url, Type: "file"})
+}
+
+func (this *AliyunModerationProvider) QueryTask(taskId string) (*moderation.AsyncTask, error) {
+	items := strings.SplitN(taskId, ":", 2)
+	if len(items) != 2 || (items[0] != "video" && items[0] != "voice") {
+		return nil, errors.New(fmt.Sprintf("invalid aliyun green task id: %s", taskId))
+	}
+
+	resp, err := this.post(fmt.Sprintf("/green/%s/results", items[0]), []string{items[1]})
+	if err != nil {
+		return nil, err
+	}
+	task := &moderation.AsyncTask{
+		TaskId: taskId,
+		Status: moderation.TASK_STATUS_FAILED,
+	}
+	if len(resp.Data) == 0 {
+		return task, nil
+	}
+	switch resp.Data[0].Code {
+	case 200:
+		task.Status = moderation.TASK_STATUS_DONE
+		task.Result = resp.Data[0].toResult()
+	case 280:
+		//检测中
+		task.Status = moderation.TASK_STATUS_PENDING
+	default:
+		beego.Warn(fmt.Sprintf("[aliyun] green task(%s) fail: %s", taskId, resp.Data[0].Msg))
+	}
+	return task, nil
+}
+
+//ParseVoiceCallback 解析语音异步检测的回调内容
+func ParseVoiceCallback(content string) (*moderation.Result, string, error) {
+	taskResult := new(aliyunTaskResult)
+	if err := json.Unmarshal([]byte(content), taskResult); err != nil {
+		return nil, "", err
+	}
+	if taskResult.Code != 200 {
+		return nil, taskResult.TaskId, errors.New(fmt.Sprintf("aliyun green voice check fail: %s", taskResult.Msg))
+	}
+	return taskResult.toResult(), taskResult.TaskId, nil
+}
+
+func init() {
+	moderation.RegisterProvider("aliyun", func() (moderation.IModerationProvider, error) {
+		if accessKeyId == "" || accessKeySecret == "" {
+			return nil, errors.New("system::ACCESS_KEY_ID or system::ACCESS_KEY_SECRET is not configured")
+		}
+		return NewAliyunModerationProvider(accessKeyId, accessKeySecret), nil
+	})
+}
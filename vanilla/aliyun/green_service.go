@@ -3,7 +3,7 @@ package aliyun
 import (
 	"fmt"
 	"github.com/kfchen81/beego/vanilla/aliyun/green"
-	"github.com/kfchen81/beego/vanilla/uuid"
+	"github.com/kfchen81/beego/vanilla/moderation"
 	"github.com/bitly/go-simplejson"
 	"github.com/kfchen81/beego"
 	"encoding/json"
@@ -12,7 +12,7 @@ import (
 	"github.com/aliyun/alibaba-cloud-sdk-go/sdk/requests"
 )
 
-const MAX_RATE = 90.0
+const MAX_RATE = moderation.REVIEW_BLOCK_SCORE
 
 type GreenService struct {
 }
@@ -23,51 +23,44 @@ var enableContentCheck, _ = beego.AppConfig.Bool("system::ENABLE_CONTENT_CHECK")
 var accessKeyId = beego.AppConfig.String("system::ACCESS_KEY_ID")
 var accessKeySecret = beego.AppConfig.String("system::ACCESS_KEY_SECRET")
 
-func (this *GreenService) shouldBlock(response string) bool {
-	jsonObj := new(simplejson.Json)
-	err := jsonObj.UnmarshalJSON([]byte(response))
+//provider 阿里云内容安全provider，新代码请使用moderation.GetModerator()
+func (this *GreenService) provider() *AliyunModerationProvider {
+	return NewAliyunModerationProvider(accessKeyId, accessKeySecret)
+}
+
+// shouldBlock 保持GreenService原有的拦截语义：只在检测场景建议拦截(block)时拦截，建议审核(review)不拦截
+// scenes为空时检查所有场景；新代码应直接使用moderation.Result.ShouldBlock
+func (this *GreenService) shouldBlock(results []*moderation.Result, err error, scenes ...string) bool {
 	if err != nil {
-		beego.Error(err)
-		return false
-	}
-	beego.Debug(jsonObj)
-	
-	code, _ := jsonObj.Get("code").Int()
-	if code != 200 {
-		msg, _ := jsonObj.Get("msg").String()
-		beego.Warn("[aliyun] green image check fail: ", msg)
+		beego.Warn("[aliyun] green check fail: ", err.Error())
 		return false
 	}
 	
-	var shouldBlock bool = false
-	checkResults := jsonObj.Get("data").MustArray()
-	for _, checkResult := range checkResults {
-		checkResultMap := checkResult.(map[string]interface{})
-		code, _ := checkResultMap["code"].(json.Number).Int64()
-		if code == 200 {
-			sceneResults := checkResultMap["results"].([]interface{})
-			for _, sceneResult := range sceneResults {
-				sceneResultMap := sceneResult.(map[string]interface{})
-				suggestion := sceneResultMap["suggestion"].(string)
-				if suggestion == "block" {
-					shouldBlock = true
-					break
-				}
-				
-				rate, _ := sceneResultMap["rate"].(json.Number).Float64()
-				if suggestion == "view" && rate > MAX_RATE {
-					shouldBlock = true
-					break
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		if len(result.Labels) == 0 {
+			if len(scenes) == 0 && result.Suggestion == moderation.SUGGESTION_BLOCK {
+				return true
+			}
+			continue
+		}
+		for _, label := range result.Labels {
+			if label.Suggestion != moderation.SUGGESTION_BLOCK {
+				continue
+			}
+			if len(scenes) == 0 {
+				return true
+			}
+			for _, scene := range scenes {
+				if label.Scene == scene {
+					return true
 				}
 			}
 		}
 	}
-	
-	if shouldBlock {
-		return true
-	} else {
-		return false
-	}
+	return false
 }
 
 func (this *GreenService) ShouldBlockImage(url string) bool {
@@ -75,23 +68,7 @@ func (this *GreenService) ShouldBlockImage(url string) bool {
 		return false
 	}
 	
-	profile := green.Profile{AccessKeyId:accessKeyId, AccessKeySecret:accessKeySecret}
-	path := "/green/image/scan";
-	
-	clientInfo := green.ClinetInfo{Ip:"127.0.0.1"}
-	bizType := "Green"
-	scenes := []string{"porn", "terrorism"}
-	
-	task := green.Task{DataId:uuid.Rand().Hex(), Url:url}
-	tasks := []green.Task{task}
-	
-	bizData := green.BizData{ bizType, scenes, tasks}
-	
-	var client green.IAliYunClient = green.DefaultClient{Profile:profile}
-	
-	resp := client.GetResponse(path, clientInfo, bizData)
-
-	return this.shouldBlock(resp)
+	return this.shouldBlock(this.provider().CheckImages([]string{url}))
 }
 
 func (this *GreenService) ShouldBlockImages(urls []string) bool {
@@ -99,27 +76,7 @@ func (this *GreenService) ShouldBlockImages(urls []string) bool {
 		return false
 	}
 	
-	profile := green.Profile{AccessKeyId:accessKeyId, AccessKeySecret:accessKeySecret}
-	path := "/green/image/scan";
-	
-	clientInfo := green.ClinetInfo{Ip:"127.0.0.1"}
-	bizType := "Green"
-	scenes := []string{"porn", "terrorism"}
-	
-	tasks := make([]green.Task, 0)
-	
-	for _, url := range urls {
-		task := green.Task{DataId:uuid.Rand().Hex(), Url:url}
-		tasks = append(tasks, task)
-	}
-	
-	bizData := green.BizData{ bizType, scenes, tasks}
-	
-	var client green.IAliYunClient = green.DefaultClient{Profile:profile}
-	
-	resp := client.GetResponse(path, clientInfo, bizData)
-	
-	return this.shouldBlock(resp)
+	return this.shouldBlock(this.provider().CheckImages(urls))
 }
 
 func (this *GreenService) ShouldBlockText(content string) bool {
@@ -127,36 +84,16 @@ func (this *GreenService) ShouldBlockText(content string) bool {
 		return false
 	}
 	
-	profile := green.Profile{AccessKeyId:accessKeyId, AccessKeySecret:accessKeySecret}
-	path := "/green/text/scan";
-	
-	clientInfo := green.ClinetInfo{Ip:"127.0.0.1"}
-	bizType := "Green"
-	scenes := []string{"antispam"}
-	
-	task := green.Task{DataId:uuid.Rand().Hex(), Content:content}
-	tasks := []green.Task{task}
-	
-	bizData := green.BizData{ bizType, scenes, tasks}
-	
-	var client green.IAliYunClient = green.DefaultClient{Profile:profile}
-	
-	resp := client.GetResponse(path, clientInfo, bizData)
-	
-	return this.shouldBlock(resp)
+	result, err := this.provider().CheckText(content)
+	return this.shouldBlock([]*moderation.Result{result}, err)
 }
 
+// ShouldBlockVideo 同步检测视频的截帧，需要完整检测视频时使用SubmitVideo异步检测
 func (this *GreenService) ShouldBlockVideo(url string) bool {
 	if !enableContentCheck {
 		return false
 	}
 	
-	profile := green.Profile{AccessKeyId: accessKeyId, AccessKeySecret: accessKeySecret}
-	path := "/green/image/scan"
-	
-	clientInfo := green.ClinetInfo{Ip: "127.0.0.1"}
-	bizType := "Green"
-	scenes := []string{"porn"}
 	times := []string{"1000", "5000", "10000", "15000", "20000"}
 	imageUrls := make([]string, 0)
 	for _, time := range times {
@@ -164,19 +101,10 @@ func (this *GreenService) ShouldBlockVideo(url string) bool {
 		beego.Notice("检测图片:", fmt.Sprintf("%s?x-oss-process=video/snapshot,t_%s,f_jpg,w_0,h_0,m_fast", url, time))
 	}
 	
-	tasks := make([]green.Task, 0)
-	for _, url := range imageUrls {
-		task := green.Task{DataId: uuid.Rand().Hex(), Url: url}
-		tasks = append(tasks, task)
-	}
-	
-	bizData := green.BizData{bizType, scenes, tasks}
-	
-	var client green.IAliYunClient = green.DefaultClient{Profile: profile}
-	
-	resp := client.GetResponse(path, clientInfo, bizData)
-	
-	return this.shouldBlock(resp)
+	//视频截帧只按porn场景拦截，也只请求porn场景
+	scenes := []string{"porn"}
+	results, err := this.provider().checkImages(imageUrls, scenes)
+	return this.shouldBlock(results, err, scenes...)
 }
 
 func (this *GreenService) SubmitVoiceCheckTask(url string) string {
@@ -228,39 +156,13 @@ func (this *GreenService) SubmitVoiceCheckTask(url string) string {
 }
 
 func (this *GreenService) ShouldBlockVoice(response string) (bool, string) {
-	jsonObj := new(simplejson.Json)
-	_ = jsonObj.UnmarshalJSON([]byte(response))
-	
-	code, _ := jsonObj.Get("code").Int()
-	taskId, _ := jsonObj.Get("taskId").String()
-	if code != 200 {
-		msg, _ := jsonObj.Get("msg").String()
-		beego.Warn("[aliyun] green voice check fail: ", msg)
+	result, taskId, err := ParseVoiceCallback(response)
+	if err != nil {
+		beego.Warn("[aliyun] green voice check fail: ", err.Error())
 		return false, taskId
 	}
 	
-	var shouldBlock bool
-	results := jsonObj.Get("results").MustArray()
-	for _, result := range results {
-		resMap := result.(map[string]interface{})
-		suggestion := resMap["suggestion"].(string)
-		if suggestion == "block" {
-			shouldBlock = true
-			break
-		}
-		
-		rate, _ := resMap["rate"].(json.Number).Float64()
-		if suggestion == "review" && rate > MAX_RATE {
-			shouldBlock = true
-			break
-		}
-	}
-	
-	if shouldBlock {
-		return true, taskId
-	} else {
-		return false, taskId
-	}
+	return result.ShouldBlock(), taskId
 }
 
 func NewGreenService() *GreenService {
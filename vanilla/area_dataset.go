@@ -0,0 +1,283 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kfchen81/beego"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const AREA_LEVEL_PROVINCE = 1
+const AREA_LEVEL_CITY = 2
+const AREA_LEVEL_DISTRICT = 3
+const AREA_LEVEL_STREET = 4
+
+// AREA_DATASET_VERSION_LEGACY 内置数据集的版本
+// 内置的只有旧版的省/市/区数据：不含街道和拼音，只有省份有GB/T 2260代码(见PROVINCENAME2GBCODE)，
+// 因此拼音搜索、街道查询以及市/区的GetAreaByGBCode在内置数据集上不可用，汉字的模糊搜索仍然可用。
+// 旧版数据之后新设的区县也不在内置数据集中，仍会被拒绝。
+//
+// 未完成：内置当前的GB/T 2260数据集(含街道和拼音)作为默认数据集不在本次实现范围内，
+// 本库只提供数据集的加载和切换，需要时通过area::DATASET_PATH配置数据集文件，格式见AreaDataset
+const AREA_DATASET_VERSION_LEGACY = "legacy"
+
+// PROVINCENAME2GBCODE 内置数据集中省份名到GB/T 2260代码的映射
+var PROVINCENAME2GBCODE = map[string]string{
+	"北京市":      "110000",
+	"天津市":      "120000",
+	"河北省":      "130000",
+	"山西省":      "140000",
+	"内蒙古自治区":   "150000",
+	"辽宁省":      "210000",
+	"吉林省":      "220000",
+	"黑龙江省":     "230000",
+	"上海市":      "310000",
+	"江苏省":      "320000",
+	"浙江省":      "330000",
+	"安徽省":      "340000",
+	"福建省":      "350000",
+	"江西省":      "360000",
+	"山东省":      "370000",
+	"河南省":      "410000",
+	"湖北省":      "420000",
+	"湖南省":      "430000",
+	"广东省":      "440000",
+	"广西壮族自治区":  "450000",
+	"海南省":      "460000",
+	"重庆市":      "500000",
+	"四川省":      "510000",
+	"贵州省":      "520000",
+	"云南省":      "530000",
+	"西藏自治区":    "540000",
+	"陕西省":      "610000",
+	"甘肃省":      "620000",
+	"青海省":      "630000",
+	"宁夏回族自治区":  "640000",
+	"新疆维吾尔自治区": "650000",
+	"台湾省":      "710000",
+	"香港特别行政区":  "810000",
+	"澳门特别行政区":  "820000",
+}
+
+// AreaDivision 数据集中的一个行政区划
+type AreaDivision struct {
+	Code       string `json:"code"`        //GB/T 2260代码，街道为9位
+	ParentCode string `json:"parent_code"` //上级行政区划代码，省份为空
+	Name       string `json:"name"`
+	Pinyin     string `json:"pinyin"` //以空格分隔的拼音，如"hang zhou shi"
+	ZipCode    string `json:"zip_code"`
+	LegacyId   int    `json:"legacy_id"` //旧版area id，用于兼容"1_1_1"格式的area code
+
+	id       int
+	parentId int
+	level    int
+}
+
+// AreaDataset 带版本的行政区划数据集
+// 数据集文件为json格式：{"version": "2023", "divisions": [{"code": "330000", "name": "浙江省", "pinyin": "zhe jiang sheng"}, ...]}
+type AreaDataset struct {
+	Version   string          `json:"version"`
+	Divisions []*AreaDivision `json:"divisions"`
+}
+
+// resolve 根据parent_code计算区划的层级与id，id优先使用legacy_id，否则使用数字形式的GB代码
+func (this *AreaDataset) resolve() error {
+	code2division := make(map[string]*AreaDivision, len(this.Divisions))
+	for _, division := range this.Divisions {
+		if division.Code == "" {
+			return errors.New(fmt.Sprintf("area division(%s) has no code", division.Name))
+		}
+		if _, ok := code2division[division.Code]; ok {
+			return errors.New(fmt.Sprintf("duplicate area division code(%s)", division.Code))
+		}
+		code2division[division.Code] = division
+
+		if division.LegacyId != 0 {
+			division.id = division.LegacyId
+		} else {
+			id, err := strconv.Atoi(division.Code)
+			if err != nil {
+				return errors.New(fmt.Sprintf("invalid area division code(%s)", division.Code))
+			}
+			division.id = id
+		}
+	}
+
+	var resolveLevel func(division *AreaDivision, depth int) error
+	resolveLevel = func(division *AreaDivision, depth int) error {
+		if division.level != 0 {
+			return nil
+		}
+		if depth > AREA_LEVEL_STREET {
+			return errors.New(fmt.Sprintf("area division(%s) is too deep", division.Code))
+		}
+		if division.ParentCode == "" {
+			division.level = AREA_LEVEL_PROVINCE
+			return nil
+		}
+		parent, ok := code2division[division.ParentCode]
+		if !ok {
+			return errors.New(fmt.Sprintf("parent(%s) of area division(%s) not found", division.ParentCode, division.Code))
+		}
+		if err := resolveLevel(parent, depth+1); err != nil {
+			return err
+		}
+		division.parentId = parent.id
+		division.level = parent.level + 1
+		return nil
+	}
+	for _, division := range this.Divisions {
+		if err := resolveLevel(division, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadAreaDataset 从json文件加载数据集
+func LoadAreaDataset(path string) (*AreaDataset, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dataset := new(AreaDataset)
+	if err := json.Unmarshal(content, dataset); err != nil {
+		return nil, err
+	}
+	if dataset.Version == "" {
+		return nil, errors.New(fmt.Sprintf("area dataset(%s) has no version", path))
+	}
+	return dataset, nil
+}
+
+// newLegacyAreaDataset 将内置的旧版area数据转换为数据集，旧版数据中只有省份有GB代码
+func newLegacyAreaDataset(area map[string][]map[string]interface{}) *AreaDataset {
+	dataset := &AreaDataset{
+		Version:   AREA_DATASET_VERSION_LEGACY,
+		Divisions: make([]*AreaDivision, 0),
+	}
+	for _, data := range area["PROVINCES"] {
+		province := NewProvince(data)
+		dataset.Divisions = append(dataset.Divisions, &AreaDivision{
+			Code:     PROVINCENAME2GBCODE[province.Name],
+			Name:     province.Name,
+			LegacyId: province.Id,
+			id:       province.Id,
+			level:    AREA_LEVEL_PROVINCE,
+		})
+	}
+	for _, data := range area["CITIES"] {
+		city := NewCity(data)
+		dataset.Divisions = append(dataset.Divisions, &AreaDivision{
+			Name:     city.Name,
+			ZipCode:  city.ZipCode,
+			LegacyId: city.Id,
+			id:       city.Id,
+			parentId: city.ProvinceId,
+			level:    AREA_LEVEL_CITY,
+		})
+	}
+	for _, data := range area["DISTRICTS"] {
+		district := NewDistrict(data)
+		dataset.Divisions = append(dataset.Divisions, &AreaDivision{
+			Name:     district.Name,
+			LegacyId: district.Id,
+			id:       district.Id,
+			parentId: district.CityId,
+			level:    AREA_LEVEL_DISTRICT,
+		})
+	}
+	return dataset
+}
+
+var currentAreaIndex atomic.Value
+
+func getAreaIndex() *areaIndex {
+	return currentAreaIndex.Load().(*areaIndex)
+}
+
+// SetAreaDataset 切换当前使用的数据集，正在进行的查询不受影响
+func SetAreaDataset(dataset *AreaDataset) error {
+	if dataset.Version != AREA_DATASET_VERSION_LEGACY {
+		if err := dataset.resolve(); err != nil {
+			return err
+		}
+	}
+	currentAreaIndex.Store(newAreaIndex(dataset))
+	beego.Info(fmt.Sprintf("[area] use dataset(%s), %d divisions", dataset.Version, len(dataset.Divisions)))
+	if dataset.Version == AREA_DATASET_VERSION_LEGACY {
+		beego.Warn("[area] legacy dataset has no street, pinyin, new districts and GB/T 2260 code of city/district, configure area::DATASET_PATH to use a GB/T 2260 dataset")
+	}
+	return nil
+}
+
+// GetAreaDatasetVersion 获取当前数据集的版本
+func GetAreaDatasetVersion() string {
+	return getAreaIndex().version
+}
+
+var areaDatasetPath string
+var areaDatasetModTime time.Time
+var areaDatasetLock sync.Mutex
+
+// ReloadAreaDataset 重新加载area::DATASET_PATH配置的数据集文件
+func ReloadAreaDataset() error {
+	if areaDatasetPath == "" {
+		return errors.New("area::DATASET_PATH is not configured")
+	}
+
+	areaDatasetLock.Lock()
+	defer areaDatasetLock.Unlock()
+	stat, err := os.Stat(areaDatasetPath)
+	if err != nil {
+		return err
+	}
+	//加载失败时保持当前数据集，等待文件再次更新
+	areaDatasetModTime = stat.ModTime()
+	dataset, err := LoadAreaDataset(areaDatasetPath)
+	if err != nil {
+		return err
+	}
+	return SetAreaDataset(dataset)
+}
+
+// watchAreaDataset 数据集文件更新后自动重新加载
+func watchAreaDataset(interval time.Duration) {
+	for range time.Tick(interval) {
+		stat, err := os.Stat(areaDatasetPath)
+		if err != nil {
+			beego.Warn(fmt.Sprintf("[area] stat dataset failed: %s", err.Error()))
+			continue
+		}
+		areaDatasetLock.Lock()
+		changed := !stat.ModTime().Equal(areaDatasetModTime)
+		areaDatasetLock.Unlock()
+		if changed {
+			if err := ReloadAreaDataset(); err != nil {
+				beego.Error(fmt.Sprintf("[area] reload dataset failed: %s", err.Error()))
+			}
+		}
+	}
+}
+
+func init() {
+	json.Unmarshal([]byte(areaJSON), &AREA)
+	SetAreaDataset(newLegacyAreaDataset(AREA))
+
+	areaDatasetPath = beego.AppConfig.String("area::DATASET_PATH")
+	if areaDatasetPath != "" {
+		if err := ReloadAreaDataset(); err != nil {
+			beego.Error(fmt.Sprintf("[area] load dataset failed, fallback to legacy dataset: %s", err.Error()))
+		}
+		interval := beego.AppConfig.DefaultInt("area::RELOAD_INTERVAL", 0)
+		if interval > 0 {
+			go watchAreaDataset(time.Duration(interval) * time.Second)
+		}
+	}
+}
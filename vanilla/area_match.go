@@ -0,0 +1,287 @@
+package vanilla
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// areaNameSuffixes 行政区划名称的通名后缀，较长的在前
+var areaNameSuffixes = []string{
+	"维吾尔自治区", "壮族自治区", "回族自治区", "特别行政区",
+	"自治区", "自治州", "自治县", "自治旗", "地区", "新区", "街道",
+	"省", "市", "区", "县", "盟", "旗", "镇", "乡",
+}
+
+// shortAreaName 去掉通名后的简称，如"浙江省"的简称为"浙江"，简称至少保留两个字
+func shortAreaName(name string) string {
+	for _, suffix := range areaNameSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			short := strings.TrimSuffix(name, suffix)
+			if utf8.RuneCountInString(short) >= 2 {
+				return short
+			}
+			return name
+		}
+	}
+	return name
+}
+
+// areaEntry 模糊搜索使用的条目
+type areaEntry struct {
+	area          *Area
+	level         int
+	name          string
+	shortName     string
+	pinyin        string //去掉空格的全拼
+	shortPinyin   string //简称的全拼
+	initials      string //拼音首字母
+	shortInitials string //简称的拼音首字母
+}
+
+func newAreaEntry(area *Area, name string, pinyin string) *areaEntry {
+	entry := &areaEntry{
+		area:      area,
+		name:      name,
+		shortName: shortAreaName(name),
+	}
+	switch {
+	case area.Street != nil:
+		entry.level = AREA_LEVEL_STREET
+	case area.District != nil:
+		entry.level = AREA_LEVEL_DISTRICT
+	case area.City != nil:
+		entry.level = AREA_LEVEL_CITY
+	default:
+		entry.level = AREA_LEVEL_PROVINCE
+	}
+
+	syllables := strings.Fields(strings.ToLower(pinyin))
+	if len(syllables) > 0 {
+		entry.pinyin = strings.Join(syllables, "")
+		for _, syllable := range syllables {
+			entry.initials += syllable[:1]
+		}
+		//拼音与汉字一一对应时才能得到简称的拼音
+		shortLen := utf8.RuneCountInString(entry.shortName)
+		if len(syllables) == utf8.RuneCountInString(name) && shortLen < len(syllables) {
+			entry.shortPinyin = strings.Join(syllables[:shortLen], "")
+			entry.shortInitials = entry.initials[:shortLen]
+		}
+	}
+	return entry
+}
+
+func isAsciiLetters(text string) bool {
+	if text == "" {
+		return false
+	}
+	for _, c := range text {
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// score 关键字与条目的匹配度，0表示不匹配
+func (this *areaEntry) score(keyword string) int {
+	switch {
+	case keyword == this.name:
+		return 100
+	case keyword == this.shortName:
+		return 90
+	}
+
+	if isAsciiLetters(keyword) && this.pinyin != "" {
+		switch {
+		case keyword == this.pinyin || keyword == this.shortPinyin:
+			return 80
+		case keyword == this.initials || keyword == this.shortInitials:
+			return 70
+		case strings.HasPrefix(this.pinyin, keyword):
+			return 50
+		case strings.HasPrefix(this.initials, keyword):
+			return 40
+		}
+		return 0
+	}
+
+	switch {
+	case strings.HasPrefix(this.name, keyword):
+		return 60
+	case strings.Contains(this.name, keyword):
+		return 30
+	}
+	return 0
+}
+
+// normalizeAreaKeyword 去掉空白，拼音转为小写
+func normalizeAreaKeyword(keyword string) string {
+	return strings.ToLower(strings.Join(strings.Fields(keyword), ""))
+}
+
+// SearchAreas 按名称、简称、拼音或拼音首字母模糊搜索行政区划，结果按匹配度与层级排序
+func (this *AreaService) SearchAreas(keyword string, limit int) []*Area {
+	keyword = normalizeAreaKeyword(keyword)
+	if keyword == "" {
+		return make([]*Area, 0)
+	}
+
+	type scoredEntry struct {
+		entry *areaEntry
+		score int
+	}
+	matched := make([]*scoredEntry, 0)
+	for _, entry := range getAreaIndex().entries {
+		if score := entry.score(keyword); score > 0 {
+			matched = append(matched, &scoredEntry{entry, score})
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].score != matched[j].score {
+			return matched[i].score > matched[j].score
+		}
+		return matched[i].entry.level < matched[j].entry.level
+	})
+
+	if limit > 0 && len(matched) > limit {
+		matched = matched[:limit]
+	}
+	areas := make([]*Area, 0, len(matched))
+	for _, item := range matched {
+		areas = append(areas, item.entry.area)
+	}
+	return areas
+}
+
+// findArea 在指定层级中查找最匹配的区划，只接受全称、简称、拼音与拼音首字母的完整匹配
+func (this *AreaService) findArea(level int, keyword string, accept func(area *Area) bool) *Area {
+	keyword = normalizeAreaKeyword(keyword)
+	var best *Area
+	bestScore := 0
+	for _, entry := range getAreaIndex().entries {
+		if entry.level != level || !accept(entry.area) {
+			continue
+		}
+		if score := entry.score(keyword); score >= 70 && score > bestScore {
+			best = entry.area
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// FindProvince 模糊查找省份，支持"浙江"、"zhejiang"、"zj"等形式
+func (this *AreaService) FindProvince(name string) *Province {
+	area := this.findArea(AREA_LEVEL_PROVINCE, name, func(area *Area) bool {
+		return true
+	})
+	if area == nil {
+		return nil
+	}
+	return area.Province
+}
+
+// FindCity 模糊查找城市，provinceId为0时在所有省份中查找
+func (this *AreaService) FindCity(provinceId int, name string) *City {
+	area := this.findArea(AREA_LEVEL_CITY, name, func(area *Area) bool {
+		return provinceId == 0 || area.Province.Id == provinceId
+	})
+	if area == nil {
+		return nil
+	}
+	return area.City
+}
+
+// FindDistrict 模糊查找区县，cityId为0时在所有城市中查找
+func (this *AreaService) FindDistrict(cityId int, name string) *District {
+	area := this.findArea(AREA_LEVEL_DISTRICT, name, func(area *Area) bool {
+		return cityId == 0 || area.City.Id == cityId
+	})
+	if area == nil {
+		return nil
+	}
+	return area.District
+}
+
+// matchAreaPrefix 返回text以name或其简称开头时匹配的长度
+func matchAreaPrefix(text string, name string) int {
+	if strings.HasPrefix(text, name) {
+		return len(name)
+	}
+	short := shortAreaName(name)
+	if short != name && strings.HasPrefix(text, short) {
+		return len(short)
+	}
+	return 0
+}
+
+// ParseAddress 将"浙江省杭州市西湖区文三路90号"形式的地址解析为行政区划与详细地址
+// 地址可以省略省份或城市，如"杭州西湖区文三路90号"，无法识别的部分作为详细地址返回
+func (this *AreaService) ParseAddress(address string) (*Area, string) {
+	idx := getAreaIndex()
+	text := strings.Join(strings.Fields(address), "")
+	area := new(Area)
+
+	matchedLen := 0
+	for _, province := range idx.provinces {
+		if n := matchAreaPrefix(text, province.Name); n > matchedLen {
+			area.Province = province
+			matchedLen = n
+		}
+	}
+	text = text[matchedLen:]
+
+	matchedLen = 0
+	for _, province := range idx.provinces {
+		if area.Province != nil && province != area.Province {
+			continue
+		}
+		for _, city := range province.Cities {
+			if n := matchAreaPrefix(text, city.Name); n > matchedLen {
+				area.Province = province
+				area.City = city
+				matchedLen = n
+			}
+		}
+	}
+	text = text[matchedLen:]
+	if area.City == nil && area.Province != nil && area.Province.IsDGC() {
+		area.City = area.Province.Cities[0]
+	}
+
+	matchedLen = 0
+	for _, province := range idx.provinces {
+		if area.Province != nil && province != area.Province {
+			continue
+		}
+		for _, city := range province.Cities {
+			if area.City != nil && city != area.City {
+				continue
+			}
+			for _, district := range city.Districts {
+				if n := matchAreaPrefix(text, district.Name); n > matchedLen {
+					area.Province = province
+					area.City = city
+					area.District = district
+					matchedLen = n
+				}
+			}
+		}
+	}
+	text = text[matchedLen:]
+
+	if area.District != nil {
+		matchedLen = 0
+		for _, street := range area.District.Streets {
+			if n := matchAreaPrefix(text, street.Name); n > matchedLen {
+				area.Street = street
+				matchedLen = n
+			}
+		}
+		text = text[matchedLen:]
+	}
+
+	return area, text
+}
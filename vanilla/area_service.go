@@ -1,7 +1,6 @@
 package vanilla
 
 import (
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -46,20 +45,51 @@ var PROVINCEID2ZONE = map[int]string{
 	34: "其它",
 }
 
+//PROVINCECODE2ZONE GB/T 2260省级代码前两位到区域的映射
+var PROVINCECODE2ZONE = map[string]string{
+	"11": "直辖市",
+	"12": "直辖市",
+	"31": "直辖市",
+	"50": "直辖市",
+	"13": "华北-东北",
+	"14": "华北-东北",
+	"15": "华北-东北",
+	"21": "华北-东北",
+	"22": "华北-东北",
+	"23": "华北-东北",
+	"32": "华东地区",
+	"33": "华东地区",
+	"34": "华东地区",
+	"35": "华东地区",
+	"36": "华东地区",
+	"37": "华东地区",
+	"41": "华南-华中",
+	"42": "华南-华中",
+	"43": "华南-华中",
+	"44": "华南-华中",
+	"45": "华南-华中",
+	"46": "华南-华中",
+	"51": "西北-西南",
+	"52": "西北-西南",
+	"53": "西北-西南",
+	"54": "西北-西南",
+	"61": "西北-西南",
+	"62": "西北-西南",
+	"63": "西北-西南",
+	"64": "西北-西南",
+	"65": "西北-西南",
+	"71": "其它",
+	"81": "其它",
+	"82": "其它",
+}
+
 var AREA = make(map[string][]map[string]interface{})
-var provinces = make([]*Province, 0)
-var cities = make([]*City, 0)
-var districts = make([]*District, 0)
-var name2Province = make(map[string]*Province)
-var id2Province = make(map[int]*Province)
-var name2City = make(map[string]*City)
-var id2City = make(map[int]*City)
-var id2District = make(map[int]*District)
-var name2District = make(map[string]*District)
 
 type Province struct {
 	Id int
+	Code string
 	Name string
+	Pinyin string
 	Zone string
 	Cities []*City
 }
@@ -80,7 +110,9 @@ func NewProvince(data map[string]interface{}) *Province{
 type City struct {
 	Id int
 	ProvinceId int
+	Code string
 	Name string
+	Pinyin string
 	ZipCode string
 	Districts []*District
 }
@@ -98,7 +130,10 @@ func NewCity(data map[string]interface{}) *City{
 type District struct {
 	Id int
 	CityId int
+	Code string
 	Name string
+	Pinyin string
+	Streets []*Street
 }
 
 func NewDistrict(data map[string]interface{}) *District{
@@ -106,13 +141,149 @@ func NewDistrict(data map[string]interface{}) *District{
 	district.Id, _ = strconv.Atoi(data["id"].(string))
 	district.CityId, _ = strconv.Atoi(data["city_id"].(string))
 	district.Name = data["name"].(string)
+	district.Streets = make([]*Street, 0)
 	return district
 }
 
+//Street 街道(乡镇)，只有GB/T 2260数据集中存在
+type Street struct {
+	Id int
+	DistrictId int
+	Code string
+	Name string
+	Pinyin string
+}
+
 type Area struct {
 	Province *Province
 	City *City
 	District *District
+	Street *Street
+}
+
+//areaIndex 由数据集构建的只读索引，切换数据集时整体替换
+type areaIndex struct {
+	version string
+	provinces []*Province
+	name2Province map[string]*Province
+	id2Province map[int]*Province
+	name2City map[string]*City
+	id2City map[int]*City
+	id2District map[int]*District
+	name2District map[string]*District
+	id2Street map[int]*Street
+	code2Area map[string]*Area
+	entries []*areaEntry
+}
+
+func newAreaIndex(dataset *AreaDataset) *areaIndex {
+	idx := &areaIndex{
+		version: dataset.Version,
+		provinces: make([]*Province, 0),
+		name2Province: make(map[string]*Province),
+		id2Province: make(map[int]*Province),
+		name2City: make(map[string]*City),
+		id2City: make(map[int]*City),
+		id2District: make(map[int]*District),
+		name2District: make(map[string]*District),
+		id2Street: make(map[int]*Street),
+		code2Area: make(map[string]*Area),
+		entries: make([]*areaEntry, 0, len(dataset.Divisions)),
+	}
+
+	divisionsOfLevel := make(map[int][]*AreaDivision)
+	for _, division := range dataset.Divisions {
+		divisionsOfLevel[division.level] = append(divisionsOfLevel[division.level], division)
+	}
+
+	for _, division := range divisionsOfLevel[AREA_LEVEL_PROVINCE] {
+		province := &Province{
+			Id: division.id,
+			Code: division.Code,
+			Name: division.Name,
+			Pinyin: division.Pinyin,
+			Cities: make([]*City, 0),
+		}
+		if zone, ok := PROVINCEID2ZONE[division.LegacyId]; ok {
+			province.Zone = zone
+		} else if len(division.Code) >= 2 {
+			province.Zone = PROVINCECODE2ZONE[division.Code[:2]]
+		}
+		idx.provinces = append(idx.provinces, province)
+		idx.name2Province[province.Name] = province
+		idx.id2Province[province.Id] = province
+		idx.addEntry(&Area{Province: province}, province.Code, province.Name, province.Pinyin)
+	}
+
+	for _, division := range divisionsOfLevel[AREA_LEVEL_CITY] {
+		province, ok := idx.id2Province[division.parentId]
+		if !ok {
+			continue
+		}
+		city := &City{
+			Id: division.id,
+			ProvinceId: province.Id,
+			Code: division.Code,
+			Name: division.Name,
+			Pinyin: division.Pinyin,
+			ZipCode: division.ZipCode,
+			Districts: make([]*District, 0),
+		}
+		if city.Name == "市辖区" {
+			//GB/T 2260中直辖市下的"市辖区"以直辖市名展示
+			city.Name = province.Name
+			city.Pinyin = province.Pinyin
+		}
+		province.Cities = append(province.Cities, city)
+		idx.name2City[city.Name] = city
+		idx.id2City[city.Id] = city
+		idx.addEntry(&Area{Province: province, City: city}, city.Code, city.Name, city.Pinyin)
+	}
+
+	for _, division := range divisionsOfLevel[AREA_LEVEL_DISTRICT] {
+		city, ok := idx.id2City[division.parentId]
+		if !ok {
+			continue
+		}
+		district := &District{
+			Id: division.id,
+			CityId: city.Id,
+			Code: division.Code,
+			Name: division.Name,
+			Pinyin: division.Pinyin,
+			Streets: make([]*Street, 0),
+		}
+		city.Districts = append(city.Districts, district)
+		idx.name2District[fmt.Sprintf("%d_%s", city.Id, district.Name)] = district
+		idx.id2District[district.Id] = district
+		idx.addEntry(&Area{Province: idx.id2Province[city.ProvinceId], City: city, District: district}, district.Code, district.Name, district.Pinyin)
+	}
+
+	for _, division := range divisionsOfLevel[AREA_LEVEL_STREET] {
+		district, ok := idx.id2District[division.parentId]
+		if !ok {
+			continue
+		}
+		street := &Street{
+			Id: division.id,
+			DistrictId: district.Id,
+			Code: division.Code,
+			Name: division.Name,
+			Pinyin: division.Pinyin,
+		}
+		district.Streets = append(district.Streets, street)
+		idx.id2Street[street.Id] = street
+		city := idx.id2City[district.CityId]
+		idx.addEntry(&Area{Province: idx.id2Province[city.ProvinceId], City: city, District: district, Street: street}, street.Code, street.Name, street.Pinyin)
+	}
+	return idx
+}
+
+func (this *areaIndex) addEntry(area *Area, code string, name string, pinyin string) {
+	if code != "" {
+		this.code2Area[code] = area
+	}
+	this.entries = append(this.entries, newAreaEntry(area, name, pinyin))
 }
 
 type AreaService struct {
@@ -129,37 +300,38 @@ func NewAreaService() *AreaService {
 */
 
 func (this *AreaService) GetProvinces() []*Province {
-	return provinces
+	return getAreaIndex().provinces
 }
 
 func (this *AreaService) GetProvinceByName(name string) *Province{
-	return name2Province[name]
+	return getAreaIndex().name2Province[name]
 }
 
 func (this *AreaService) GetProvincesByNames(names []string) []*Province {
+	idx := getAreaIndex()
 	provinces := make([]*Province, 0)
 	for _, name := range names {
-		if province, ok := name2Province[name]; ok {
+		if province, ok := idx.name2Province[name]; ok {
 			provinces = append(provinces, province)
 		}
 	}
-	
+
 	return provinces
 }
 
-
 func (this *AreaService) GetProvinceById(id int) *Province {
-	return id2Province[id]
+	return getAreaIndex().id2Province[id]
 }
 
 func (this *AreaService) GetProvincesByIds(ids []int) []*Province {
+	idx := getAreaIndex()
 	provinces := make([]*Province, 0)
 	for _, id := range ids {
-		if province, ok := id2Province[id]; ok {
+		if province, ok := idx.id2Province[id]; ok {
 			provinces = append(provinces, province)
 		}
 	}
-	
+
 	return provinces
 }
 
@@ -168,7 +340,7 @@ func (this *AreaService) GetProvincesByIds(ids []int) []*Province {
 */
 
 func (this *AreaService) GetCityByName(name string) *City{
-	return name2City[name]
+	return getAreaIndex().name2City[name]
 }
 
 func (this *AreaService) GetCityByNameInProvince(provinceId int, name string) *City{
@@ -184,33 +356,35 @@ func (this *AreaService) GetCityByNameInProvince(provinceId int, name string) *C
 }
 
 func (this *AreaService) GetCitiesByNames(names []string) []*City {
+	idx := getAreaIndex()
 	cities := make([]*City, 0)
 	for _, name := range names {
-		if city, ok := name2City[name]; ok {
+		if city, ok := idx.name2City[name]; ok {
 			cities = append(cities, city)
 		}
 	}
-	
+
 	return cities
 }
 
 func (this *AreaService) GetCityById(id int) *City{
-	return id2City[id]
+	return getAreaIndex().id2City[id]
 }
 
 func (this *AreaService) GetCitiesByIds(ids []int) []*City {
+	idx := getAreaIndex()
 	cities := make([]*City, 0)
 	for _, id := range ids {
-		if city, ok := id2City[id]; ok {
+		if city, ok := idx.id2City[id]; ok {
 			cities = append(cities, city)
 		}
 	}
-	
+
 	return cities
 }
 
 func (this *AreaService) GetCitiesForProvince(provinceId int) []*City {
-	if province, ok := id2Province[provinceId]; ok {
+	if province, ok := getAreaIndex().id2Province[provinceId]; ok {
 		return province.Cities
 	} else {
 		return make([]*City, 0)
@@ -222,44 +396,62 @@ func (this *AreaService) GetCitiesForProvince(provinceId int) []*City {
 */
 func (this *AreaService) GetDistrictByName(cityId int, name string) *District{
 	name = fmt.Sprintf("%d_%s", cityId, name)
-	return name2District[name]
+	return getAreaIndex().name2District[name]
 }
 
 func (this *AreaService) GetDistrictsByNames(cityId int, names []string) []*District{
+	idx := getAreaIndex()
 	districts := make([]*District, 0)
 	for _, name := range names {
 		_name := fmt.Sprintf("%d_%s", cityId, name)
-		if district, ok := name2District[_name]; ok {
+		if district, ok := idx.name2District[_name]; ok {
 			districts = append(districts, district)
 		}
 	}
-	
+
 	return districts
 }
 
 func (this *AreaService) GetDistrictById(id int) *District{
-	return id2District[id]
+	return getAreaIndex().id2District[id]
 }
 
 func (this *AreaService) GetDistrictsByIds(ids []int) []*District {
+	idx := getAreaIndex()
 	districts := make([]*District, 0)
 	for _, id := range ids {
-		if district, ok := id2District[id]; ok {
+		if district, ok := idx.id2District[id]; ok {
 			districts = append(districts, district)
 		}
 	}
-	
+
 	return districts
 }
 
 func (this *AreaService) GetDistrictsForCity(cityId int) []*District {
-	if city, ok := id2City[cityId]; ok {
+	if city, ok := getAreaIndex().id2City[cityId]; ok {
 		return city.Districts
 	} else {
 		return make([]*District, 0)
 	}
 }
 
+/*
+ Street相关api
+*/
+
+func (this *AreaService) GetStreetById(id int) *Street {
+	return getAreaIndex().id2Street[id]
+}
+
+func (this *AreaService) GetStreetsForDistrict(districtId int) []*Street {
+	if district, ok := getAreaIndex().id2District[districtId]; ok {
+		return district.Streets
+	} else {
+		return make([]*Street, 0)
+	}
+}
+
 /*
  Area相关api
 */
@@ -276,7 +468,7 @@ func (this *AreaService) GetAreaByName(name string) *Area {
 	province := this.GetProvinceByName(items[0])
 	city := this.GetCityByNameInProvince(province.Id, items[1])
 	district := this.GetDistrictByName(city.Id, items[2])
-	
+
 	return &Area{
 		Province: province,
 		City: city,
@@ -287,102 +479,33 @@ func (this *AreaService) GetAreaByName(name string) *Area {
 // GetAreaByCode 根据area code(1_1_1)获取area
 func (this *AreaService) GetAreaByCode(code string) *Area {
 	items := strings.Split(code, "_")
-	
+
 	provinceId, _ := strconv.Atoi(items[0])
 	province := this.GetProvinceById(provinceId)
-	
+
 	cityId, _ := strconv.Atoi(items[1])
 	city := this.GetCityById(cityId)
-	
+
 	districtId, _ := strconv.Atoi(items[2])
 	district := this.GetDistrictById(districtId)
-	
+
+	var street *Street
+	if len(items) > 3 {
+		streetId, _ := strconv.Atoi(items[3])
+		street = this.GetStreetById(streetId)
+	}
+
 	return &Area{
 		Province: province,
 		City: city,
 		District: district,
+		Street: street,
 	}
 }
 
-
-func init(){
-	json.Unmarshal([]byte(areaJSON), &AREA)
-	for name, arrs := range AREA {
-		switch name {
-		case "PROVINCES":
-			for _, data := range arrs {
-				province := NewProvince(data)
-				provinces = append(provinces, province)
-				name2Province[province.Name] = province
-				id2Province[province.Id] = province
-			}
-		case "CITIES":
-			for _, data := range arrs {
-				city := NewCity(data)
-				cities = append(cities, city)
-			}
-		case "DISTRICTS":
-			for _, data := range arrs {
-				district := NewDistrict(data)
-				districts = append(districts, district)
-			}
-		}
-	}
-
-	for _, city := range cities {
-		name2City[city.Name] = city
-		id2City[city.Id] = city
-		//向province.Cities中加入city
-		if province, ok := id2Province[city.ProvinceId]; ok {
-			province.Cities = append(province.Cities, city)
-		}
-	}
-
-	for _, district := range districts {
-		name := fmt.Sprintf("%d_%s", district.CityId, district.Name)
-		name2District[name] = district
-		id2District[district.Id] = district
-
-		if city, ok := id2City[district.CityId]; ok {
-			city.Districts = append(city.Districts, district)
-		}
-	}
-
-	//for name, arrs := range AREA{
-	//	switch name {
-	//	case "PROVINCES":
-	//		for _, data := range arrs {
-	//			province := NewProvince(data)
-	//			provinces = append(provinces, province)
-	//			name2Province[province.Name] = province
-	//			id2Province[province.Id] = province
-	//		}
-	//
-	//	case "CITIES":
-	//		for _, data := range arrs {
-	//			city := NewCity(data)
-	//			name2City[city.Name] = city
-	//			id2City[city.Id] = city
-	//			//cities = append(cities, city)
-	//
-	//			//向province.Cities中加入city
-	//			if province, ok := id2Province[city.ProvinceId]; ok {
-	//				province.Cities = append(province.Cities, city)
-	//			}
-	//		}
-	//	case "DISTRICTS":
-	//		for _, data := range arrs {
-	//			district := NewDistrict(data)
-	//			name := fmt.Sprintf("%d_%s", district.CityId, district.Name)
-	//			name2District[name] = district
-	//			id2District[district.Id] = district
-	//
-	//			if city, ok := id2City[district.CityId]; ok {
-	//				city.Districts = append(city.Districts, district)
-	//			}
-	//		}
-	//	}
-	//}
+// GetAreaByGBCode 根据GB/T 2260代码获取area，代码可以是任意层级
+func (this *AreaService) GetAreaByGBCode(code string) *Area {
+	return getAreaIndex().code2Area[code]
 }
 
 const areaJSON = `{
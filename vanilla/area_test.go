@@ -0,0 +1,90 @@
+package vanilla
+
+import (
+	"testing"
+)
+
+func TestAreaService_ParseAddress(t *testing.T) {
+	service := NewAreaService()
+
+	area, detail := service.ParseAddress("浙江省 杭州市西湖区文三路90号")
+	if area.Province == nil || area.Province.Name != "浙江省" || area.City == nil || area.City.Name != "杭州市" || area.District == nil || area.District.Name != "西湖区" {
+		t.Fatalf("wrong area: %+v", area)
+	}
+	if detail != "文三路90号" {
+		t.Errorf("wrong detail: %s", detail)
+	}
+
+	area, detail = service.ParseAddress("北京东城区王府井大街1号")
+	if area.City == nil || area.City.Name != "北京市" || area.District == nil || area.District.Name != "东城区" {
+		t.Fatalf("wrong area: %+v", area)
+	}
+	if detail != "王府井大街1号" {
+		t.Errorf("wrong detail: %s", detail)
+	}
+}
+
+func TestAreaService_GBDataset(t *testing.T) {
+	legacy := getAreaIndex()
+	defer currentAreaIndex.Store(legacy)
+
+	err := SetAreaDataset(&AreaDataset{
+		Version: "test",
+		Divisions: []*AreaDivision{
+			{Code: "330000", Name: "浙江省", Pinyin: "zhe jiang sheng", LegacyId: 11},
+			{Code: "330100", ParentCode: "330000", Name: "杭州市", Pinyin: "hang zhou shi"},
+			{Code: "330106", ParentCode: "330100", Name: "西湖区", Pinyin: "xi hu qu"},
+			{Code: "330106001", ParentCode: "330106", Name: "北山街道", Pinyin: "bei shan jie dao"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewAreaService()
+
+	if GetAreaDatasetVersion() != "test" {
+		t.Errorf("wrong version: %s", GetAreaDatasetVersion())
+	}
+	if province := service.FindProvince("zj"); province == nil || province.Id != 11 || province.Zone != "华东地区" {
+		t.Errorf("wrong province: %+v", province)
+	}
+	if city := service.FindCity(0, "hangzhou"); city == nil || city.Id != 330100 {
+		t.Errorf("wrong city: %+v", city)
+	}
+
+	area := service.GetAreaByGBCode("330106001")
+	if area == nil || area.Street == nil || area.District.Name != "西湖区" {
+		t.Fatalf("wrong area: %+v", area)
+	}
+
+	areas := service.SearchAreas("xihu", 10)
+	if len(areas) != 1 || areas[0].District.Code != "330106" {
+		t.Errorf("wrong search result: %+v", areas)
+	}
+
+	area, detail := service.ParseAddress("杭州西湖区北山街道1号")
+	if area.Province == nil || area.Street == nil || area.Street.Code != "330106001" || detail != "1号" {
+		t.Errorf("wrong area: %+v, detail: %s", area, detail)
+	}
+}
+
+func TestAreaService_LegacyDataset(t *testing.T) {
+	if GetAreaDatasetVersion() != AREA_DATASET_VERSION_LEGACY {
+		t.Skip("dataset is configured by area::DATASET_PATH")
+	}
+	service := NewAreaService()
+
+	//内置数据集不含拼音，只支持汉字搜索
+	if areas := service.SearchAreas("hangzhou", 10); len(areas) != 0 {
+		t.Errorf("legacy dataset has no pinyin, got %+v", areas)
+	}
+	if areas := service.SearchAreas("杭州", 10); len(areas) == 0 {
+		t.Errorf("legacy dataset should match chinese name")
+	}
+	if area := service.GetAreaByGBCode("330000"); area == nil || area.Province.Name != "浙江省" {
+		t.Errorf("legacy dataset should have GB code of province, got %+v", area)
+	}
+	if area := service.GetAreaByGBCode("330100"); area != nil {
+		t.Errorf("legacy dataset has no GB code of city, got %+v", area)
+	}
+}
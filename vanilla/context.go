@@ -2,6 +2,8 @@ package vanilla
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	
 	//	"github.com/kfchen81/beego"
 	"github.com/kfchen81/beego/orm"
@@ -13,4 +15,31 @@ func GetOrmFromContext(ctx context.Context) orm.Ormer {
 		return nil
 	}
 	return o.(orm.Ormer)
-}
\ No newline at end of file
+}
+
+// CommitAllTx 逐层提交未结束的嵌套事务(savepoint)，直到最外层事务
+// 提交失败时回滚整个事务，避免RELEASE SAVEPOINT失败后最外层事务一直未结束
+func CommitAllTx(o orm.Ormer) error {
+	for {
+		err := o.Commit()
+		if err == nil {
+			continue
+		}
+		if err == orm.ErrTxDone {
+			return nil
+		}
+		if rerr := o.RollbackAll(); rerr != nil && rerr != orm.ErrTxDone {
+			return errors.New(fmt.Sprintf("%s, rollback error: %s", err.Error(), rerr.Error()))
+		}
+		return err
+	}
+}
+
+// RollbackAllTx 回滚整个事务，包括未结束的嵌套事务(savepoint)
+func RollbackAllTx(o orm.Ormer) error {
+	err := o.RollbackAll()
+	if err == orm.ErrTxDone {
+		return nil
+	}
+	return err
+}
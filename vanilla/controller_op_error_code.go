@@ -0,0 +1,18 @@
+package vanilla
+
+import (
+	"github.com/kfchen81/beego"
+)
+
+//OpErrorCodeController 导出服务注册的所有错误码，供客户端生成提示与多语言
+type OpErrorCodeController struct {
+	beego.Controller
+}
+
+func (c *OpErrorCodeController) Get() {
+	c.Data["json"] = MakeResponse(Map{
+		"service":     beego.AppConfig.String("appname"),
+		"error_codes": GetErrorCatalog(),
+	})
+	c.ServeJSON()
+}
@@ -22,14 +22,54 @@ func (c *OpHealthController) Get() {
 	k8sEnv := os.Getenv("_K8S_ENV")
 	now := time.Now().Format("2006-01-02 15:04:05")
 	serviceName := beego.AppConfig.String("appname")
+	isReady, checks := CheckReadiness()
+	//兼容旧的探针：只在不在线时返回503，依赖检查失败通过is_healthy和checks报告
+	if !isReady {
+		c.Ctx.Output.SetStatus(503)
+	}
 	resp := MakeResponse(Map{
 		"service":   serviceName,
-		"is_online": true,
+		"is_online": isReady,
+		"is_healthy": !hasFailedCheck(checks),
 		"time":      now,
 		"image":     string(content),
 		"mode": beegoMode,
 		"k8s_env": k8sEnv,
+		"checks": checks,
 	})
 	c.Data["json"] = resp
 	c.ServeJSON()
 }
+
+//OpLivenessController 存活检查，进程能响应请求即返回200，不检查依赖
+type OpLivenessController struct {
+	beego.Controller
+}
+
+func (c *OpLivenessController) Get() {
+	c.Data["json"] = MakeResponse(Map{
+		"service": beego.AppConfig.String("appname"),
+		"is_alive": true,
+		"uptime": int64(time.Since(healthCheckerInstance.startedAt).Seconds()),
+	})
+	c.ServeJSON()
+}
+
+//OpReadinessController 就绪检查，关键依赖不可用或正在优雅退出时返回503
+type OpReadinessController struct {
+	beego.Controller
+}
+
+func (c *OpReadinessController) Get() {
+	isReady, checks := CheckReadiness()
+	if !isReady {
+		c.Ctx.Output.SetStatus(503)
+	}
+	c.Data["json"] = MakeResponse(Map{
+		"service": beego.AppConfig.String("appname"),
+		"is_ready": isReady,
+		"is_shutting_down": IsShuttingDown(),
+		"checks": checks,
+	})
+	c.ServeJSON()
+}
@@ -34,7 +34,8 @@ func newTaskCtx(args ...bool) *TaskContext{
 		switch len(args) {
 		case 1:
 			usingSlave := args[0]
-			if usingSlave && beego.AppConfig.DefaultBool("slave::ENABLE_SLAVE", false){
+			//注册了replica时，读操作会自动路由到replica，不需要切换db
+			if usingSlave && beego.AppConfig.DefaultBool("slave::ENABLE_SLAVE", false) && !orm.HasReplica("default"){
 				o.Using("slave")
 			}
 		}
@@ -66,7 +67,10 @@ func taskWrapper(task taskInterface) toolbox.TaskFunc{
 		if o != nil && task.IsEnableTx(){
 			o.Begin()
 			fnErr = task.Run(taskCtx)
-			o.Commit()
+			//逐层提交未结束的嵌套事务(savepoint)，失败时回滚整个事务
+			if err := vanilla.CommitAllTx(o); err != nil {
+				beego.Error(fmt.Sprintf("[%s] commit transaction failed: %s", taskName, err.Error()))
+			}
 		}else{
 			fnErr = task.Run(taskCtx)
 		}
@@ -85,7 +89,13 @@ func fetchData(pi pipeInterface){
 				fetchData(pi)
 				errMsg := err.(error).Error()
 				dingMsg := fmt.Sprintf("> goroutine from task(%s) dead \n\n 错误信息: %s \n\n", taskName, errMsg)
-				vanilla.NewDingBot().Use("xiuer").Error(dingMsg)
+				vanilla.Alerter.Fire(&vanilla.Alert{
+					Service:     "cron",
+					Severity:    vanilla.ALERT_ERROR,
+					Title:       "cron goroutine dead",
+					Content:     dingMsg,
+					Fingerprint: fmt.Sprintf("cron:fetch_data:%s", taskName),
+				})
 				beego.CaptureTaskErrorToSentry(context.Background(), errMsg)
 			}
 		}()
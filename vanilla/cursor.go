@@ -0,0 +1,115 @@
+package vanilla
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kfchen81/beego"
+	"strings"
+)
+
+//cursorSecret 游标签名的密钥，由system::CURSOR_SECRET配置，未配置时不能使用游标分页
+var cursorSecret string
+
+var ErrCursorSecretNotConfigured = errors.New("system::CURSOR_SECRET未配置，不能使用游标分页")
+
+//Cursor 游标分页的游标，记录排序键及上一页最后一条数据的排序值
+type Cursor struct {
+	Keys   []string      `json:"k"`
+	Values []interface{} `json:"v"`
+}
+
+//MatchKeys 游标的排序键是否与查询的排序键一致
+func (this *Cursor) MatchKeys(keys []string) bool {
+	if len(this.Keys) != len(keys) {
+		return false
+	}
+	for i, key := range keys {
+		if this.Keys[i] != key {
+			return false
+		}
+	}
+	return true
+}
+
+func signCursor(payload string) string {
+	h := hmac.New(sha256.New, []byte(cursorSecret))
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+//EncodeCursor 将游标编码为带签名的不透明字符串
+func EncodeCursor(cursor *Cursor) (string, error) {
+	if cursorSecret == "" {
+		return "", ErrCursorSecretNotConfigured
+	}
+	content, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(content)
+	return fmt.Sprintf("%s.%s", payload, signCursor(payload)), nil
+}
+
+//DecodeCursor 校验签名并解码游标
+func DecodeCursor(token string) (*Cursor, error) {
+	if cursorSecret == "" {
+		return nil, ErrCursorSecretNotConfigured
+	}
+	items := strings.Split(token, ".")
+	if len(items) != 2 {
+		return nil, errors.New(fmt.Sprintf("无效的cursor 1 - [%s]", token))
+	}
+	payload, signature := items[0], items[1]
+	if !hmac.Equal([]byte(signature), []byte(signCursor(payload))) {
+		return nil, errors.New(fmt.Sprintf("无效的cursor 2 - [%s]", token))
+	}
+
+	content, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("无效的cursor 3 - [%s]", token))
+	}
+	cursor := new(Cursor)
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(cursor); err != nil {
+		return nil, errors.New(fmt.Sprintf("无效的cursor 4 - [%s]", token))
+	}
+
+	//数字还原为int64或float64，避免大整数丢失精度
+	for i, value := range cursor.Values {
+		if number, ok := value.(json.Number); ok {
+			if intValue, err := number.Int64(); err == nil {
+				cursor.Values[i] = intValue
+			} else if floatValue, err := number.Float64(); err == nil {
+				cursor.Values[i] = floatValue
+			}
+		}
+	}
+	return cursor, nil
+}
+
+//CursorNextPageInfo 游标模式的分页结果，TotalCount为-1时表示未统计总数
+type CursorNextPageInfo struct {
+	HasNext     bool
+	NextCursor  string
+	TotalCount  int64
+	IsEstimated bool
+}
+
+func (this CursorNextPageInfo) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"has_next":     this.HasNext,
+		"next_cursor":  this.NextCursor,
+		"total_count":  this.TotalCount,
+		"is_estimated": this.IsEstimated,
+	}
+}
+
+func init() {
+	cursorSecret = beego.AppConfig.String("system::CURSOR_SECRET")
+}
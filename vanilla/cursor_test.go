@@ -0,0 +1,71 @@
+package vanilla
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCursor_EncodeDecode(t *testing.T) {
+	cursorSecret = "test"
+	token, err := EncodeCursor(&Cursor{
+		Keys:   []string{"-created_at", "-id"},
+		Values: []interface{}{"2019-12-01 10:00:00", int64(9007199254740993)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cursor, err := DecodeCursor(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cursor.MatchKeys([]string{"-created_at", "-id"}) {
+		t.Errorf("keys not match: %v", cursor.Keys)
+	}
+	if cursor.MatchKeys([]string{"created_at", "id"}) {
+		t.Errorf("keys should not match: %v", cursor.Keys)
+	}
+	if cursor.Values[0] != "2019-12-01 10:00:00" {
+		t.Errorf("wrong value: %v", cursor.Values[0])
+	}
+	if cursor.Values[1] != int64(9007199254740993) {
+		t.Errorf("wrong value: %v", cursor.Values[1])
+	}
+}
+
+func TestCursor_Tampered(t *testing.T) {
+	cursorSecret = "test"
+	token, _ := EncodeCursor(&Cursor{
+		Keys:   []string{"id"},
+		Values: []interface{}{100},
+	})
+	forged, _ := EncodeCursor(&Cursor{
+		Keys:   []string{"id"},
+		Values: []interface{}{1},
+	})
+
+	items := strings.Split(token, ".")
+	forgedItems := strings.Split(forged, ".")
+	if _, err := DecodeCursor(forgedItems[0] + "." + items[1]); err == nil {
+		t.Error("cursor with mismatched signature should be rejected")
+	}
+	if _, err := DecodeCursor(items[0]); err == nil {
+		t.Error("cursor without signature should be rejected")
+	}
+}
+
+func TestCursor_SecretNotConfigured(t *testing.T) {
+	cursorSecret = "test"
+	token, _ := EncodeCursor(&Cursor{Keys: []string{"id"}, Values: []interface{}{1}})
+
+	cursorSecret = ""
+	defer func() {
+		cursorSecret = "test"
+	}()
+	if _, err := EncodeCursor(&Cursor{Keys: []string{"id"}, Values: []interface{}{1}}); err != ErrCursorSecretNotConfigured {
+		t.Errorf("encode should fail without secret, got %v", err)
+	}
+	if _, err := DecodeCursor(token); err != ErrCursorSecretNotConfigured {
+		t.Errorf("decode should fail without secret, got %v", err)
+	}
+}
@@ -13,6 +13,7 @@ var mode string
 var platformName string
 var envName string
 
+//DingBot 钉钉机器人，新代码请使用Alerter以获得分组限流与路由
 type DingBot struct{
 	apiUrl string
 	token string
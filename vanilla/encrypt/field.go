@@ -0,0 +1,108 @@
+package encrypt
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/kfchen81/beego"
+	"github.com/kfchen81/beego/orm"
+)
+
+var fieldKeyring = newKeyring()
+
+// SetFieldKeys 设置字段加密的密钥，通常由encrypt::FIELD_KEYS配置
+func SetFieldKeys(keys map[byte][]byte, activeId byte) error {
+	return fieldKeyring.set(keys, activeId)
+}
+
+// EncryptString 使用AES-GCM加密字符串，返回base64编码的密文，空字符串不加密
+func EncryptString(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	data, err := fieldKeyring.seal([]byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecryptString 解密EncryptString的结果
+func DecryptString(ciphertext string) (string, error) {
+	if ciphertext == "" {
+		return "", nil
+	}
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", ErrInvalidCipherText
+	}
+	plaintext, err := fieldKeyring.open(data)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// EncryptedString 存储时加密的字符串字段，用于手机号、身份证号等个人信息
+// 每次加密使用随机nonce，所以不能用于Filter查询和索引
+//
+//	type Member struct {
+//		Id    int
+//		Phone encrypt.EncryptedString
+//	}
+type EncryptedString string
+
+// String 返回明文
+func (this EncryptedString) String() string {
+	return string(this)
+}
+
+// FieldType implements orm.Fielder, 密文存储在text字段中
+func (this EncryptedString) FieldType() int {
+	return orm.TypeTextField
+}
+
+// RawValue implements orm.Fielder, 返回密文，未配置密钥时panic
+// orm的Insert、Update使用CheckedRawValue，未配置密钥时返回错误
+func (this EncryptedString) RawValue() interface{} {
+	ciphertext, err := EncryptString(string(this))
+	if err != nil {
+		panic(err)
+	}
+	return ciphertext
+}
+
+// CheckedRawValue implements orm.CheckedFielder, 返回密文，未配置密钥时返回ErrNoKey
+func (this *EncryptedString) CheckedRawValue() (interface{}, error) {
+	return EncryptString(string(*this))
+}
+
+// SetRaw implements orm.Fielder, 解密数据库中的密文
+func (this *EncryptedString) SetRaw(value interface{}) error {
+	var ciphertext string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		ciphertext = v
+	case []byte:
+		ciphertext = string(v)
+	default:
+		return errors.New(fmt.Sprintf("encrypt: can not set %T to EncryptedString", value))
+	}
+	plaintext, err := DecryptString(ciphertext)
+	if err != nil {
+		return err
+	}
+	*this = EncryptedString(plaintext)
+	return nil
+}
+
+func init() {
+	keys, activeId, err := parseKeys(beego.AppConfig.String("encrypt::FIELD_KEYS"), beego.AppConfig.String("encrypt::FIELD_ACTIVE_KEY_ID"))
+	if err == nil {
+		err = SetFieldKeys(keys, activeId)
+	}
+	if err != nil {
+		beego.Error(fmt.Sprintf("[encrypt] load field keys failed: %s", err.Error()))
+	}
+}
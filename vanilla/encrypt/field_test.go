@@ -0,0 +1,61 @@
+package encrypt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kfchen81/beego/orm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type EncryptTestMember struct {
+	Id    int
+	Phone EncryptedString
+}
+
+func TestEncryptedString(t *testing.T) {
+	dir, err := os.MkdirTemp("", "vanilla_encrypt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	orm.RegisterModel(new(EncryptTestMember))
+	if err := orm.RegisterDataBase("default", "sqlite3", filepath.Join(dir, "encrypt.db")); err != nil {
+		t.Fatal(err)
+	}
+	if err := orm.RunSyncdb("default", true, false); err != nil {
+		t.Fatal(err)
+	}
+	o := orm.NewOrm()
+
+	//未配置密钥时返回错误，不panic
+	SetFieldKeys(nil, 0)
+	member := &EncryptTestMember{Phone: "13800000000"}
+	if _, err := o.Insert(member); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("insert without key should fail with ErrNoKey, got %v", err)
+	}
+
+	if err := SetFieldKeys(map[byte][]byte{1: []byte("0123456789abcdef")}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Insert(member); err != nil {
+		t.Fatal(err)
+	}
+	var phone string
+	o.Raw("SELECT phone FROM encrypt_test_member WHERE id = ?", member.Id).QueryRow(&phone)
+	if phone == "" || phone == "13800000000" {
+		t.Errorf("phone should be encrypted, got %s", phone)
+	}
+	read := &EncryptTestMember{Id: member.Id}
+	if err := o.Read(read); err != nil || read.Phone != "13800000000" {
+		t.Fatalf("read failed: %+v, %v", read, err)
+	}
+
+	SetFieldKeys(nil, 0)
+	member.Phone = "13900000000"
+	if _, err := o.Update(member); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("update without key should fail with ErrNoKey, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//加密数据的格式版本
+const _CIPHER_VERSION_1 byte = 1
+
+var ErrNoKey = errors.New("encrypt: no key configured")
+var ErrUnknownKey = errors.New("encrypt: unknown key id")
+var ErrInvalidCipherText = errors.New("encrypt: invalid cipher text")
+
+//keyring 一组AES-GCM密钥，使用activeId加密，使用密文中的key id解密
+//轮换密钥时先增加新key并设为active，待旧密文过期（或重新加密）后再删除旧key
+type keyring struct {
+	lock     sync.RWMutex
+	aeads    map[byte]cipher.AEAD
+	activeId byte
+}
+
+func newKeyring() *keyring {
+	return &keyring{aeads: make(map[byte]cipher.AEAD)}
+}
+
+//set 设置密钥，keys的值为16、24或32字节的AES密钥
+func (this *keyring) set(keys map[byte][]byte, activeId byte) error {
+	aeads := make(map[byte]cipher.AEAD, len(keys))
+	for id, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return errors.New(fmt.Sprintf("encrypt: invalid key %d: %s", id, err.Error()))
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		aeads[id] = aead
+	}
+	if len(aeads) > 0 {
+		if _, ok := aeads[activeId]; !ok {
+			return errors.New(fmt.Sprintf("encrypt: active key %d is not configured", activeId))
+		}
+	}
+
+	this.lock.Lock()
+	this.aeads = aeads
+	this.activeId = activeId
+	this.lock.Unlock()
+	return nil
+}
+
+//hasKey 是否配置了密钥
+func (this *keyring) hasKey() bool {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return len(this.aeads) > 0
+}
+
+//seal 加密，返回 version(1) | key id(1) | nonce | ciphertext+tag，头部作为附加数据参与认证
+func (this *keyring) seal(plaintext []byte) ([]byte, error) {
+	this.lock.RLock()
+	aead, ok := this.aeads[this.activeId]
+	keyId := this.activeId
+	this.lock.RUnlock()
+	if !ok {
+		return nil, ErrNoKey
+	}
+
+	headerSize := 2 + aead.NonceSize()
+	out := make([]byte, headerSize, headerSize+len(plaintext)+aead.Overhead())
+	out[0] = _CIPHER_VERSION_1
+	out[1] = keyId
+	if _, err := io.ReadFull(rand.Reader, out[2:headerSize]); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, out[2:headerSize], plaintext, out[:2]), nil
+}
+
+//open 解密seal的结果
+func (this *keyring) open(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != _CIPHER_VERSION_1 {
+		return nil, ErrInvalidCipherText
+	}
+	this.lock.RLock()
+	aead, ok := this.aeads[data[1]]
+	this.lock.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	headerSize := 2 + aead.NonceSize()
+	if len(data) < headerSize+aead.Overhead() {
+		return nil, ErrInvalidCipherText
+	}
+	plaintext, err := aead.Open(nil, data[2:headerSize], data[headerSize:], data[:2])
+	if err != nil {
+		return nil, ErrInvalidCipherText
+	}
+	return plaintext, nil
+}
+
+//parseKeys 解析"id:base64_key,id:base64_key"格式的密钥配置，未指定activeId时使用最大的key id
+func parseKeys(value string, activeIdStr string) (keys map[byte][]byte, activeId byte, err error) {
+	keys = make(map[byte][]byte)
+	ids := make([]int, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pos := strings.Index(item, ":")
+		if pos == -1 {
+			return nil, 0, errors.New("encrypt: key should be in format id:base64_key")
+		}
+		id, err := strconv.ParseUint(item[:pos], 10, 8)
+		if err != nil {
+			return nil, 0, errors.New(fmt.Sprintf("encrypt: invalid key id '%s'", item[:pos]))
+		}
+		key, err := base64.StdEncoding.DecodeString(item[pos+1:])
+		if err != nil {
+			return nil, 0, errors.New(fmt.Sprintf("encrypt: key %d is not valid base64", id))
+		}
+		keys[byte(id)] = key
+		ids = append(ids, int(id))
+	}
+	if len(ids) == 0 {
+		return keys, 0, nil
+	}
+
+	if activeIdStr == "" {
+		sort.Ints(ids)
+		return keys, byte(ids[len(ids)-1]), nil
+	}
+	id, err := strconv.ParseUint(activeIdStr, 10, 8)
+	if err != nil {
+		return nil, 0, errors.New(fmt.Sprintf("encrypt: invalid active key id '%s'", activeIdStr))
+	}
+	return keys, byte(id), nil
+}
@@ -1,18 +1,111 @@
 package encrypt
 
 import (
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/kfchen81/beego"
+	"github.com/kfchen81/beego/metrics"
 	"net/url"
 	"strings"
+	"time"
 )
 
 const _MAGIC_CODE = "<->";
 
+var ErrInvalidToken = errors.New("invalid token")
+var ErrTokenExpired = errors.New("token expired")
+
+var tokenKeyring = newKeyring()
+var tokenTTL time.Duration
+var enableLegacyToken = false
+
+// SetTokenKeys 设置corp token的密钥，通常由encrypt::TOKEN_KEYS配置
+func SetTokenKeys(keys map[byte][]byte, activeId byte) error {
+	return tokenKeyring.set(keys, activeId)
+}
+
+// SetLegacyTokenEnabled 是否兼容旧的hex格式token，通常由encrypt::ENABLE_LEGACY_TOKEN配置
+func SetLegacyTokenEnabled(enabled bool) {
+	enableLegacyToken = enabled
+}
+
+// NewToken 生成AES-GCM加密的token，ttl为0时不过期
+// token格式为base64url(version | key id | nonce | AES-GCM(expire_at | len(key1) | key1 | key2))
+func NewToken(key1 string, key2 string, ttl time.Duration) (string, error) {
+	var expireAt int64
+	if ttl > 0 {
+		expireAt = time.Now().Add(ttl).Unix()
+	}
+	payload := make([]byte, 8+binary.MaxVarintLen64, 8+binary.MaxVarintLen64+len(key1)+len(key2))
+	binary.BigEndian.PutUint64(payload, uint64(expireAt))
+	n := binary.PutUvarint(payload[8:], uint64(len(key1)))
+	payload = append(payload[:8+n], key1...)
+	payload = append(payload, key2...)
+
+	data, err := tokenKeyring.seal(payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// ParseToken 解析NewToken生成的token
+func ParseToken(token string) (string, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", "", ErrInvalidToken
+	}
+	payload, err := tokenKeyring.open(data)
+	if err != nil {
+		return "", "", err
+	}
+	if len(payload) < 8 {
+		return "", "", ErrInvalidToken
+	}
+	expireAt := int64(binary.BigEndian.Uint64(payload))
+	if expireAt > 0 && time.Now().Unix() >= expireAt {
+		return "", "", ErrTokenExpired
+	}
+	key1Len, n := binary.Uvarint(payload[8:])
+	if n <= 0 || uint64(len(payload)-8-n) < key1Len {
+		return "", "", ErrInvalidToken
+	}
+	key1 := payload[8+n : 8+n+int(key1Len)]
+	key2 := payload[8+n+int(key1Len):]
+	return string(key1), string(key2), nil
+}
+
+// EncodeToken 使用encrypt::TOKEN_TTL生成token
+// 未配置encrypt::TOKEN_KEYS时panic，不再生成可以被伪造的旧格式token
+func EncodeToken(key1 string, key2 string) string {
+	token, err := NewToken(key1, key2, tokenTTL)
+	if err == ErrNoKey {
+		beego.Error("[encrypt] encrypt::TOKEN_KEYS is not configured, cannot encode token")
+	}
+	if err != nil {
+		panic(err)
+	}
+	return token
+}
+
+// DecodeToken 解析token，开启encrypt::ENABLE_LEGACY_TOKEN时兼容旧的hex格式token
+func DecodeToken(token string) (string, string, error) {
+	key1, key2, err := ParseToken(token)
+	if err == nil || err == ErrTokenExpired {
+		return key1, key2, err
+	}
+	if enableLegacyToken && isLegacyToken(token) {
+		metrics.GetLegacyCorpTokenCounter().WithLabelValues("decode").Inc()
+		return decodeLegacyToken(token)
+	}
+	return "", "", err
+}
+
 func hexCharCodeToStr(hexCode string) string {
-	if hexCode[0] == '0' && (hexCode[1] == 'x' || hexCode[1] == 'X') {
+	if len(hexCode) >= 2 && hexCode[0] == '0' && (hexCode[1] == 'x' || hexCode[1] == 'X') {
 		hexCode = hexCode[2:]
 	}
 	
@@ -36,10 +129,28 @@ func hexCharCodeToStr(hexCode string) string {
 	}
 }
 
-func DecodeToken(token string) (string, string, error) {
+//isLegacyToken 旧格式的token为大写hex字符串，每个字符后跟一个'0'
+func isLegacyToken(token string) bool {
+	if len(token) == 0 || len(token) % 2 != 0 {
+		return false
+	}
+	for i := 0; i < len(token); i++ {
+		c := token[i]
+		if i % 2 == 1 && c != '0' {
+			return false
+		}
+		if !('0' <= c && c <= '9' || 'A' <= c && c <= 'F' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Deprecated: 旧格式的token只是hex编码，可以被伪造
+func decodeLegacyToken(token string) (string, string, error) {
 	count := len(token)
 	if count == 0 || count % 2 != 0 {
-		return "", "", errors.New("invalid token")
+		return "", "", ErrInvalidToken
 	}
 	
 	decodedHexBytes := make([]byte, 0)
@@ -51,6 +162,9 @@ func DecodeToken(token string) (string, string, error) {
 	hexCode := string(decodedHexBytes)
 	originStr := hexCharCodeToStr(strings.Trim(hexCode, " "))
 	items := strings.Split(originStr, "_<->_")
+	if len(items) < 2 {
+		return "", "", ErrInvalidToken
+	}
 	return items[0], items[1], nil
 }
 
@@ -62,7 +176,8 @@ func strToHexCharCode(str string) string {
 	return hex.EncodeToString([]byte(str))
 }
 
-func EncodeToken(key1 string, key2 string) string {
+// Deprecated: 旧格式的token只是hex编码，可以被伪造
+func encodeLegacyToken(key1 string, key2 string) string {
 	str := fmt.Sprintf("%s_%s_%s", key1, _MAGIC_CODE, key2)
 	hexStr := strToHexCharCode(str)
 	
@@ -74,4 +189,19 @@ func EncodeToken(key1 string, key2 string) string {
 	}
 	
 	return strings.ToUpper(string(buf))
-}
\ No newline at end of file
+}
+
+func init() {
+	keys, activeId, err := parseKeys(beego.AppConfig.String("encrypt::TOKEN_KEYS"), beego.AppConfig.String("encrypt::TOKEN_ACTIVE_KEY_ID"))
+	if err == nil {
+		err = SetTokenKeys(keys, activeId)
+	}
+	if err != nil {
+		beego.Error(fmt.Sprintf("[encrypt] load token keys failed: %s", err.Error()))
+	}
+	tokenTTL = time.Duration(beego.AppConfig.DefaultInt64("encrypt::TOKEN_TTL", 0)) * time.Second
+	enableLegacyToken = beego.AppConfig.DefaultBool("encrypt::ENABLE_LEGACY_TOKEN", false)
+	if !tokenKeyring.hasKey() {
+		beego.Error("[encrypt] encrypt::TOKEN_KEYS is not configured, corp token cannot be encoded or decoded")
+	}
+}
@@ -0,0 +1,88 @@
+package encrypt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToken(t *testing.T) {
+	if err := SetTokenKeys(map[byte][]byte{1: []byte("0123456789abcdef0123456789abcdef")}, 1); err != nil {
+		t.Fatal(err)
+	}
+	token, err := NewToken("123", "openid_<->_x", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key1, key2, err := DecodeToken(token)
+	if err != nil || key1 != "123" || key2 != "openid_<->_x" {
+		t.Fatalf("decode failed: %s, %s, %v", key1, key2, err)
+	}
+
+	//篡改密文
+	forged := []byte(token)
+	forged[len(forged)-2] ^= 1
+	if _, _, err := DecodeToken(string(forged)); err == nil {
+		t.Error("forged token should fail")
+	}
+
+	//过期
+	expired, _ := NewToken("123", "", time.Nanosecond)
+	if _, _, err := DecodeToken(expired); err != ErrTokenExpired {
+		t.Errorf("expect ErrTokenExpired, got %v", err)
+	}
+
+	//轮换密钥后旧token仍可解析
+	SetTokenKeys(map[byte][]byte{1: []byte("0123456789abcdef0123456789abcdef"), 2: []byte("fedcba9876543210")}, 2)
+	if key1, _, err := DecodeToken(token); err != nil || key1 != "123" {
+		t.Errorf("decode token of old key failed: %v", err)
+	}
+}
+
+func TestLegacyToken(t *testing.T) {
+	legacy := encodeLegacyToken("123", "abc")
+
+	SetLegacyTokenEnabled(false)
+	if _, _, err := DecodeToken(legacy); err == nil {
+		t.Error("legacy token should fail when disabled")
+	}
+
+	SetLegacyTokenEnabled(true)
+	defer SetLegacyTokenEnabled(false)
+	key1, key2, err := DecodeToken(legacy)
+	if err != nil || key1 != "123" || key2 != "abc" {
+		t.Fatalf("decode legacy token failed: %s, %s, %v", key1, key2, err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, activeId, err := parseKeys("1:MDEyMzQ1Njc4OWFiY2RlZg==, 3:ZmVkY2JhOTg3NjU0MzIxMA==", "")
+	if err != nil || len(keys) != 2 || activeId != 3 {
+		t.Fatalf("parse keys failed: %v, %d", err, activeId)
+	}
+	if _, _, err := parseKeys("abc", ""); err == nil {
+		t.Error("invalid keys should fail")
+	}
+}
+
+func TestEncodeToken_NoKey(t *testing.T) {
+	if err := SetTokenKeys(nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer SetTokenKeys(map[byte][]byte{1: []byte("0123456789abcdef0123456789abcdef")}, 1)
+	SetLegacyTokenEnabled(false)
+
+	//未配置密钥时不生成旧格式的token
+	func() {
+		defer func() {
+			if r := recover(); r != ErrNoKey {
+				t.Errorf("expect panic of ErrNoKey, got %v", r)
+			}
+		}()
+		EncodeToken("123", "abc")
+	}()
+
+	//未开启encrypt::ENABLE_LEGACY_TOKEN时拒绝旧格式的token
+	if _, _, err := DecodeToken(encodeLegacyToken("123", "abc")); err == nil {
+		t.Error("legacy token should fail without keys")
+	}
+}
@@ -0,0 +1,209 @@
+package vanilla
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/kfchen81/beego"
+	"github.com/kfchen81/beego/metrics"
+	"github.com/kfchen81/beego/vanilla/gogap/errors"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+//ErrorCode 在注册表中声明的错误码
+type ErrorCode struct {
+	Code       string `json:"code"`        //即BusinessError.ErrCode，如order:not_found
+	Namespace  string `json:"namespace"`   //默认为Code中:之前的部分
+	Number     uint64 `json:"number"`      //gogap错误模板的数字码，可选
+	HttpStatus int32  `json:"http_status"` //错误响应中的code，默认500
+	Template   string `json:"template"`    //给用户的提示，text/template语法，如"订单{{.id}}不存在"，客户端多语言以Code为key
+	Alert      bool   `json:"alert"`       //发生时是否告警
+	IsSystem   bool   `json:"is_system"`   //是否为系统错误，系统错误计入panic counter
+
+	tmpl *template.Template
+}
+
+var errorCodeLock sync.RWMutex
+var code2errorCode = make(map[string]*ErrorCode)
+var number2errorCode = make(map[string]*ErrorCode) //key为namespace#number
+
+// RegisterErrorCode 注册错误码，错误码重复或模板有误时panic，应在init中调用
+func RegisterErrorCode(errorCode *ErrorCode) *ErrorCode {
+	if errorCode.Code == "" {
+		panic("error code is empty")
+	}
+	if errorCode.Namespace == "" {
+		errorCode.Namespace = strings.SplitN(errorCode.Code, ":", 2)[0]
+	}
+	if errorCode.HttpStatus == 0 {
+		errorCode.HttpStatus = 500
+	}
+	tmpl, err := template.New(errorCode.Code).Parse(errorCode.Template)
+	if err != nil {
+		panic(fmt.Sprintf("parse template of error code %s failed: %s", errorCode.Code, err.Error()))
+	}
+	errorCode.tmpl = tmpl
+
+	errorCodeLock.Lock()
+	defer errorCodeLock.Unlock()
+	if _, ok := code2errorCode[errorCode.Code]; ok {
+		panic(fmt.Sprintf("error code %s already exist", errorCode.Code))
+	}
+	code2errorCode[errorCode.Code] = errorCode
+	metrics.RegisterErrCode(errorCode.Code)
+	if errorCode.Number > 0 {
+		key := fmt.Sprintf("%s#%d", errorCode.Namespace, errorCode.Number)
+		if _, ok := number2errorCode[key]; ok {
+			panic(fmt.Sprintf("error code %s already exist", key))
+		}
+		number2errorCode[key] = errorCode
+	}
+	return errorCode
+}
+
+// DefineErrorCode 注册业务错误码
+func DefineErrorCode(code string, httpStatus int32, template string) *ErrorCode {
+	return RegisterErrorCode(&ErrorCode{
+		Code:       code,
+		HttpStatus: httpStatus,
+		Template:   template,
+	})
+}
+
+// RegisterErrorCodeTemplate 将gogap的错误模板注册为错误码，使其产生的ErrCode也能按注册表渲染
+func RegisterErrorCodeTemplate(tpl *errors.ErrCodeTemplate, code string, httpStatus int32) *ErrorCode {
+	return RegisterErrorCode(&ErrorCode{
+		Code:       code,
+		Namespace:  tpl.Namespace(),
+		Number:     tpl.Code(),
+		HttpStatus: httpStatus,
+		Template:   tpl.Template(),
+	})
+}
+
+// GetErrorCode 获取注册的错误码，未注册时返回nil
+func GetErrorCode(code string) *ErrorCode {
+	errorCodeLock.RLock()
+	defer errorCodeLock.RUnlock()
+	return code2errorCode[code]
+}
+
+func getErrorCodeByNumber(namespace string, number uint64) *ErrorCode {
+	errorCodeLock.RLock()
+	defer errorCodeLock.RUnlock()
+	return number2errorCode[fmt.Sprintf("%s#%d", namespace, number)]
+}
+
+// GetErrorCatalog 获取所有注册的错误码，按Code排序
+func GetErrorCatalog() []*ErrorCode {
+	errorCodeLock.RLock()
+	errorCodes := make([]*ErrorCode, 0, len(code2errorCode))
+	for _, errorCode := range code2errorCode {
+		errorCodes = append(errorCodes, errorCode)
+	}
+	errorCodeLock.RUnlock()
+
+	sort.Slice(errorCodes, func(i, j int) bool {
+		return errorCodes[i].Code < errorCodes[j].Code
+	})
+	return errorCodes
+}
+
+// Render 用params渲染用户提示，缺少的参数渲染为空，渲染失败时返回模板本身
+func (this *ErrorCode) Render(params Map) string {
+	if params == nil {
+		params = Map{}
+	}
+	var buf bytes.Buffer
+	if err := this.tmpl.Execute(&buf, params); err != nil {
+		return this.Template
+	}
+	return strings.Replace(buf.String(), "<no value>", "", -1)
+}
+
+// New 创建BusinessError，用户提示由params渲染
+func (this *ErrorCode) New(params ...Map) *BusinessError {
+	merged := Map{}
+	for _, param := range params {
+		for k, v := range param {
+			merged[k] = v
+		}
+	}
+	errType := ERROR_TYPE_BUSINESS
+	if this.IsSystem {
+		errType = ERROR_TYPE_SYSTEM
+	}
+	return &BusinessError{
+		Type:    errType,
+		ErrCode: this.Code,
+		ErrMsg:  this.Render(merged),
+	}
+}
+
+// Is err是否为该错误码产生的错误
+func (this *ErrorCode) Is(err error) bool {
+	switch e := err.(type) {
+	case *BusinessError:
+		return e.ErrCode == this.Code
+	case errors.ErrCode:
+		return this.Number > 0 && e.Namespace() == this.Namespace && e.Code() == this.Number
+	}
+	return false
+}
+
+// RenderError 按注册表渲染panic的错误，返回错误响应及对应的错误码
+// 支持BusinessError与gogap的ErrCode，其他错误及未注册的ErrCode返回nil，由调用方按system:exception渲染
+// 未注册的BusinessError按原有方式渲染，code为500
+func RenderError(err interface{}) (*Response, *ErrorCode) {
+	switch e := err.(type) {
+	case *BusinessError:
+		errorCode := GetErrorCode(e.ErrCode)
+		if errorCode == nil {
+			return MakeErrorResponse(500, e.ErrCode, e.ErrMsg), nil
+		}
+		errMsg := e.ErrMsg
+		if errMsg == "" {
+			errMsg = errorCode.Render(nil)
+		}
+		return MakeErrorResponse(errorCode.HttpStatus, e.ErrCode, errMsg), errorCode
+	case errors.ErrCode:
+		errorCode := getErrorCodeByNumber(e.Namespace(), e.Code())
+		if errorCode == nil {
+			return nil, nil
+		}
+		return MakeErrorResponse(errorCode.HttpStatus, errorCode.Code, e.Error()), errorCode
+	}
+	return nil, nil
+}
+
+// alertErrorCode 需要告警的错误码发生时发送告警，相同错误码的告警会被分组限流
+// 在panic恢复中调用，告警在goroutine中异步发送，不阻塞错误响应
+func alertErrorCode(errorCode *ErrorCode, errMsg string, endpoint string) {
+	alerter := Alerter
+	if alerter == nil {
+		return
+	}
+	alert := &Alert{
+		Service:     beego.AppConfig.String("appname"),
+		Severity:    ALERT_ERROR,
+		Title:       fmt.Sprintf("error %s", errorCode.Code),
+		Content:     fmt.Sprintf("%s\n\nendpoint: %s", errMsg, endpoint),
+		Fingerprint: "error_code:" + errorCode.Code,
+		Labels:      map[string]string{"error_code": errorCode.Code},
+	}
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				beego.Error(fmt.Sprintf("[error_code] fire alert failed: %v", err))
+			}
+		}()
+		alerter.Fire(alert)
+	}()
+}
+
+func init() {
+	//panic未注册的错误时的错误码
+	metrics.RegisterErrCode("system:exception", "restws:exception")
+}
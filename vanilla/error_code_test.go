@@ -0,0 +1,98 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"github.com/kfchen81/beego/context"
+	"github.com/kfchen81/beego/vanilla/gogap/errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorCode(t *testing.T) {
+	errOrderNotFound := RegisterErrorCode(&ErrorCode{
+		Code:       "test_order:not_found",
+		HttpStatus: 404,
+		Template:   "订单{{.id}}不存在",
+	})
+	if errOrderNotFound.Namespace != "test_order" {
+		t.Errorf("wrong namespace: %s", errOrderNotFound.Namespace)
+	}
+
+	be := errOrderNotFound.New(Map{"id": 3})
+	if be.ErrMsg != "订单3不存在" || !errOrderNotFound.Is(be) {
+		t.Errorf("wrong business error: %+v", be)
+	}
+	resp, errorCode := RenderError(be)
+	if resp.Code != 404 || resp.ErrCode != "test_order:not_found" || errorCode != errOrderNotFound {
+		t.Errorf("wrong response: %+v", resp)
+	}
+
+	resp = MakeErrorResponse(0, "test_order:not_found", "")
+	if resp.Code != 404 || resp.ErrMsg != "订单不存在" {
+		t.Errorf("wrong response: %+v", resp)
+	}
+
+	resp, errorCode = RenderError(NewBusinessError("test_order:unknown", "unknown"))
+	if resp.Code != 500 || errorCode != nil {
+		t.Errorf("wrong response: %+v", resp)
+	}
+
+	tpl := errors.TN("TEST_ORDER", 1, "order {{.id}} is closed")
+	errOrderClosed := RegisterErrorCodeTemplate(&tpl, "test_order:closed", 400)
+	resp, errorCode = RenderError(tpl.New(errors.Params{"id": 5}))
+	if resp.Code != 400 || resp.ErrCode != "test_order:closed" || resp.ErrMsg != "order 5 is closed" || errorCode != errOrderClosed {
+		t.Errorf("wrong response: %+v", resp)
+	}
+
+	//未注册的ErrCode由调用方按system:exception渲染
+	tplLocked := errors.TN("TEST_ORDER", 2, "order is locked")
+	resp, errorCode = RenderError(tplLocked.New())
+	if resp != nil || errorCode != nil {
+		t.Errorf("unregistered ErrCode should not be rendered: %+v", resp)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("register duplicated error code should panic")
+		}
+	}()
+	DefineErrorCode("test_order:not_found", 500, "")
+}
+
+func TestRecoverPanic_ErrorCode(t *testing.T) {
+	errPaymentRequired := DefineErrorCode("test_order:payment_required", 402, "需要支付")
+	tpl := errors.TN("TEST_ORDER", 3, "order is expired")
+
+	cases := []struct {
+		err     interface{}
+		status  int
+		code    int
+		errCode string
+	}{
+		{errPaymentRequired.New(), 402, 402, "test_order:payment_required"},
+		{NewBusinessError("test_order:unknown", "unknown"), 200, 500, "test_order:unknown"},
+		{tpl.New(), 200, 531, "system:exception"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", "/order/order/?id=1", nil)
+		ctx := context.NewContext()
+		ctx.Reset(w, r)
+		func() {
+			defer RecoverPanic(ctx)
+			panic(c.err)
+		}()
+
+		resp := struct {
+			Code    int    `json:"code"`
+			ErrCode string `json:"errCode"`
+		}{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != c.status || resp.Code != c.code || resp.ErrCode != c.errCode {
+			t.Errorf("%v: expected %d %d %s, got %d %+v", c.err, c.status, c.code, c.errCode, w.Code, resp)
+		}
+	}
+}
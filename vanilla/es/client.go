@@ -3,6 +3,7 @@ package es
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/kfchen81/beego"
 	"github.com/kfchen81/beego/metrics"
@@ -27,6 +28,9 @@ type ESClient struct{
 	withNoHits bool
 
 	pageInfo *vanilla.PageInfo
+	cursorPageInfo *vanilla.CursorNextPageInfo
+	cursorKeys []string
+	tieBreaker string
 
 	searchResult *elastic.SearchResult
 }
@@ -42,6 +46,12 @@ func (this *ESClient) Select(docType string) *ESClient{
 	return this
 }
 
+// UseTieBreaker 设置游标分页时用于稳定排序的唯一字段，默认为id
+func (this *ESClient) UseTieBreaker(field string) *ESClient{
+	this.tieBreaker = field
+	return this
+}
+
 func (this *ESClient) NoHits() *ESClient{
 	this.withNoHits = true
 	return this
@@ -71,6 +81,18 @@ func (this *ESClient) prepareUpdateData(updateService *elastic.UpdateByQueryServ
 }
 
 func (this *ESClient) Update(data map[string]interface{}, filters map[string]interface{}) error{
+	if vanilla.IsTestRequest(this.Ctx){
+		if vanilla.GetTestDataPolicy("es") == vanilla.REQUEST_MODE_POLICY_SKIP{
+			beego.Info(fmt.Sprintf("[es] skip update index %s in test mode", this.indexName))
+			return nil
+		}
+		taggedData := make(map[string]interface{}, len(data)+1)
+		for k, v := range data{
+			taggedData[k] = v
+		}
+		taggedData[vanilla.REQUEST_MODE_DATA_KEY] = vanilla.REQUEST_MODE_TEST
+		data = taggedData
+	}
 	startTime := time.Now()
 	updateService := this.client.UpdateByQuery(this.indexName).Type(this.docType)
 	this.prepareUpdateData(updateService, data, filters)
@@ -92,7 +114,31 @@ func (this *ESClient) Update(data map[string]interface{}, filters map[string]int
 	return err
 }
 
+// tagTestDocument 为文档加上测试数据标记
+func tagTestDocument(data interface{}) interface{}{
+	bytes, err := json.Marshal(data)
+	if err != nil{
+		return data
+	}
+	doc := make(map[string]interface{})
+	if err := json.Unmarshal(bytes, &doc); err != nil{
+		//不是对象，无法标记
+		return data
+	}
+	doc[vanilla.REQUEST_MODE_DATA_KEY] = vanilla.REQUEST_MODE_TEST
+	return doc
+}
+
+// Push 写入文档
+// TEST模式的请求按es::TEST_MODE_POLICY处理：tag为文档加上_request_mode字段，skip不写入
 func (this *ESClient) Push(id string, data interface{}) {
+	if vanilla.IsTestRequest(this.Ctx){
+		if vanilla.GetTestDataPolicy("es") == vanilla.REQUEST_MODE_POLICY_SKIP{
+			beego.Info(fmt.Sprintf("[es] skip push doc(id:%s) to index %s in test mode", id, this.indexName))
+			return
+		}
+		data = tagTestDocument(data)
+	}
 	// Add a document
 	startTime := time.Now()
 	indexResult, err := this.client.Index().
@@ -130,8 +176,11 @@ func (this *ESClient) Search(filters map[string]interface{}, args ...map[string]
 			// 分页
 			pageInfo := p.(*vanilla.PageInfo)
 			this.pageInfo = pageInfo
-			searchService = searchService.From((pageInfo.Page-1)*pageInfo.CountPerPage).Size(pageInfo.CountPerPage)
+			if !pageInfo.IsCursorMode(){
+				searchService = searchService.From((pageInfo.Page-1)*pageInfo.CountPerPage).Size(pageInfo.CountPerPage)
+			}
 		}
+		sortKeys := make([]string, 0)
 		if p, ok := param["sortAttrs"]; ok && p != nil{
 			// 排序
 			sortAttrs := p.([]string)
@@ -147,8 +196,17 @@ func (this *ESClient) Search(filters map[string]interface{}, args ...map[string]
 					asc = sps[0] == "+"
 				}
 				searchService = searchService.Sort(sortField, asc)
+				if asc{
+					sortKeys = append(sortKeys, sortField)
+				}else{
+					sortKeys = append(sortKeys, "-"+sortField)
+				}
 			}
 		}
+		if this.pageInfo != nil && this.pageInfo.IsCursorMode(){
+			// 游标分页
+			searchService = this.applyCursor(searchService, sortKeys)
+		}
 		if p, ok := param["rawAggs"]; ok && p != nil{
 			// 聚合
 			var aggs map[string]interface{}
@@ -180,9 +238,81 @@ func (this *ESClient) Search(filters map[string]interface{}, args ...map[string]
 		beego.Error(err)
 	}
 	this.searchResult = result
+	if err == nil && this.pageInfo != nil && this.pageInfo.IsCursorMode(){
+		this.fillCursorPageInfo()
+	}
 	return this
 }
 
+// applyCursor 使用search_after实现游标分页，排序键末尾追加tie breaker保证排序稳定
+func (this *ESClient) applyCursor(searchService *elastic.SearchService, sortKeys []string) *elastic.SearchService{
+	tieBreaker := this.tieBreaker
+	if tieBreaker == ""{
+		tieBreaker = "id"
+	}
+	hasTieBreaker := false
+	desc := false
+	for _, key := range sortKeys{
+		desc = strings.HasPrefix(key, "-")
+		if strings.TrimPrefix(key, "-") == tieBreaker{
+			hasTieBreaker = true
+		}
+	}
+	if !hasTieBreaker{
+		searchService = searchService.Sort(tieBreaker, !desc)
+		if desc{
+			tieBreaker = "-" + tieBreaker
+		}
+		sortKeys = append(sortKeys, tieBreaker)
+	}
+
+	this.cursorKeys = sortKeys
+	this.cursorPageInfo = &vanilla.CursorNextPageInfo{
+		TotalCount: -1,
+	}
+
+	if this.pageInfo.Cursor != ""{
+		cursor, err := vanilla.DecodeCursor(this.pageInfo.Cursor)
+		if err != nil{
+			panic(vanilla.NewBusinessError("es:invalid_cursor", err.Error()))
+		}
+		if !cursor.MatchKeys(sortKeys){
+			panic(vanilla.NewBusinessError("es:invalid_cursor", fmt.Sprintf("cursor的排序%v与查询的排序%v不一致", cursor.Keys, sortKeys)))
+		}
+		searchService = searchService.SearchAfter(cursor.Values...)
+	}
+	//多取1个，用于判断是否有下一页
+	return searchService.Size(this.pageInfo.CountPerPage + 1)
+}
+
+// fillCursorPageInfo 根据搜索结果生成下一页的游标
+func (this *ESClient) fillCursorPageInfo(){
+	countMode := this.pageInfo.CountMode
+	if countMode != "" && countMode != vanilla.PAGE_COUNT_MODE_NONE{
+		this.cursorPageInfo.TotalCount = this.searchResult.TotalHits()
+		//与数据库分页一致，estimate模式下的总数标记为估算值
+		this.cursorPageInfo.IsEstimated = countMode == vanilla.PAGE_COUNT_MODE_ESTIMATE
+	}
+	if this.searchResult.Hits == nil{
+		return
+	}
+
+	hits := this.searchResult.Hits.Hits
+	countPerPage := this.pageInfo.CountPerPage
+	if countPerPage > 0 && len(hits) > countPerPage{
+		this.searchResult.Hits.Hits = hits[:countPerPage]
+		this.cursorPageInfo.HasNext = true
+		nextCursor, err := vanilla.EncodeCursor(&vanilla.Cursor{
+			Keys: this.cursorKeys,
+			Values: hits[countPerPage-1].Sort,
+		})
+		if err != nil{
+			panic(vanilla.NewBusinessError("es:invalid_cursor", err.Error()))
+		}
+		this.cursorPageInfo.NextCursor = nextCursor
+	}
+}
+
 // BindRecords 将搜索记录绑定到一个包含struct的slice中
 // container一定是某个slice的地址，如:
 //		var orders []*Order
@@ -215,6 +345,9 @@ func (this *ESClient) GetSearchResult() *elastic.SearchResult{
 }
 
 func (this *ESClient) GetPageResult() vanilla.INextPageInfo{
+	if this.cursorPageInfo != nil{
+		return this.cursorPageInfo
+	}
 	if this.pageInfo != nil{
 		return vanilla.MockPaginate(this.searchResult.TotalHits(), this.pageInfo)
 	}
@@ -275,27 +408,40 @@ func (this *ESClient) GetNestedSumAggWithFilter(nestedName, aggName string) floa
 	return 0
 }
 
-func NewESClient(ctx context.Context) *ESClient{
-
+func esURL() string {
 	host := beego.AppConfig.String("es::ES_SEARCH_HOST")
 	port := beego.AppConfig.String("es::ES_SEARCH_PORT")
+	return "http://"+host+":"+port
+}
+
+//newElasticClient 根据es::ES_*配置创建elastic client
+func newElasticClient() (*elastic.Client, error){
 	user := beego.AppConfig.String("es::ES_AUTH_USER")
 	pwd := beego.AppConfig.String("es::ES_AUTH_SECRET")
 
-	beego.Info(host, port, user, pwd)
-
 	optionFunc := func (c *elastic.Client) error{
 		var err error
 		if user != ""{
 			err = elastic.SetBasicAuth(user, pwd)(c)
 		}
-		err = elastic.SetURL("http://"+host+":"+port)(c)
+		err = elastic.SetURL(esURL())(c)
 		return err
 	}
+	return elastic.NewSimpleClient(optionFunc)
+}
+
+func NewESClient(ctx context.Context) *ESClient{
+
+	host := beego.AppConfig.String("es::ES_SEARCH_HOST")
+	port := beego.AppConfig.String("es::ES_SEARCH_PORT")
+	user := beego.AppConfig.String("es::ES_AUTH_USER")
+	pwd := beego.AppConfig.String("es::ES_AUTH_SECRET")
+
+	beego.Info(host, port, user, pwd)
 
 	client := new(ESClient)
 	client.Ctx = ctx
-	c, err := elastic.NewSimpleClient(optionFunc)
+	c, err := newElasticClient()
 	if err != nil{
 		beego.Error(err)
 		panic(vanilla.NewSystemError("es:link_failed", "连接es服务失败"))
@@ -304,3 +450,23 @@ func NewESClient(ctx context.Context) *ESClient{
 	return client
 }
 
+func init() {
+	vanilla.RegisterHealthCheck("es", func(ctx context.Context) error {
+		if beego.AppConfig.String("es::ES_SEARCH_HOST") == "" {
+			return vanilla.ErrHealthCheckSkipped
+		}
+		c, err := newElasticClient()
+		if err != nil {
+			return err
+		}
+		_, code, err := c.Ping(esURL()).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if code >= 400 {
+			return errors.New(fmt.Sprintf("es ping returns %d", code))
+		}
+		return nil
+	})
+}
+
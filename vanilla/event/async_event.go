@@ -1,8 +1,10 @@
 package event
 
 import (
+	"context"
 	"fmt"
 	"github.com/kfchen81/beego"
+	"github.com/kfchen81/beego/vanilla"
 	"github.com/kfchen81/beego/vanilla/event/engine"
 	"time"
 )
@@ -11,6 +13,19 @@ import (
 type asyncEvent struct{}
 
 func (ae *asyncEvent) Send(event *Event, data map[string]interface{}){
+	ae.send(event, data, vanilla.REQUEST_MODE_PROD)
+}
+
+// SendWithContext 发送消息，TEST模式请求产生的消息发送到测试topic(aliyun::MNS_TEST_TOPIC)
+func (ae *asyncEvent) SendWithContext(ctx context.Context, event *Event, data map[string]interface{}){
+	mode := vanilla.REQUEST_MODE_PROD
+	if vanilla.IsTestRequest(ctx){
+		mode = vanilla.REQUEST_MODE_TEST
+	}
+	ae.send(event, data, mode)
+}
+
+func (ae *asyncEvent) send(event *Event, data map[string]interface{}, mode string){
 	data["_time"] = time.Now().Format("2006-01-02 15:04:05")
 	messageData := map[string]interface{}{
 		"_event_name": event.Name,
@@ -18,7 +33,8 @@ func (ae *asyncEvent) Send(event *Event, data map[string]interface{}){
 	}
 	engineType := beego.AppConfig.String("event::ASYNC_EVENT_ENGINE")
 	if validEngine, ok := engine.Type2Engine[engineType]; ok{
-		validEngine.Send(messageData, event.Tag)
+		//请求模式由engine决定发送的topic，不写入消息体
+		validEngine.Send(messageData, event.Tag, mode)
 	}else{
 		fmt.Printf("[Event] NO ENGINE FOUND")
 	}
@@ -28,4 +44,12 @@ var AsyncEvent *asyncEvent
 
 func init()  {
 	AsyncEvent = new(asyncEvent)
+
+	vanilla.RegisterHealthCheck("event", func(ctx context.Context) error {
+		engineType := beego.AppConfig.String("event::ASYNC_EVENT_ENGINE")
+		if engineType == "" {
+			return vanilla.ErrHealthCheckSkipped
+		}
+		return engine.HealthCheck(engineType)
+	})
 }
\ No newline at end of file
@@ -14,9 +14,9 @@ func newConsoleEngine() *consoleEngine{
 	return eg
 }
 
-func (this *consoleEngine) Send(data map[string]interface{}, tag string){
+func (this *consoleEngine) Send(data map[string]interface{}, tag string, mode string){
 	eventName := data["_event_name"]
-	fmt.Printf("[Event] CONSOLE ENGINE: receive event %s with tag: %s", eventName, tag)
+	fmt.Printf("[Event] CONSOLE ENGINE: receive event %s with tag: %s, mode: %s", eventName, tag, mode)
 }
 
 func init(){
@@ -1,9 +1,15 @@
 package engine
 
+import (
+	"errors"
+	"fmt"
+)
+
 var Type2Engine map[string]iEngine
 
+//iEngine 发送消息，mode为请求模式(PROD/TEST)，作为消息的元数据由engine处理，不写入消息体
 type iEngine interface {
-	Send(data map[string]interface{}, tag string)
+	Send(data map[string]interface{}, tag string, mode string)
 }
 
 func registerEngine(engineType string, eg iEngine){
@@ -11,4 +17,21 @@ func registerEngine(engineType string, eg iEngine){
 		Type2Engine = make(map[string]iEngine)
 	}
 	Type2Engine[engineType] = eg
-}
\ No newline at end of file
+}
+
+//iHealthChecker 可以检查连通性的engine
+type iHealthChecker interface {
+	HealthCheck() error
+}
+
+// HealthCheck 检查engine的连通性，未实现检查的engine直接返回nil
+func HealthCheck(engineType string) error {
+	eg, ok := Type2Engine[engineType]
+	if !ok {
+		return errors.New(fmt.Sprintf("event engine(%s) not found", engineType))
+	}
+	if checker, ok := eg.(iHealthChecker); ok {
+		return checker.HealthCheck()
+	}
+	return nil
+}
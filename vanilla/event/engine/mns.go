@@ -3,6 +3,7 @@ package engine
 import (
 	"encoding/json"
 	"github.com/kfchen81/beego"
+	"github.com/kfchen81/beego/vanilla"
 	ali_mns "github.com/kfchen81/beego/vanilla/aliyun/mns"
 )
 
@@ -11,6 +12,7 @@ type mnsConf struct{
 	accessId string
 	accessKey string
 	topic string
+	testTopic string
 }
 
 var conf *mnsConf
@@ -38,9 +40,13 @@ func (this *mnsEngine) getFormattedMessage(data map[string]interface{}, tag stri
 	}
 }
 
-func (this *mnsEngine) Send(data map[string]interface{}, tag string){
+// Send TEST模式的消息发送到测试topic(aliyun::MNS_TEST_TOPIC)，未配置时发送到topic
+func (this *mnsEngine) Send(data map[string]interface{}, tag string, mode string){
 	client := this.getMnsClient()
 	topicName := conf.topic
+	if mode == vanilla.REQUEST_MODE_TEST && conf.testTopic != ""{
+		topicName = conf.testTopic
+	}
 	topic := ali_mns.NewMNSTopic(topicName, client)
 
 	_, err := topic.PublishMessage(this.getFormattedMessage(data, tag))
@@ -50,6 +56,11 @@ func (this *mnsEngine) Send(data map[string]interface{}, tag string){
 	
 }
 
+// HealthCheck 获取topic属性，检查mns的连通性
+func (this *mnsEngine) HealthCheck() error{
+	_, err := ali_mns.NewMNSTopicManager(this.getMnsClient()).GetTopicAttributes(conf.topic)
+	return err
+}
 
 func init(){
 
@@ -61,4 +72,5 @@ func init(){
 	conf.accessKey = beego.AppConfig.String("aliyun::MNS_ACCESS_KEY")
 	conf.endpoint = beego.AppConfig.String("aliyun::MNS_ENDPOINT")
 	conf.topic = beego.AppConfig.String("aliyun::MNS_TOPIC")
+	conf.testTopic = beego.AppConfig.String("aliyun::MNS_TEST_TOPIC")
 }
\ No newline at end of file
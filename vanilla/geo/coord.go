@@ -0,0 +1,98 @@
+package geo
+
+import (
+	"math"
+)
+
+//GCJ-02使用的克拉索夫斯基椭球参数
+const krasovskyA = 6378245.0
+const krasovskyEE = 0.00669342162296594323
+
+const bd09Pi = math.Pi * 3000.0 / 180.0
+
+// OutOfChina 坐标是否在中国境外，境外坐标不做GCJ-02偏移
+func OutOfChina(lat, lng float64) bool {
+	return lng < 72.004 || lng > 137.8347 || lat < 0.8293 || lat > 55.8271
+}
+
+func transformLat(x, y float64) float64 {
+	ret := -100.0 + 2.0*x + 3.0*y + 0.2*y*y + 0.1*x*y + 0.2*math.Sqrt(math.Abs(x))
+	ret += (20.0*math.Sin(6.0*x*math.Pi) + 20.0*math.Sin(2.0*x*math.Pi)) * 2.0 / 3.0
+	ret += (20.0*math.Sin(y*math.Pi) + 40.0*math.Sin(y/3.0*math.Pi)) * 2.0 / 3.0
+	ret += (160.0*math.Sin(y/12.0*math.Pi) + 320*math.Sin(y*math.Pi/30.0)) * 2.0 / 3.0
+	return ret
+}
+
+func transformLng(x, y float64) float64 {
+	ret := 300.0 + x + 2.0*y + 0.1*x*x + 0.1*x*y + 0.1*math.Sqrt(math.Abs(x))
+	ret += (20.0*math.Sin(6.0*x*math.Pi) + 20.0*math.Sin(2.0*x*math.Pi)) * 2.0 / 3.0
+	ret += (20.0*math.Sin(x*math.Pi) + 40.0*math.Sin(x/3.0*math.Pi)) * 2.0 / 3.0
+	ret += (150.0*math.Sin(x/12.0*math.Pi) + 300.0*math.Sin(x/30.0*math.Pi)) * 2.0 / 3.0
+	return ret
+}
+
+//gcj02Offset WGS84坐标在GCJ-02下的偏移量
+func gcj02Offset(lat, lng float64) (float64, float64) {
+	dLat := transformLat(lng-105.0, lat-35.0)
+	dLng := transformLng(lng-105.0, lat-35.0)
+	radLat := toRadians(lat)
+	magic := math.Sin(radLat)
+	magic = 1 - krasovskyEE*magic*magic
+	sqrtMagic := math.Sqrt(magic)
+	dLat = (dLat * 180.0) / ((krasovskyA * (1 - krasovskyEE)) / (magic * sqrtMagic) * math.Pi)
+	dLng = (dLng * 180.0) / (krasovskyA / sqrtMagic * math.Cos(radLat) * math.Pi)
+	return dLat, dLng
+}
+
+// WGS84ToGCJ02 GPS坐标转换为国测局坐标(高德、腾讯地图使用)
+func WGS84ToGCJ02(lat, lng float64) (float64, float64) {
+	if OutOfChina(lat, lng) {
+		return lat, lng
+	}
+	dLat, dLng := gcj02Offset(lat, lng)
+	return lat + dLat, lng + dLng
+}
+
+// GCJ02ToWGS84 国测局坐标转换为GPS坐标，迭代求解，误差小于0.01米
+func GCJ02ToWGS84(lat, lng float64) (float64, float64) {
+	if OutOfChina(lat, lng) {
+		return lat, lng
+	}
+	wgsLat, wgsLng := lat, lng
+	for i := 0; i < 10; i++ {
+		gcjLat, gcjLng := WGS84ToGCJ02(wgsLat, wgsLng)
+		dLat, dLng := gcjLat-lat, gcjLng-lng
+		wgsLat -= dLat
+		wgsLng -= dLng
+		if math.Abs(dLat) < 1e-9 && math.Abs(dLng) < 1e-9 {
+			break
+		}
+	}
+	return wgsLat, wgsLng
+}
+
+// GCJ02ToBD09 国测局坐标转换为百度坐标
+func GCJ02ToBD09(lat, lng float64) (float64, float64) {
+	z := math.Sqrt(lng*lng+lat*lat) + 0.00002*math.Sin(lat*bd09Pi)
+	theta := math.Atan2(lat, lng) + 0.000003*math.Cos(lng*bd09Pi)
+	return z*math.Sin(theta) + 0.006, z*math.Cos(theta) + 0.0065
+}
+
+// BD09ToGCJ02 百度坐标转换为国测局坐标
+func BD09ToGCJ02(lat, lng float64) (float64, float64) {
+	x := lng - 0.0065
+	y := lat - 0.006
+	z := math.Sqrt(x*x+y*y) - 0.00002*math.Sin(y*bd09Pi)
+	theta := math.Atan2(y, x) - 0.000003*math.Cos(x*bd09Pi)
+	return z * math.Sin(theta), z * math.Cos(theta)
+}
+
+// WGS84ToBD09 GPS坐标转换为百度坐标
+func WGS84ToBD09(lat, lng float64) (float64, float64) {
+	return GCJ02ToBD09(WGS84ToGCJ02(lat, lng))
+}
+
+// BD09ToWGS84 百度坐标转换为GPS坐标
+func BD09ToWGS84(lat, lng float64) (float64, float64) {
+	return GCJ02ToWGS84(BD09ToGCJ02(lat, lng))
+}
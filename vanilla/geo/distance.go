@@ -0,0 +1,127 @@
+package geo
+
+import (
+	"errors"
+	"math"
+)
+
+//EARTH_RADIUS 地球平均半径(米)
+const EARTH_RADIUS = 6371008.8
+
+//WGS84椭球参数
+const wgs84A = 6378137.0
+const wgs84F = 1 / 298.257223563
+const wgs84B = wgs84A * (1 - wgs84F)
+
+var ErrVincentyNotConverge = errors.New("vincenty formula failed to converge")
+
+func toRadians(degree float64) float64 {
+	return degree * math.Pi / 180
+}
+
+func toDegrees(radian float64) float64 {
+	return radian * 180 / math.Pi
+}
+
+// Haversine 使用haversine公式计算两点间的球面距离(米)，近距离时数值稳定
+func Haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLng := toRadians(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EARTH_RADIUS * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Vincenty 使用Vincenty反解公式计算两点在WGS84椭球上的距离(米)，精度为毫米级
+// 两点接近对跖点时可能不收敛，返回ErrVincentyNotConverge
+func Vincenty(lat1, lng1, lat2, lng2 float64) (float64, error) {
+	L := toRadians(lng2 - lng1)
+	U1 := math.Atan((1 - wgs84F) * math.Tan(toRadians(lat1)))
+	U2 := math.Atan((1 - wgs84F) * math.Tan(toRadians(lat2)))
+	sinU1, cosU1 := math.Sin(U1), math.Cos(U1)
+	sinU2, cosU2 := math.Sin(U2), math.Cos(U2)
+
+	lambda := L
+	var sinSigma, cosSigma, sigma, cos2Alpha, cos2SigmaM float64
+	converged := false
+	for i := 0; i < 200; i++ {
+		sinLambda, cosLambda := math.Sin(lambda), math.Cos(lambda)
+		sinSigma = math.Sqrt((cosU2*sinLambda)*(cosU2*sinLambda) +
+			(cosU1*sinU2-sinU1*cosU2*cosLambda)*(cosU1*sinU2-sinU1*cosU2*cosLambda))
+		if sinSigma == 0 {
+			return 0, nil //重合的点
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cos2Alpha = 1 - sinAlpha*sinAlpha
+		if cos2Alpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cos2Alpha
+		} else {
+			cos2SigmaM = 0 //赤道上的两点
+		}
+		C := wgs84F / 16 * cos2Alpha * (4 + wgs84F*(4-3*cos2Alpha))
+		prev := lambda
+		lambda = L + (1-C)*wgs84F*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) < 1e-12 {
+			converged = true
+			break
+		}
+	}
+	if !converged {
+		return 0, ErrVincentyNotConverge
+	}
+
+	uSq := cos2Alpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+	A := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+	B := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+	deltaSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+		B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+	return wgs84B * A * (sigma - deltaSigma), nil
+}
+
+// Distance 计算两点间的距离(米)，优先使用Vincenty公式，不收敛时退化为Haversine公式
+func Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	if distance, err := Vincenty(lat1, lng1, lat2, lng2); err == nil {
+		return distance
+	}
+	return Haversine(lat1, lng1, lat2, lng2)
+}
+
+//Box 经纬度矩形范围
+type Box struct {
+	MinLat float64
+	MinLng float64
+	MaxLat float64
+	MaxLng float64
+}
+
+// Contains 点是否在矩形范围内
+func (this Box) Contains(lat, lng float64) bool {
+	return lat >= this.MinLat && lat <= this.MaxLat && lng >= this.MinLng && lng <= this.MaxLng
+}
+
+// 外接矩形按球面计算，而Distance按椭球计算，两者相差不超过0.5%，因此矩形的半径放大0.5%再加1米
+const boxPaddingRatio = 1.005
+const boxPaddingMeters = 1
+
+// BoundingBox 计算以(lat, lng)为中心、radius(米)为半径的圆的外接矩形
+// 矩形包含所有Distance在radius以内的点，跨越极点或180度经线时，经度范围取[-180, 180]
+func BoundingBox(lat, lng, radius float64) Box {
+	radius = radius*boxPaddingRatio + boxPaddingMeters
+	dLat := toDegrees(radius / EARTH_RADIUS)
+	box := Box{
+		MinLat: math.Max(lat-dLat, -90),
+		MaxLat: math.Min(lat+dLat, 90),
+		MinLng: -180,
+		MaxLng: 180,
+	}
+	if box.MinLat > -90 && box.MaxLat < 90 {
+		dLng := toDegrees(math.Asin(math.Min(1, math.Sin(radius/EARTH_RADIUS)/math.Cos(toRadians(lat)))))
+		if lng-dLng >= -180 && lng+dLng <= 180 {
+			box.MinLng = lng - dLng
+			box.MaxLng = lng + dLng
+		}
+	}
+	return box
+}
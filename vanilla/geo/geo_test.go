@@ -0,0 +1,101 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	//上海人民广场到东方明珠，约3.1公里
+	haversine := Haversine(31.2304, 121.4737, 31.2397, 121.4998)
+	vincenty, err := Vincenty(31.2304, 121.4737, 31.2397, 121.4998)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(haversine-vincenty) > 10 || math.Abs(vincenty-2680) > 100 {
+		t.Errorf("wrong distance: haversine(%f), vincenty(%f)", haversine, vincenty)
+	}
+
+	//近距离时不应出现NaN或0
+	if d := Haversine(31.2304, 121.4737, 31.2304, 121.47371); d <= 0 || math.IsNaN(d) {
+		t.Errorf("wrong distance of close points: %f", d)
+	}
+}
+
+func TestGeohash(t *testing.T) {
+	hash := EncodeGeohash(57.64911, 10.40744, 11)
+	if hash != "u4pruydqqvj" {
+		t.Fatalf("wrong geohash: %s", hash)
+	}
+	lat, lng, err := DecodeGeohash(hash)
+	if err != nil || math.Abs(lat-57.64911) > 1e-5 || math.Abs(lng-10.40744) > 1e-5 {
+		t.Errorf("wrong decode: %f, %f, %v", lat, lng, err)
+	}
+
+	neighbors, _ := GeohashNeighbors("u4pruyd")
+	expected := []string{"u4pruyf", "u4pruyg", "u4pruye", "u4pruy7", "u4pruy6", "u4pruy3", "u4pruy9", "u4pruyc"}
+	for i := range expected {
+		if neighbors[i] != expected[i] {
+			t.Errorf("wrong neighbors: %v", neighbors)
+			break
+		}
+	}
+
+	//覆盖的格子应包含圆上的点
+	lat, lng, radius := 31.2304, 121.4737, 3000.0
+	cover := GeohashCover(lat, lng, radius)
+	box := BoundingBox(lat, lng, radius)
+	precision := len(cover[0])
+	for _, point := range [][2]float64{{box.MinLat, lng}, {box.MaxLat, lng}, {lat, box.MinLng}, {lat, box.MaxLng}} {
+		hash := EncodeGeohash(point[0], point[1], precision)
+		found := false
+		for _, item := range cover {
+			found = found || item == hash
+		}
+		if !found {
+			t.Errorf("point %v not covered by %v", point, cover)
+		}
+	}
+}
+
+func TestCoordTransform(t *testing.T) {
+	lat, lng := 39.908823, 116.397470
+	gcjLat, gcjLng := WGS84ToGCJ02(lat, lng)
+	if math.Abs(gcjLat-lat) < 1e-4 || math.Abs(gcjLng-lng) < 1e-4 {
+		t.Errorf("gcj02 should be offset: %f, %f", gcjLat, gcjLng)
+	}
+	wgsLat, wgsLng := GCJ02ToWGS84(gcjLat, gcjLng)
+	if Haversine(lat, lng, wgsLat, wgsLng) > 0.01 {
+		t.Errorf("wrong gcj02 to wgs84: %f, %f", wgsLat, wgsLng)
+	}
+	wgsLat, wgsLng = BD09ToWGS84(WGS84ToBD09(lat, lng))
+	if Haversine(lat, lng, wgsLat, wgsLng) > 1 {
+		t.Errorf("wrong bd09 round trip: %f, %f", wgsLat, wgsLng)
+	}
+
+	//境外坐标不偏移
+	if lat, lng := WGS84ToGCJ02(51.5074, -0.1278); lat != 51.5074 || lng != -0.1278 {
+		t.Errorf("should not offset out of china: %f, %f", lat, lng)
+	}
+}
+
+func TestBoundingBox_ContainsDistance(t *testing.T) {
+	//沿经线和纬线找到距离刚好小于radius的点，这些点应在外接矩形内
+	lat, lng, radius := 30.0, 120.0, 10000.0
+	box := BoundingBox(lat, lng, radius)
+	for _, direction := range [][2]float64{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+		low, high := 0.0, 1.0
+		for i := 0; i < 60; i++ {
+			mid := (low + high) / 2
+			if Distance(lat, lng, lat+direction[0]*mid, lng+direction[1]*mid) < radius-0.01 {
+				low = mid
+			} else {
+				high = mid
+			}
+		}
+		pointLat, pointLng := lat+direction[0]*low, lng+direction[1]*low
+		if !box.Contains(pointLat, pointLng) {
+			t.Errorf("(%f, %f) of distance %f should be in box %+v", pointLat, pointLng, Distance(lat, lng, pointLat, pointLng), box)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const geohashBase32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+const GEOHASH_MAX_PRECISION = 12
+
+//geohashCellHeights 各精度的geohash格子高度(米)，由纬度跨度换算
+var geohashCellHeights = func() []float64 {
+	heights := make([]float64, GEOHASH_MAX_PRECISION+1)
+	for precision := 1; precision <= GEOHASH_MAX_PRECISION; precision++ {
+		latBits := (precision * 5) / 2
+		heights[precision] = 180 / math.Pow(2, float64(latBits)) * math.Pi / 180 * EARTH_RADIUS
+	}
+	return heights
+}()
+
+// EncodeGeohash 将经纬度编码为指定精度(1~12)的geohash
+func EncodeGeohash(lat, lng float64, precision int) string {
+	if precision < 1 {
+		precision = 1
+	} else if precision > GEOHASH_MAX_PRECISION {
+		precision = GEOHASH_MAX_PRECISION
+	}
+
+	minLat, maxLat := -90.0, 90.0
+	minLng, maxLng := -180.0, 180.0
+	var hash strings.Builder
+	bit, ch := 0, 0
+	isLng := true
+	for hash.Len() < precision {
+		if isLng {
+			mid := (minLng + maxLng) / 2
+			if lng >= mid {
+				ch = ch<<1 | 1
+				minLng = mid
+			} else {
+				ch = ch << 1
+				maxLng = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch = ch << 1
+				maxLat = mid
+			}
+		}
+		isLng = !isLng
+		bit++
+		if bit == 5 {
+			hash.WriteByte(geohashBase32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return hash.String()
+}
+
+// DecodeGeohashBox 将geohash解码为对应的矩形范围
+func DecodeGeohashBox(hash string) (Box, error) {
+	box := Box{MinLat: -90, MaxLat: 90, MinLng: -180, MaxLng: 180}
+	if hash == "" {
+		return box, errors.New("empty geohash")
+	}
+	isLng := true
+	for _, c := range strings.ToLower(hash) {
+		index := strings.IndexRune(geohashBase32, c)
+		if index < 0 {
+			return box, errors.New(fmt.Sprintf("invalid geohash: %s", hash))
+		}
+		for mask := 16; mask > 0; mask >>= 1 {
+			if isLng {
+				mid := (box.MinLng + box.MaxLng) / 2
+				if index&mask != 0 {
+					box.MinLng = mid
+				} else {
+					box.MaxLng = mid
+				}
+			} else {
+				mid := (box.MinLat + box.MaxLat) / 2
+				if index&mask != 0 {
+					box.MinLat = mid
+				} else {
+					box.MaxLat = mid
+				}
+			}
+			isLng = !isLng
+		}
+	}
+	return box, nil
+}
+
+// DecodeGeohash 将geohash解码为格子中心的经纬度
+func DecodeGeohash(hash string) (float64, float64, error) {
+	box, err := DecodeGeohashBox(hash)
+	if err != nil {
+		return 0, 0, err
+	}
+	return (box.MinLat + box.MaxLat) / 2, (box.MinLng + box.MaxLng) / 2, nil
+}
+
+// GeohashNeighbors 获取geohash周围的8个格子，顺序为北、东北、东、东南、南、西南、西、西北
+// 极点附近越界的格子会被忽略
+func GeohashNeighbors(hash string) ([]string, error) {
+	box, err := DecodeGeohashBox(hash)
+	if err != nil {
+		return nil, err
+	}
+	lat := (box.MinLat + box.MaxLat) / 2
+	lng := (box.MinLng + box.MaxLng) / 2
+	height := box.MaxLat - box.MinLat
+	width := box.MaxLng - box.MinLng
+
+	directions := [][2]float64{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	neighbors := make([]string, 0, len(directions))
+	for _, direction := range directions {
+		neighborLat := lat + direction[0]*height
+		if neighborLat > 90 || neighborLat < -90 {
+			continue
+		}
+		neighborLng := lng + direction[1]*width
+		if neighborLng > 180 {
+			neighborLng -= 360
+		} else if neighborLng < -180 {
+			neighborLng += 360
+		}
+		neighbors = append(neighbors, EncodeGeohash(neighborLat, neighborLng, len(hash)))
+	}
+	return neighbors, nil
+}
+
+// GeohashPrecisionForRadius 获取纬度lat处格子宽高都不小于radius(米)的最大精度
+// 使用该精度时，圆心所在格子及其8个邻居一定能覆盖整个圆
+func GeohashPrecisionForRadius(lat, radius float64) int {
+	for precision := GEOHASH_MAX_PRECISION; precision > 1; precision-- {
+		width := geohashCellWidth(precision) * math.Cos(toRadians(lat))
+		if geohashCellHeights[precision] >= radius && width >= radius {
+			return precision
+		}
+	}
+	return 1
+}
+
+//geohashCellWidth 赤道处的格子宽度(米)
+func geohashCellWidth(precision int) float64 {
+	lngBits := (precision*5 + 1) / 2
+	return 360 / math.Pow(2, float64(lngBits)) * math.Pi / 180 * EARTH_RADIUS
+}
+
+// GeohashCover 获取覆盖以(lat, lng)为中心、radius(米)为半径的圆的geohash列表(中心格子及其邻居)
+func GeohashCover(lat, lng, radius float64) []string {
+	hash := EncodeGeohash(lat, lng, GeohashPrecisionForRadius(lat, radius))
+	neighbors, _ := GeohashNeighbors(hash)
+	return append([]string{hash}, neighbors...)
+}
@@ -2,10 +2,15 @@ package vanilla
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/kfchen81/beego"
-	"math"
+	"github.com/kfchen81/beego/orm"
+	"github.com/kfchen81/beego/vanilla/geo"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 const MAX_DISTANCE = -1
@@ -20,55 +25,153 @@ func NewGeoService(ctx context.Context) *GeoService {
 	return service
 }
 
+// CalculateDistance 计算两点间的距离(千米)
 func (this *GeoService) CalculateDistance(lat1, lng1, lat2, lng2 float64) float64 {
-	radius := float64(6371000)
-	rad := math.Pi / 180.0
-	
-	lat1 = lat1 * rad
-	lng1 = lng1 * rad
-	lat2 = lat2 * rad
-	lng2 = lng2 * rad
-	theta := lng2 - lng1
-	
-	distance := math.Acos(math.Sin(lat1)*math.Sin(lat2) + math.Cos(lat1)*math.Cos(lat2)*math.Cos(theta))
-	
-	value := distance * radius / 1000
-	value, _ = strconv.ParseFloat(fmt.Sprintf("%.3f", value), 64)
-	
-	return value
+	return geo.Haversine(lat1, lng1, lat2, lng2) / 1000
 }
 
 func (this *GeoService) CalculateDistanceUseStr(strLat1, strLng1, strLat2, strLng2 string) float64 {
 	if strLat1 == "" || strLng1 == "" || strLat2 == "" || strLng2 == "" {
 		return MAX_DISTANCE
 	}
-	
+
 	lat1, err := strconv.ParseFloat(strLat1, 64)
 	if err != nil {
 		beego.Error(err)
 		return MAX_DISTANCE
 	}
-	
+
 	lng1, err := strconv.ParseFloat(strLng1, 64)
 	if err != nil {
 		beego.Error(err)
 		return MAX_DISTANCE
 	}
-	
+
 	lat2, err := strconv.ParseFloat(strLat2, 64)
 	if err != nil {
 		beego.Error(err)
 		return MAX_DISTANCE
 	}
-	
+
 	lng2, err := strconv.ParseFloat(strLng2, 64)
 	if err != nil {
 		beego.Error(err)
 		return MAX_DISTANCE
 	}
-	
+
 	return this.CalculateDistance(lat1, lng1, lat2, lng2)
 }
 
+// FilterBoundingBox 为qs加上以(lat, lng)为中心、radius(千米)为半径的外接矩形过滤条件
+// latField、lngField为model中存储纬度、经度的字段，字段需为数值类型才能正确比较
+func (this *GeoService) FilterBoundingBox(qs orm.QuerySeter, latField string, lngField string, lat float64, lng float64, radius float64) orm.QuerySeter {
+	box := geo.BoundingBox(lat, lng, radius*1000)
+	return qs.Filter(latField+"__gte", box.MinLat).
+		Filter(latField+"__lte", box.MaxLat).
+		Filter(lngField+"__gte", box.MinLng).
+		Filter(lngField+"__lte", box.MaxLng)
+}
+
+// FilterGeohash 为qs加上geohash前缀过滤条件，hashField为model中存储geohash的字段
+// 字段中的geohash精度需不小于geo.GeohashPrecisionForRadius返回的精度
+func (this *GeoService) FilterGeohash(qs orm.QuerySeter, hashField string, lat float64, lng float64, radius float64) orm.QuerySeter {
+	cond := orm.NewCondition()
+	for _, hash := range geo.GeohashCover(lat, lng, radius*1000) {
+		cond = cond.Or(hashField+"__startswith", hash)
+	}
+	origin := qs.GetCond()
+	if origin == nil {
+		origin = orm.NewCondition()
+	}
+	return qs.SetCond(origin.AndCond(cond))
+}
+
+//findGeoField 查找与字段名对应的struct field，忽略大小写与下划线
+func findGeoField(typ reflect.Type, name string) (int, error) {
+	name = strings.ToLower(strings.Replace(name, "_", "", -1))
+	for i := 0; i < typ.NumField(); i++ {
+		if strings.ToLower(typ.Field(i).Name) == name {
+			return i, nil
+		}
+	}
+	return -1, errors.New(fmt.Sprintf("field(%s) not found in %s", name, typ.Name()))
+}
+
+func parseGeoValue(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	case reflect.String:
+		f, err := strconv.ParseFloat(value.String(), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// RefineByDistance 从container(指向struct指针slice的指针)中去掉距离超过radius(千米)的数据，
+// 剩余数据按距离由近到远排序，返回与之一一对应的距离(千米)
+func (this *GeoService) RefineByDistance(container interface{}, latField string, lngField string, lat float64, lng float64, radius float64) ([]float64, error) {
+	ptr := reflect.ValueOf(container)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Slice {
+		return nil, errors.New("container should be a pointer to slice")
+	}
+	slice := ptr.Elem()
+	elemType := slice.Type().Elem()
+	if elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return nil, errors.New("element of container should be struct or pointer to struct")
+	}
+	latIndex, err := findGeoField(elemType, latField)
+	if err != nil {
+		return nil, err
+	}
+	lngIndex, err := findGeoField(elemType, lngField)
+	if err != nil {
+		return nil, err
+	}
+
+	type geoItem struct {
+		value    reflect.Value
+		distance float64
+	}
+	items := make([]*geoItem, 0, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		elem := reflect.Indirect(slice.Index(i))
+		itemLat, ok1 := parseGeoValue(elem.Field(latIndex))
+		itemLng, ok2 := parseGeoValue(elem.Field(lngIndex))
+		if !ok1 || !ok2 {
+			continue
+		}
+		distance := geo.Distance(lat, lng, itemLat, itemLng) / 1000
+		if distance <= radius {
+			items = append(items, &geoItem{slice.Index(i), distance})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].distance < items[j].distance
+	})
+
+	refined := reflect.MakeSlice(slice.Type(), 0, len(items))
+	distances := make([]float64, 0, len(items))
+	for _, item := range items {
+		refined = reflect.Append(refined, item.value)
+		distances = append(distances, item.distance)
+	}
+	slice.Set(refined)
+	return distances, nil
+}
+
+// QueryNearby 查询距离(lat, lng)在radius(千米)以内的数据，先用外接矩形过滤，再按精确距离筛选排序
+// 返回与container中数据一一对应的距离(千米)，矩形内的数据受qs的limit限制
+func (this *GeoService) QueryNearby(qs orm.QuerySeter, latField string, lngField string, lat float64, lng float64, radius float64, container interface{}) ([]float64, error) {
+	_, err := this.FilterBoundingBox(qs, latField, lngField, lat, lng, radius).All(container)
+	if err != nil {
+		return nil, err
+	}
+	return this.RefineByDistance(container, latField, lngField, lat, lng, radius)
+}
+
 func init() {
 }
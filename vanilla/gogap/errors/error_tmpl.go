@@ -107,3 +107,15 @@ func (p *ErrCodeTemplate) IsEqual(err error) bool {
 	}
 	return false
 }
+
+func (p *ErrCodeTemplate) Namespace() string {
+	return p.namespace
+}
+
+func (p *ErrCodeTemplate) Code() uint64 {
+	return p.code
+}
+
+func (p *ErrCodeTemplate) Template() string {
+	return p.template
+}
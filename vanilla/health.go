@@ -0,0 +1,287 @@
+package vanilla
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/kfchen81/beego"
+	"github.com/kfchen81/beego/metrics"
+	"github.com/kfchen81/beego/orm"
+	"github.com/kfchen81/beego/toolbox"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+//ErrHealthCheckSkipped 依赖未配置时返回，不影响健康状态
+var ErrHealthCheckSkipped = errors.New("skipped")
+
+const HEALTH_STATUS_OK = "ok"
+const HEALTH_STATUS_FAIL = "fail"
+const HEALTH_STATUS_SKIPPED = "skipped"
+
+//HealthCheckFunc 依赖检查函数，应在ctx超时后尽快返回
+type HealthCheckFunc func(ctx context.Context) error
+
+//HealthCheckResult 一个依赖的检查结果
+type HealthCheckResult struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	Duration  float64   `json:"duration"` //秒
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type healthCheck struct {
+	name   string
+	fn     HealthCheckFunc
+	lock   sync.Mutex
+	result *HealthCheckResult
+}
+
+//healthChecker 管理已注册的依赖检查，检查结果缓存cacheTTL
+type healthChecker struct {
+	timeout        time.Duration
+	cacheTTL       time.Duration
+	criticalChecks map[string]bool
+
+	lock   sync.RWMutex
+	checks map[string]*healthCheck
+
+	shuttingDown int32
+	startedAt    time.Time
+}
+
+var healthCheckerInstance = &healthChecker{
+	timeout:        3 * time.Second,
+	cacheTTL:       2 * time.Second,
+	criticalChecks: map[string]bool{"db": true, "redis": true, "redis_lock": true},
+	checks:         make(map[string]*healthCheck),
+	startedAt:      time.Now(),
+}
+
+// RegisterHealthCheck 注册依赖检查，同时注册到toolbox，可以在admin的/healthcheck中查看
+// 是否影响readiness由health::CRITICAL_CHECKS配置，默认为db,redis,redis_lock
+func RegisterHealthCheck(name string, fn HealthCheckFunc) {
+	healthCheckerInstance.lock.Lock()
+	healthCheckerInstance.checks[name] = &healthCheck{name: name, fn: fn}
+	healthCheckerInstance.lock.Unlock()
+
+	toolbox.AddHealthCheck(name, toolboxHealthCheck(name))
+}
+
+type toolboxHealthCheck string
+
+func (this toolboxHealthCheck) Check() error {
+	for _, result := range healthCheckerInstance.run(string(this)) {
+		if result.Status == HEALTH_STATUS_FAIL {
+			return errors.New(result.Error)
+		}
+	}
+	return nil
+}
+
+//run 执行指定的检查，names为空时执行所有检查，各检查并发执行
+func (this *healthChecker) run(names ...string) []*HealthCheckResult {
+	this.lock.RLock()
+	checks := make([]*healthCheck, 0, len(this.checks))
+	for name, check := range this.checks {
+		if len(names) == 0 || names[0] == name {
+			checks = append(checks, check)
+		}
+	}
+	this.lock.RUnlock()
+
+	results := make([]*HealthCheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check *healthCheck) {
+			defer wg.Done()
+			results[i] = this.runCheck(check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+func (this *healthChecker) runCheck(check *healthCheck) *HealthCheckResult {
+	check.lock.Lock()
+	defer check.lock.Unlock()
+	if check.result != nil && time.Since(check.result.CheckedAt) < this.cacheTTL {
+		return check.result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), this.timeout)
+	defer cancel()
+	startTime := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				errCh <- errors.New(fmt.Sprintf("panic: %v", err))
+			}
+		}()
+		errCh <- check.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = errors.New(fmt.Sprintf("timeout after %s", this.timeout))
+	}
+
+	result := &HealthCheckResult{
+		Name:      check.name,
+		Status:    HEALTH_STATUS_OK,
+		Critical:  this.criticalChecks[check.name],
+		Duration:  time.Since(startTime).Seconds(),
+		CheckedAt: time.Now(),
+	}
+	if err == ErrHealthCheckSkipped {
+		result.Status = HEALTH_STATUS_SKIPPED
+	} else if err != nil {
+		result.Status = HEALTH_STATUS_FAIL
+		result.Error = err.Error()
+		beego.Warn(fmt.Sprintf("[health] check %s failed: %s", check.name, result.Error))
+	}
+	if result.Status == HEALTH_STATUS_FAIL {
+		metrics.GetHealthCheckGauge().WithLabelValues(check.name).Set(0)
+	} else {
+		metrics.GetHealthCheckGauge().WithLabelValues(check.name).Set(1)
+	}
+	check.result = result
+	return result
+}
+
+// IsShuttingDown 是否正在优雅退出
+func IsShuttingDown() bool {
+	return atomic.LoadInt32(&healthCheckerInstance.shuttingDown) == 1
+}
+
+// CheckReadiness 执行所有依赖检查，正在退出或关键依赖检查失败时返回false
+func CheckReadiness() (bool, []*HealthCheckResult) {
+	results := healthCheckerInstance.run()
+	if IsShuttingDown() {
+		return false, results
+	}
+	for _, result := range results {
+		if result.Critical && result.Status == HEALTH_STATUS_FAIL {
+			return false, results
+		}
+	}
+	return true, results
+}
+
+func hasFailedCheck(results []*HealthCheckResult) bool {
+	for _, result := range results {
+		if result.Status == HEALTH_STATUS_FAIL {
+			return true
+		}
+	}
+	return false
+}
+
+func pingRedisPool(ctx context.Context, p *redis.Pool) error {
+	if p == nil {
+		return ErrHealthCheckSkipped
+	}
+	c, err := p.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = c.Do("PING")
+	return err
+}
+
+func checkDatabases(ctx context.Context) error {
+	names := orm.GetAllDBNames()
+	if len(names) == 0 {
+		return ErrHealthCheckSkipped
+	}
+	sort.Strings(names)
+	errMsgs := make([]string, 0)
+	for _, name := range names {
+		db, err := orm.GetDB(name)
+		if err == nil {
+			err = db.PingContext(ctx)
+		}
+		if err != nil {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s: %s", name, err.Error()))
+		}
+	}
+	if len(errMsgs) > 0 {
+		return errors.New(strings.Join(errMsgs, "; "))
+	}
+	return nil
+}
+
+//waitForShutdown 收到SIGTERM/SIGINT后执行gracefulShutdown，然后恢复信号的默认处理并重新发送信号，使进程按默认行为退出
+func waitForShutdown(delay time.Duration, timeout time.Duration) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	sig := <-sigs
+
+	gracefulShutdown(sig, delay, timeout)
+
+	signal.Stop(sigs)
+	if p, err := os.FindProcess(os.Getpid()); err == nil {
+		p.Signal(sig)
+	}
+}
+
+//gracefulShutdown 先让readiness失败，等待k8s将pod摘除后再关闭server
+func gracefulShutdown(sig os.Signal, delay time.Duration, timeout time.Duration) {
+	atomic.StoreInt32(&healthCheckerInstance.shuttingDown, 1)
+	beego.Info(fmt.Sprintf("[health] receive %s, shutdown after %s", sig, delay))
+	time.Sleep(delay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := beego.BeeApp.Shutdown(ctx); err != nil {
+		beego.Error(fmt.Sprintf("[health] shutdown server failed: %s", err.Error()))
+	}
+}
+
+func init() {
+	healthCheckerInstance.timeout = time.Duration(beego.AppConfig.DefaultInt("health::CHECK_TIMEOUT", 3)) * time.Second
+	healthCheckerInstance.cacheTTL = time.Duration(beego.AppConfig.DefaultInt("health::CACHE_TTL", 2)) * time.Second
+	criticalChecks := beego.AppConfig.DefaultStrings("health::CRITICAL_CHECKS", []string{"db", "redis", "redis_lock"})
+	healthCheckerInstance.criticalChecks = make(map[string]bool)
+	for _, name := range criticalChecks {
+		healthCheckerInstance.criticalChecks[strings.TrimSpace(name)] = true
+	}
+
+	RegisterHealthCheck("db", checkDatabases)
+	RegisterHealthCheck("redis", func(ctx context.Context) error {
+		return pingRedisPool(ctx, pool)
+	})
+	RegisterHealthCheck("redis_lock", func(ctx context.Context) error {
+		return pingRedisPool(ctx, lockRedisPool)
+	})
+
+	//默认不处理信号，避免引入vanilla的程序改变Ctrl-C等信号的行为；grace模式下由grace处理信号
+	if beego.AppConfig.DefaultBool("health::ENABLE_GRACEFUL_SHUTDOWN", false) && !beego.BConfig.Listen.Graceful {
+		defaultDelay := 0
+		if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+			defaultDelay = 5
+		}
+		delay := beego.AppConfig.DefaultInt("health::SHUTDOWN_DELAY", defaultDelay)
+		timeout := beego.AppConfig.DefaultInt("health::SHUTDOWN_TIMEOUT", 20)
+		beego.Info(fmt.Sprintf("[health] graceful shutdown: delay(%ds), timeout(%ds)", delay, timeout))
+		go waitForShutdown(time.Duration(delay)*time.Second, time.Duration(timeout)*time.Second)
+	}
+}
@@ -0,0 +1,117 @@
+package vanilla
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kfchen81/beego"
+)
+
+func TestHealthChecker_Run(t *testing.T) {
+	checker := &healthChecker{
+		timeout:        50 * time.Millisecond,
+		cacheTTL:       time.Minute,
+		criticalChecks: map[string]bool{"db": true},
+		checks:         make(map[string]*healthCheck),
+	}
+	calls := 0
+	checker.checks["db"] = &healthCheck{name: "db", fn: func(ctx context.Context) error {
+		calls += 1
+		return nil
+	}}
+	checker.checks["mns"] = &healthCheck{name: "mns", fn: func(ctx context.Context) error {
+		return ErrHealthCheckSkipped
+	}}
+	checker.checks["panic"] = &healthCheck{name: "panic", fn: func(ctx context.Context) error {
+		panic("boom")
+	}}
+	checker.checks["slow"] = &healthCheck{name: "slow", fn: func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}}
+
+	results := checker.run()
+	expected := map[string]string{
+		"db":    HEALTH_STATUS_OK,
+		"mns":   HEALTH_STATUS_SKIPPED,
+		"panic": HEALTH_STATUS_FAIL,
+		"slow":  HEALTH_STATUS_FAIL,
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+	for _, result := range results {
+		if result.Status != expected[result.Name] {
+			t.Errorf("%s: expected %s, got %s(%s)", result.Name, expected[result.Name], result.Status, result.Error)
+		}
+		if result.Critical != (result.Name == "db") {
+			t.Errorf("%s: wrong critical", result.Name)
+		}
+	}
+	if !hasFailedCheck(results) {
+		t.Error("should have failed check")
+	}
+
+	//结果被缓存
+	checker.run("db")
+	if calls != 1 {
+		t.Errorf("result should be cached, called %d times", calls)
+	}
+}
+
+func TestOpHealthController(t *testing.T) {
+	var body string
+	serve := func(url string) int {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest("GET", url, nil)
+		beego.BeeApp.Handlers.ServeHTTP(w, r)
+		body = w.Body.String()
+		return w.Code
+	}
+
+	RegisterHealthCheck("test_fail", func(ctx context.Context) error {
+		return errors.New("unavailable")
+	})
+	defer func() {
+		healthCheckerInstance.lock.Lock()
+		delete(healthCheckerInstance.checks, "test_fail")
+		healthCheckerInstance.lock.Unlock()
+	}()
+
+	//非关键依赖失败不影响readiness，/op/health/仍返回200，在body中报告失败
+	if code := serve("/op/health/"); code != 200 {
+		t.Errorf("/op/health/ should return 200 when a non-critical check fails, got %d", code)
+	}
+	if !strings.Contains(body, `"is_healthy": false`) || !strings.Contains(body, "test_fail") {
+		t.Errorf("/op/health/ should report the failed check, got %s", body)
+	}
+	if code := serve("/op/health/ready/"); code != 200 {
+		t.Errorf("/op/health/ready/ should return 200, got %d", code)
+	}
+	if code := serve("/op/health/live/"); code != 200 {
+		t.Errorf("/op/health/live/ should return 200, got %d", code)
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	defer atomic.StoreInt32(&healthCheckerInstance.shuttingDown, 0)
+
+	if IsShuttingDown() {
+		t.Fatal("should not be shutting down")
+	}
+	gracefulShutdown(syscall.SIGTERM, 0, time.Second)
+	if !IsShuttingDown() {
+		t.Error("should be shutting down")
+	}
+	if isReady, _ := CheckReadiness(); isReady {
+		t.Error("should not be ready when shutting down")
+	}
+}
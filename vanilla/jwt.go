@@ -112,4 +112,18 @@ func ParseUserIdFromJwtData(js *simplejson.Json) (int, int, error){
 	}
 
 	return userId, authUserId, nil
-}
\ No newline at end of file
+}
+
+// IsJwtExpired 判断jwt token是否已过期
+// 注意：EncodeJWT将过期时间写入了iat字段，无法解析时视为未过期
+func IsJwtExpired(js *simplejson.Json) bool {
+	expireStr, err := js.Get("iat").String()
+	if err != nil {
+		return false
+	}
+	expireTime, err := time.ParseInLocation("2006-01-02 15:04", expireStr, time.Local)
+	if err != nil {
+		return false
+	}
+	return time.Now().After(expireTime)
+}
@@ -0,0 +1,52 @@
+package vanilla
+
+import (
+	"fmt"
+	"github.com/kfchen81/beego"
+	"github.com/kfchen81/beego/metrics"
+	"os"
+	"strings"
+	"time"
+)
+
+// parseMetricsConstLabels 解析metrics::CONST_LABELS，格式为k1=v1,k2=v2
+func parseMetricsConstLabels(value string) map[string]string {
+	labels := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) == 2 && kv[0] != "" {
+			labels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return labels
+}
+
+func init() {
+	//metrics包不能依赖beego的配置，在这里设置
+	slowQueryThreshold := beego.AppConfig.DefaultInt("metrics::DB_SLOW_QUERY_THRESHOLD", 500) //毫秒
+	largeResultRows := beego.AppConfig.DefaultInt64("metrics::DB_LARGE_RESULT_ROWS", 1000)
+	metrics.SetDBQueryThresholds(time.Duration(slowQueryThreshold)*time.Millisecond, largeResultRows)
+
+	constLabels := parseMetricsConstLabels(beego.AppConfig.String("metrics::CONST_LABELS"))
+	if beego.AppConfig.DefaultBool("metrics::ENABLE_DEFAULT_LABELS", false) {
+		constLabels["service"] = beego.AppConfig.String("appname")
+		constLabels["pod"] = os.Getenv("HOSTNAME")
+		constLabels["k8s_env"] = os.Getenv("_K8S_ENV")
+	}
+	options := metrics.Options{
+		Namespace:    beego.AppConfig.String("metrics::NAMESPACE"),
+		ConstLabels:  constLabels,
+		PushURL:      beego.AppConfig.String("metrics::PUSH_GATEWAY"),
+		PushJob:      beego.AppConfig.DefaultString("metrics::PUSH_JOB", beego.AppConfig.String("appname")),
+		PushInterval: time.Duration(beego.AppConfig.DefaultInt("metrics::PUSH_INTERVAL", 0)) * time.Second,
+	}
+	//应用可以调用metrics.Configure覆盖按配置文件的默认配置
+	if err := metrics.ConfigureDefault(options); err != nil {
+		beego.Error(fmt.Sprintf("[metrics] configure failed: %s", err.Error()))
+	}
+
+	//默认保留旧的db_connection_pool_gauge，dashboard迁移到db_pool_*后可以关闭
+	if beego.AppConfig.DefaultBool("metrics::ENABLE_LEGACY_DB_REPORTOR", true) {
+		metrics.StartDBReportService()
+	}
+}
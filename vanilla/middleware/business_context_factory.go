@@ -1,6 +1,13 @@
 package middleware
 
 import (
+	go_context "context"
+	"errors"
+	"fmt"
+	
+	"github.com/bitly/go-simplejson"
+	"github.com/kfchen81/beego"
+	"github.com/kfchen81/beego/orm"
 	"github.com/kfchen81/beego/vanilla"
 )
 
@@ -8,4 +15,50 @@ var gBContextFactory vanilla.IBusinessContextFactory
 
 func SetBusinessContextFactory(factory vanilla.IBusinessContextFactory) {
 	gBContextFactory = factory
-}
\ No newline at end of file
+}
+
+// withCorpTenant 将corp设为orm的租户，由bCtx创建的orm只能访问该corp的数据
+// corp依次取自：gBContextFactory通过orm.WithTenant设置的租户，gBContextFactory设置的corp_id，
+// 数据中的corp_id，type 3的jwt中的corp_user.corp_id
+// 注册了租户model的服务找不到corp时返回错误，不会使用未隔离的orm
+func withCorpTenant(bCtx go_context.Context, data *simplejson.Json) (go_context.Context, error) {
+	if _, ok := orm.GetTenant(bCtx); ok {
+		return bCtx, nil
+	}
+	if corpId := bCtx.Value("corp_id"); corpId != nil && corpId != 0 && corpId != "" {
+		return orm.WithTenant(bCtx, corpId), nil
+	}
+	
+	if data != nil {
+		for _, path := range [][]string{{"corp_id"}, {"corp_user", "corp_id"}} {
+			value, ok := data.CheckGet(path[0])
+			if ok && len(path) > 1 {
+				value, ok = value.CheckGet(path[1])
+			}
+			if !ok {
+				continue
+			}
+			corpId, err := value.Int()
+			if err != nil || corpId <= 0 {
+				return bCtx, errors.New(fmt.Sprintf("invalid corp id: %v", value.Interface()))
+			}
+			return orm.WithTenant(bCtx, corpId), nil
+		}
+	}
+	
+	if orm.HasTenantModel() {
+		return bCtx, errors.New("corp is not found")
+	}
+	return bCtx, nil
+}
+
+func init() {
+	//跨租户访问记录corp和用户
+	orm.TenantAuditFunc = func(ctx go_context.Context, tenant interface{}, caller string, reason string) {
+		var userId interface{}
+		if ctx != nil {
+			userId = ctx.Value("user_id")
+		}
+		beego.Warn(fmt.Sprintf("[orm] cross tenant access of corp `%v` by user `%v` at %s: %s", tenant, userId, caller, reason))
+	}
+}
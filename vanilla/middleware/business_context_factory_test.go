@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	go_context "context"
+	"testing"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/kfchen81/beego/orm"
+)
+
+func TestWithCorpTenant(t *testing.T) {
+	//type 3的jwt中corp在corp_user中
+	js, _ := simplejson.NewJson([]byte(`{"type": 3, "user": {"uid": 1}, "corp_user": {"uid": 2, "corp_id": 3}}`))
+	bCtx, err := withCorpTenant(go_context.Background(), js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant, _ := orm.GetTenant(bCtx); tenant != 3 {
+		t.Errorf("expect tenant 3, got %v", tenant)
+	}
+
+	//gBContextFactory设置的corp优先
+	bCtx = go_context.WithValue(go_context.Background(), "corp_id", 5)
+	bCtx, err = withCorpTenant(bCtx, js)
+	if tenant, _ := orm.GetTenant(bCtx); err != nil || tenant != 5 {
+		t.Errorf("expect tenant 5, got %v, %v", tenant, err)
+	}
+
+	//无效的corp_id
+	js, _ = simplejson.NewJson([]byte(`{"type": 1, "user_id": 1, "uid": 2, "corp_id": "abc"}`))
+	if _, err := withCorpTenant(go_context.Background(), js); err == nil {
+		t.Error("invalid corp id should fail")
+	}
+	js, _ = simplejson.NewJson([]byte(`{"corp_id": 0}`))
+	if _, err := withCorpTenant(go_context.Background(), js); err == nil {
+		t.Error("zero corp id should fail")
+	}
+}
@@ -49,8 +49,14 @@ var CorpTokenAuthFilter = func(ctx *context.Context) {
 		span := vanilla.Tracer.StartSpan(operationName, ext.RPCServerOption(spanCtx))
 		bCtx = opentracing.ContextWithSpan(bCtx, span)
 		
-		//add orm
-		o := orm.NewOrmWithSpan(span)
+		//add orm, 按corp隔离数据
+		bCtx, err = withCorpTenant(bCtx, jsonData)
+		if err != nil {
+			response := vanilla.MakeErrorResponse(500, "corp_token:invalid_corp", fmt.Sprintf("无效的corp token 2 - [%s]", token))
+			ctx.Output.JSON(response, true, false)
+			return
+		}
+		o := orm.NewOrmWithContext(bCtx)
 		bCtx = go_context.WithValue(bCtx, "orm", o)
 		
 		ctx.Input.SetData("bContext", bCtx)
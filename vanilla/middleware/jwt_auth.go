@@ -110,7 +110,14 @@ var JWTAuthFilter = func(ctx *context.Context) {
 			
 			//add orm
 			bCtx = go_context.WithValue(bCtx, "jwt", jwtToken)
-			o := orm.NewOrmWithSpan(span)
+			//按corp隔离数据
+			bCtx, err = withCorpTenant(bCtx, js)
+			if err != nil {
+				response := vanilla.MakeErrorResponse(500, "jwt:invalid_corp", err.Error())
+				ctx.Output.JSON(response, true, false)
+				return
+			}
+			o := orm.NewOrmWithContext(bCtx)
 			bCtx = go_context.WithValue(bCtx, "orm", o)
 		}
 		
@@ -9,19 +9,25 @@ import (
 	go_context "context"
 )
 
+// RequestHeaderDetectFilter 确定请求模式并写入bContext，需要在认证filter之后执行，
+// 以便TEST模式的请求切换bContext中的orm
 var RequestHeaderDetectFilter = func(ctx *context.Context) {
 	reqMode := ctx.Input.Header(vanilla.REQUEST_HEADER_FORMAT)
-	if reqMode != ""{
-		v := ctx.Input.GetData("bContext")
-		var bCtx go_context.Context
-		if v == nil{
-			bCtx = go_context.Background()
-		}else{
-			bCtx = v.(go_context.Context)
+	v := ctx.Input.GetData("bContext")
+	var bCtx go_context.Context
+	if v == nil{
+		if reqMode == ""{
+			//认证filter尚未执行，不能创建bContext
+			return
 		}
+		bCtx = go_context.Background()
+	}else{
+		bCtx = v.(go_context.Context)
+	}
 
-		bCtx = go_context.WithValue(bCtx, "REQUEST_MODE", reqMode)
-		ctx.Input.SetData("bContext", bCtx)
+	bCtx = vanilla.ResolveRequestMode(bCtx, reqMode)
+	ctx.Input.SetData("bContext", bCtx)
+	if reqMode != ""{
 		beego.Info(fmt.Sprintf("set request mod: %s", reqMode))
 	}
-}
\ No newline at end of file
+}
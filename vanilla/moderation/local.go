@@ -0,0 +1,299 @@
+package moderation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kfchen81/beego"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+//LocalRule 本地文本检测规则，关键词使用DFA匹配，patterns为正则表达式
+type LocalRule struct {
+	Label      string   `json:"label"`
+	Suggestion string   `json:"suggestion"` //命中时的建议，默认为block
+	Keywords   []string `json:"keywords"`
+	Patterns   []string `json:"patterns"`
+}
+
+//LocalRuleSet 本地规则集，规则文件为json格式：
+//{"version": "1", "rules": [{"label": "ad", "suggestion": "review", "keywords": ["加微信"], "patterns": ["v[x信]\\s*[:：]?\\s*\\w{6,}"]}]}
+type LocalRuleSet struct {
+	Version string       `json:"version"`
+	Rules   []*LocalRule `json:"rules"`
+}
+
+type dfaNode struct {
+	children map[rune]*dfaNode
+	rules    []int //以该节点结尾的关键词所属的规则
+}
+
+func newDfaNode() *dfaNode {
+	return &dfaNode{
+		children: make(map[rune]*dfaNode),
+	}
+}
+
+type localPattern struct {
+	regexp *regexp.Regexp
+	rule   int
+}
+
+//localMatcher 由规则集编译得到，切换规则时整体替换
+type localMatcher struct {
+	version  string
+	rules    []*LocalRule
+	root     *dfaNode
+	patterns []*localPattern
+}
+
+func newLocalMatcher(ruleSet *LocalRuleSet) (*localMatcher, error) {
+	matcher := &localMatcher{
+		version:  ruleSet.Version,
+		rules:    make([]*LocalRule, 0, len(ruleSet.Rules)),
+		root:     newDfaNode(),
+		patterns: make([]*localPattern, 0),
+	}
+	for index, ruleOfSet := range ruleSet.Rules {
+		if ruleOfSet.Label == "" {
+			return nil, errors.New("moderation: local rule without label")
+		}
+		//复制规则，不修改调用者的规则集
+		rule := *ruleOfSet
+		if rule.Suggestion == "" {
+			rule.Suggestion = SUGGESTION_BLOCK
+		}
+		matcher.rules = append(matcher.rules, &rule)
+		if _, ok := suggestionLevels[rule.Suggestion]; !ok {
+			return nil, errors.New(fmt.Sprintf("moderation: invalid suggestion(%s) of rule(%s)", rule.Suggestion, rule.Label))
+		}
+
+		for _, keyword := range rule.Keywords {
+			node := matcher.root
+			for _, c := range keyword {
+				if isNoiseRune(c) {
+					continue
+				}
+				c = unicode.ToLower(c)
+				child, ok := node.children[c]
+				if !ok {
+					child = newDfaNode()
+					node.children[c] = child
+				}
+				node = child
+			}
+			if node != matcher.root {
+				node.rules = append(node.rules, index)
+			}
+		}
+
+		for _, pattern := range rule.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("moderation: invalid pattern(%s) of rule(%s): %s", pattern, rule.Label, err.Error()))
+			}
+			matcher.patterns = append(matcher.patterns, &localPattern{re, index})
+		}
+	}
+	return matcher, nil
+}
+
+//isNoiseRune 空白、标点与符号，匹配关键词时跳过，避免"敏 感 词"、"敏*感*词"绕过检测
+func isNoiseRune(c rune) bool {
+	return unicode.IsSpace(c) || unicode.IsPunct(c) || unicode.IsSymbol(c)
+}
+
+//match 返回命中的规则及对应的关键词
+func (this *localMatcher) match(content string) map[int][]string {
+	hits := make(map[int][]string)
+	addHit := func(rule int, hit string) {
+		for _, item := range hits[rule] {
+			if item == hit {
+				return
+			}
+		}
+		hits[rule] = append(hits[rule], hit)
+	}
+
+	runes := []rune(content)
+	for i := 0; i < len(runes); {
+		if isNoiseRune(runes[i]) {
+			i++
+			continue
+		}
+
+		//从i开始的最长匹配
+		node := this.root
+		end := -1
+		var endNode *dfaNode
+		for j := i; j < len(runes); j++ {
+			if isNoiseRune(runes[j]) {
+				continue
+			}
+			child, ok := node.children[unicode.ToLower(runes[j])]
+			if !ok {
+				break
+			}
+			node = child
+			if len(node.rules) > 0 {
+				end = j
+				endNode = node
+			}
+		}
+
+		if endNode == nil {
+			i++
+			continue
+		}
+		for _, rule := range endNode.rules {
+			addHit(rule, string(runes[i:end+1]))
+		}
+		i = end + 1
+	}
+
+	for _, pattern := range this.patterns {
+		for _, hit := range pattern.regexp.FindAllString(content, -1) {
+			addHit(pattern.rule, hit)
+		}
+	}
+	return hits
+}
+
+//LocalTextFilter 离线的文本检测provider，规则可以从文件热加载
+type LocalTextFilter struct {
+	matcher atomic.Value
+
+	lock    sync.Mutex
+	path    string
+	modTime time.Time
+}
+
+func NewLocalTextFilter() *LocalTextFilter {
+	filter := new(LocalTextFilter)
+	filter.matcher.Store(&localMatcher{root: newDfaNode()})
+	return filter
+}
+
+func (this *LocalTextFilter) Name() string {
+	return "local"
+}
+
+// SetRules 切换规则集，规则有误时保持原有规则
+func (this *LocalTextFilter) SetRules(ruleSet *LocalRuleSet) error {
+	matcher, err := newLocalMatcher(ruleSet)
+	if err != nil {
+		return err
+	}
+	this.matcher.Store(matcher)
+	beego.Info(fmt.Sprintf("[moderation] use local rules(%s), %d rules", ruleSet.Version, len(ruleSet.Rules)))
+	return nil
+}
+
+// LoadRules 从json文件加载规则集
+func (this *LocalTextFilter) LoadRules(path string) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	this.path = path
+	this.modTime = stat.ModTime()
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	ruleSet := new(LocalRuleSet)
+	if err := json.Unmarshal(content, ruleSet); err != nil {
+		return err
+	}
+	return this.SetRules(ruleSet)
+}
+
+// Watch 每隔interval检查一次规则文件，文件更新后重新加载
+func (this *LocalTextFilter) Watch(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			this.lock.Lock()
+			path, modTime := this.path, this.modTime
+			this.lock.Unlock()
+
+			stat, err := os.Stat(path)
+			if err != nil {
+				beego.Warn(fmt.Sprintf("[moderation] stat local rules failed: %s", err.Error()))
+				continue
+			}
+			if !stat.ModTime().Equal(modTime) {
+				if err := this.LoadRules(path); err != nil {
+					beego.Error(fmt.Sprintf("[moderation] reload local rules failed: %s", err.Error()))
+				}
+			}
+		}
+	}()
+}
+
+// GetVersion 获取当前规则集的版本
+func (this *LocalTextFilter) GetVersion() string {
+	return this.matcher.Load().(*localMatcher).version
+}
+
+func (this *LocalTextFilter) CheckText(content string) (*Result, error) {
+	matcher := this.matcher.Load().(*localMatcher)
+	result := NewPassResult("")
+	hits := matcher.match(content)
+	for index, rule := range matcher.rules {
+		if details, ok := hits[index]; ok {
+			result.AddLabel(&Label{
+				Scene:      "antispam",
+				Label:      rule.Label,
+				Score:      100,
+				Suggestion: rule.Suggestion,
+				Details:    details,
+			})
+		}
+	}
+	return result, nil
+}
+
+func (this *LocalTextFilter) CheckImages(urls []string) ([]*Result, error) {
+	return nil, ErrUnsupported
+}
+
+func (this *LocalTextFilter) SubmitVideo(url string) (string, error) {
+	return "", ErrUnsupported
+}
+
+func (this *LocalTextFilter) SubmitVoice(url string) (string, error) {
+	return "", ErrUnsupported
+}
+
+func (this *LocalTextFilter) QueryTask(taskId string) (*AsyncTask, error) {
+	return nil, ErrUnsupported
+}
+
+func init() {
+	RegisterProvider("local", func() (IModerationProvider, error) {
+		filter := NewLocalTextFilter()
+		path := strings.TrimSpace(beego.AppConfig.String("moderation::LOCAL_RULE_FILE"))
+		if path == "" {
+			return filter, nil
+		}
+		if err := filter.LoadRules(path); err != nil {
+			return nil, err
+		}
+		interval := beego.AppConfig.DefaultInt("moderation::LOCAL_RELOAD_INTERVAL", 0)
+		if interval > 0 {
+			filter.Watch(time.Duration(interval) * time.Second)
+		}
+		return filter, nil
+	})
+}
@@ -0,0 +1,273 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/kfchen81/beego"
+	"strings"
+	"sync"
+	"time"
+)
+
+const SUGGESTION_PASS = "pass"
+const SUGGESTION_REVIEW = "review"
+const SUGGESTION_BLOCK = "block"
+
+const TASK_STATUS_PENDING = "pending"
+const TASK_STATUS_DONE = "done"
+const TASK_STATUS_FAILED = "failed"
+
+//REVIEW_BLOCK_SCORE 建议人工审核但置信度超过该值的内容也会被拦截
+const REVIEW_BLOCK_SCORE = 90.0
+
+var ErrUnsupported = errors.New("moderation: unsupported by provider")
+
+var suggestionLevels = map[string]int{
+	SUGGESTION_PASS:   0,
+	SUGGESTION_REVIEW: 1,
+	SUGGESTION_BLOCK:  2,
+}
+
+// worseSuggestion 返回两个建议中更严重的一个
+func worseSuggestion(a string, b string) string {
+	if suggestionLevels[b] > suggestionLevels[a] {
+		return b
+	}
+	return a
+}
+
+//Label 一个检测场景的结果
+type Label struct {
+	Scene      string   `json:"scene"`      //检测场景，如porn、terrorism、antispam
+	Label      string   `json:"label"`      //结果分类，如normal、porn、ad
+	Score      float64  `json:"score"`      //置信度，0~100
+	Suggestion string   `json:"suggestion"` //pass、review或block
+	Details    []string `json:"details"`    //命中的关键词等详情
+}
+
+//Result 一条内容的检测结果
+type Result struct {
+	DataId     string   `json:"data_id"`
+	Suggestion string   `json:"suggestion"`
+	Labels     []*Label `json:"labels"`
+}
+
+func NewPassResult(dataId string) *Result {
+	return &Result{
+		DataId:     dataId,
+		Suggestion: SUGGESTION_PASS,
+		Labels:     make([]*Label, 0),
+	}
+}
+
+// AddLabel 添加场景结果，并更新整体建议
+func (this *Result) AddLabel(label *Label) {
+	this.Labels = append(this.Labels, label)
+	this.Suggestion = worseSuggestion(this.Suggestion, label.Suggestion)
+}
+
+// Merge 合并其他provider对同一内容的检测结果
+func (this *Result) Merge(other *Result) {
+	for _, label := range other.Labels {
+		this.AddLabel(label)
+	}
+	this.Suggestion = worseSuggestion(this.Suggestion, other.Suggestion)
+}
+
+// ShouldBlock 建议拦截，或建议审核且置信度超过REVIEW_BLOCK_SCORE时返回true
+func (this *Result) ShouldBlock() bool {
+	if this.Suggestion == SUGGESTION_BLOCK {
+		return true
+	}
+	for _, label := range this.Labels {
+		if label.Suggestion == SUGGESTION_REVIEW && label.Score > REVIEW_BLOCK_SCORE {
+			return true
+		}
+	}
+	return false
+}
+
+//AsyncTask 视频、语音等异步检测任务
+type AsyncTask struct {
+	TaskId string  `json:"task_id"`
+	Status string  `json:"status"`
+	Result *Result `json:"result"`
+}
+
+//IModerationProvider 内容安全检测服务，不支持的检测类型返回ErrUnsupported
+type IModerationProvider interface {
+	Name() string
+	CheckText(content string) (*Result, error)
+	CheckImages(urls []string) ([]*Result, error)
+	SubmitVideo(url string) (string, error)
+	SubmitVoice(url string) (string, error)
+	QueryTask(taskId string) (*AsyncTask, error)
+}
+
+var providerFactories = make(map[string]func() (IModerationProvider, error))
+
+// RegisterProvider 注册provider，可以在moderation::PROVIDERS中通过name使用
+func RegisterProvider(name string, factory func() (IModerationProvider, error)) {
+	if _, ok := providerFactories[name]; ok {
+		panic("moderation: RegisterProvider called twice for provider " + name)
+	}
+	providerFactories[name] = factory
+}
+
+//Moderator 组合多个provider：文本依次经过所有provider检测并合并结果，
+//图片、视频与语音由第一个支持的provider检测
+type Moderator struct {
+	providers []IModerationProvider
+}
+
+func NewModerator(providers ...IModerationProvider) *Moderator {
+	return &Moderator{
+		providers: providers,
+	}
+}
+
+// CheckText 检测文本，某个provider建议拦截时不再调用后续provider
+func (this *Moderator) CheckText(content string) (*Result, error) {
+	var result *Result
+	var lastErr error
+	for _, provider := range this.providers {
+		providerResult, err := provider.CheckText(content)
+		if err == ErrUnsupported {
+			continue
+		}
+		if err != nil {
+			beego.Error(fmt.Sprintf("[moderation] %s check text failed: %s", provider.Name(), err.Error()))
+			lastErr = err
+			continue
+		}
+		if result == nil {
+			result = providerResult
+		} else {
+			result.Merge(providerResult)
+		}
+		if result.Suggestion == SUGGESTION_BLOCK {
+			break
+		}
+	}
+
+	if result == nil {
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, ErrUnsupported
+	}
+	return result, nil
+}
+
+// CheckImages 检测图片，返回与urls一一对应的结果
+func (this *Moderator) CheckImages(urls []string) ([]*Result, error) {
+	for _, provider := range this.providers {
+		results, err := provider.CheckImages(urls)
+		if err == ErrUnsupported {
+			continue
+		}
+		return results, err
+	}
+	return nil, ErrUnsupported
+}
+
+func (this *Moderator) submit(submit func(provider IModerationProvider) (string, error)) (string, error) {
+	for _, provider := range this.providers {
+		taskId, err := submit(provider)
+		if err == ErrUnsupported {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		//任务id带上provider名，查询时据此找到provider
+		return fmt.Sprintf("%s:%s", provider.Name(), taskId), nil
+	}
+	return "", ErrUnsupported
+}
+
+// SubmitVideo 提交视频异步检测任务
+func (this *Moderator) SubmitVideo(url string) (string, error) {
+	return this.submit(func(provider IModerationProvider) (string, error) {
+		return provider.SubmitVideo(url)
+	})
+}
+
+// SubmitVoice 提交语音异步检测任务
+func (this *Moderator) SubmitVoice(url string) (string, error) {
+	return this.submit(func(provider IModerationProvider) (string, error) {
+		return provider.SubmitVoice(url)
+	})
+}
+
+// QueryTask 查询异步检测任务
+func (this *Moderator) QueryTask(taskId string) (*AsyncTask, error) {
+	items := strings.SplitN(taskId, ":", 2)
+	if len(items) != 2 {
+		return nil, errors.New(fmt.Sprintf("invalid moderation task id: %s", taskId))
+	}
+	for _, provider := range this.providers {
+		if provider.Name() == items[0] {
+			task, err := provider.QueryTask(items[1])
+			if task != nil {
+				task.TaskId = taskId
+			}
+			return task, err
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("moderation provider(%s) not found", items[0]))
+}
+
+// WaitTask 每隔interval轮询一次异步检测任务，直到任务结束或ctx取消
+func (this *Moderator) WaitTask(ctx context.Context, taskId string, interval time.Duration) (*Result, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		task, err := this.QueryTask(taskId)
+		if err != nil {
+			return nil, err
+		}
+		switch task.Status {
+		case TASK_STATUS_DONE:
+			return task.Result, nil
+		case TASK_STATUS_FAILED:
+			return nil, errors.New(fmt.Sprintf("moderation task(%s) failed", taskId))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+var defaultModerator *Moderator
+var defaultModeratorOnce sync.Once
+
+// GetModerator 获取按moderation::PROVIDERS配置的Moderator，配置为逗号分隔的provider名，默认为local
+// provider在各自包的init中注册，需要在首次调用前import对应的包
+func GetModerator() *Moderator {
+	defaultModeratorOnce.Do(func() {
+		names := beego.AppConfig.DefaultString("moderation::PROVIDERS", "local")
+		providers := make([]IModerationProvider, 0)
+		for _, name := range strings.Split(names, ",") {
+			name = strings.TrimSpace(name)
+			factory, ok := providerFactories[name]
+			if !ok {
+				beego.Error(fmt.Sprintf("[moderation] unknown provider: %s", name))
+				continue
+			}
+			provider, err := factory()
+			if err != nil {
+				beego.Error(fmt.Sprintf("[moderation] create provider(%s) failed: %s", name, err.Error()))
+				continue
+			}
+			providers = append(providers, provider)
+		}
+		beego.Info(fmt.Sprintf("[moderation] use providers: %s", names))
+		defaultModerator = NewModerator(providers...)
+	})
+	return defaultModerator
+}
@@ -0,0 +1,98 @@
+package moderation
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestFilter(t *testing.T) *LocalTextFilter {
+	filter := NewLocalTextFilter()
+	err := filter.SetRules(&LocalRuleSet{
+		Version: "test",
+		Rules: []*LocalRule{
+			{Label: "abuse", Keywords: []string{"傻瓜", "笨蛋"}},
+			{Label: "ad", Suggestion: SUGGESTION_REVIEW, Keywords: []string{"加微信"}, Patterns: []string{`[vV][xX]\d{6,}`}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filter
+}
+
+func TestLocalTextFilter(t *testing.T) {
+	filter := newTestFilter(t)
+
+	result, _ := filter.CheckText("今天天气不错")
+	if result.Suggestion != SUGGESTION_PASS || len(result.Labels) != 0 {
+		t.Errorf("should pass: %+v", result)
+	}
+
+	result, _ = filter.CheckText("你这个傻 * 瓜")
+	if result.Suggestion != SUGGESTION_BLOCK || result.Labels[0].Label != "abuse" || result.Labels[0].Details[0] != "傻 * 瓜" {
+		t.Errorf("should block: %+v", result.Labels[0])
+	}
+
+	result, _ = filter.CheckText("有需要请加微信 vx1234567")
+	if result.Suggestion != SUGGESTION_REVIEW || len(result.Labels[0].Details) != 2 {
+		t.Errorf("should review: %+v", result.Labels[0])
+	}
+
+	//不修改调用者的规则
+	rule := &LocalRule{Label: "abuse", Keywords: []string{"傻瓜"}}
+	if err := NewLocalTextFilter().SetRules(&LocalRuleSet{Rules: []*LocalRule{rule}}); err != nil {
+		t.Fatal(err)
+	}
+	if rule.Suggestion != "" {
+		t.Errorf("rule of caller should not be changed: %+v", rule)
+	}
+
+	err := filter.SetRules(&LocalRuleSet{Rules: []*LocalRule{{Label: "bad", Patterns: []string{"("}}}})
+	if err == nil || filter.GetVersion() != "test" {
+		t.Errorf("invalid rules should not be used")
+	}
+}
+
+type testProvider struct {
+	*LocalTextFilter
+	queries int
+}
+
+func (this *testProvider) Name() string {
+	return "test"
+}
+
+func (this *testProvider) SubmitVideo(url string) (string, error) {
+	return "video:1", nil
+}
+
+func (this *testProvider) QueryTask(taskId string) (*AsyncTask, error) {
+	this.queries += 1
+	if this.queries < 3 {
+		return &AsyncTask{TaskId: taskId, Status: TASK_STATUS_PENDING}, nil
+	}
+	result := NewPassResult("")
+	result.AddLabel(&Label{Scene: "porn", Label: "porn", Score: 95, Suggestion: SUGGESTION_REVIEW})
+	return &AsyncTask{TaskId: taskId, Status: TASK_STATUS_DONE, Result: result}, nil
+}
+
+func TestModerator(t *testing.T) {
+	moderator := NewModerator(newTestFilter(t), &testProvider{LocalTextFilter: NewLocalTextFilter()})
+
+	if _, err := moderator.CheckImages([]string{"http://a.jpg"}); err != ErrUnsupported {
+		t.Errorf("should be unsupported: %v", err)
+	}
+
+	taskId, err := moderator.SubmitVideo("http://a.mp4")
+	if err != nil || taskId != "test:video:1" {
+		t.Fatalf("wrong task id: %s, %v", taskId, err)
+	}
+	result, err := moderator.WaitTask(context.Background(), taskId, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Suggestion != SUGGESTION_REVIEW || !result.ShouldBlock() {
+		t.Errorf("should block: %+v", result)
+	}
+}
@@ -6,6 +6,17 @@ import (
 	"github.com/kfchen81/beego/context"
 )
 
+const PAGE_MODE_CURSOR = "cursor"
+
+//游标模式下总数的统计方式
+const PAGE_COUNT_MODE_NONE = "none"
+const PAGE_COUNT_MODE_EXACT = "exact"
+const PAGE_COUNT_MODE_ESTIMATE = "estimate"
+
+//游标模式下每页数量的默认值与最大值
+const CURSOR_DEFAULT_COUNT_PER_PAGE = 20
+const CURSOR_MAX_COUNT_PER_PAGE = 500
+
 //PageInfo 指示当前查询的数据的page信息
 type PageInfo struct {
 	Page         int
@@ -13,6 +24,8 @@ type PageInfo struct {
 	CountPerPage int
 	Mode         string
 	Direction    string
+	Cursor       string
+	CountMode    string
 }
 
 //type MobilePageInfo struct {
@@ -24,6 +37,35 @@ func (self *PageInfo) IsApiServerMode() bool {
 	return self.Mode == "apiserver"
 }
 
+func (self *PageInfo) IsCursorMode() bool {
+	return self.Mode == PAGE_MODE_CURSOR
+}
+
+//NewCursorPageInfo 创建游标模式的page信息，cursor为空时获取第一页
+//countPerPage不大于0时使用默认值，超过最大值时使用最大值
+func NewCursorPageInfo(cursor string, countPerPage int, countMode string) *PageInfo {
+	if countMode == "" {
+		countMode = PAGE_COUNT_MODE_NONE
+	}
+	return &PageInfo{
+		Page:         -1,
+		CountPerPage: clampCursorCountPerPage(countPerPage),
+		Mode:         PAGE_MODE_CURSOR,
+		Cursor:       cursor,
+		CountMode:    countMode,
+	}
+}
+
+func clampCursorCountPerPage(countPerPage int) int {
+	if countPerPage <= 0 {
+		return CURSOR_DEFAULT_COUNT_PER_PAGE
+	}
+	if countPerPage > CURSOR_MAX_COUNT_PER_PAGE {
+		return CURSOR_MAX_COUNT_PER_PAGE
+	}
+	return countPerPage
+}
+
 func (self *PageInfo) Desc() *PageInfo {
 	self.Direction = "desc"
 	if self.FromId == 0 {
@@ -47,6 +89,12 @@ func getInt(ctx *context.Context, key string, def ...int) (int, error) {
 
 //ExtractPageInfoFromRequest 从Request中抽取page信息
 func ExtractPageInfoFromRequest(ctx *context.Context) *PageInfo {
+	cursor := ctx.Input.Query("_p_cursor")
+	if cursor != "" || ctx.Input.Query("_p_mode") == PAGE_MODE_CURSOR {
+		countPerPage, _ := getInt(ctx, "_p_count", 20)
+		return NewCursorPageInfo(cursor, countPerPage, ctx.Input.Query("_p_total"))
+	}
+
 	fromParam := ctx.Input.Query("_p_from")
 	if fromParam == "" {
 		fromParam = ctx.Input.Param("_p_from")
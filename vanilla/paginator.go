@@ -1,6 +1,8 @@
 package vanilla
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 
 	//	"github.com/kfchen81/beego"
@@ -184,11 +186,77 @@ func (this APIServiceNextPageInfo) ToMap() map[string]interface{} {
 //	return nextPageInfo, err
 //}
 
+//cursorPaginate 游标模式分页，按排序键定位，不使用offset
+func cursorPaginate(objects orm.QuerySeter, page *PageInfo, container interface{}) (INextPageInfo, error) {
+	keys, err := objects.KeysetOrders()
+	if err != nil {
+		return nil, err
+	}
+	//PageInfo可能不是由NewCursorPageInfo创建的
+	countPerPage := clampCursorCountPerPage(page.CountPerPage)
+
+	nextPageInfo := &CursorNextPageInfo{
+		TotalCount: -1,
+	}
+	switch page.CountMode {
+	case PAGE_COUNT_MODE_EXACT:
+		nextPageInfo.TotalCount, err = objects.Count()
+	case PAGE_COUNT_MODE_ESTIMATE:
+		nextPageInfo.TotalCount, err = objects.CountEstimate()
+		nextPageInfo.IsEstimated = true
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if page.Cursor != "" {
+		cursor, err := DecodeCursor(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if !cursor.MatchKeys(keys) {
+			return nil, errors.New(fmt.Sprintf("cursor的排序%v与查询的排序%v不一致", cursor.Keys, keys))
+		}
+		objects, err = objects.SeekAfter(cursor.Values...)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		objects = objects.OrderBy(keys...)
+	}
+
+	//多取1个，用于判断是否有下一页
+	_, err = objects.Limit(countPerPage + 1).All(container)
+	if err != nil {
+		return nil, err
+	}
+
+	ind := reflect.Indirect(reflect.ValueOf(container))
+	if ind.Len() > countPerPage {
+		ind.Set(ind.Slice(0, countPerPage))
+		lastValues, err := objects.KeysetValues(ind.Index(countPerPage - 1).Interface())
+		if err != nil {
+			return nil, err
+		}
+		nextPageInfo.HasNext = true
+		nextPageInfo.NextCursor, err = EncodeCursor(&Cursor{
+			Keys:   keys,
+			Values: lastValues,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nextPageInfo, nil
+}
+
 //PaginateAndFill 进行分页，并获取填充数据
 func Paginate(objects orm.QuerySeter, page *PageInfo, container interface{}) (INextPageInfo, error) {
 	var err error
 	var nextPageInfo INextPageInfo
-	if page.IsApiServerMode() {
+	if page.IsCursorMode() {
+		return cursorPaginate(objects, page, container)
+	} else if page.IsApiServerMode() {
 		//多取1个，用于进行分页判断
 		_, err = objects.Limit(page.CountPerPage + 1).All(container)
 		val := reflect.ValueOf(container)
@@ -0,0 +1,121 @@
+package vanilla
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/kfchen81/beego/orm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type CursorTestItem struct {
+	Id    int
+	Score int
+}
+
+var cursorTestDBOnce sync.Once
+
+func prepareCursorTestDB(t *testing.T) orm.Ormer {
+	cursorTestDBOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "vanilla_cursor")
+		if err != nil {
+			t.Fatal(err)
+		}
+		orm.RegisterModel(new(CursorTestItem))
+		if err := orm.RegisterDataBase("default", "sqlite3", filepath.Join(dir, "cursor.db")); err != nil {
+			t.Fatal(err)
+		}
+		if err := orm.RunSyncdb("default", true, false); err != nil {
+			t.Fatal(err)
+		}
+		o := orm.NewOrm()
+		for _, score := range []int{3, 1, 3, 2, 3} {
+			if _, err := o.Insert(&CursorTestItem{Score: score}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	return orm.NewOrm()
+}
+
+func TestCursorPaginate(t *testing.T) {
+	cursorSecret = "test"
+	o := prepareCursorTestDB(t)
+
+	var ids []int
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("too many pages")
+		}
+		var items []*CursorTestItem
+		page := NewCursorPageInfo(cursor, 2, PAGE_COUNT_MODE_EXACT)
+		result, err := Paginate(o.QueryTable("cursor_test_item").OrderBy("-score"), page, &items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range items {
+			ids = append(ids, item.Id)
+		}
+		info := result.(*CursorNextPageInfo)
+		if info.TotalCount != 5 || info.IsEstimated {
+			t.Errorf("wrong total count: %+v", info)
+		}
+		if !info.HasNext {
+			break
+		}
+		cursor = info.NextCursor
+	}
+
+	expected := []int{5, 3, 1, 4, 2}
+	if len(ids) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, ids)
+		}
+	}
+
+	//排序与cursor不一致
+	var items []*CursorTestItem
+	page := NewCursorPageInfo(cursor, 2, "")
+	if _, err := Paginate(o.QueryTable("cursor_test_item").OrderBy("score"), page, &items); err == nil {
+		t.Error("cursor of other order should be rejected")
+	}
+}
+
+func TestCursorPaginate_CountPerPage(t *testing.T) {
+	cursorSecret = "test"
+	o := prepareCursorTestDB(t)
+
+	for _, page := range []*PageInfo{
+		NewCursorPageInfo("", 0, PAGE_COUNT_MODE_ESTIMATE),
+		NewCursorPageInfo("", -1, ""),
+		{Mode: PAGE_MODE_CURSOR, CountPerPage: 0},
+	} {
+		var items []*CursorTestItem
+		result, err := Paginate(o.QueryTable("cursor_test_item"), page, &items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 5 || result.(*CursorNextPageInfo).HasNext {
+			t.Errorf("count per page should be default, got %d items", len(items))
+		}
+	}
+
+	if page := NewCursorPageInfo("", 100000, ""); page.CountPerPage != CURSOR_MAX_COUNT_PER_PAGE {
+		t.Errorf("count per page should be clamped, got %d", page.CountPerPage)
+	}
+
+	var items []*CursorTestItem
+	result, err := Paginate(o.QueryTable("cursor_test_item"), NewCursorPageInfo("", 1, PAGE_COUNT_MODE_ESTIMATE), &items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := result.(*CursorNextPageInfo); !info.IsEstimated || info.TotalCount != 5 {
+		t.Errorf("wrong estimated count: %+v", info)
+	}
+}
@@ -21,7 +21,10 @@ func RecoverPanic(ctx *context.Context) {
 		//rollback tx
 		o := ctx.Input.Data()["sessionOrm"]
 		if o != nil {
-			o.(orm.Ormer).Rollback()
+			//回滚整个事务，包括未结束的嵌套事务(savepoint)
+			if err := RollbackAllTx(o.(orm.Ormer)); err != nil {
+				beego.Error(fmt.Sprintf("[ORM] rollback transaction failed: %s", err.Error()))
+			}
 			beego.Warn("[ORM] rollback transaction")
 		}
 
@@ -93,21 +96,28 @@ func RecoverPanic(ctx *context.Context) {
 		}
 
 		//return error response
+		endpoint := ctx.Request.RequestURI
+		pos := strings.Index(endpoint, "?")
+		if pos != -1 {
+			endpoint = endpoint[:pos]
+		}
 		var resp Map
-		if be, ok := err.(*BusinessError); ok {
+		if errResp, errorCode := RenderError(err); errResp != nil {
 			resp = Map{
-				"code":        500,
+				"code":        errResp.Code,
 				"data":        nil,
-				"errCode":     be.ErrCode,
-				"errMsg":      be.ErrMsg,
+				"errCode":     errResp.ErrCode,
+				"errMsg":      errResp.ErrMsg,
 				"innerErrMsg": "",
 			}
-		} else {
-			endpoint := ctx.Request.RequestURI
-			pos := strings.Index(endpoint, "?")
-			if pos != -1 {
-				endpoint = endpoint[:pos]
+			if errorCode != nil {
+				//已注册的错误码使用注册的HttpStatus作为响应的状态码
+				ctx.Output.SetStatus(int(errorCode.HttpStatus))
+				if errorCode.Alert {
+					alertErrorCode(errorCode, errResp.ErrMsg, endpoint)
+				}
 			}
+		} else {
 			resp = Map{
 				"code": 531,
 				"data": Map{
@@ -128,7 +138,9 @@ func RecoverFromCronTaskPanic(ctx go_context.Context) {
 	if err := recover(); err!=nil{
 		beego.Info("recover from cron task panic...")
 		if o != nil{
-			o.Rollback()
+			if err := RollbackAllTx(o); err != nil {
+				beego.Error(fmt.Sprintf("[ORM] rollback transaction for cron task failed: %s", err.Error()))
+			}
 			beego.Warn("[ORM] rollback transaction for cron task")
 		}
 		
@@ -2,8 +2,14 @@ package vanilla
 
 import (
 	"context"
+	"fmt"
+	"github.com/kfchen81/beego"
+	"github.com/kfchen81/beego/metrics"
+	"github.com/kfchen81/beego/orm"
 	"os"
 	"strings"
+	"sync"
+	"time"
 )
 
 var (
@@ -13,6 +19,45 @@ var (
 	REQUEST_MODE_CTX_KEY = "REQUEST_MODE"
 )
 
+//TEST模式下写入ES、ThinkingData等外部系统的策略
+const REQUEST_MODE_POLICY_TAG = "tag"   //写入，并标记为测试数据
+const REQUEST_MODE_POLICY_SKIP = "skip" //不写入
+
+//REQUEST_MODE_DATA_KEY 测试数据中标记请求模式的字段
+const REQUEST_MODE_DATA_KEY = "_request_mode"
+
+//testDBAlias TEST模式的请求使用的数据库alias，为空时与PROD使用相同的数据库
+var testDBAlias string
+//testTableSuffix TEST模式的请求使用的表名后缀，如_test，为空时与PROD使用相同的表
+var testTableSuffix string
+
+//gaiaRequestModeCache 缓存从gaia获取的请求模式，避免每个请求都访问gaia
+type gaiaRequestModeCache struct {
+	lock      sync.Mutex
+	ttl       time.Duration
+	define    string
+	expiredAt time.Time
+}
+
+func (this *gaiaRequestModeCache) get(ctx context.Context) string {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.define != "" && time.Now().Before(this.expiredAt) {
+		return this.define
+	}
+	
+	resp, err := NewResource(ctx).Get("gaia", "system.request_mode", Map{})
+	if err == nil && resp.IsSuccess() {
+		this.define = resp.Data().MustString()
+	} else {
+		this.define = REQUEST_MODE_TEST
+	}
+	this.expiredAt = time.Now().Add(this.ttl)
+	return this.define
+}
+
+var gaiaRequestMode = &gaiaRequestModeCache{ttl: 10 * time.Second}
+
 type requestMode struct {
 	define string
 }
@@ -32,22 +77,101 @@ func (this *requestMode) IsProd() bool{
 // GetRequestModeFromCtx 获取请求模式
 // 首先从ctx中获取，如果没有则进行以下判断
 // 		生产环境：默认prod
-// 		测试环境：如果请求gaia出错，则默认为test
+// 		测试环境：如果请求gaia出错，则默认为test，gaia的结果缓存request_mode::GAIA_CACHE_TTL秒
+// 经过ResolveRequestMode处理的ctx中总是有请求模式，不会再请求gaia
 func GetRequestModeFromCtx(ctx context.Context) *requestMode{
 	mode := new(requestMode)
 	mode.define = REQUEST_MODE_PROD
 	modeIf := ctx.Value(REQUEST_MODE_CTX_KEY)
 	if modeIf == nil && os.Getenv("_K8S_ENV") == "test"{
-		resp, err := NewResource(ctx).Get("gaia", "system.request_mode", Map{})
-		if err == nil && resp.IsSuccess(){
-			mode.define = resp.Data().MustString()
-		}else{
-			mode.define = REQUEST_MODE_TEST
-		}
+		mode.define = gaiaRequestMode.get(ctx)
 		return mode
 	}
 	if modeIf != nil{
 		mode.define = modeIf.(string)
 	}
 	return mode
-}
\ No newline at end of file
+}
+
+// IsTestRequest ctx是否来自TEST模式的请求，只读取ctx中已确定的请求模式
+func IsTestRequest(ctx context.Context) bool{
+	if ctx == nil{
+		return false
+	}
+	modeIf := ctx.Value(REQUEST_MODE_CTX_KEY)
+	if modeIf == nil{
+		return false
+	}
+	mode := &requestMode{define: modeIf.(string)}
+	return mode.IsTest()
+}
+
+//isValidRequestMode 请求头只允许PROD和TEST(不区分大小写)
+func isValidRequestMode(header string) bool{
+	mode := strings.ToUpper(header)
+	return mode == REQUEST_MODE_PROD || mode == REQUEST_MODE_TEST
+}
+
+// ResolveRequestMode 确定请求模式并写入ctx，每个请求只需调用一次
+// header为Request-Mode请求头，为空或不是PROD/TEST时按GetRequestModeFromCtx的规则确定
+// TEST模式的请求，ctx中的orm切换到request_mode::TEST_DB_ALIAS，并使用request_mode::TEST_TABLE_SUFFIX后缀的表
+// orm已开始事务时不能切换，需要在开始事务前调用
+func ResolveRequestMode(ctx context.Context, header string) context.Context{
+	var mode *requestMode
+	if header != "" && !isValidRequestMode(header){
+		beego.Warn(fmt.Sprintf("[request_mode] ignore invalid request mode: %q", header))
+		header = ""
+	}
+	if header != ""{
+		mode = &requestMode{define: header}
+	}else{
+		mode = GetRequestModeFromCtx(ctx)
+	}
+	modeStr := mode.String()
+	ctx = context.WithValue(ctx, REQUEST_MODE_CTX_KEY, modeStr)
+	metrics.GetRequestModeCounter().WithLabelValues(modeStr).Inc()
+
+	if mode.IsTest(){
+		if o := GetOrmFromContext(ctx); o != nil{
+			useTestDB(o)
+		}
+	}
+	return ctx
+}
+
+//useTestDB 将orm切换到TEST模式的数据库和表
+func useTestDB(o orm.Ormer){
+	if testDBAlias == "" && testTableSuffix == ""{
+		return
+	}
+	if o.InTransaction(){
+		beego.Error("[request_mode] orm is in transaction, cannot use test db")
+		return
+	}
+	if testDBAlias != ""{
+		if err := o.Using(testDBAlias); err != nil{
+			beego.Error(fmt.Sprintf("[request_mode] use db alias(%s) failed: %s", testDBAlias, err.Error()))
+		}
+	}
+	if testTableSuffix != ""{
+		if err := o.UsingTableSuffix(testTableSuffix); err != nil{
+			beego.Error(fmt.Sprintf("[request_mode] use table suffix(%s) failed: %s", testTableSuffix, err.Error()))
+		}
+	}
+}
+
+// GetTestDataPolicy 获取TEST模式下写入外部系统的策略，section为配置所在的section，如es、ta
+// 配置项为section::TEST_MODE_POLICY，默认为tag
+func GetTestDataPolicy(section string) string{
+	policy := beego.AppConfig.DefaultString(fmt.Sprintf("%s::TEST_MODE_POLICY", section), REQUEST_MODE_POLICY_TAG)
+	if policy != REQUEST_MODE_POLICY_SKIP{
+		policy = REQUEST_MODE_POLICY_TAG
+	}
+	return policy
+}
+
+func init(){
+	testDBAlias = beego.AppConfig.DefaultString("request_mode::TEST_DB_ALIAS", "")
+	testTableSuffix = beego.AppConfig.DefaultString("request_mode::TEST_TABLE_SUFFIX", "")
+	gaiaRequestMode.ttl = time.Duration(beego.AppConfig.DefaultInt("request_mode::GAIA_CACHE_TTL", 10)) * time.Second
+}
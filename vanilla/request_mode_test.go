@@ -0,0 +1,79 @@
+package vanilla
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestResolveRequestMode(t *testing.T) {
+	o := prepareCursorTestDB(t)
+	if _, err := o.Raw("CREATE TABLE IF NOT EXISTS cursor_test_item_test (id integer NOT NULL PRIMARY KEY AUTOINCREMENT, score integer NOT NULL DEFAULT 0)").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	testTableSuffix = "_test"
+	defer func() {
+		testTableSuffix = ""
+	}()
+
+	ctx := ResolveRequestMode(context.WithValue(context.Background(), "orm", o), "test")
+	if !IsTestRequest(ctx) {
+		t.Fatal("should be test request")
+	}
+	if _, err := o.Insert(&CursorTestItem{Score: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if cnt, _ := o.QueryTable("cursor_test_item").Filter("score", 100).Count(); cnt != 1 {
+		t.Errorf("test data should be in suffixed table, got %d", cnt)
+	}
+	var cnt int
+	o.Raw("SELECT COUNT(*) FROM cursor_test_item WHERE score = 100").QueryRow(&cnt)
+	if cnt != 0 {
+		t.Errorf("test data should not be in prod table, got %d", cnt)
+	}
+
+	//事务中不切换
+	prodOrm := prepareCursorTestDB(t)
+	if err := prodOrm.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	defer prodOrm.Rollback()
+	ctx = ResolveRequestMode(context.WithValue(context.Background(), "orm", prodOrm), "TEST")
+	if !IsTestRequest(ctx) {
+		t.Fatal("should be test request")
+	}
+	if cnt, _ := prodOrm.QueryTable("cursor_test_item").Filter("score", 100).Count(); cnt != 0 {
+		t.Errorf("orm in transaction should not be switched")
+	}
+
+	ctx = ResolveRequestMode(context.Background(), "prod")
+	if IsTestRequest(ctx) || GetRequestModeFromCtx(ctx).String() != REQUEST_MODE_PROD {
+		t.Error("should be prod request")
+	}
+
+	//不在白名单中的请求头被忽略
+	ctx = ResolveRequestMode(context.Background(), "hack_TEST")
+	if IsTestRequest(ctx) || GetRequestModeFromCtx(ctx).String() != REQUEST_MODE_PROD {
+		t.Error("invalid request mode should be ignored")
+	}
+}
+
+func TestGetRequestModeFromCtx_GaiaCache(t *testing.T) {
+	t.Setenv("_K8S_ENV", "test")
+	gaiaRequestMode.lock.Lock()
+	gaiaRequestMode.define = REQUEST_MODE_PROD
+	gaiaRequestMode.expiredAt = time.Now().Add(time.Minute)
+	gaiaRequestMode.lock.Unlock()
+
+	//缓存未过期时不请求gaia
+	for i := 0; i < 3; i++ {
+		if mode := GetRequestModeFromCtx(context.Background()); !mode.IsProd() {
+			t.Fatalf("should use cached mode, got %s", mode)
+		}
+	}
+
+	ctx := ResolveRequestMode(context.Background(), "")
+	if IsTestRequest(ctx) {
+		t.Error("should use cached prod mode")
+	}
+}
@@ -20,6 +20,11 @@ type Response struct {
 	MachineInfo map[string]interface{} `json:"_pod"`
 }
 
+// GetErrCode 实现context.ErrCoder，router据此记录错误码指标
+func (this *Response) GetErrCode() string {
+	return this.ErrCode
+}
+
 func MakeResponse2(data map[string]interface{}) *Response {
 	return &Response{
 		200,
@@ -42,11 +47,21 @@ func MakeResponse(data interface{}) *Response {
 	}
 }
 
+// MakeErrorResponse 创建错误响应
+// errCode已注册时，code为0则使用注册的HttpStatus，errMsg为空则使用注册的提示
 func MakeErrorResponse(code int32, errCode string, errMsg string, innerErrMsgs ...string) *Response {
 	innerErrMsg := ""
 	if len(innerErrMsgs) > 0 {
 		innerErrMsg = innerErrMsgs[0]
 	}
+	if errorCode := GetErrorCode(errCode); errorCode != nil {
+		if code == 0 {
+			code = errorCode.HttpStatus
+		}
+		if errMsg == "" {
+			errMsg = errorCode.Render(nil)
+		}
+	}
 
 	return &Response{
 		code,
@@ -395,8 +395,12 @@ func (r *RestResource) Finish() {
 		if o != nil {
 			if app, ok := r.AppController.(RestResourceInterface); ok {
 				if !app.DisableTx() {
-					o.(orm.Ormer).Commit()
-					beego.Debug("[ORM] commit transaction")
+					//逐层提交未结束的嵌套事务(savepoint)，直到最外层事务，失败时回滚整个事务
+					if err := CommitAllTx(o.(orm.Ormer)); err != nil {
+						beego.Error(fmt.Sprintf("[ORM] commit transaction failed: %s", err.Error()))
+					} else {
+						beego.Debug("[ORM] commit transaction")
+					}
 				}
 			}
 		}
@@ -7,7 +7,7 @@ import (
 	"reflect"
 
 	"github.com/kfchen81/beego"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/kfchen81/beego/metrics"
 	"os"
 )
 
@@ -86,7 +86,10 @@ func Router(r RestResourceInterface) {
 func init() {
 	beego.Router("/console/console/", &ConsoleController{})
 	beego.Router("/op/health/", &OpHealthController{})
-	beego.Handler("/metrics", promhttp.Handler())
+	beego.Router("/op/health/live/", &OpLivenessController{})
+	beego.Router("/op/health/ready/", &OpReadinessController{})
+	beego.Router("/op/error_codes/", &OpErrorCodeController{})
+	beego.Handler("/metrics", metrics.Handler())
 	beego.Router("/", &IndexController{})
 	Router(&RestProxy{})
 }
\ No newline at end of file
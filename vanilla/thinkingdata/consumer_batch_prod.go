@@ -2,6 +2,7 @@ package thinkingdata
 
 import (
 	"bytes"
+	"context"
 	"compress/gzip"
 	"encoding/base64"
 	"encoding/json"
@@ -9,6 +10,7 @@ import (
 	"fmt"
 	"github.com/kfchen81/beego"
 	"github.com/kfchen81/beego/metrics"
+	"github.com/kfchen81/beego/vanilla"
 	"io/ioutil"
 	"net/http"
 	"sync"
@@ -312,6 +314,24 @@ func Track(eventName, accountId, distinctId string, data map[string]interface{})
 	}
 }
 
+// TrackWithContext 上报事件，TEST模式的请求按ta::TEST_MODE_POLICY处理：
+// tag为事件加上request_mode属性(ta的属性名不能以_开头)，skip不上报
+func TrackWithContext(ctx context.Context, eventName, accountId, distinctId string, data map[string]interface{}){
+	if vanilla.IsTestRequest(ctx){
+		if vanilla.GetTestDataPolicy("ta") == vanilla.REQUEST_MODE_POLICY_SKIP{
+			beego.Info(fmt.Sprintf("[ta] skip event %s in test mode", eventName))
+			return
+		}
+		taggedData := make(map[string]interface{}, len(data)+1)
+		for k, v := range data{
+			taggedData[k] = v
+		}
+		taggedData["request_mode"] = vanilla.REQUEST_MODE_TEST
+		data = taggedData
+	}
+	Track(eventName, accountId, distinctId, data)
+}
+
 func init(){
 	bufferSize := beego.AppConfig.DefaultInt("ta::TA_BUFFER_SIZE", DEFAULT_PROD_BATCH_SIZE)
 	consumerCount := beego.AppConfig.DefaultInt("ta::TA_CONSUMER_COUNT", DEFAULT_CONSUMER_COUNT)
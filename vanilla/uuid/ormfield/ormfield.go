@@ -0,0 +1,60 @@
+// Package ormfield adapts the UUID and ULID of vanilla/uuid to orm fields,
+// so the uuid package itself does not depend on orm.
+//
+//	type Order struct {
+//		Id  int
+//		Uid ormfield.UUID `orm:"type(binary)"`
+//	}
+//	order := &Order{Uid: ormfield.UUID{uuid.NewV7()}}
+package ormfield
+
+import (
+	"github.com/kfchen81/beego/orm"
+	"github.com/kfchen81/beego/vanilla/uuid"
+)
+
+// UUID is an orm field of uuid.UUID, it is stored as CHAR(36),
+// or as BINARY(16) with tag `orm:"type(binary)"`
+type UUID struct {
+	uuid.UUID
+}
+
+var _ orm.BinaryFielder = new(UUID)
+
+// FieldType implements orm.Fielder.
+func (this UUID) FieldType() int {
+	return orm.TypeCharField
+}
+
+// RawValue implements orm.Fielder.
+func (this UUID) RawValue() interface{} {
+	return this.Hex()
+}
+
+// SetRaw implements orm.Fielder.
+func (this *UUID) SetRaw(value interface{}) error {
+	return this.Scan(value)
+}
+
+// ULID is an orm field of uuid.ULID, it is stored as CHAR(26),
+// or as BINARY(16) with tag `orm:"type(binary)"`
+type ULID struct {
+	uuid.ULID
+}
+
+var _ orm.BinaryFielder = new(ULID)
+
+// FieldType implements orm.Fielder.
+func (this ULID) FieldType() int {
+	return orm.TypeCharField
+}
+
+// RawValue implements orm.Fielder.
+func (this ULID) RawValue() interface{} {
+	return this.String()
+}
+
+// SetRaw implements orm.Fielder.
+func (this *ULID) SetRaw(value interface{}) error {
+	return this.Scan(value)
+}
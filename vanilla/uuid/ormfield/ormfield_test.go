@@ -0,0 +1,46 @@
+package ormfield
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kfchen81/beego/orm"
+	"github.com/kfchen81/beego/vanilla/uuid"
+)
+
+func TestUUID_Fielder(t *testing.T) {
+	id := UUID{uuid.NewV7()}
+	if id.FieldType() != orm.TypeCharField {
+		t.Errorf("field type should be char, got %d", id.FieldType())
+	}
+
+	var f UUID
+	if err := f.SetRaw(id.RawValue()); err != nil || f != id {
+		t.Errorf("set raw hex failed: %v, %v", f, err)
+	}
+	f = UUID{}
+	if err := f.SetRaw(id.RawBytes()); err != nil || f != id {
+		t.Errorf("set raw bytes failed: %v, %v", f, err)
+	}
+
+	data, _ := json.Marshal(id)
+	if string(data) != `"`+id.String()+`"` {
+		t.Errorf("json should be a string, got %s", data)
+	}
+}
+
+func TestULID_Fielder(t *testing.T) {
+	id := ULID{uuid.NewULID()}
+	if id.FieldType() != orm.TypeCharField {
+		t.Errorf("field type should be char, got %d", id.FieldType())
+	}
+
+	var f ULID
+	if err := f.SetRaw(id.RawValue()); err != nil || f != id {
+		t.Errorf("set raw string failed: %v, %v", f, err)
+	}
+	f = ULID{}
+	if err := f.SetRaw(id.RawBytes()); err != nil || f != id {
+		t.Errorf("set raw bytes failed: %v, %v", f, err)
+	}
+}
@@ -0,0 +1,217 @@
+package uuid
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// crockford base32 alphabet used by ULID
+const ulidEncoding = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var ulidDecoding [256]byte
+
+// ULID type: 48 bits unix milliseconds followed by 80 bits random,
+// the 26 characters string form sorts in the same order as the time.
+type ULID [16]byte
+
+// ulidGenerator increases the random part by one inside one millisecond,
+// so ULIDs generated by the same process are strictly increasing.
+type ulidGenerator struct {
+	lock    sync.Mutex
+	lastMs  int64
+	lastRnd [10]byte
+}
+
+var defaultULIDGenerator = &ulidGenerator{}
+
+// NewULID generates a new monotonic ULID.
+func NewULID() ULID {
+	return defaultULIDGenerator.next(time.Now())
+}
+
+func (this *ulidGenerator) next(now time.Time) ULID {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	ms := now.UnixNano() / int64(time.Millisecond)
+	if ms > this.lastMs {
+		this.lastMs = ms
+		randBytes(this.lastRnd[:])
+	} else if !incrBytes(this.lastRnd[:]) {
+		//the random part overflows, move to the next millisecond
+		this.lastMs++
+		randBytes(this.lastRnd[:])
+	}
+
+	var x ULID
+	ms = this.lastMs
+	x[0] = byte(ms >> 40)
+	x[1] = byte(ms >> 32)
+	x[2] = byte(ms >> 24)
+	x[3] = byte(ms >> 16)
+	x[4] = byte(ms >> 8)
+	x[5] = byte(ms)
+	copy(x[6:], this.lastRnd[:])
+	return x
+}
+
+// incrBytes increases a big endian number by one, returns false when it overflows.
+func incrBytes(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseULID returns a ULID based on its 26 characters string, case insensitive.
+func ParseULID(s string) (id ULID, err error) {
+	if len(s) != 26 {
+		err = errors.New("Invalid ULID length")
+		return
+	}
+	if v := ulidDecoding[s[0]]; v == 0xFF {
+		err = errors.New(fmt.Sprintf("Invalid ULID character '%c'", s[0]))
+		return
+	} else if v > 7 {
+		err = errors.New("ULID overflows 128 bits")
+		return
+	}
+
+	//decode 5 bits per character from the lowest bits
+	var bits uint
+	var acc uint16
+	pos := 15
+	for i := 25; i >= 0; i-- {
+		v := ulidDecoding[s[i]]
+		if v == 0xFF {
+			err = errors.New(fmt.Sprintf("Invalid ULID character '%c'", s[i]))
+			return
+		}
+		acc |= uint16(v) << bits
+		bits += 5
+		if bits >= 8 {
+			id[pos] = byte(acc)
+			pos--
+			acc >>= 8
+			bits -= 8
+		}
+	}
+	return
+}
+
+// MustParseULID behaves similarly to ParseULID except that it'll panic instead of
+// returning an error.
+func MustParseULID(s string) ULID {
+	id, err := ParseULID(s)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// String returns the 26 characters crockford base32 representation of the ULID.
+func (this ULID) String() string {
+	var out [26]byte
+	var bits uint
+	var acc uint16
+	pos := 25
+	for i := 15; i >= 0; i-- {
+		acc |= uint16(this[i]) << bits
+		bits += 8
+		for bits >= 5 {
+			out[pos] = ulidEncoding[acc&0x1F]
+			pos--
+			acc >>= 5
+			bits -= 5
+		}
+	}
+	out[0] = ulidEncoding[acc&0x1F]
+	return string(out[:])
+}
+
+// Time returns the timestamp of the ULID.
+func (this ULID) Time() time.Time {
+	return msToTime(this[:6])
+}
+
+// IsZero reports whether the ULID is the zero value.
+func (this ULID) IsZero() bool {
+	return this == ULID{}
+}
+
+// UUID returns the ULID as a UUID with the same 16 bytes.
+func (this ULID) UUID() UUID {
+	return UUID(this)
+}
+
+// MarshalText implements encoding.TextMarshaler, so the ULID is a string in JSON.
+func (this ULID) MarshalText() ([]byte, error) {
+	return []byte(this.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, an empty string is the zero ULID.
+func (this *ULID) UnmarshalText(b []byte) (err error) {
+	if len(b) == 0 {
+		*this = ULID{}
+		return nil
+	}
+	*this, err = ParseULID(string(b))
+	return
+}
+
+// Scan implements sql.Scanner, it accepts both BINARY(16) and CHAR columns.
+func (this *ULID) Scan(src interface{}) (err error) {
+	switch v := src.(type) {
+	case nil:
+		*this = ULID{}
+	case []byte:
+		if len(v) == 16 {
+			copy(this[:], v)
+			return nil
+		}
+		return this.UnmarshalText(v)
+	case string:
+		if len(v) == 16 {
+			copy(this[:], v)
+			return nil
+		}
+		return this.UnmarshalText([]byte(v))
+	default:
+		err = errors.New(fmt.Sprintf("uuid: can not scan %T into ULID", src))
+	}
+	return
+}
+
+// Value implements driver.Valuer, the ULID is stored as string.
+func (this ULID) Value() (driver.Value, error) {
+	return this.String(), nil
+}
+
+// RawBytes returns the 16 raw bytes of the ULID.
+func (this ULID) RawBytes() []byte {
+	return this[:]
+}
+
+func init() {
+	for i := range ulidDecoding {
+		ulidDecoding[i] = 0xFF
+	}
+	for i := 0; i < len(ulidEncoding); i++ {
+		ulidDecoding[ulidEncoding[i]] = byte(i)
+		ulidDecoding[strings.ToLower(ulidEncoding[i : i+1])[0]] = byte(i)
+	}
+	//crockford base32 aliases
+	for _, c := range "Oo" {
+		ulidDecoding[c] = 0
+	}
+	for _, c := range "IiLl" {
+		ulidDecoding[c] = 1
+	}
+}
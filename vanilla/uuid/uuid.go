@@ -2,6 +2,7 @@ package uuid
 
 import (
 	crand "crypto/rand"
+	"database/sql/driver"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -31,6 +32,21 @@ func (this UUID) Hex() string {
 
 }
 
+// String returns the same string as Hex.
+func (this UUID) String() string {
+	return this.Hex()
+}
+
+// IsZero reports whether the UUID is the zero value.
+func (this UUID) IsZero() bool {
+	return this == UUID{}
+}
+
+// Version returns the version of the UUID.
+func (this UUID) Version() int {
+	return int(this[6] >> 4)
+}
+
 // Rand generates a new version 4 UUID.
 func Rand() UUID {
 	var x [16]byte
@@ -79,6 +95,64 @@ func MustFromStr(s string) UUID {
 	return id
 }
 
+// fromBytes returns a UUID from 16 raw bytes or its string representation.
+func fromBytes(b []byte) (id UUID, err error) {
+	if len(b) == 16 {
+		copy(id[:], b)
+		return
+	}
+	return FromStr(string(b))
+}
+
+// MarshalText implements encoding.TextMarshaler, so the UUID is a string in JSON.
+func (this UUID) MarshalText() ([]byte, error) {
+	return []byte(this.Hex()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, an empty string is the zero UUID.
+func (this *UUID) UnmarshalText(b []byte) (err error) {
+	if len(b) == 0 {
+		*this = UUID{}
+		return nil
+	}
+	*this, err = FromStr(string(b))
+	return
+}
+
+// Scan implements sql.Scanner, it accepts both BINARY(16) and CHAR columns.
+func (this *UUID) Scan(src interface{}) (err error) {
+	switch v := src.(type) {
+	case nil:
+		*this = UUID{}
+	case []byte:
+		if len(v) == 0 {
+			*this = UUID{}
+			return nil
+		}
+		*this, err = fromBytes(v)
+	case string:
+		if v == "" {
+			*this = UUID{}
+			return nil
+		}
+		*this, err = fromBytes([]byte(v))
+	default:
+		err = errors.New(fmt.Sprintf("uuid: can not scan %T into UUID", src))
+	}
+	return
+}
+
+// Value implements driver.Valuer, the UUID is stored as string.
+// use RawBytes to store it in a BINARY(16) column.
+func (this UUID) Value() (driver.Value, error) {
+	return this.Hex(), nil
+}
+
+// RawBytes returns the 16 raw bytes of the UUID.
+func (this UUID) RawBytes() []byte {
+	return this[:]
+}
+
 // randBytes uses crypto random to get random numbers. If fails then it uses math random.
 func randBytes(x []byte) {
 
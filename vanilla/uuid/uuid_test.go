@@ -0,0 +1,84 @@
+package uuid
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewV7_Monotonic(t *testing.T) {
+	g := &v7Generator{}
+	now := time.Unix(1700000000, 0)
+	prev := g.next(now)
+	for i := 0; i < 10000; i++ {
+		id := g.next(now)
+		if bytes.Compare(prev[:], id[:]) >= 0 {
+			t.Fatalf("%s should be greater than %s", id, prev)
+		}
+		if id.Version() != 7 || id[8]&0xC0 != 0x80 {
+			t.Fatalf("wrong version or variant: %s", id)
+		}
+		prev = id
+	}
+	if g.next(now.Add(-time.Second)).Time().Before(now) {
+		t.Error("clock goes backwards should not break the order")
+	}
+}
+
+func TestULID_Monotonic(t *testing.T) {
+	g := &ulidGenerator{}
+	now := time.Unix(1700000000, 0)
+	prev := g.next(now)
+	for i := 0; i < 1000; i++ {
+		id := g.next(now)
+		if prev.String() >= id.String() {
+			t.Fatalf("%s should be greater than %s", id, prev)
+		}
+		prev = id
+	}
+	if !prev.Time().Equal(now) {
+		t.Errorf("expect time %s, got %s", now, prev.Time())
+	}
+}
+
+func TestULID_Parse(t *testing.T) {
+	id := NewULID()
+	parsed, err := ParseULID(id.String())
+	if err != nil || parsed != id {
+		t.Fatalf("parse %s failed: %v", id, err)
+	}
+	if _, err := ParseULID("8ZZZZZZZZZZZZZZZZZZZZZZZZZ"); err == nil {
+		t.Error("overflow ULID should fail")
+	}
+	if _, err := ParseULID("UZZZZZZZZZZZZZZZZZZZZZZZZZ"); err == nil || !strings.Contains(err.Error(), "Invalid ULID character 'U'") {
+		t.Errorf("invalid first character should fail, got %v", err)
+	}
+	if MustParseULID("7ZZZZZZZZZZZZZZZZZZZZZZZZZ") != (ULID{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}) {
+		t.Error("max ULID should be all 0xFF")
+	}
+}
+
+func TestUUID_ScanAndJSON(t *testing.T) {
+	id := NewV7()
+
+	var fromBinary, fromText UUID
+	if err := fromBinary.Scan(id.RawBytes()); err != nil || fromBinary != id {
+		t.Fatalf("scan binary failed: %v", err)
+	}
+	if err := fromText.Scan([]byte(id.Hex())); err != nil || fromText != id {
+		t.Fatalf("scan text failed: %v", err)
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{"id": id})
+	if string(data) != `{"id":"`+id.Hex()+`"}` {
+		t.Fatalf("unexpected json: %s", data)
+	}
+	var v struct {
+		Id UUID `json:"id"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil || v.Id != id {
+		t.Fatalf("unmarshal json failed: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package uuid
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// v7Generator generates version 7 UUIDs which are ordered by time.
+// rand_a (12 bits) is used as a counter inside one millisecond, so UUIDs
+// generated by the same process are strictly increasing.
+type v7Generator struct {
+	lock    sync.Mutex
+	lastMs  int64
+	counter uint16
+}
+
+var defaultV7Generator = &v7Generator{}
+
+// NewV7 generates a new version 7 UUID (RFC 9562), the first 48 bits are unix milliseconds.
+// Use it instead of Rand for primary keys, which keeps InnoDB inserts appending to the index.
+func NewV7() UUID {
+	return defaultV7Generator.next(time.Now())
+}
+
+func (this *v7Generator) next(now time.Time) UUID {
+	var x [16]byte
+	randBytes(x[6:])
+
+	this.lock.Lock()
+	ms := now.UnixNano() / int64(time.Millisecond)
+	if ms > this.lastMs {
+		//clear the highest bit, leave room for at least 2048 UUIDs in one millisecond
+		this.counter = binary.BigEndian.Uint16(x[6:8]) & 0x07FF
+		this.lastMs = ms
+	} else {
+		//same millisecond or the clock goes backwards
+		this.counter++
+		if this.counter > 0x0FFF {
+			this.lastMs++
+			this.counter = binary.BigEndian.Uint16(x[6:8]) & 0x07FF
+		}
+		ms = this.lastMs
+	}
+	counter := this.counter
+	this.lock.Unlock()
+
+	x[0] = byte(ms >> 40)
+	x[1] = byte(ms >> 32)
+	x[2] = byte(ms >> 24)
+	x[3] = byte(ms >> 16)
+	x[4] = byte(ms >> 8)
+	x[5] = byte(ms)
+	x[6] = 0x70 | byte(counter>>8)
+	x[7] = byte(counter)
+	x[8] = (x[8] & 0x3F) | 0x80
+	return x
+}
+
+// Time returns the timestamp of a version 7 UUID, zero time for other versions.
+func (this UUID) Time() time.Time {
+	if this.Version() != 7 {
+		return time.Time{}
+	}
+	return msToTime(this[:6])
+}
+
+func msToTime(b []byte) time.Time {
+	var ms int64
+	for _, c := range b {
+		ms = ms<<8 | int64(c)
+	}
+	return time.Unix(ms/1000, ms%1000*int64(time.Millisecond))
+}
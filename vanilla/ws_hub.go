@@ -0,0 +1,276 @@
+package vanilla
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kfchen81/beego"
+	"github.com/kfchen81/beego/metrics"
+	"sync"
+)
+
+//wsHubMessage 在Hub之间流转的推送消息，UserId为0时推送给topic的所有订阅者
+type wsHubMessage struct {
+	UserId int         `json:"user_id"`
+	Topic  string      `json:"topic"`
+	Data   interface{} `json:"data"`
+}
+
+//IWsHubEngine Hub的消息分发与在线状态引擎
+type IWsHubEngine interface {
+	Publish(msg *wsHubMessage) error
+	Listen(handler func(msg *wsHubMessage))
+	Join(userId int, connId string) error
+	Leave(userId int, connId string) error
+	IsOnline(userId int) bool
+}
+
+//LocalWsHubEngine 单pod的Hub引擎
+type LocalWsHubEngine struct {
+	handler func(msg *wsHubMessage)
+}
+
+func (this *LocalWsHubEngine) Publish(msg *wsHubMessage) error {
+	if this.handler != nil {
+		this.handler(msg)
+	}
+	return nil
+}
+
+func (this *LocalWsHubEngine) Listen(handler func(msg *wsHubMessage)) {
+	this.handler = handler
+}
+
+func (this *LocalWsHubEngine) Join(userId int, connId string) error {
+	return nil
+}
+
+func (this *LocalWsHubEngine) Leave(userId int, connId string) error {
+	return nil
+}
+
+func (this *LocalWsHubEngine) IsOnline(userId int) bool {
+	return false
+}
+
+//WsTopicAuthorizer 判断用户能否订阅topic
+type WsTopicAuthorizer func(userId int, topic string) bool
+
+//WsHub 管理本pod的websocket连接、订阅关系与在线状态
+type WsHub struct {
+	engine     IWsHubEngine
+	authorizer WsTopicAuthorizer
+
+	lock        sync.RWMutex
+	conns       map[string]*wsConn
+	user2conns  map[int]map[string]*wsConn
+	topic2conns map[string]map[string]*wsConn
+}
+
+func NewWsHub(engine IWsHubEngine) *WsHub {
+	hub := &WsHub{
+		engine:      engine,
+		conns:       make(map[string]*wsConn),
+		user2conns:  make(map[int]map[string]*wsConn),
+		topic2conns: make(map[string]map[string]*wsConn),
+	}
+	engine.Listen(hub.deliver)
+	return hub
+}
+
+// SetTopicAuthorizer 设置订阅鉴权函数，未设置时拒绝所有订阅
+// 允许订阅任意topic时需要显式设置返回true的函数
+func (this *WsHub) SetTopicAuthorizer(authorizer WsTopicAuthorizer) {
+	this.authorizer = authorizer
+}
+
+// PushToUser 推送事件到用户已订阅topic的所有连接，连接可以在任意pod上
+func (this *WsHub) PushToUser(userId int, topic string, data interface{}) error {
+	if userId == 0 {
+		return errors.New("invalid user id 0")
+	}
+	return this.publish(&wsHubMessage{
+		UserId: userId,
+		Topic:  topic,
+		Data:   data,
+	})
+}
+
+// Publish 推送事件到topic的所有订阅连接，连接可以在任意pod上
+func (this *WsHub) Publish(topic string, data interface{}) error {
+	return this.publish(&wsHubMessage{
+		Topic: topic,
+		Data:  data,
+	})
+}
+
+func (this *WsHub) publish(msg *wsHubMessage) error {
+	err := this.engine.Publish(msg)
+	if err != nil {
+		beego.Error(fmt.Sprintf("[restws] publish to topic(%s) failed: %s", msg.Topic, err.Error()))
+		metrics.GetRestwsPushCounter().WithLabelValues("publish_fail").Inc()
+	}
+	return err
+}
+
+// IsOnline 用户是否有打开的连接
+func (this *WsHub) IsOnline(userId int) bool {
+	this.lock.RLock()
+	_, ok := this.user2conns[userId]
+	this.lock.RUnlock()
+	if ok {
+		return true
+	}
+	return this.engine.IsOnline(userId)
+}
+
+// ConnectionCount 本pod的连接数
+func (this *WsHub) ConnectionCount() int {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return len(this.conns)
+}
+
+//deliver 将消息投递到本pod的连接
+func (this *WsHub) deliver(msg *wsHubMessage) {
+	targets := make([]*wsConn, 0)
+	this.lock.RLock()
+	subscribers := this.topic2conns[msg.Topic]
+	if msg.UserId == 0 {
+		for _, conn := range subscribers {
+			targets = append(targets, conn)
+		}
+	} else {
+		for connId, conn := range this.user2conns[msg.UserId] {
+			if _, ok := subscribers[connId]; ok {
+				targets = append(targets, conn)
+			}
+		}
+	}
+	this.lock.RUnlock()
+
+	event := &WsEvent{
+		Type:  WS_MSG_EVENT,
+		Topic: msg.Topic,
+		Data:  msg.Data,
+	}
+	for _, conn := range targets {
+		if conn.push(event) {
+			metrics.GetRestwsPushCounter().WithLabelValues("success").Inc()
+		} else {
+			metrics.GetRestwsPushCounter().WithLabelValues("drop").Inc()
+		}
+	}
+}
+
+func (this *WsHub) register(conn *wsConn) {
+	this.lock.Lock()
+	this.conns[conn.id] = conn
+	if conn.userId != 0 {
+		if _, ok := this.user2conns[conn.userId]; !ok {
+			this.user2conns[conn.userId] = make(map[string]*wsConn)
+		}
+		this.user2conns[conn.userId][conn.id] = conn
+	}
+	this.lock.Unlock()
+
+	this.touch(conn)
+}
+
+func (this *WsHub) unregister(conn *wsConn) {
+	this.lock.Lock()
+	delete(this.conns, conn.id)
+	if conns, ok := this.user2conns[conn.userId]; ok {
+		delete(conns, conn.id)
+		if len(conns) == 0 {
+			delete(this.user2conns, conn.userId)
+		}
+	}
+	for topic := range conn.topics {
+		this.removeSubscriber(topic, conn)
+	}
+	conn.topics = make(map[string]bool)
+	this.lock.Unlock()
+
+	if conn.userId != 0 {
+		if err := this.engine.Leave(conn.userId, conn.id); err != nil {
+			beego.Warn(fmt.Sprintf("[restws] leave presence failed: %s", err.Error()))
+		}
+	}
+}
+
+//touch 刷新连接的在线状态
+func (this *WsHub) touch(conn *wsConn) {
+	if conn.userId == 0 {
+		return
+	}
+	if err := this.engine.Join(conn.userId, conn.id); err != nil {
+		beego.Warn(fmt.Sprintf("[restws] refresh presence failed: %s", err.Error()))
+	}
+}
+
+func (this *WsHub) subscribe(conn *wsConn, topic string) error {
+	if topic == "" {
+		return errors.New("empty topic")
+	}
+	//未设置鉴权函数时默认拒绝，避免订阅到其他用户的topic
+	if this.authorizer == nil {
+		return errors.New(fmt.Sprintf("no topic authorizer, can not subscribe topic: %s", topic))
+	}
+	if !this.authorizer(conn.userId, topic) {
+		return errors.New(fmt.Sprintf("no permission to subscribe topic: %s", topic))
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if _, ok := conn.topics[topic]; ok {
+		return nil
+	}
+	if len(conn.topics) >= wsMaxTopicsOfConn {
+		return errors.New(fmt.Sprintf("too many topics, max is %d", wsMaxTopicsOfConn))
+	}
+	conn.topics[topic] = true
+	if _, ok := this.topic2conns[topic]; !ok {
+		this.topic2conns[topic] = make(map[string]*wsConn)
+	}
+	this.topic2conns[topic][conn.id] = conn
+	return nil
+}
+
+func (this *WsHub) unsubscribe(conn *wsConn, topic string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	delete(conn.topics, topic)
+	this.removeSubscriber(topic, conn)
+}
+
+//removeSubscriber 调用者需持有写锁
+func (this *WsHub) removeSubscriber(topic string, conn *wsConn) {
+	if conns, ok := this.topic2conns[topic]; ok {
+		delete(conns, conn.id)
+		if len(conns) == 0 {
+			delete(this.topic2conns, topic)
+		}
+	}
+}
+
+var Hub *WsHub //暴露的websocket hub
+
+func init() {
+	hubEngine := beego.AppConfig.DefaultString("restws::HUB_ENGINE", "local")
+
+	var engine IWsHubEngine
+	if hubEngine == "redis" {
+		if pool == nil {
+			beego.Warn("[restws] redis is not configured, fallback to LocalWsHubEngine")
+			engine = new(LocalWsHubEngine)
+		} else {
+			presenceTTL := beego.AppConfig.DefaultInt("restws::PRESENCE_TTL", int(2*pingPeriod.Seconds()))
+			beego.Info(fmt.Sprintf("[restws] use RedisWsHubEngine, presence_ttl(%d)", presenceTTL))
+			engine = NewRedisWsHubEngine(pool, presenceTTL)
+		}
+	} else {
+		beego.Info("[restws] use LocalWsHubEngine")
+		engine = new(LocalWsHubEngine)
+	}
+	Hub = NewWsHub(engine)
+}
@@ -0,0 +1,111 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/kfchen81/beego"
+	"github.com/kfchen81/beego/metrics"
+	"time"
+)
+
+const wsHubChannel = "restws:hub"
+const wsPresenceKeyPrefix = "restws:presence"
+
+//RedisWsHubEngine 基于redis pub/sub的Hub引擎，推送可以到达任意pod上的连接
+//在线状态使用sorted set记录，score为连接最近一次活跃的时间
+type RedisWsHubEngine struct {
+	pool        *redis.Pool
+	presenceTTL int
+}
+
+func NewRedisWsHubEngine(pool *redis.Pool, presenceTTL int) *RedisWsHubEngine {
+	return &RedisWsHubEngine{
+		pool:        pool,
+		presenceTTL: presenceTTL,
+	}
+}
+
+func (this *RedisWsHubEngine) presenceKey(userId int) string {
+	return fmt.Sprintf("%s:%d", wsPresenceKeyPrefix, userId)
+}
+
+func (this *RedisWsHubEngine) Publish(msg *wsHubMessage) error {
+	content, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c := this.pool.Get()
+	defer c.Close()
+	_, err = c.Do("PUBLISH", wsHubChannel, content)
+	return err
+}
+
+func (this *RedisWsHubEngine) Listen(handler func(msg *wsHubMessage)) {
+	go func() {
+		for {
+			err := this.listen(handler)
+			beego.Error(fmt.Sprintf("[restws] redis hub listener exit: %v", err))
+			metrics.GetRestwsErrorCounter().WithLabelValues("hub_listen").Inc()
+			time.Sleep(time.Second)
+		}
+	}()
+}
+
+func (this *RedisWsHubEngine) listen(handler func(msg *wsHubMessage)) error {
+	psc := redis.PubSubConn{Conn: this.pool.Get()}
+	defer psc.Close()
+
+	if err := psc.Subscribe(wsHubChannel); err != nil {
+		return err
+	}
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			msg := new(wsHubMessage)
+			if err := json.Unmarshal(v.Data, msg); err != nil {
+				beego.Error(fmt.Sprintf("[restws] decode hub message failed: %s", err.Error()))
+				continue
+			}
+			handler(msg)
+		case redis.Subscription:
+			beego.Info(fmt.Sprintf("[restws] %s redis channel: %s", v.Kind, v.Channel))
+		case error:
+			return v
+		}
+	}
+}
+
+func (this *RedisWsHubEngine) Join(userId int, connId string) error {
+	c := this.pool.Get()
+	defer c.Close()
+
+	key := this.presenceKey(userId)
+	c.Send("MULTI")
+	c.Send("ZADD", key, time.Now().Unix(), connId)
+	c.Send("EXPIRE", key, this.presenceTTL)
+	_, err := c.Do("EXEC")
+	return err
+}
+
+func (this *RedisWsHubEngine) Leave(userId int, connId string) error {
+	c := this.pool.Get()
+	defer c.Close()
+
+	_, err := c.Do("ZREM", this.presenceKey(userId), connId)
+	return err
+}
+
+func (this *RedisWsHubEngine) IsOnline(userId int) bool {
+	c := this.pool.Get()
+	defer c.Close()
+
+	minScore := time.Now().Unix() - int64(this.presenceTTL)
+	count, err := redis.Int(c.Do("ZCOUNT", this.presenceKey(userId), minScore, "+inf"))
+	if err != nil {
+		beego.Warn(fmt.Sprintf("[restws] query presence failed: %s", err.Error()))
+		return false
+	}
+	return count > 0
+}
@@ -1,6 +1,7 @@
 package vanilla
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/kfchen81/beego"
@@ -13,7 +14,24 @@ import (
 
 type WsResponse struct {
 	*Response
-	Rid string `json:"rid"`
+	Rid  string `json:"rid"`
+	Type string `json:"type,omitempty"`
+}
+
+func newWsSuccessResponse(rid string, data interface{}) WsResponse {
+	return WsResponse{
+		Response: MakeResponse(data),
+		Rid:      rid,
+		Type:     WS_MSG_RESPONSE,
+	}
+}
+
+func newWsErrorResponse(rid string, code int32, errCode string, errMsg string) WsResponse {
+	return WsResponse{
+		Response: MakeErrorResponse(code, errCode, errMsg),
+		Rid:      rid,
+		Type:     WS_MSG_RESPONSE,
+	}
 }
 
 type fakeResponseWriter struct{}
@@ -26,7 +44,7 @@ func (f *fakeResponseWriter) Write(b []byte) (int, error) {
 }
 func (f *fakeResponseWriter) WriteHeader(n int) {}
 
-func handleRequest(restReq RestRequest, rawCtx *beecontext.Context) (resp WsResponse) {
+func handleRequest(restReq RestRequest, conn *wsConn) (resp WsResponse) {
 	ctx := beecontext.NewContext()
 	defer func() {
 		err := recover()
@@ -34,10 +52,12 @@ func handleRequest(restReq RestRequest, rawCtx *beecontext.Context) (resp WsResp
 			resp = WsRestRecoverPanic(err, ctx, restReq)
 		}
 	}()
-	resp = mockContext(rawCtx, ctx, restReq)
+	resp = mockContext(conn, ctx, restReq)
 	if resp.Rid != "" {
 		return
 	}
+	cancel := bindRequestContext(ctx, conn.ctx)
+	defer cancel()
 
 	cr := beego.BeeApp.Handlers
 	controllerInfo, findRouter := cr.FindRouter(ctx)
@@ -74,11 +94,29 @@ func handleRequest(restReq RestRequest, rawCtx *beecontext.Context) (resp WsResp
 	execController.Finish()
 	vcData := reflect.ValueOf(execController).Elem().FieldByName("Data")
 	respData := vcData.Interface().(map[interface{}]interface{})["json"]
-	resp = WsResponse{respData.(*Response), restReq.Rid}
+	resp = WsResponse{Response: respData.(*Response), Rid: restReq.Rid}
 	return
 }
 
-func mockContext(rawCtx *beecontext.Context, ctx *beecontext.Context, restReq RestRequest) (resp WsResponse) {
+//bindRequestContext 为每个请求创建独立的business context，请求超时或连接关闭时取消
+func bindRequestContext(ctx *beecontext.Context, connCtx context.Context) context.CancelFunc {
+	data := ctx.Input.GetData("bContext")
+	if data == nil {
+		return func() {}
+	}
+	reqCtx, cancel := context.WithTimeout(data.(context.Context), wsRequestTimeout)
+	go func() {
+		select {
+		case <-connCtx.Done():
+			cancel()
+		case <-reqCtx.Done():
+		}
+	}()
+	ctx.Input.SetData("bContext", reqCtx)
+	return cancel
+}
+
+func mockContext(conn *wsConn, ctx *beecontext.Context, restReq RestRequest) (resp WsResponse) {
 	restReq.Method = strings.ToUpper(restReq.Method)
 	if !strings.HasPrefix(restReq.Path, "/") {
 		restReq.Path = fmt.Sprintf("/%s", restReq.Path)
@@ -86,6 +124,7 @@ func mockContext(rawCtx *beecontext.Context, ctx *beecontext.Context, restReq Re
 	if !strings.HasSuffix(restReq.Path, "/") {
 		restReq.Path = fmt.Sprintf("%s/", restReq.Path)
 	}
+	rawCtx := conn.rawCtx
 	rawRequest := rawCtx.Request
 	req := &http.Request{
 		URL:    &url.URL{Scheme: rawRequest.URL.Scheme, Host: rawRequest.URL.Host, Path: restReq.Path},
@@ -102,7 +141,7 @@ func mockContext(rawCtx *beecontext.Context, ctx *beecontext.Context, restReq Re
 	req.Form = formData
 
 	token := rawCtx.Input.Query("token")
-	jwtToken := rawCtx.Input.Query("_jwt")
+	jwtToken := conn.getJwt()
 	ctx.Request.Form.Set("token", token)
 	ctx.Request.Form.Set("_jwt", jwtToken)
 
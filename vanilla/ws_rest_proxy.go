@@ -1,15 +1,19 @@
 package vanilla
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/kfchen81/beego"
 	beeContext "github.com/kfchen81/beego/context"
 	"github.com/kfchen81/beego/metrics"
+	"github.com/kfchen81/beego/vanilla/uuid"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -33,6 +37,28 @@ const (
 
 	// Send pings to client with this period. Must be less than pongWait.
 	pingPeriod = (pongWait * 8) / 10
+
+	// Maximum message size allowed from the client.
+	maxMessageSize = 1 << 20
+)
+
+const (
+	WS_MSG_REQUEST     = "request"
+	WS_MSG_RESPONSE    = "response"
+	WS_MSG_SUBSCRIBE   = "subscribe"
+	WS_MSG_UNSUBSCRIBE = "unsubscribe"
+	WS_MSG_PING        = "ping"
+	WS_MSG_PONG        = "pong"
+	WS_MSG_EVENT       = "event"
+)
+
+var (
+	wsMaxConcurrency  = 16
+	wsRateLimit       = 50.0
+	wsRateBurst       = 100
+	wsSendBufferSize  = 256
+	wsRequestTimeout  = 30 * time.Second
+	wsMaxTopicsOfConn = 100
 )
 
 var upgrader = websocket.Upgrader{
@@ -56,6 +82,21 @@ type RestRequest struct {
 	Rid    string `json:"rid"`
 }
 
+// WsMessage 客户端发送的消息，Type为空时视为request
+type WsMessage struct {
+	RestRequest
+	Type  string `json:"type"`
+	Topic string `json:"topic"`
+	Jwt   string `json:"jwt"`
+}
+
+// WsEvent 服务端推送的事件
+type WsEvent struct {
+	Type  string      `json:"type"`
+	Topic string      `json:"topic"`
+	Data  interface{} `json:"data"`
+}
+
 func (this *RestProxy) Get() {
 	ws, err := upgrader.Upgrade(this.Ctx.ResponseWriter, this.Ctx.Request, nil)
 	if err != nil {
@@ -66,33 +107,206 @@ func (this *RestProxy) Get() {
 
 	metrics.GetRestwsGauge().Inc()
 
-	respChan := make(chan WsResponse)
-	stopRespCh := make(chan struct{})
-	closeCh := make(chan struct{})
+	conn := newWsConn(ws, this.Ctx)
+	Hub.register(conn)
 	defer func() {
-		close(closeCh)
-		ws.Close()
+		Hub.unregister(conn)
+		conn.close()
 		metrics.GetRestwsGauge().Dec()
 	}()
-	go writer(ws, respChan, stopRespCh, closeCh)
-	reader(ws, this.Ctx, respChan, stopRespCh)
+	go conn.writer()
+	conn.reader()
+}
+
+//wsConn 一个websocket连接
+type wsConn struct {
+	id      string
+	userId  int
+	ws      *websocket.Conn
+	rawCtx  *beeContext.Context
+	ctx     context.Context
+	cancel  context.CancelFunc
+	send    chan interface{}
+	slots   chan struct{}
+	limiter *wsRateLimiter
+
+	lock     sync.Mutex
+	jwtToken string
+
+	//topics由Hub加锁维护
+	topics map[string]bool
+
+	closeOnce sync.Once
+}
+
+func newWsConn(ws *websocket.Conn, rawCtx *beeContext.Context) *wsConn {
+	conn := &wsConn{
+		id:      uuid.Rand().Hex(),
+		ws:      ws,
+		rawCtx:  rawCtx,
+		send:    make(chan interface{}, wsSendBufferSize),
+		slots:   make(chan struct{}, wsMaxConcurrency),
+		limiter: newWsRateLimiter(wsRateLimit, wsRateBurst),
+		topics:  make(map[string]bool),
+	}
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+
+	conn.jwtToken = rawCtx.Input.Query("_jwt")
+	if data := rawCtx.Input.GetData("bContext"); data != nil {
+		bCtx := data.(context.Context)
+		if userId, ok := bCtx.Value("user_id").(int); ok {
+			conn.userId = userId
+		}
+		if jwtToken, ok := bCtx.Value("jwt").(string); ok && jwtToken != "" {
+			conn.jwtToken = jwtToken
+		}
+	}
+	//连接绑定建立时的用户，之后消息中的jwt必须属于该用户
+	if conn.userId == 0 && conn.jwtToken != "" {
+		if userId, _, err := ParseUserIdFromJwtToken(conn.jwtToken); err == nil {
+			conn.userId = userId
+		}
+	}
+	return conn
+}
+
+func (this *wsConn) getJwt() string {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.jwtToken
+}
+
+func (this *wsConn) setJwt(jwtToken string) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.jwtToken = jwtToken
+}
+
+func (this *wsConn) close() {
+	this.closeOnce.Do(func() {
+		this.cancel()
+		this.ws.Close()
+	})
+}
+
+//validateJwt 每条消息都重新校验jwt，消息中携带的同一用户的新jwt会替换连接上的jwt
+//未绑定用户的连接(如corp token连接)不接受消息中携带的jwt
+func (this *wsConn) validateJwt(jwtToken string) error {
+	if jwtToken == "" {
+		jwtToken = this.getJwt()
+	} else if this.userId == 0 {
+		return errors.New("连接未绑定用户，不能使用消息中的jwt token")
+	}
+	if jwtToken == "" {
+		//corp token连接由filter校验
+		return nil
+	}
+
+	js, err := DecodeJWT(jwtToken)
+	if err != nil {
+		return err
+	}
+	userId, _, err := ParseUserIdFromJwtData(js)
+	if err != nil {
+		return err
+	}
+	if IsJwtExpired(js) {
+		return errors.New(fmt.Sprintf("过期的jwt token - [%s]", jwtToken))
+	}
+	if userId != this.userId {
+		return errors.New(fmt.Sprintf("jwt token的用户(%d)与连接的用户(%d)不一致", userId, this.userId))
+	}
+
+	this.setJwt(jwtToken)
+	return nil
+}
+
+//reply 发送响应，发送缓冲满时阻塞，从而对handler形成背压
+func (this *wsConn) reply(v interface{}) {
+	select {
+	case <-this.ctx.Done():
+	case this.send <- v:
+	}
+}
+
+//push 推送事件，发送缓冲满时视为慢消费者并关闭连接
+func (this *wsConn) push(event *WsEvent) bool {
+	select {
+	case <-this.ctx.Done():
+		return false
+	case this.send <- event:
+		return true
+	default:
+		beego.Warn(fmt.Sprintf("[restws] close slow consumer: conn(%s) user(%d)", this.id, this.userId))
+		metrics.GetRestwsErrorCounter().WithLabelValues("slow_consumer").Inc()
+		this.close()
+		return false
+	}
 }
 
-func reader(ws *websocket.Conn, ctx *beeContext.Context, respChan chan<- WsResponse, stopRespCh chan struct{}) {
+func (this *wsConn) reader() {
+	this.ws.SetReadLimit(maxMessageSize)
+	this.ws.SetPongHandler(func(string) error {
+		this.ws.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
 	for {
-		req := new(RestRequest)
-		ws.SetReadDeadline(time.Now().Add(readWait))
-		err := ws.ReadJSON(req)
+		msg := new(WsMessage)
+		this.ws.SetReadDeadline(time.Now().Add(readWait))
+		err := this.ws.ReadJSON(msg)
 		if err != nil {
 			beego.Info("Read Error:", err)
 			metrics.GetRestwsErrorCounter().WithLabelValues("reader").Inc()
 			break
 		}
-		go handle(ctx, req, respChan, stopRespCh)
+
+		if msg.Type == "" {
+			msg.Type = WS_MSG_REQUEST
+		}
+		metrics.GetRestwsMessageCounter().WithLabelValues(msg.Type).Inc()
+
+		if !this.limiter.Allow() {
+			metrics.GetRestwsErrorCounter().WithLabelValues("rate_limit").Inc()
+			this.reply(newWsErrorResponse(msg.Rid, 429, "restws:rate_limited", "too many messages"))
+			continue
+		}
+
+		switch msg.Type {
+		case WS_MSG_REQUEST:
+			if err := this.validateJwt(msg.Jwt); err != nil {
+				metrics.GetRestwsErrorCounter().WithLabelValues("jwt").Inc()
+				this.reply(newWsErrorResponse(msg.Rid, 500, "jwt:invalid_jwt_token", err.Error()))
+				continue
+			}
+			//并发已满时停止读取新的消息
+			select {
+			case <-this.ctx.Done():
+				return
+			case this.slots <- struct{}{}:
+			}
+			go this.handle(&msg.RestRequest)
+		case WS_MSG_SUBSCRIBE:
+			if err := Hub.subscribe(this, msg.Topic); err != nil {
+				this.reply(newWsErrorResponse(msg.Rid, 500, "restws:subscribe_fail", err.Error()))
+			} else {
+				this.reply(newWsSuccessResponse(msg.Rid, Map{"topic": msg.Topic}))
+			}
+		case WS_MSG_UNSUBSCRIBE:
+			Hub.unsubscribe(this, msg.Topic)
+			this.reply(newWsSuccessResponse(msg.Rid, Map{"topic": msg.Topic}))
+		case WS_MSG_PING:
+			Hub.touch(this)
+			this.reply(&WsEvent{Type: WS_MSG_PONG})
+		default:
+			this.reply(newWsErrorResponse(msg.Rid, 500, "restws:invalid_type", fmt.Sprintf("invalid message type: %s", msg.Type)))
+		}
 	}
 }
 
-func handle(ctx *beeContext.Context, req *RestRequest, respChan chan<- WsResponse, stopRespCh chan struct{}) {
+func (this *wsConn) handle(req *RestRequest) {
+	defer func() {
+		<-this.slots
+	}()
 	defer func() {
 		err := recover()
 		if err != nil {
@@ -101,15 +315,13 @@ func handle(ctx *beeContext.Context, req *RestRequest, respChan chan<- WsRespons
 		}
 	}()
 	log(req)
-	resp := handleRequest(*req, ctx)
-	select {
-	case <-stopRespCh:
-		return
-	case respChan <- resp:
-	}
+	resp := handleRequest(*req, this)
+	resp.Type = WS_MSG_RESPONSE
+	this.reply(resp)
 }
 
-func writer(ws *websocket.Conn, respChan <-chan WsResponse, stopRespCh chan struct{}, closeCh chan struct{}) {
+func (this *wsConn) writer() {
+	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		err := recover()
 		if err != nil {
@@ -118,29 +330,71 @@ func writer(ws *websocket.Conn, respChan <-chan WsResponse, stopRespCh chan stru
 		}
 	}()
 	defer func() {
-		close(stopRespCh)
-		ws.Close()
+		ticker.Stop()
+		this.close()
 	}()
 	for {
 		select {
-		case <- closeCh:
+		case <-this.ctx.Done():
 			return
-		case resp := <-	respChan:
+		case resp := <-this.send:
 			content, err := json.Marshal(resp)
 			if err != nil {
 				beego.Error("Encode websocket data error:", err)
 				return
 			}
-			ws.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := ws.WriteMessage(websocket.TextMessage, content); err != nil {
+			this.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := this.ws.WriteMessage(websocket.TextMessage, content); err != nil {
 				beego.Info("Write Error:", err)
 				metrics.GetRestwsErrorCounter().WithLabelValues("writer").Inc()
 				return
 			}
+		case <-ticker.C:
+			this.ws.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := this.ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				beego.Info("Ping Error:", err)
+				metrics.GetRestwsErrorCounter().WithLabelValues("ping").Inc()
+				return
+			}
+			Hub.touch(this)
 		}
 	}
 }
 
+//wsRateLimiter 令牌桶限流，只在reader goroutine中使用
+type wsRateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newWsRateLimiter(rate float64, burst int) *wsRateLimiter {
+	return &wsRateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (this *wsRateLimiter) Allow() bool {
+	if this.rate <= 0 {
+		return true
+	}
+	now := time.Now()
+	this.tokens += now.Sub(this.last).Seconds() * this.rate
+	if this.tokens > this.burst {
+		this.tokens = this.burst
+	}
+	this.last = now
+	if this.tokens < 1 {
+		return false
+	}
+	this.tokens -= 1
+	return true
+}
+
 func log(req *RestRequest) {
 	now := time.Now().Format("2006-01-02 15:04:05")
 	if !strings.HasPrefix(req.Path, "/") {
@@ -149,3 +403,17 @@ func log(req *RestRequest) {
 	beego.Info(fmt.Sprintf("[%s] Method:%s Path:%s Params:%s Rid:%s",
 		now, req.Method, req.Path, req.Params, req.Rid))
 }
+
+func init() {
+	wsMaxConcurrency = beego.AppConfig.DefaultInt("restws::MAX_CONCURRENCY", wsMaxConcurrency)
+	wsRateLimit = beego.AppConfig.DefaultFloat("restws::RATE_LIMIT", wsRateLimit)
+	wsRateBurst = beego.AppConfig.DefaultInt("restws::RATE_BURST", wsRateBurst)
+	wsSendBufferSize = beego.AppConfig.DefaultInt("restws::SEND_BUFFER_SIZE", wsSendBufferSize)
+	wsRequestTimeout = time.Duration(beego.AppConfig.DefaultInt("restws::REQUEST_TIMEOUT", 30)) * time.Second
+	wsMaxTopicsOfConn = beego.AppConfig.DefaultInt("restws::MAX_TOPICS_OF_CONN", wsMaxTopicsOfConn)
+	if wsMaxConcurrency <= 0 {
+		wsMaxConcurrency = 1
+	}
+	beego.Info(fmt.Sprintf("[restws] max_concurrency(%d), rate_limit(%v), rate_burst(%d), send_buffer_size(%d), request_timeout(%v)",
+		wsMaxConcurrency, wsRateLimit, wsRateBurst, wsSendBufferSize, wsRequestTimeout))
+}
@@ -21,7 +21,10 @@ func WsRestRecoverPanic(err interface{}, ctx *context.Context, restReq RestReque
 		//rollback tx
 		o := ctx.Input.Data()["sessionOrm"]
 		if o != nil {
-			o.(orm.Ormer).Rollback()
+			//回滚整个事务，包括未结束的嵌套事务(savepoint)
+			if err := RollbackAllTx(o.(orm.Ormer)); err != nil {
+				beego.Error(fmt.Sprintf("[ORM] rollback transaction failed: %s", err.Error()))
+			}
 			beego.Warn("[ORM] rollback transaction")
 		}
 
@@ -93,16 +96,19 @@ func WsRestRecoverPanic(err interface{}, ctx *context.Context, restReq RestReque
 		}
 
 		//return error response
-		if be, ok := err.(*BusinessError); ok {
+		if errResp, errorCode := RenderError(err); errResp != nil {
 			resp = WsResponse{
 				Response: &Response{
-					Code: 500,
+					Code: errResp.Code,
 					Data: nil,
-					ErrMsg: be.ErrMsg,
-					ErrCode: be.ErrCode,
+					ErrMsg: errResp.ErrMsg,
+					ErrCode: errResp.ErrCode,
 				},
 				Rid: restReq.Rid,
 			}
+			if errorCode != nil && errorCode.Alert {
+				alertErrorCode(errorCode, errResp.ErrMsg, restReq.Path)
+			}
 		} else {
 			endpoint := ctx.Request.RequestURI
 			pos := strings.Index(endpoint, "?")
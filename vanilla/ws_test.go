@@ -0,0 +1,124 @@
+package vanilla
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestWsConn(id string, userId int) *wsConn {
+	conn := &wsConn{
+		id:     id,
+		userId: userId,
+		send:   make(chan interface{}, 4),
+		topics: make(map[string]bool),
+	}
+	conn.ctx, conn.cancel = context.WithCancel(context.Background())
+	return conn
+}
+
+func TestWsRateLimiter(t *testing.T) {
+	limiter := newWsRateLimiter(10, 2)
+	if !limiter.Allow() || !limiter.Allow() {
+		t.Fatal("burst should be allowed")
+	}
+	if limiter.Allow() {
+		t.Fatal("should be limited after burst")
+	}
+	limiter.last = limiter.last.Add(-200 * time.Millisecond)
+	if !limiter.Allow() {
+		t.Fatal("tokens should be refilled")
+	}
+
+	unlimited := newWsRateLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		if !unlimited.Allow() {
+			t.Fatal("rate 0 should not limit")
+		}
+	}
+}
+
+func TestWsHub_Push(t *testing.T) {
+	hub := NewWsHub(new(LocalWsHubEngine))
+	conn1 := newTestWsConn("c1", 1)
+	conn2 := newTestWsConn("c2", 2)
+	hub.register(conn1)
+	hub.register(conn2)
+	//未设置鉴权函数时拒绝订阅
+	if err := hub.subscribe(conn1, "order"); err == nil {
+		t.Fatal("subscribe should be denied without authorizer")
+	}
+	hub.SetTopicAuthorizer(func(userId int, topic string) bool {
+		return topic != "admin"
+	})
+	for _, conn := range []*wsConn{conn1, conn2} {
+		if err := hub.subscribe(conn, "order"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := hub.PushToUser(1, "order", "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if len(conn1.send) != 1 || len(conn2.send) != 0 {
+		t.Fatalf("push to user 1 only, got %d, %d", len(conn1.send), len(conn2.send))
+	}
+	event := (<-conn1.send).(*WsEvent)
+	if event.Topic != "order" || event.Data != "paid" {
+		t.Errorf("wrong event: %+v", event)
+	}
+
+	if err := hub.Publish("order", "closed"); err != nil {
+		t.Fatal(err)
+	}
+	if len(conn1.send) != 1 || len(conn2.send) != 1 {
+		t.Fatalf("publish to all subscribers, got %d, %d", len(conn1.send), len(conn2.send))
+	}
+
+	hub.unsubscribe(conn2, "order")
+	hub.Publish("order", "refunded")
+	if len(conn2.send) != 1 {
+		t.Errorf("unsubscribed conn should not receive event")
+	}
+
+	if err := hub.subscribe(conn1, "admin"); err == nil {
+		t.Errorf("subscribe should be denied")
+	}
+
+	hub.unregister(conn1)
+	if hub.ConnectionCount() != 1 || hub.IsOnline(1) {
+		t.Errorf("conn1 should be unregistered")
+	}
+}
+
+func TestWsConn_ValidateJwt(t *testing.T) {
+	jwt1 := EncodeJWT(Map{"type": 1, "user_id": 1, "uid": 11})
+	jwt2 := EncodeJWT(Map{"type": 1, "user_id": 2, "uid": 22})
+
+	conn := newTestWsConn("c1", 1)
+	conn.jwtToken = jwt1
+	if err := conn.validateJwt(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.validateJwt(jwt2); err == nil {
+		t.Errorf("jwt of other user should be rejected")
+	}
+	if conn.getJwt() != jwt1 {
+		t.Errorf("jwt should not be replaced")
+	}
+	if err := conn.validateJwt("invalid"); err == nil {
+		t.Errorf("invalid jwt should be rejected")
+	}
+
+	//corp token连接
+	corpConn := newTestWsConn("c2", 0)
+	if err := corpConn.validateJwt(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := corpConn.validateJwt(jwt1); err == nil {
+		t.Errorf("corp token conn should not accept jwt in message")
+	}
+	if corpConn.getJwt() != "" {
+		t.Errorf("jwt should not be bound to corp token conn")
+	}
+}